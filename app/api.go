@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -25,17 +26,43 @@ import (
 )
 
 type competitionSummary struct {
-	ID             int64     `json:"id"`
-	CompetitionID  string    `json:"competitionID"`
-	Name           string    `json:"name"`
-	Description    string    `json:"description"`
-	Host           string    `json:"host"`
-	TeamCount      int       `json:"teamCount"`
-	ContainerCount int       `json:"containerCount"`
-	NetworkCIDR    string    `json:"networkCIDR"`
-	IsPrivate      bool      `json:"isPrivate"`
-	ScoringActive  bool      `json:"scoringActive"`
-	CreatedAt      time.Time `json:"createdAt"`
+	ID              int64                    `json:"id"`
+	CompetitionID   string                   `json:"competitionID"`
+	Name            string                   `json:"name"`
+	Description     string                   `json:"description"`
+	Host            string                   `json:"host"`
+	TeamCount       int                      `json:"teamCount"`
+	ContainerCount  int                      `json:"containerCount"`
+	NetworkCIDR     string                   `json:"networkCIDR"`
+	NetworkCIDR6    string                   `json:"networkCIDR6,omitempty"`
+	IsPrivate       bool                     `json:"isPrivate"`
+	ScoringActive   bool                     `json:"scoringActive"`
+	ScoringSchedule scoringScheduleSummary   `json:"scoringSchedule"`
+	Window          competitionWindowSummary `json:"window"`
+	Provisioning    provisioningSummary      `json:"provisioning"`
+	CreatedAt       time.Time                `json:"createdAt"`
+}
+
+type provisioningSummary struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+type provisionResumeRequest struct {
+	EnableAdvancedLogging bool `json:"enableAdvancedLogging"`
+}
+
+type competitionWindowSummary struct {
+	StartsAt       time.Time `json:"startsAt"`
+	EndsAt         time.Time `json:"endsAt"`
+	FreezeMinutes  int       `json:"freezeMinutes"`
+	PowerOnAtStart bool      `json:"powerOnAtStart"`
+	FrozenAt       time.Time `json:"frozenAt"`
+}
+
+type scoringScheduleSummary struct {
+	IntervalSeconds int `json:"intervalSeconds"`
+	JitterSeconds   int `json:"jitterSeconds"`
 }
 
 type scoreboardTeam struct {
@@ -56,6 +83,7 @@ type scoreboardCheck struct {
 	ID         string `json:"id"`
 	Name       string `json:"name"`
 	Passed     bool   `json:"passed"`
+	State      string `json:"state"` // pass, fail or unknown when the scorer could not evaluate the check
 	PassPoints int    `json:"passPoints"`
 	FailPoints int    `json:"failPoints"`
 }
@@ -71,6 +99,38 @@ type scoreboardCompetition struct {
 	IsPrivate      bool             `json:"isPrivate"`
 	ScoringActive  bool             `json:"scoringActive"`
 	Teams          []scoreboardTeam `json:"teams"`
+	Hills          []scoreboardHill `json:"hills"`
+	StartsAt       time.Time        `json:"startsAt"`
+	EndsAt         time.Time        `json:"endsAt"`
+	Frozen         bool             `json:"frozen"`
+	FrozenAt       time.Time        `json:"frozenAt"`
+	Snapshot       bool             `json:"snapshot"`
+}
+
+type scoreboardHill struct {
+	ContainerName string    `json:"containerName"`
+	HillTeamID    int64     `json:"hillTeamID"`
+	HillTeamName  string    `json:"hillTeamName"`
+	KingTeamID    int64     `json:"kingTeamID"`
+	KingTeamName  string    `json:"kingTeamName"`
+	Since         time.Time `json:"since"`
+	TenureSeconds int64     `json:"tenureSeconds"`
+	PointsAwarded int       `json:"pointsAwarded"`
+}
+
+type scoreHistoryRound struct {
+	Round      int64                   `json:"round"`
+	RecordedAt time.Time               `json:"recordedAt"`
+	Teams      []scoreHistoryTeamRound `json:"teams"`
+}
+
+type scoreHistoryTeamRound struct {
+	TeamID     int64 `json:"teamID"`
+	Points     int   `json:"points"`
+	Cumulative int   `json:"cumulative"`
+	Passed     int   `json:"passed"`
+	Failed     int   `json:"failed"`
+	Unknown    int   `json:"unknown"`
 }
 
 type teamAdminSummary struct {
@@ -79,6 +139,46 @@ type teamAdminSummary struct {
 	Score       int       `json:"score"`
 	LastUpdated time.Time `json:"lastUpdated"`
 	NetworkCIDR string    `json:"networkCIDR"`
+	ClaimToken  string    `json:"claimToken"`
+	LDAPGroup   string    `json:"ldapGroup"`
+	Members     []string  `json:"members"`
+}
+
+type teamMembersRequest struct {
+	LDAPGroup string   `json:"ldapGroup"`
+	Members   []string `json:"members"`
+}
+
+type playerTeamSummary struct {
+	CompetitionID   string                  `json:"competitionID"`
+	CompetitionName string                  `json:"competitionName"`
+	TeamID          int64                   `json:"teamID"`
+	TeamName        string                  `json:"teamName"`
+	Score           int                     `json:"score"`
+	Rank            int                     `json:"rank"`
+	TeamCount       int                     `json:"teamCount"`
+	Frozen          bool                    `json:"frozen"`
+	ScoringActive   bool                    `json:"scoringActive"`
+	NetworkCIDR     string                  `json:"networkCIDR"`
+	ClaimToken      string                  `json:"claimToken"`
+	Members         []string                `json:"members"`
+	Containers      []playerContainerDetail `json:"containers"`
+	Checks          []scoreboardContainer   `json:"checks"`
+	Redeploy        koth.SelfRedeployStatus `json:"redeploy"`
+}
+
+type teamRedeployRequest struct {
+	ContainerID int64 `json:"containerID"`
+}
+
+type playerContainerDetail struct {
+	ID         int64  `json:"id"`
+	ConfigName string `json:"containerConfigName"`
+	IPv4       string `json:"ipAddress"`
+	IPv6       string `json:"ipv6Address,omitempty"`
+	Status     string `json:"status"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
 }
 
 type containerTeamSummary struct {
@@ -96,12 +196,17 @@ type containerAdminSummary struct {
 	ID          int64                        `json:"id"`
 	Name        string                       `json:"name"`
 	IPv4        string                       `json:"ipAddress"`
+	IPv6        string                       `json:"ipv6Address,omitempty"`
 	Node        string                       `json:"node"`
 	Status      string                       `json:"status"`
 	ConfigName  string                       `json:"containerConfigName"`
 	LastUpdated time.Time                    `json:"lastUpdated"`
 	Team        *containerTeamSummary        `json:"team,omitempty"`
 	Competition *containerCompetitionSummary `json:"competition,omitempty"`
+	Shared      bool                         `json:"shared"`
+
+	ExecCredentialError string     `json:"execCredentialError,omitempty"`
+	ExecCredentialSince *time.Time `json:"execCredentialSince,omitempty"`
 }
 
 type containerPowerRequest struct {
@@ -403,6 +508,13 @@ func apiCreateCompetition(c *fiber.Ctx) (err error) {
 	compReq.EnableAdvancedLogging = enableAdvancedLogging
 	ctx.logf("advanced logging: %t", enableAdvancedLogging)
 
+	if raw := strings.TrimSpace(c.FormValue("keepPartialOnFailure")); raw != "" {
+		if parsed, parseErr := strconv.ParseBool(raw); parseErr == nil {
+			compReq.KeepPartialOnFailure = parsed
+		}
+	}
+	ctx.logf("keep partial provisioning on failure: %t", compReq.KeepPartialOnFailure)
+
 	if err = validateCompetitionTemplates(&compReq); err != nil {
 		return ctx.fail(c, fiber.StatusBadRequest, "invalid container configuration", err)
 	}
@@ -414,7 +526,7 @@ func apiCreateCompetition(c *fiber.Ctx) (err error) {
 
 	ctx.logf("stored package at %s (packageID=%d)", packageRecord.StoragePath, packageRecord.ID)
 
-	var job *uploadJob = newUploadJob(user)
+	var job *uploadJob = newUploadJob(user, compReq.CompetitionID)
 	job.appendLogs(ctx.logs)
 	job.log("waiting for provisioning to start")
 
@@ -562,6 +674,142 @@ func apiGetArtifactFile(c *fiber.Ctx) (err error) {
 	return c.SendFile(targetPath, false)
 }
 
+// jobVisibleTo reports whether a user may see a persisted job: administrators see every job, everyone else sees
+// the jobs they started and their team's self-service redeploys.
+func jobVisibleTo(user *auth.AuthUser, job *db.Job, teamIDs []int64) bool {
+	if user.Permissions() >= auth.AuthPermsAdministrator || job.Owner == uploadActor(user) {
+		return true
+	}
+
+	return job.TeamID != 0 && slices.Contains(teamIDs, job.TeamID)
+}
+
+func apiListJobs(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	var (
+		kind          = strings.ToLower(strings.TrimSpace(c.Query("kind")))
+		status        = strings.ToLower(strings.TrimSpace(c.Query("status")))
+		owner         = strings.TrimSpace(c.Query("owner"))
+		competitionID = strings.TrimSpace(c.Query("competitionID"))
+		limit         = 50
+	)
+
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(limit, 500)
+	}
+
+	var teamIDs []int64
+	if teamIDs, err = db.GetUserTeamIDs(user.LDAPConn.Username, fetchUserGroups(user)); err != nil {
+		appLog.Errorf("failed to resolve teams for %s: %v\n", user.LDAPConn.Username, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load team membership")
+	}
+
+	var jobs []*db.Job
+	if jobs, err = db.GetJobs(); err != nil {
+		appLog.Errorf("failed to load jobs: %v\n", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load jobs")
+	}
+
+	payload := make([]*db.Job, 0, min(len(jobs), limit))
+	for _, job := range jobs {
+		if len(payload) >= limit {
+			break
+		}
+
+		if !jobVisibleTo(user, job, teamIDs) ||
+			(kind != "" && job.Kind != kind) ||
+			(status != "" && job.Status != status) ||
+			(owner != "" && !strings.EqualFold(job.Owner, owner)) ||
+			(competitionID != "" && !strings.EqualFold(job.CompetitionID, competitionID)) {
+			continue
+		}
+
+		payload = append(payload, job)
+	}
+
+	return c.JSON(fiber.Map{
+		"jobs": payload,
+	})
+}
+
+// apiGetJob returns a persisted job together with its full log, so finished jobs (including ones from before a
+// restart) can be replayed.
+func apiGetJob(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	job, logs, err := loadVisibleJob(user, c.Params("jobID"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"job":  job,
+		"logs": logs,
+	})
+}
+
+func loadVisibleJob(user *auth.AuthUser, jobID string) (*db.Job, []string, error) {
+	job, err := db.Jobs.Select(jobID)
+	if err != nil || job == nil {
+		return nil, nil, fiber.ErrNotFound
+	}
+
+	teamIDs, err := db.GetUserTeamIDs(user.LDAPConn.Username, fetchUserGroups(user))
+	if err != nil {
+		appLog.Errorf("failed to resolve teams for %s: %v\n", user.LDAPConn.Username, err)
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to load team membership")
+	}
+
+	if !jobVisibleTo(user, job, teamIDs) {
+		return nil, nil, fiber.ErrForbidden
+	}
+
+	lines, err := db.GetJobLogs(job.ID)
+	if err != nil {
+		appLog.Errorf("failed to load logs for job %s: %v\n", job.ID, err)
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "failed to load job logs")
+	}
+
+	logs := make([]string, 0, len(lines))
+	for _, line := range lines {
+		logs = append(logs, line.Message)
+	}
+
+	return job, logs, nil
+}
+
+// streamPersistedJob replays the stored log of a job that is no longer held in memory, e.g. after a restart, and
+// then closes the stream.
+func streamPersistedJob(c *fiber.Ctx, user *auth.AuthUser, jobID string) error {
+	_, logs, err := loadVisibleJob(user, jobID)
+	if err != nil {
+		return err
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		for _, message := range logs {
+			fmt.Fprintf(w, "data: %s\n\n", sanitizeLogMessage(message))
+		}
+		w.Flush()
+	})
+
+	return nil
+}
+
 func apiStreamUploadJob(c *fiber.Ctx) (err error) {
 	var user *auth.AuthUser = auth.IsAuthenticated(c, jwtSigningKey)
 	if user == nil {
@@ -571,7 +819,7 @@ func apiStreamUploadJob(c *fiber.Ctx) (err error) {
 	var jobID = c.Params("jobID")
 	var job *uploadJob = getUploadJob(jobID)
 	if job == nil {
-		return fiber.ErrNotFound
+		return streamPersistedJob(c, user, jobID)
 	}
 
 	if !job.canView(user) {
@@ -604,7 +852,7 @@ func apiStreamRedeployJob(c *fiber.Ctx) (err error) {
 	var jobID = c.Params("jobID")
 	var job *redeployJob = getRedeployJob(jobID)
 	if job == nil {
-		return fiber.ErrNotFound
+		return streamPersistedJob(c, user, jobID)
 	}
 
 	if !job.canView(user) {
@@ -628,6 +876,49 @@ func apiStreamRedeployJob(c *fiber.Ctx) (err error) {
 	return nil
 }
 
+// apiResumeCompetitionProvisioning provisions the containers a failed keep-partial provisioning run left missing.
+func apiResumeCompetitionProvisioning(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	}
+
+	var payload provisionResumeRequest
+	if len(c.Body()) > 0 {
+		if err = c.BodyParser(&payload); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+		}
+	}
+
+	var comp *db.Competition
+	if comp, err = loadCompetitionByIdentifier(c.Params("competitionID")); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competition")
+	}
+	if comp == nil {
+		return fiber.ErrNotFound
+	}
+
+	if err = koth.ClaimProvisioningResume(comp); err != nil {
+		if errors.Is(err, koth.ErrProvisioningNotResumable) {
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		appLog.Errorf("failed to claim provisioning resume for %s: %v\n", comp.SystemID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to resume provisioning")
+	}
+
+	job := newUploadJob(user, comp.SystemID)
+	startProvisioningResumeJob(job, comp, payload.EnableAdvancedLogging)
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("provisioning resume queued (%s)", job.ID),
+		"jobID":   job.ID,
+	})
+}
+
 func apiStreamTeardownJob(c *fiber.Ctx) (err error) {
 	var user *auth.AuthUser = auth.IsAuthenticated(c, jwtSigningKey)
 	if user == nil {
@@ -637,7 +928,7 @@ func apiStreamTeardownJob(c *fiber.Ctx) (err error) {
 	var jobID = c.Params("jobID")
 	var job *teardownJob = getTeardownJob(jobID)
 	if job == nil {
-		return fiber.ErrNotFound
+		return streamPersistedJob(c, user, jobID)
 	}
 
 	if !job.canView(user) {
@@ -729,10 +1020,14 @@ func apiSetCompetitionScoring(c *fiber.Ctx) (err error) {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
 	}
 
-	comp.ScoringActive = payload.Active
-	if err = db.Competitions.Update(comp); err != nil {
-		appLog.Errorf("failed to update scoring flag for %s: %v\n", comp.SystemID, err)
+	if comp, err = db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+		current.ScoringActive = payload.Active
+		return true
+	}); err != nil {
+		appLog.Errorf("failed to update scoring flag for %s: %v\n", identifier, err)
 		return fiber.NewError(fiber.StatusInternalServerError, "failed to update competition")
+	} else if comp == nil {
+		return fiber.ErrNotFound
 	}
 
 	action := "paused"
@@ -746,7 +1041,12 @@ func apiSetCompetitionScoring(c *fiber.Ctx) (err error) {
 	})
 }
 
-func apiListContainers(c *fiber.Ctx) (err error) {
+type scoringScheduleRequest struct {
+	IntervalSeconds int `json:"intervalSeconds"`
+	JitterSeconds   int `json:"jitterSeconds"`
+}
+
+func apiSetCompetitionScoringSchedule(c *fiber.Ctx) (err error) {
 	user := auth.IsAuthenticated(c, jwtSigningKey)
 	if user == nil {
 		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
@@ -756,109 +1056,317 @@ func apiListContainers(c *fiber.Ctx) (err error) {
 		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
 	}
 
-	var (
-		filter = strings.TrimSpace(c.Query("competition"))
-		comps  []*db.Competition
-	)
-
-	if filter != "" {
-		var comp *db.Competition
-		if comp, err = loadCompetitionByIdentifier(filter); err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "failed to load competition")
-		}
-
-		if comp == nil {
-			return fiber.ErrNotFound
-		}
+	identifier := strings.TrimSpace(c.Params("competitionID"))
+	if identifier == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "competition identifier required")
+	}
 
-		comps = []*db.Competition{comp}
-	} else {
-		if comps, err = db.Competitions.SelectAll(); err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "failed to load competitions")
-		}
+	var comp *db.Competition
+	if comp, err = loadCompetitionByIdentifier(identifier); err != nil {
+		appLog.Errorf("failed to resolve competition %q: %v\n", identifier, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competition")
 	}
 
-	if len(comps) == 0 {
-		return c.JSON(fiber.Map{"containers": []containerAdminSummary{}})
+	if comp == nil {
+		return fiber.ErrNotFound
 	}
 
-	ctSet := make(map[int64]struct{})
-	for _, comp := range comps {
-		if comp == nil {
-			continue
-		}
-		for _, id := range comp.ContainerIDs {
-			if id <= 0 {
-				continue
-			}
-			ctSet[id] = struct{}{}
-		}
+	var payload scoringScheduleRequest
+	if err = c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
 	}
 
-	if len(ctSet) == 0 {
-		return c.JSON(fiber.Map{"containers": []containerAdminSummary{}})
+	var interval, jitter int
+	if interval, jitter, err = koth.NormalizeScoringSchedule(payload.IntervalSeconds, payload.JitterSeconds); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	teamIDs := make(map[int64]struct{})
-	for _, comp := range comps {
-		for _, teamID := range comp.TeamIDs {
-			teamIDs[teamID] = struct{}{}
-		}
+	if comp, err = db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+		current.ScoringIntervalSeconds = interval
+		current.ScoringJitterSeconds = jitter
+		return true
+	}); err != nil {
+		appLog.Errorf("failed to update scoring schedule for %s: %v\n", identifier, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to update competition")
+	} else if comp == nil {
+		return fiber.ErrNotFound
 	}
 
-	teamLookup := make(map[int64]*db.Team, len(teamIDs))
-	if len(teamIDs) > 0 {
-		var teams []*db.Team
-		if teams, err = db.Teams.SelectAll(); err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "failed to load teams")
-		}
+	koth.ReconfigureScoringSchedule(comp.SystemID)
 
-		for _, team := range teams {
-			if team == nil {
-				continue
-			}
-			if _, needed := teamIDs[team.ID]; needed {
-				teamLookup[team.ID] = team
-			}
-		}
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("scoring every %ds ±%ds for %s", interval, jitter, comp.SystemID),
+		"scoringSchedule": scoringScheduleSummary{
+			IntervalSeconds: interval,
+			JitterSeconds:   jitter,
+		},
+	})
+}
+
+type competitionWindowRequest struct {
+	StartsAt       string `json:"startsAt"`
+	EndsAt         string `json:"endsAt"`
+	FreezeMinutes  int    `json:"freezeMinutes"`
+	PowerOnAtStart bool   `json:"powerOnAtStart"`
+}
+
+func apiSetCompetitionWindow(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
 	}
 
-	ctToTeam := make(map[int64]*db.Team)
-	for _, team := range teamLookup {
-		for _, ctID := range team.ContainerIDs {
-			if _, needed := ctSet[ctID]; needed {
-				ctToTeam[ctID] = team
-			}
-		}
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
 	}
 
-	ctToComp := make(map[int64]*db.Competition)
-	for _, comp := range comps {
-		if comp == nil {
-			continue
-		}
-		for _, ctID := range comp.ContainerIDs {
-			if _, needed := ctSet[ctID]; needed {
-				ctToComp[ctID] = comp
-			}
-		}
+	identifier := strings.TrimSpace(c.Params("competitionID"))
+	if identifier == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "competition identifier required")
 	}
 
-	var runtime map[int64]koth.ContainerRuntime
-	var runtimeErr error
-	ctIDs := make([]int64, 0, len(ctSet))
-	for id := range ctSet {
-		ctIDs = append(ctIDs, id)
+	var comp *db.Competition
+	if comp, err = loadCompetitionByIdentifier(identifier); err != nil {
+		appLog.Errorf("failed to resolve competition %q: %v\n", identifier, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competition")
 	}
 
-	if runtime, runtimeErr = koth.ContainerRuntimeSnapshot(ctIDs); runtimeErr != nil {
-		runtime = make(map[int64]koth.ContainerRuntime)
-		appLog.Errorf("failed to fetch container runtime data: %v\n", runtimeErr)
+	if comp == nil {
+		return fiber.ErrNotFound
 	}
 
-	allContainers, err := db.Containers.SelectAll()
-	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to load containers")
+	var payload competitionWindowRequest
+	if err = c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	}
+
+	var startsAt, endsAt time.Time
+	if startsAt, err = parseHistoryTime(payload.StartsAt); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "startsAt must be an RFC3339 timestamp or unix seconds")
+	}
+	if endsAt, err = parseHistoryTime(payload.EndsAt); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "endsAt must be an RFC3339 timestamp or unix seconds")
+	}
+
+	if err = koth.ValidateCompetitionWindow(startsAt, endsAt, payload.FreezeMinutes); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if comp, err = db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+		// Moving a start or end time re-arms that transition; one already in the past fires on the scheduler's next pass.
+		if !startsAt.Equal(current.StartsAt) {
+			current.StartApplied = false
+		}
+		if !endsAt.Equal(current.EndsAt) {
+			current.EndApplied = false
+		}
+
+		current.StartsAt = startsAt
+		current.EndsAt = endsAt
+		current.FreezeMinutes = payload.FreezeMinutes
+		current.PowerOnAtStart = payload.PowerOnAtStart
+		return true
+	}); err != nil {
+		appLog.Errorf("failed to update schedule for %s: %v\n", identifier, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to update competition")
+	} else if comp == nil {
+		return fiber.ErrNotFound
+	}
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("schedule updated for %s", comp.SystemID),
+		"window":  summarizeCompetition(comp).Window,
+	})
+}
+
+func apiGetCompetitionFirewall(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	}
+
+	identifier := strings.TrimSpace(c.Params("competitionID"))
+	if identifier == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "competition identifier required")
+	}
+
+	var comp *db.Competition
+	if comp, err = loadCompetitionByIdentifier(identifier); err != nil {
+		appLog.Errorf("failed to resolve competition %q: %v\n", identifier, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competition")
+	}
+
+	if comp == nil {
+		return fiber.ErrNotFound
+	}
+
+	return c.JSON(fiber.Map{
+		"firewall": comp.Firewall,
+	})
+}
+
+func apiSetCompetitionFirewall(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	}
+
+	identifier := strings.TrimSpace(c.Params("competitionID"))
+	if identifier == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "competition identifier required")
+	}
+
+	var comp *db.Competition
+	if comp, err = loadCompetitionByIdentifier(identifier); err != nil {
+		appLog.Errorf("failed to resolve competition %q: %v\n", identifier, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competition")
+	}
+
+	if comp == nil {
+		return fiber.ErrNotFound
+	}
+
+	var payload db.FirewallPolicy
+	if err = c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	}
+
+	if _, err = koth.NormalizeFirewallPolicy(payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err = koth.UpdateCompetitionFirewall(comp, payload); err != nil {
+		appLog.Errorf("failed to apply firewall policy for %s: %v\n", comp.SystemID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("failed to apply firewall policy: %v", err))
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  fmt.Sprintf("firewall policy applied to %s", comp.SystemID),
+		"firewall": comp.Firewall,
+	})
+}
+
+func apiListContainers(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	}
+
+	var (
+		filter = strings.TrimSpace(c.Query("competition"))
+		comps  []*db.Competition
+	)
+
+	if filter != "" {
+		var comp *db.Competition
+		if comp, err = loadCompetitionByIdentifier(filter); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to load competition")
+		}
+
+		if comp == nil {
+			return fiber.ErrNotFound
+		}
+
+		comps = []*db.Competition{comp}
+	} else {
+		if comps, err = db.Competitions.SelectAll(); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to load competitions")
+		}
+	}
+
+	if len(comps) == 0 {
+		return c.JSON(fiber.Map{"containers": []containerAdminSummary{}})
+	}
+
+	ctSet := make(map[int64]struct{})
+	for _, comp := range comps {
+		if comp == nil {
+			continue
+		}
+		for _, id := range comp.ContainerIDs {
+			if id <= 0 {
+				continue
+			}
+			ctSet[id] = struct{}{}
+		}
+	}
+
+	if len(ctSet) == 0 {
+		return c.JSON(fiber.Map{"containers": []containerAdminSummary{}})
+	}
+
+	teamIDs := make(map[int64]struct{})
+	for _, comp := range comps {
+		for _, teamID := range comp.TeamIDs {
+			teamIDs[teamID] = struct{}{}
+		}
+	}
+
+	teamLookup := make(map[int64]*db.Team, len(teamIDs))
+	if len(teamIDs) > 0 {
+		var teams []*db.Team
+		if teams, err = db.Teams.SelectAll(); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to load teams")
+		}
+
+		for _, team := range teams {
+			if team == nil {
+				continue
+			}
+			if _, needed := teamIDs[team.ID]; needed {
+				teamLookup[team.ID] = team
+			}
+		}
+	}
+
+	ctToTeam := make(map[int64]*db.Team)
+	for _, team := range teamLookup {
+		for _, ctID := range team.ContainerIDs {
+			if _, needed := ctSet[ctID]; needed {
+				ctToTeam[ctID] = team
+			}
+		}
+	}
+
+	ctToComp := make(map[int64]*db.Competition)
+	for _, comp := range comps {
+		if comp == nil {
+			continue
+		}
+		for _, ctID := range comp.ContainerIDs {
+			if _, needed := ctSet[ctID]; needed {
+				ctToComp[ctID] = comp
+			}
+		}
+	}
+
+	var runtime map[int64]koth.ContainerRuntime
+	var runtimeErr error
+	ctIDs := make([]int64, 0, len(ctSet))
+	for id := range ctSet {
+		ctIDs = append(ctIDs, id)
+	}
+
+	if runtime, runtimeErr = koth.ContainerRuntimeSnapshot(ctIDs); runtimeErr != nil {
+		runtime = make(map[int64]koth.ContainerRuntime)
+		appLog.Errorf("failed to fetch container runtime data: %v\n", runtimeErr)
+	}
+
+	allContainers, err := db.Containers.SelectAll()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load containers")
 	}
 
 	recordLookup := make(map[int64]*db.Container)
@@ -895,10 +1403,18 @@ func apiListContainers(c *fiber.Ctx) (err error) {
 			ID:          record.PVEID,
 			Name:        name,
 			IPv4:        record.IPAddress,
+			IPv6:        record.IPv6Address,
 			Status:      status,
 			Node:        rt.Node,
 			ConfigName:  record.ConfigName,
 			LastUpdated: record.LastUpdated,
+			Shared:      team == nil && record.TeamID == 0 && comp != nil,
+		}
+
+		if record.ExecCredentialError != "" {
+			since := record.ExecCredentialSince
+			summary.ExecCredentialError = record.ExecCredentialError
+			summary.ExecCredentialSince = &since
 		}
 
 		if team != nil {
@@ -949,12 +1465,38 @@ func apiListContainers(c *fiber.Ctx) (err error) {
 	})
 }
 
-type teamScoreMutationRequest struct {
-	Action string `json:"action"`
-	Amount int    `json:"amount"`
+// apiGetConsoleStats reports how often container console execution reused PVE tickets and logged-in consoles.
+func apiGetConsoleStats(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	}
+
+	return c.JSON(koth.ConsoleStats())
 }
 
-func apiGetCompetitionTeams(c *fiber.Ctx) (err error) {
+type scoringHealthPass struct {
+	Round      int64                `json:"round"`
+	RecordedAt time.Time            `json:"recordedAt"`
+	Issues     []scoringHealthIssue `json:"issues"`
+}
+
+type scoringHealthIssue struct {
+	TeamID        int64  `json:"teamID"`
+	TeamName      string `json:"teamName"`
+	ContainerName string `json:"containerName"`
+	Source        string `json:"source"`
+	Message       string `json:"message"`
+	UnknownChecks int    `json:"unknownChecks"`
+}
+
+// apiGetScoringHealth lists the scorer infrastructure errors of a competition's recent scoring passes, newest first.
+// Passes without errors are omitted; the optional rounds query parameter sets how many passes back to look.
+func apiGetScoringHealth(c *fiber.Ctx) (err error) {
 	user := auth.IsAuthenticated(c, jwtSigningKey)
 	if user == nil {
 		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
@@ -979,45 +1521,391 @@ func apiGetCompetitionTeams(c *fiber.Ctx) (err error) {
 		return fiber.ErrNotFound
 	}
 
-	summaries := make([]teamAdminSummary, 0, len(comp.TeamIDs))
-	for teamIndex, teamID := range comp.TeamIDs {
-		var team *db.Team
-		if team, err = db.Teams.Select(teamID); err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "failed to load teams")
+	var rounds = 20
+	if raw := strings.TrimSpace(c.Query("rounds")); raw != "" {
+		if rounds, err = strconv.Atoi(raw); err != nil || rounds < 1 || rounds > 500 {
+			return fiber.NewError(fiber.StatusBadRequest, "rounds must be between 1 and 500")
 		}
-		if team == nil {
-			continue
+	}
+
+	var issues []*db.ScoringIssue
+	if issues, err = db.GetScoringIssues(comp.SystemID, comp.ScoringRound-int64(rounds)+1); err != nil {
+		appLog.Errorf("failed to load scoring issues for %s: %v\n", comp.SystemID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load scoring health")
+	}
+
+	var (
+		teamNames = make(map[int64]string)
+		passes    = []scoringHealthPass{}
+	)
+
+	for _, teamID := range comp.TeamIDs {
+		if team, teamErr := db.Teams.Select(teamID); teamErr == nil && team != nil {
+			teamNames[teamID] = team.Name
 		}
+	}
 
-		network := strings.TrimSpace(team.NetworkCIDR)
-		if network == "" {
-			if computed, netErr := koth.TeamSubnetCIDR(comp, teamIndex); netErr == nil {
-				network = computed
-			}
+	// Issues arrive oldest first; walk them backwards so the newest pass leads.
+	for i := len(issues) - 1; i >= 0; i-- {
+		issue := issues[i]
+		if len(passes) == 0 || passes[len(passes)-1].Round != issue.Round {
+			passes = append(passes, scoringHealthPass{Round: issue.Round, RecordedAt: issue.RecordedAt})
 		}
 
-		summaries = append(summaries, teamAdminSummary{
-			ID:          team.ID,
-			Name:        team.Name,
-			Score:       team.Score,
-			LastUpdated: team.LastUpdated,
-			NetworkCIDR: network,
+		pass := &passes[len(passes)-1]
+		pass.Issues = append(pass.Issues, scoringHealthIssue{
+			TeamID:        issue.TeamID,
+			TeamName:      teamNames[issue.TeamID],
+			ContainerName: issue.ContainerName,
+			Source:        issue.Source,
+			Message:       issue.Message,
+			UnknownChecks: issue.UnknownChecks,
 		})
 	}
 
-	sort.SliceStable(summaries, func(i, j int) bool {
-		if summaries[i].Score == summaries[j].Score {
-			return strings.ToLower(summaries[i].Name) < strings.ToLower(summaries[j].Name)
-		}
-		return summaries[i].Score > summaries[j].Score
-	})
+	policy, policyErr := koth.UnknownCheckPolicyFor(comp)
+	if policyErr != nil {
+		appLog.Errorf("failed to load unknown check policy for %s: %v\n", comp.SystemID, policyErr)
+	}
 
 	return c.JSON(fiber.Map{
-		"teams": summaries,
+		"competitionID":      comp.SystemID,
+		"currentRound":       comp.ScoringRound,
+		"roundsChecked":      min(int64(rounds), comp.ScoringRound),
+		"unknownCheckPolicy": policy,
+		"passes":             passes,
 	})
 }
 
-func apiModifyTeamScore(c *fiber.Ctx) (err error) {
+type teamScoreMutationRequest struct {
+	Action string `json:"action"`
+	Amount int    `json:"amount"`
+}
+
+func apiGetCompetitionTeams(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	}
+
+	identifier := strings.TrimSpace(c.Params("competitionID"))
+	if identifier == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "competition identifier required")
+	}
+
+	var comp *db.Competition
+	if comp, err = loadCompetitionByIdentifier(identifier); err != nil {
+		appLog.Errorf("failed to resolve competition %q: %v\n", identifier, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competition")
+	}
+
+	if comp == nil {
+		return fiber.ErrNotFound
+	}
+
+	summaries := make([]teamAdminSummary, 0, len(comp.TeamIDs))
+	for teamIndex, teamID := range comp.TeamIDs {
+		var team *db.Team
+		if team, err = db.Teams.Select(teamID); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to load teams")
+		}
+		if team == nil {
+			continue
+		}
+
+		network := strings.TrimSpace(team.NetworkCIDR)
+		if network == "" {
+			if computed, netErr := koth.TeamSubnetCIDR(comp, teamIndex); netErr == nil {
+				network = computed
+			}
+		}
+
+		claimToken, tokenErr := koth.EnsureTeamClaimToken(team)
+		if tokenErr != nil {
+			appLog.Errorf("failed to ensure claim token for team %d: %v\n", team.ID, tokenErr)
+		}
+
+		members, membersErr := teamMemberNames(team.ID)
+		if membersErr != nil {
+			appLog.Errorf("failed to load roster for team %d: %v\n", team.ID, membersErr)
+		}
+
+		summaries = append(summaries, teamAdminSummary{
+			ID:          team.ID,
+			Name:        team.Name,
+			Score:       team.Score,
+			LastUpdated: team.LastUpdated,
+			NetworkCIDR: network,
+			ClaimToken:  claimToken,
+			LDAPGroup:   team.LDAPGroup,
+			Members:     members,
+		})
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].Score == summaries[j].Score {
+			return strings.ToLower(summaries[i].Name) < strings.ToLower(summaries[j].Name)
+		}
+		return summaries[i].Score > summaries[j].Score
+	})
+
+	return c.JSON(fiber.Map{
+		"teams": summaries,
+	})
+}
+
+func apiSetTeamMembers(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	}
+
+	identifier := strings.TrimSpace(c.Params("competitionID"))
+	if identifier == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "competition identifier required")
+	}
+
+	var comp *db.Competition
+	if comp, err = loadCompetitionByIdentifier(identifier); err != nil {
+		appLog.Errorf("failed to resolve competition %q: %v\n", identifier, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competition")
+	}
+
+	if comp == nil {
+		return fiber.ErrNotFound
+	}
+
+	teamIDParam := strings.TrimSpace(c.Params("teamID"))
+	if teamIDParam == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "team identifier required")
+	}
+
+	teamID, convErr := strconv.ParseInt(teamIDParam, 10, 64)
+	if convErr != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "team identifier invalid")
+	}
+
+	if !slices.Contains(comp.TeamIDs, teamID) {
+		return fiber.NewError(fiber.StatusNotFound, "team not found in competition")
+	}
+
+	var team *db.Team
+	if team, err = db.Teams.Select(teamID); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load team")
+	}
+	if team == nil {
+		return fiber.ErrNotFound
+	}
+
+	var payload teamMembersRequest
+	if err = c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	}
+
+	// A player belongs to one team per competition, so reject usernames already rostered elsewhere.
+	for _, otherID := range comp.TeamIDs {
+		if otherID == teamID {
+			continue
+		}
+
+		others, membersErr := teamMemberNames(otherID)
+		if membersErr != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to load team rosters")
+		}
+
+		for _, member := range payload.Members {
+			if slices.Contains(others, db.NormalizeUsername(member)) {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("user %q is already on team %d", db.NormalizeUsername(member), otherID))
+			}
+		}
+	}
+
+	var ldapGroup = strings.TrimSpace(payload.LDAPGroup)
+	if team, err = db.UpdateTeam(team.ID, func(current *db.Team) bool {
+		current.LDAPGroup = ldapGroup
+		return true
+	}); err != nil {
+		appLog.Errorf("failed to update team %d: %v\n", teamID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to update team")
+	} else if team == nil {
+		return fiber.ErrNotFound
+	}
+
+	if err = db.SetTeamMembers(comp.SystemID, team.ID, payload.Members); err != nil {
+		appLog.Errorf("failed to update roster for team %d: %v\n", team.ID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to update team roster")
+	}
+
+	members, err := teamMemberNames(team.ID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load team roster")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":   fmt.Sprintf("roster updated for %s", team.Name),
+		"ldapGroup": team.LDAPGroup,
+		"members":   members,
+	})
+}
+
+func apiGetMyTeams(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	var teamIDs []int64
+	if teamIDs, err = db.GetUserTeamIDs(user.LDAPConn.Username, fetchUserGroups(user)); err != nil {
+		appLog.Errorf("failed to resolve teams for %s: %v\n", user.LDAPConn.Username, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load team membership")
+	}
+
+	var comps []*db.Competition
+	if comps, err = db.Competitions.SelectAll(); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competitions")
+	}
+
+	summaries := make([]playerTeamSummary, 0, len(teamIDs))
+	for _, teamID := range teamIDs {
+		var comp *db.Competition
+		for _, candidate := range comps {
+			if candidate != nil && slices.Contains(candidate.TeamIDs, teamID) {
+				comp = candidate
+				break
+			}
+		}
+		if comp == nil {
+			continue
+		}
+
+		var summary playerTeamSummary
+		if summary, err = buildPlayerTeamSummary(user, comp, teamID); err != nil {
+			appLog.Errorf("failed to build team view for team %d: %v\n", teamID, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to load team")
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return c.JSON(fiber.Map{
+		"teams": summaries,
+	})
+}
+
+// buildPlayerTeamSummary collects everything a team member needs about their own team. Scores and check results
+// come from the scoreboard the member is allowed to see, so a freeze applies here too.
+func buildPlayerTeamSummary(user *auth.AuthUser, comp *db.Competition, teamID int64) (playerTeamSummary, error) {
+	team, err := db.Teams.Select(teamID)
+	if err != nil {
+		return playerTeamSummary{}, err
+	}
+	if team == nil {
+		return playerTeamSummary{}, fmt.Errorf("team %d not found", teamID)
+	}
+
+	summary := playerTeamSummary{
+		CompetitionID:   comp.SystemID,
+		CompetitionName: comp.Name,
+		TeamID:          team.ID,
+		TeamName:        team.Name,
+		ScoringActive:   comp.ScoringActive,
+		NetworkCIDR:     team.NetworkCIDR,
+		Containers:      []playerContainerDetail{},
+		Checks:          []scoreboardContainer{},
+	}
+
+	if summary.ClaimToken, err = koth.EnsureTeamClaimToken(team); err != nil {
+		appLog.Errorf("failed to ensure claim token for team %d: %v\n", team.ID, err)
+	}
+
+	if summary.Members, err = teamMemberNames(team.ID); err != nil {
+		return summary, err
+	}
+
+	scoreboard, err := buildVisibleScoreboard(user, comp)
+	if err != nil {
+		return summary, err
+	}
+
+	summary.Frozen = scoreboard.Snapshot
+	summary.TeamCount = len(scoreboard.Teams)
+	for idx, entry := range scoreboard.Teams {
+		if entry.ID == team.ID {
+			summary.Score = entry.Score
+			summary.Rank = idx + 1
+			summary.Checks = entry.Containers
+			break
+		}
+	}
+
+	if summary.Redeploy, err = koth.SelfRedeployStatusFor(comp, team.ID, time.Now()); err != nil {
+		return summary, err
+	}
+
+	credentials, credErr := koth.TeamContainerCredentials(comp)
+	if credErr != nil {
+		appLog.Errorf("failed to load container credentials for %s: %v\n", comp.SystemID, credErr)
+	}
+
+	for _, ctID := range team.ContainerIDs {
+		record, recErr := db.Containers.Select(ctID)
+		if recErr != nil {
+			return summary, recErr
+		}
+		if record == nil {
+			continue
+		}
+
+		status := strings.ToLower(strings.TrimSpace(record.Status))
+		if status == "" {
+			status = "unknown"
+		}
+
+		detail := playerContainerDetail{
+			ID:         record.PVEID,
+			ConfigName: record.ConfigName,
+			IPv4:       record.IPAddress,
+			IPv6:       record.IPv6Address,
+			Status:     status,
+		}
+		if credential, ok := credentials[strings.ToLower(strings.TrimSpace(record.ConfigName))]; ok {
+			detail.Username = credential.Username
+			detail.Password = credential.Password
+		}
+
+		summary.Containers = append(summary.Containers, detail)
+	}
+
+	sort.SliceStable(summary.Containers, func(i, j int) bool {
+		return strings.ToLower(summary.Containers[i].ConfigName) < strings.ToLower(summary.Containers[j].ConfigName)
+	})
+
+	return summary, nil
+}
+
+func teamMemberNames(teamID int64) ([]string, error) {
+	members, err := db.GetTeamMembers(teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		names = append(names, member.Username)
+	}
+
+	return names, nil
+}
+
+func apiModifyTeamScore(c *fiber.Ctx) (err error) {
 	user := auth.IsAuthenticated(c, jwtSigningKey)
 	if user == nil {
 		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
@@ -1063,199 +1951,638 @@ func apiModifyTeamScore(c *fiber.Ctx) (err error) {
 		return fiber.NewError(fiber.StatusNotFound, "team not found in competition")
 	}
 
-	var team *db.Team
-	if team, err = db.Teams.Select(teamID); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to load team")
-	}
-	if team == nil {
-		return fiber.ErrNotFound
+	var team *db.Team
+	if team, err = db.Teams.Select(teamID); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load team")
+	}
+	if team == nil {
+		return fiber.ErrNotFound
+	}
+
+	var payload teamScoreMutationRequest
+	if err = c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	}
+
+	var (
+		action = strings.ToLower(strings.TrimSpace(payload.Action))
+		delta  int
+		reason string
+	)
+
+	switch action {
+	case "reset":
+		delta = -team.Score
+		reason = "Score reset by administrator"
+	case "adjust":
+		if payload.Amount == 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "amount must be non-zero")
+		}
+		delta = payload.Amount
+		reason = "Score adjusted by administrator"
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "action must be 'reset' or 'adjust'")
+	}
+
+	if err = koth.AdjustTeamScore(comp, team, delta, reason, uploadActor(user)); err != nil {
+		appLog.Errorf("failed to update team %d score: %v\n", team.ID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to update team score")
+	}
+
+	message := "team score updated"
+	if action == "reset" {
+		message = "team score reset"
+	}
+
+	return c.JSON(fiber.Map{
+		"message":     message,
+		"score":       team.Score,
+		"lastUpdated": team.LastUpdated,
+	})
+}
+
+func apiSetContainerPower(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	}
+
+	var payload containerPowerRequest
+	if err = c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	}
+
+	ids := normalizeRequestedContainers(payload.IDs)
+	if len(ids) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "container IDs required")
+	}
+
+	action := strings.ToLower(strings.TrimSpace(payload.Action))
+	if action != "start" && action != "stop" {
+		return fiber.NewError(fiber.StatusBadRequest, "action must be 'start' or 'stop'")
+	}
+
+	for _, id := range ids {
+		if record, selErr := db.Containers.Select(id); selErr != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to validate containers")
+		} else if record == nil {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("container %d not found", id))
+		}
+	}
+
+	switch action {
+	case "start":
+		if err = koth.BulkStartContainers(ids); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to start containers")
+		}
+	case "stop":
+		if err = koth.BulkStopContainers(ids); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to stop containers")
+		}
+	}
+
+	if refreshErr := koth.RefreshContainerStatuses(ids); refreshErr != nil {
+		appLog.Errorf("failed to refresh container statuses after %s request: %v\n", action, refreshErr)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("containers queued to %s", action),
+		"updated": len(ids),
+	})
+}
+
+func apiRedeployContainers(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	}
+
+	var payload containerRedeployRequest
+	if err = c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	}
+
+	ids := normalizeRequestedContainers(payload.IDs)
+	if len(ids) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "container IDs required")
+	}
+
+	for _, id := range ids {
+		record, selErr := db.Containers.Select(id)
+		if selErr != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to validate containers")
+		}
+		if record == nil {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("container %d not found", id))
+		}
+	}
+
+	job := newRedeployJob(user, ids, payload.StartAfter, payload.EnableAdvancedLogging)
+	startRedeployJob(job)
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("redeploy queued (%s)", job.ID),
+		"jobID":   job.ID,
+	})
+}
+
+// apiResetContainers rolls containers back to the baseline snapshot taken after provisioning. Containers without a
+// snapshot are redeployed instead. Progress streams from the redeploy stream endpoint.
+func apiResetContainers(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	if user.Permissions() < auth.AuthPermsAdministrator {
+		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	}
+
+	var payload containerRedeployRequest
+	if err = c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	}
+
+	ids := normalizeRequestedContainers(payload.IDs)
+	if len(ids) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "container IDs required")
+	}
+
+	for _, id := range ids {
+		record, selErr := db.Containers.Select(id)
+		if selErr != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to validate containers")
+		}
+		if record == nil {
+			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("container %d not found", id))
+		}
+		if strings.EqualFold(strings.TrimSpace(record.Status), "redeploying") {
+			return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("container %d is already being redeployed", id))
+		}
+	}
+
+	job := newResetJob(user, ids, payload.StartAfter, payload.EnableAdvancedLogging)
+	startRedeployJob(job)
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("reset queued (%s)", job.ID),
+		"jobID":   job.ID,
+	})
+}
+
+// apiTeamRedeployContainer lets a team member redeploy one of their own containers, subject to the competition's
+// self-service redeploy policy.
+func apiTeamRedeployContainer(c *fiber.Ctx) (err error) {
+	user := auth.IsAuthenticated(c, jwtSigningKey)
+	if user == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+	}
+
+	var payload teamRedeployRequest
+	if err = c.BodyParser(&payload); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	}
+
+	if payload.ContainerID <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "containerID required")
+	}
+
+	var record *db.Container
+	if record, err = db.Containers.Select(payload.ContainerID); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load container")
+	}
+	if record == nil {
+		return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("container %d not found", payload.ContainerID))
+	}
+
+	var teamIDs []int64
+	if teamIDs, err = db.GetUserTeamIDs(user.LDAPConn.Username, fetchUserGroups(user)); err != nil {
+		appLog.Errorf("failed to resolve teams for %s: %v\n", user.LDAPConn.Username, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load team membership")
+	}
+
+	if record.TeamID == 0 || !slices.Contains(teamIDs, record.TeamID) {
+		return fiber.NewError(fiber.StatusForbidden, "container does not belong to your team")
+	}
+
+	var team *db.Team
+	if team, err = db.Teams.Select(record.TeamID); err != nil || team == nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load team")
+	}
+
+	var comps []*db.Competition
+	if comps, err = db.Competitions.SelectAll(); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competitions")
+	}
+
+	var comp *db.Competition
+	for _, candidate := range comps {
+		if candidate != nil && slices.Contains(candidate.TeamIDs, team.ID) {
+			comp = candidate
+			break
+		}
+	}
+	if comp == nil {
+		return fiber.NewError(fiber.StatusNotFound, "competition not found")
+	}
+
+	var claim *db.TeamRedeploy
+	if claim, err = koth.ClaimSelfRedeploy(comp, team, record.PVEID, uploadActor(user), time.Now()); err != nil {
+		if errors.Is(err, koth.ErrContainerRedeploying) {
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		if errors.Is(err, koth.ErrSelfRedeployDenied) {
+			if !comp.SelfRedeploy.Enabled {
+				return fiber.NewError(fiber.StatusForbidden, err.Error())
+			}
+			return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+		}
+		if claim == nil {
+			appLog.Errorf("failed to record redeploy for team %d: %v\n", team.ID, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to record redeploy")
+		}
+		appLog.Errorf("failed to apply redeploy penalty for team %d: %v\n", team.ID, err)
+	}
+
+	job := newTeamRedeployJob(user, team.ID, record.PVEID)
+	startRedeployJob(job)
+
+	claim.JobID = job.ID
+	if err = db.TeamRedeploys.Update(claim); err != nil {
+		appLog.Errorf("failed to link redeploy record %d to job %s: %v\n", claim.ID, job.ID, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": fmt.Sprintf("redeploy queued (%s)", job.ID),
+		"jobID":   job.ID,
+	})
+}
+
+func apiGetScoreboard(c *fiber.Ctx) (err error) {
+	var (
+		user    *auth.AuthUser = auth.IsAuthenticated(c, jwtSigningKey)
+		records []*db.Competition
+		payload []scoreboardCompetition
+	)
+
+	if records, err = db.Competitions.SelectAll(); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competitions")
+	}
+
+	var groups []string = fetchUserGroups(user)
+
+	for _, comp := range records {
+		if !userCanViewCompetition(user, groups, comp) {
+			continue
+		}
+
+		var scoreComp scoreboardCompetition
+		if scoreComp, err = buildVisibleScoreboard(user, comp); err != nil {
+			appLog.Errorf("scoreboard build failed for %s: %v\n", comp.Name, err)
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to build scoreboard")
+		}
+
+		payload = append(payload, scoreComp)
+	}
+
+	sort.SliceStable(payload, func(i, j int) bool {
+		return payload[i].Name < payload[j].Name
+	})
+
+	return c.JSON(fiber.Map{
+		"competitions": payload,
+	})
+}
+
+func apiGetScoreboardCompetition(c *fiber.Ctx) (err error) {
+	var match *db.Competition
+	if match, err = loadVisibleScoreboardCompetition(c); err != nil {
+		return err
+	}
+
+	var payload scoreboardCompetition
+	if payload, err = buildVisibleScoreboard(auth.IsAuthenticated(c, jwtSigningKey), match); err != nil {
+		appLog.Errorf("scoreboard build failed for %s: %v\n", match.Name, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to build scoreboard")
+	}
+
+	return c.JSON(payload)
+}
+
+func apiGetScoreHistory(c *fiber.Ctx) (err error) {
+	var comp *db.Competition
+	if comp, err = loadVisibleScoreboardCompetition(c); err != nil {
+		return err
 	}
 
-	var payload teamScoreMutationRequest
-	if err = c.BodyParser(&payload); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	var from, to time.Time
+	if from, to, err = parseHistoryRange(c); err != nil {
+		return err
 	}
 
-	action := strings.ToLower(strings.TrimSpace(payload.Action))
-	switch action {
-	case "reset":
-		team.Score = 0
-	case "adjust":
-		if payload.Amount == 0 {
-			return fiber.NewError(fiber.StatusBadRequest, "amount must be non-zero")
+	from, to = clampFrozenHistoryRange(c, comp, from, to)
+
+	var teamFilter int64
+	if raw := strings.TrimSpace(c.Query("team")); raw != "" {
+		if teamFilter, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "team must be a numeric team ID")
 		}
-		team.Score += payload.Amount
-	default:
-		return fiber.NewError(fiber.StatusBadRequest, "action must be 'reset' or 'adjust'")
 	}
 
-	team.LastUpdated = time.Now().UTC()
-	if err = db.Teams.Update(team); err != nil {
-		appLog.Errorf("failed to update team %d score: %v\n", team.ID, err)
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to update team score")
+	var entries []*db.ScoreHistoryEntry
+	if entries, err = db.GetScoreHistory(comp.SystemID, from, to); err != nil {
+		appLog.Errorf("failed to load score history for %s: %v\n", comp.SystemID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load score history")
 	}
 
-	message := "team score updated"
-	if action == "reset" {
-		message = "team score reset"
+	filtered := make([]*db.ScoreHistoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if teamFilter != 0 && entry.TeamID != teamFilter {
+			continue
+		}
+		filtered = append(filtered, entry)
 	}
 
 	return c.JSON(fiber.Map{
-		"message":     message,
-		"score":       team.Score,
-		"lastUpdated": team.LastUpdated,
+		"competitionID": comp.SystemID,
+		"entries":       filtered,
 	})
 }
 
-func apiSetContainerPower(c *fiber.Ctx) (err error) {
-	user := auth.IsAuthenticated(c, jwtSigningKey)
-	if user == nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+func apiGetOwnershipHistory(c *fiber.Ctx) (err error) {
+	var comp *db.Competition
+	if comp, err = loadVisibleScoreboardCompetition(c); err != nil {
+		return err
 	}
 
-	if user.Permissions() < auth.AuthPermsAdministrator {
-		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	var records []*db.OwnershipRecord
+	if records, err = db.GetOwnershipHistory(comp.SystemID); err != nil {
+		appLog.Errorf("failed to load ownership history for %s: %v\n", comp.SystemID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load ownership history")
 	}
 
-	var payload containerPowerRequest
-	if err = c.BodyParser(&payload); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	if scoreboardFrozenFor(auth.IsAuthenticated(c, jwtSigningKey), comp) {
+		records = ownershipAsOf(records, comp.FrozenAt)
 	}
 
-	ids := normalizeRequestedContainers(payload.IDs)
-	if len(ids) == 0 {
-		return fiber.NewError(fiber.StatusBadRequest, "container IDs required")
+	return c.JSON(fiber.Map{
+		"competitionID": comp.SystemID,
+		"records":       records,
+	})
+}
+
+func apiGetScoreHistoryRounds(c *fiber.Ctx) (err error) {
+	var comp *db.Competition
+	if comp, err = loadVisibleScoreboardCompetition(c); err != nil {
+		return err
 	}
 
-	action := strings.ToLower(strings.TrimSpace(payload.Action))
-	if action != "start" && action != "stop" {
-		return fiber.NewError(fiber.StatusBadRequest, "action must be 'start' or 'stop'")
+	var from, to time.Time
+	if from, to, err = parseHistoryRange(c); err != nil {
+		return err
 	}
 
-	for _, id := range ids {
-		if record, selErr := db.Containers.Select(id); selErr != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "failed to validate containers")
-		} else if record == nil {
-			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("container %d not found", id))
-		}
+	from, to = clampFrozenHistoryRange(c, comp, from, to)
+
+	// Cumulative totals are computed over the full history so ranged queries still report absolute values.
+	var entries []*db.ScoreHistoryEntry
+	if entries, err = db.GetScoreHistory(comp.SystemID, time.Time{}, to); err != nil {
+		appLog.Errorf("failed to load score history for %s: %v\n", comp.SystemID, err)
+		return fiber.NewError(fiber.StatusInternalServerError, "failed to load score history")
 	}
 
-	switch action {
-	case "start":
-		if err = koth.BulkStartContainers(ids); err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "failed to start containers")
+	return c.JSON(fiber.Map{
+		"competitionID": comp.SystemID,
+		"rounds":        summarizeScoreHistoryRounds(entries, from, to),
+	})
+}
+
+func summarizeScoreHistoryRounds(entries []*db.ScoreHistoryEntry, from, to time.Time) []scoreHistoryRound {
+	var (
+		rounds     = []scoreHistoryRound{}
+		cumulative = make(map[int64]int)
+		current    *scoreHistoryRound
+		teamIndex  map[int64]int
+	)
+
+	flush := func() {
+		if current == nil {
+			return
 		}
-	case "stop":
-		if err = koth.BulkStopContainers(ids); err != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "failed to stop containers")
+		for idx := range current.Teams {
+			cumulative[current.Teams[idx].TeamID] += current.Teams[idx].Points
+			current.Teams[idx].Cumulative = cumulative[current.Teams[idx].TeamID]
 		}
+		sort.SliceStable(current.Teams, func(i, j int) bool {
+			return current.Teams[i].TeamID < current.Teams[j].TeamID
+		})
+		inRange := (from.IsZero() || !current.RecordedAt.Before(from)) && (to.IsZero() || !current.RecordedAt.After(to))
+		if inRange {
+			rounds = append(rounds, *current)
+		}
+		current = nil
 	}
 
-	if refreshErr := koth.RefreshContainerStatuses(ids); refreshErr != nil {
-		appLog.Errorf("failed to refresh container statuses after %s request: %v\n", action, refreshErr)
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+
+		if current == nil || current.Round != entry.Round {
+			flush()
+			current = &scoreHistoryRound{
+				Round:      entry.Round,
+				RecordedAt: entry.RecordedAt,
+				Teams:      []scoreHistoryTeamRound{},
+			}
+			teamIndex = make(map[int64]int)
+		}
+
+		idx, ok := teamIndex[entry.TeamID]
+		if !ok {
+			idx = len(current.Teams)
+			teamIndex[entry.TeamID] = idx
+			current.Teams = append(current.Teams, scoreHistoryTeamRound{TeamID: entry.TeamID})
+		}
+
+		current.Teams[idx].Points += entry.PointsAwarded
+		switch {
+		case entry.State == db.CheckStateUnknown:
+			current.Teams[idx].Unknown++
+		case entry.Passed:
+			current.Teams[idx].Passed++
+		default:
+			current.Teams[idx].Failed++
+		}
 	}
+	flush()
 
-	return c.JSON(fiber.Map{
-		"message": fmt.Sprintf("containers queued to %s", action),
-		"updated": len(ids),
-	})
+	return rounds
 }
 
-func apiRedeployContainers(c *fiber.Ctx) (err error) {
-	user := auth.IsAuthenticated(c, jwtSigningKey)
-	if user == nil {
-		return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+// parseHistoryRange reads the optional from/to query parameters as RFC3339 timestamps or unix seconds.
+func parseHistoryRange(c *fiber.Ctx) (from, to time.Time, err error) {
+	if from, err = parseHistoryTime(c.Query("from")); err != nil {
+		return from, to, fiber.NewError(fiber.StatusBadRequest, "from must be an RFC3339 timestamp or unix seconds")
 	}
 
-	if user.Permissions() < auth.AuthPermsAdministrator {
-		return fiber.NewError(fiber.StatusForbidden, "administrator access required")
+	if to, err = parseHistoryTime(c.Query("to")); err != nil {
+		return from, to, fiber.NewError(fiber.StatusBadRequest, "to must be an RFC3339 timestamp or unix seconds")
 	}
 
-	var payload containerRedeployRequest
-	if err = c.BodyParser(&payload); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request payload")
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return from, to, fiber.NewError(fiber.StatusBadRequest, "to must not be before from")
 	}
 
-	ids := normalizeRequestedContainers(payload.IDs)
-	if len(ids) == 0 {
-		return fiber.NewError(fiber.StatusBadRequest, "container IDs required")
+	return from, to, nil
+}
+
+func parseHistoryTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
 	}
 
-	for _, id := range ids {
-		record, selErr := db.Containers.Select(id)
-		if selErr != nil {
-			return fiber.NewError(fiber.StatusInternalServerError, "failed to validate containers")
-		}
-		if record == nil {
-			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("container %d not found", id))
-		}
+	if seconds, convErr := strconv.ParseInt(raw, 10, 64); convErr == nil {
+		return time.Unix(seconds, 0), nil
 	}
 
-	job := newRedeployJob(user, ids, payload.StartAfter, payload.EnableAdvancedLogging)
-	startRedeployJob(job)
+	return time.Parse(time.RFC3339, raw)
+}
 
-	return c.JSON(fiber.Map{
-		"message": fmt.Sprintf("redeploy queued (%s)", job.ID),
-		"jobID":   job.ID,
-	})
+// scoreboardFrozenFor reports whether the viewer should see the competition's frozen scoreboard rather than live
+// data. Administrators always see live data.
+func scoreboardFrozenFor(user *auth.AuthUser, comp *db.Competition) bool {
+	if comp == nil || comp.FrozenAt.IsZero() {
+		return false
+	}
+
+	return user == nil || user.Permissions() < auth.AuthPermsAdministrator
 }
 
-func apiGetScoreboard(c *fiber.Ctx) (err error) {
-	var (
-		user    *auth.AuthUser = auth.IsAuthenticated(c, jwtSigningKey)
-		records []*db.Competition
-		payload []scoreboardCompetition
-	)
+// clampFrozenHistoryRange caps a public history query at the moment the scoreboard froze.
+func clampFrozenHistoryRange(c *fiber.Ctx, comp *db.Competition, from, to time.Time) (time.Time, time.Time) {
+	if !scoreboardFrozenFor(auth.IsAuthenticated(c, jwtSigningKey), comp) {
+		return from, to
+	}
 
-	if records, err = db.Competitions.SelectAll(); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competitions")
+	if to.IsZero() || to.After(comp.FrozenAt) {
+		to = comp.FrozenAt
+	}
+	if from.After(to) {
+		from = to
 	}
 
-	var groups []string = fetchUserGroups(user)
+	return from, to
+}
 
-	for _, comp := range records {
-		if !userCanViewCompetition(user, groups, comp) {
+// ownershipAsOf rewinds ownership records to how they looked at the given time.
+func ownershipAsOf(records []*db.OwnershipRecord, at time.Time) []*db.OwnershipRecord {
+	result := make([]*db.OwnershipRecord, 0, len(records))
+	for _, record := range records {
+		if record == nil || record.ClaimedAt.After(at) {
 			continue
 		}
 
-		var scoreComp scoreboardCompetition
-		if scoreComp, err = buildScoreboardCompetition(comp); err != nil {
-			appLog.Errorf("scoreboard build failed for %s: %v\n", comp.Name, err)
-			return fiber.NewError(fiber.StatusInternalServerError, "failed to build scoreboard")
+		clone := *record
+		if !clone.ReleasedAt.IsZero() && clone.ReleasedAt.After(at) {
+			clone.ReleasedAt = time.Time{}
+			clone.ReleasedAtUnix = 0
 		}
+		result = append(result, &clone)
+	}
 
-		payload = append(payload, scoreComp)
+	return result
+}
+
+// buildVisibleScoreboard returns the live scoreboard, or the snapshot taken when the freeze began for viewers who
+// are not administrators.
+func buildVisibleScoreboard(user *auth.AuthUser, comp *db.Competition) (scoreboardCompetition, error) {
+	if !scoreboardFrozenFor(user, comp) {
+		return buildScoreboardCompetition(comp)
 	}
 
-	sort.SliceStable(payload, func(i, j int) bool {
-		return payload[i].Name < payload[j].Name
-	})
+	snapshot, err := db.GetScoreboardSnapshot(comp.SystemID)
+	if err != nil {
+		return scoreboardCompetition{}, err
+	}
 
-	return c.JSON(fiber.Map{
-		"competitions": payload,
-	})
+	if snapshot == nil {
+		CaptureScoreboardSnapshot(comp)
+		if snapshot, err = db.GetScoreboardSnapshot(comp.SystemID); err != nil {
+			return scoreboardCompetition{}, err
+		}
+		if snapshot == nil {
+			return scoreboardCompetition{}, fmt.Errorf("scoreboard snapshot unavailable for %s", comp.SystemID)
+		}
+	}
+
+	var payload scoreboardCompetition
+	if err = json.Unmarshal(snapshot.Payload, &payload); err != nil {
+		return scoreboardCompetition{}, fmt.Errorf("decode scoreboard snapshot: %w", err)
+	}
+
+	payload.ScoringActive = comp.ScoringActive
+	payload.Frozen = true
+	payload.FrozenAt = comp.FrozenAt
+	payload.Snapshot = true
+
+	return payload, nil
 }
 
-func apiGetScoreboardCompetition(c *fiber.Ctx) (err error) {
+// CaptureScoreboardSnapshot stores the competition's current scoreboard so it can be served while the public
+// scoreboard is frozen. It is registered as the koth scoreboard freeze handler.
+func CaptureScoreboardSnapshot(comp *db.Competition) {
+	if comp == nil {
+		return
+	}
+
+	scoreboard, err := buildScoreboardCompetition(comp)
+	if err != nil {
+		appLog.Errorf("failed to build scoreboard snapshot for %s: %v\n", comp.SystemID, err)
+		return
+	}
+
+	payload, err := json.Marshal(scoreboard)
+	if err != nil {
+		appLog.Errorf("failed to encode scoreboard snapshot for %s: %v\n", comp.SystemID, err)
+		return
+	}
+
+	if err = db.SaveScoreboardSnapshot(&db.ScoreboardSnapshot{
+		CompetitionID: comp.SystemID,
+		Payload:       payload,
+		TakenAt:       time.Now(),
+	}); err != nil {
+		appLog.Errorf("failed to store scoreboard snapshot for %s: %v\n", comp.SystemID, err)
+	}
+}
+
+// loadVisibleScoreboardCompetition resolves the :competitionID route parameter and enforces scoreboard visibility.
+func loadVisibleScoreboardCompetition(c *fiber.Ctx) (*db.Competition, error) {
 	var (
-		competitionSlug = c.Params("competitionID")
-		records         []*db.Competition
+		competitionSlug                = c.Params("competitionID")
 		user            *auth.AuthUser = auth.IsAuthenticated(c, jwtSigningKey)
 	)
 
 	if competitionSlug == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "competition identifier required")
+		return nil, fiber.NewError(fiber.StatusBadRequest, "competition identifier required")
 	}
 
-	if records, err = db.Competitions.SelectAll(); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to load competitions")
+	records, err := db.Competitions.SelectAll()
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "failed to load competitions")
 	}
 
-	var (
-		groups []string = fetchUserGroups(user)
-		match  *db.Competition
-	)
-
+	var match *db.Competition
 	for _, comp := range records {
 		if strings.EqualFold(comp.SystemID, competitionSlug) || fmt.Sprint(comp.ID) == competitionSlug {
 			match = comp
@@ -1264,20 +2591,14 @@ func apiGetScoreboardCompetition(c *fiber.Ctx) (err error) {
 	}
 
 	if match == nil {
-		return fiber.ErrNotFound
-	}
-
-	if !userCanViewCompetition(user, groups, match) {
-		return fiber.NewError(fiber.StatusForbidden, "competition is restricted")
+		return nil, fiber.ErrNotFound
 	}
 
-	var payload scoreboardCompetition
-	if payload, err = buildScoreboardCompetition(match); err != nil {
-		appLog.Errorf("scoreboard build failed for %s: %v\n", match.Name, err)
-		return fiber.NewError(fiber.StatusInternalServerError, "failed to build scoreboard")
+	if !userCanViewCompetition(user, fetchUserGroups(user), match) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "competition is restricted")
 	}
 
-	return c.JSON(payload)
+	return match, nil
 }
 
 func normalizeRequestedContainers(ids []int64) []int64 {
@@ -1302,6 +2623,16 @@ func normalizeRequestedContainers(ids []int64) []int64 {
 }
 
 func summarizeCompetition(comp *db.Competition) competitionSummary {
+	interval, jitter, err := koth.NormalizeScoringSchedule(comp.ScoringIntervalSeconds, comp.ScoringJitterSeconds)
+	if err != nil {
+		interval, jitter = koth.DefaultScoringIntervalSeconds, 0
+	}
+
+	provisioningStatus := comp.ProvisioningStatus
+	if provisioningStatus == "" {
+		provisioningStatus = db.ProvisioningStatusReady
+	}
+
 	return competitionSummary{
 		ID:             comp.ID,
 		CompetitionID:  comp.SystemID,
@@ -1311,9 +2642,25 @@ func summarizeCompetition(comp *db.Competition) competitionSummary {
 		TeamCount:      len(comp.TeamIDs),
 		ContainerCount: len(comp.ContainerIDs),
 		NetworkCIDR:    comp.NetworkCIDR,
+		NetworkCIDR6:   comp.NetworkCIDR6,
 		IsPrivate:      comp.IsPrivate,
 		ScoringActive:  comp.ScoringActive,
-		CreatedAt:      comp.CreatedAt,
+		ScoringSchedule: scoringScheduleSummary{
+			IntervalSeconds: interval,
+			JitterSeconds:   jitter,
+		},
+		Window: competitionWindowSummary{
+			StartsAt:       comp.StartsAt,
+			EndsAt:         comp.EndsAt,
+			FreezeMinutes:  comp.FreezeMinutes,
+			PowerOnAtStart: comp.PowerOnAtStart,
+			FrozenAt:       comp.FrozenAt,
+		},
+		Provisioning: provisioningSummary{
+			Status: provisioningStatus,
+			Error:  comp.ProvisioningError,
+		},
+		CreatedAt: comp.CreatedAt,
 	}
 }
 
@@ -1329,8 +2676,14 @@ func buildScoreboardCompetition(comp *db.Competition) (scoreboardCompetition, er
 		IsPrivate:      comp.IsPrivate,
 		ScoringActive:  comp.ScoringActive,
 		Teams:          []scoreboardTeam{},
+		Hills:          []scoreboardHill{},
+		StartsAt:       comp.StartsAt,
+		EndsAt:         comp.EndsAt,
+		Frozen:         !comp.FrozenAt.IsZero(),
+		FrozenAt:       comp.FrozenAt,
 	}
 
+	teamNames := make(map[int64]string, len(comp.TeamIDs))
 	for teamIndex, teamID := range comp.TeamIDs {
 		team, err := db.Teams.Select(teamID)
 		if err != nil {
@@ -1352,6 +2705,7 @@ func buildScoreboardCompetition(comp *db.Competition) (scoreboardCompetition, er
 			}
 		}
 
+		teamNames[team.ID] = team.Name
 		scoreboard.Teams = append(scoreboard.Teams, scoreboardTeam{
 			ID:          team.ID,
 			Name:        team.Name,
@@ -1374,6 +2728,25 @@ func buildScoreboardCompetition(comp *db.Competition) (scoreboardCompetition, er
 		return scoreboard.Teams[i].Score > scoreboard.Teams[j].Score
 	})
 
+	owners, err := db.GetCurrentOwnership(comp.SystemID)
+	if err != nil {
+		return scoreboard, err
+	}
+
+	now := time.Now()
+	for _, owner := range owners {
+		scoreboard.Hills = append(scoreboard.Hills, scoreboardHill{
+			ContainerName: owner.ContainerName,
+			HillTeamID:    owner.HillTeamID,
+			HillTeamName:  teamNames[owner.HillTeamID],
+			KingTeamID:    owner.OwnerTeamID,
+			KingTeamName:  teamNames[owner.OwnerTeamID],
+			Since:         owner.ClaimedAt,
+			TenureSeconds: int64(now.Sub(owner.ClaimedAt).Seconds()),
+			PointsAwarded: owner.PointsAwarded,
+		})
+	}
+
 	return scoreboard, nil
 }
 
@@ -1417,6 +2790,7 @@ func loadTeamScoreResults(teamID int64) ([]scoreboardContainer, error) {
 			ID:         record.CheckID,
 			Name:       record.CheckName,
 			Passed:     record.Passed,
+			State:      checkResultState(record),
 			PassPoints: record.PassPoints,
 			FailPoints: record.FailPoints,
 		})
@@ -1425,6 +2799,19 @@ func loadTeamScoreResults(teamID int64) ([]scoreboardContainer, error) {
 	return containers, nil
 }
 
+// checkResultState returns a score result's state, deriving it for results recorded before states were tracked.
+func checkResultState(record *db.ScoreResult) string {
+	if record.State != "" {
+		return record.State
+	}
+
+	if record.Passed {
+		return db.CheckStatePass
+	}
+
+	return db.CheckStateFail
+}
+
 func persistCompetitionPackage(req *db.CreateCompetitionRequest, configBytes []byte, originalFilename string) (record *db.CompetitionPackage, err error) {
 	if req == nil {
 		return nil, fmt.Errorf("competition request is nil")
@@ -1506,10 +2893,42 @@ func validateCompetitionTemplates(req *db.CreateCompetitionRequest) error {
 	}
 	req.TemplateLookup = lookup
 
+	if _, _, err = koth.NormalizeScoringSchedule(req.ScoringIntervalSeconds, req.ScoringJitterSeconds); err != nil {
+		return err
+	}
+
+	if err = koth.ValidateCompetitionWindow(req.Schedule.StartsAt, req.Schedule.EndsAt, req.Schedule.FreezeMinutes); err != nil {
+		return err
+	}
+
+	if err = koth.ValidateTeamRosters(req); err != nil {
+		return err
+	}
+
+	if err = koth.ValidateSelfRedeployPolicy(req.SelfRedeploy); err != nil {
+		return err
+	}
+
+	if _, err = koth.NormalizeUnknownCheckPolicy(req.UnknownCheckPolicy); err != nil {
+		return err
+	}
+
+	if _, err = koth.CompetitionPrefixForTeams(req.NumTeams); err != nil {
+		return err
+	}
+
+	if err = koth.ValidateNetworkIsolation(req); err != nil {
+		return err
+	}
+
+	if req.Firewall, err = koth.NormalizeFirewallPolicy(req.Firewall); err != nil {
+		return err
+	}
+
 	restrictions := config.Config.ContainerRestrictions
 	for name, spec := range lookup {
-		if strings.TrimSpace(spec.TemplatePath) == "" {
-			return fmt.Errorf("template %q missing templatePath", name)
+		if strings.TrimSpace(spec.TemplatePath) == "" && spec.VMTemplateID <= 0 {
+			return fmt.Errorf("template %q missing templatePath or vmTemplateID", name)
 		}
 		if strings.TrimSpace(spec.StoragePool) == "" {
 			return fmt.Errorf("template %q missing storagePool", name)
@@ -1517,7 +2936,7 @@ func validateCompetitionTemplates(req *db.CreateCompetitionRequest) error {
 		if strings.TrimSpace(spec.RootPassword) == "" {
 			return fmt.Errorf("template %q missing rootPassword", name)
 		}
-		if spec.StorageSizeGB <= 0 {
+		if strings.TrimSpace(spec.TemplatePath) != "" && spec.StorageSizeGB <= 0 {
 			return fmt.Errorf("template %q invalid storageSizeGB (%d)", name, spec.StorageSizeGB)
 		}
 		if spec.MemoryMB <= 0 {
@@ -1527,9 +2946,12 @@ func validateCompetitionTemplates(req *db.CreateCompetitionRequest) error {
 			return fmt.Errorf("template %q invalid cores (%d)", name, spec.Cores)
 		}
 
-		if len(restrictions.AllowedLXCTemplates) > 0 && !containsString(restrictions.AllowedLXCTemplates, spec.TemplatePath) {
+		if strings.TrimSpace(spec.TemplatePath) != "" && len(restrictions.AllowedLXCTemplates) > 0 && !containsString(restrictions.AllowedLXCTemplates, spec.TemplatePath) {
 			return fmt.Errorf("template %q uses disallowed template path %q", name, spec.TemplatePath)
 		}
+		if spec.VMTemplateID > 0 && len(restrictions.AllowedVMTemplates) > 0 && !slices.Contains(restrictions.AllowedVMTemplates, spec.VMTemplateID) {
+			return fmt.Errorf("template %q uses disallowed template VM %d", name, spec.VMTemplateID)
+		}
 		if len(restrictions.AllowedStoragePools) > 0 && !containsString(restrictions.AllowedStoragePools, spec.StoragePool) {
 			return fmt.Errorf("template %q uses disallowed storage pool %q", name, spec.StoragePool)
 		}
@@ -1552,9 +2974,60 @@ func validateCompetitionTemplates(req *db.CreateCompetitionRequest) error {
 		if strings.TrimSpace(cfg.ContainerSpecsTemplate) == "" {
 			return fmt.Errorf("team container %s missing containerSpecsTemplate", cfg.Name)
 		}
-		if _, err = koth.ResolveContainerSpecTemplate(lookup, cfg.ContainerSpecsTemplate); err != nil {
+		var spec db.ContainerSpecTemplate
+		if spec, err = koth.ResolveContainerSpecTemplate(lookup, cfg.ContainerSpecsTemplate); err != nil {
 			return fmt.Errorf("team container %s references invalid template %q: %w", cfg.Name, cfg.ContainerSpecsTemplate, err)
 		}
+		if err = koth.ValidateGuestKind(cfg.Kind, spec); err != nil {
+			return fmt.Errorf("team container %s: %w", cfg.Name, err)
+		}
+		if err = koth.ValidateContainerInterfaces(cfg); err != nil {
+			return err
+		}
+		if _, err = koth.NormalizeScoringRunner(cfg.ScoringRunner); err != nil {
+			return fmt.Errorf("team container %s: %w", cfg.Name, err)
+		}
+		if _, err = koth.NormalizeExecTransport(cfg.ExecTransport); err != nil {
+			return fmt.Errorf("team container %s: %w", cfg.Name, err)
+		}
+		for _, check := range cfg.ScoringSchema {
+			if err = koth.ValidateScoringCheck(check); err != nil {
+				return fmt.Errorf("team container %s: %w", cfg.Name, err)
+			}
+			if err = koth.ValidateCheckScript(check, cfg.ScoringScript); err != nil {
+				return fmt.Errorf("team container %s: %w", cfg.Name, err)
+			}
+		}
+	}
+
+	teamNames := make(map[string]struct{}, len(req.TeamContainerConfigs))
+	for _, cfg := range req.TeamContainerConfigs {
+		teamNames[strings.ToLower(strings.TrimSpace(cfg.Name))] = struct{}{}
+	}
+
+	for _, cfg := range req.SharedContainerConfigs {
+		if strings.TrimSpace(cfg.Name) == "" {
+			return fmt.Errorf("shared container missing name")
+		}
+		if _, clash := teamNames[strings.ToLower(strings.TrimSpace(cfg.Name))]; clash {
+			return fmt.Errorf("shared container %s reuses a team container name", cfg.Name)
+		}
+		if strings.TrimSpace(cfg.ContainerSpecsTemplate) == "" {
+			return fmt.Errorf("shared container %s missing containerSpecsTemplate", cfg.Name)
+		}
+		var spec db.ContainerSpecTemplate
+		if spec, err = koth.ResolveContainerSpecTemplate(lookup, cfg.ContainerSpecsTemplate); err != nil {
+			return fmt.Errorf("shared container %s references invalid template %q: %w", cfg.Name, cfg.ContainerSpecsTemplate, err)
+		}
+		if err = koth.ValidateGuestKind(cfg.Kind, spec); err != nil {
+			return fmt.Errorf("shared container %s: %w", cfg.Name, err)
+		}
+		if err = koth.ValidateContainerInterfaces(cfg); err != nil {
+			return err
+		}
+		if _, err = koth.NormalizeExecTransport(cfg.ExecTransport); err != nil {
+			return fmt.Errorf("shared container %s: %w", cfg.Name, err)
+		}
 	}
 
 	return nil
@@ -33,6 +33,7 @@ func CreateApp() (app *fiber.App) {
 
 	// Authenticated areas
 	app.Get("/dashboard", mustBeLoggedIn, showDashboard)
+	app.Get("/team", mustBeLoggedIn, showTeam)
 
 	// API
 	var api = app.Group("/api")
@@ -47,22 +48,42 @@ func CreateApp() (app *fiber.App) {
 	competitions.Post(":competitionID/teardown", apiTeardownCompetition)
 	competitions.Get("teardown/:jobID/stream", apiStreamTeardownJob)
 	competitions.Post(":competitionID/scoring", apiSetCompetitionScoring)
+	competitions.Post(":competitionID/scoring/schedule", apiSetCompetitionScoringSchedule)
+	competitions.Get(":competitionID/scoring/health", apiGetScoringHealth)
+	competitions.Post(":competitionID/window", apiSetCompetitionWindow)
+	competitions.Get(":competitionID/firewall", apiGetCompetitionFirewall)
+	competitions.Post(":competitionID/firewall", apiSetCompetitionFirewall)
+	competitions.Post(":competitionID/provision/resume", apiResumeCompetitionProvisioning)
 	competitions.Get(":competitionID/teams", apiGetCompetitionTeams)
 	competitions.Post(":competitionID/teams/:teamID/score", apiModifyTeamScore)
+	competitions.Post(":competitionID/teams/:teamID/members", apiSetTeamMembers)
 	competitions.Post("/upload", apiCreateCompetition)
 	competitions.Get("/upload/:jobID/stream", apiStreamUploadJob)
 
+	api.Get("/team", apiGetMyTeams)
+	api.Post("/team/redeploy", apiTeamRedeployContainer)
+
+	var jobs = api.Group("/jobs")
+	jobs.Get("/", apiListJobs)
+	jobs.Get("", apiListJobs)
+	jobs.Get(":jobID", apiGetJob)
+
 	var containersAPI = api.Group("/containers")
 	containersAPI.Get("/", apiListContainers)
 	containersAPI.Get("", apiListContainers)
 	containersAPI.Post("/power", apiSetContainerPower)
 	containersAPI.Post("/redeploy", apiRedeployContainers)
+	containersAPI.Post("/reset", apiResetContainers)
+	containersAPI.Get("/console-stats", apiGetConsoleStats)
 	containersAPI.Get("/redeploy/:jobID/stream", apiStreamRedeployJob)
 
 	var scoreboard = api.Group("/scoreboard")
 	scoreboard.Get("/", apiGetScoreboard)
 	scoreboard.Get("", apiGetScoreboard)
 	scoreboard.Get(":competitionID", apiGetScoreboardCompetition)
+	scoreboard.Get(":competitionID/history", apiGetScoreHistory)
+	scoreboard.Get(":competitionID/history/rounds", apiGetScoreHistoryRounds)
+	scoreboard.Get(":competitionID/ownership", apiGetOwnershipHistory)
 
 	return
 }
@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -15,6 +16,8 @@ type redeployJob struct {
 	containerIDs          []int64
 	startAfter            bool
 	enableAdvancedLogging bool
+	teamID                int64
+	reset                 bool // Roll back to the baseline snapshot instead of rebuilding
 }
 
 var (
@@ -30,6 +33,36 @@ func newRedeployJob(user *auth.AuthUser, ids []int64, startAfter, enableAdvanced
 		enableAdvancedLogging: enableAdvancedLogging,
 	}
 
+	job.persist(&db.Job{Kind: "redeploy", ContainerIDs: job.containerIDs})
+	registerRedeployJob(job)
+	return job
+}
+
+// newResetJob creates a job that rolls containers back to their baseline snapshot. It streams like a redeploy job.
+func newResetJob(user *auth.AuthUser, ids []int64, startAfter, enableAdvancedLogging bool) *redeployJob {
+	var job = &redeployJob{
+		streamJob:             newStreamJob("reset_job", uploadActor(user)),
+		containerIDs:          append([]int64(nil), ids...),
+		startAfter:            startAfter,
+		enableAdvancedLogging: enableAdvancedLogging,
+		reset:                 true,
+	}
+
+	job.persist(&db.Job{Kind: "reset", ContainerIDs: job.containerIDs})
+	registerRedeployJob(job)
+	return job
+}
+
+// newTeamRedeployJob creates a job for a team's self-service redeploy. Every member of the team can follow it.
+func newTeamRedeployJob(user *auth.AuthUser, teamID, containerID int64) *redeployJob {
+	var job = &redeployJob{
+		streamJob:    newStreamJob("redeploy_job", uploadActor(user)),
+		containerIDs: []int64{containerID},
+		startAfter:   true,
+		teamID:       teamID,
+	}
+
+	job.persist(&db.Job{Kind: "redeploy", TeamID: teamID, ContainerIDs: job.containerIDs})
 	registerRedeployJob(job)
 	return job
 }
@@ -47,7 +80,16 @@ func getRedeployJob(id string) *redeployJob {
 }
 
 func (job *redeployJob) canView(user *auth.AuthUser) bool {
-	return job.Owner == uploadActor(user)
+	if job.Owner == uploadActor(user) {
+		return true
+	}
+
+	if job.teamID == 0 || user == nil || user.LDAPConn == nil {
+		return false
+	}
+
+	teamIDs, err := db.GetUserTeamIDs(user.LDAPConn.Username, fetchUserGroups(user))
+	return err == nil && slices.Contains(teamIDs, job.teamID)
 }
 
 func (job *redeployJob) subscribe() chan string {
@@ -82,11 +124,22 @@ func startRedeployJob(job *redeployJob) {
 	markContainersRedeploying(job.containerIDs)
 	go func() {
 		defer job.markDone()
-		job.Statusf("Redeploy job started for containers: %v (start when finished: %t, advanced logging: %t)", job.containerIDs, job.startAfter, job.enableAdvancedLogging)
-		if err := koth.RedeployContainersWithLogger(job.containerIDs, job, job.startAfter, job.enableAdvancedLogging); err != nil {
-			job.Errorf("Redeploy failed: %v", err)
+		if job.reset {
+			job.Statusf("Reset job started for containers: %v (start when finished: %t, advanced logging: %t)", job.containerIDs, job.startAfter, job.enableAdvancedLogging)
+			if err := koth.ResetContainersWithLogger(job.containerIDs, job, job.startAfter, job.enableAdvancedLogging); err != nil {
+				job.Errorf("Reset failed: %v", err)
+				job.recordFailure(err.Error())
+			} else {
+				job.Successf("Reset completed successfully")
+			}
 		} else {
-			job.Successf("Redeploy completed successfully")
+			job.Statusf("Redeploy job started for containers: %v (start when finished: %t, advanced logging: %t)", job.containerIDs, job.startAfter, job.enableAdvancedLogging)
+			if err := koth.RedeployContainersWithLogger(job.containerIDs, job, job.startAfter, job.enableAdvancedLogging); err != nil {
+				job.Errorf("Redeploy failed: %v", err)
+				job.recordFailure(err.Error())
+			} else {
+				job.Successf("Redeploy completed successfully")
+			}
 		}
 
 		if refreshErr := koth.RefreshContainerStatuses(job.containerIDs); refreshErr != nil {
@@ -5,6 +5,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
 )
 
 type streamJob struct {
@@ -13,9 +15,12 @@ type streamJob struct {
 	CreatedAt time.Time
 
 	mu        sync.Mutex
+	persistMu sync.Mutex // Serializes log line inserts outside mu
 	logs      []string
 	listeners map[chan string]struct{}
 	done      bool
+	failure   string
+	record    *db.Job // nil when the job is not persisted
 }
 
 func newStreamJob(prefix, owner string) *streamJob {
@@ -29,16 +34,50 @@ func newStreamJob(prefix, owner string) *streamJob {
 	return job
 }
 
+// persist records the job in the database so its status and log outlive the process. The record's kind and target
+// fields are filled by the caller; everything else comes from the job.
+func (job *streamJob) persist(record *db.Job) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	record.ID = job.ID
+	record.Owner = job.Owner
+	record.Status = db.JobStatusRunning
+	record.StartedAt = job.CreatedAt
+	record.StartedAtUnix = job.CreatedAt.Unix()
+
+	if err := db.Jobs.Insert(record); err != nil {
+		appLog.Errorf("failed to persist job %s: %v\n", job.ID, err)
+		return
+	}
+
+	job.record = record
+}
+
+// logMessage appends a line to the job's log and hands it to listeners. Persisted jobs also write the line to the
+// database after the job is unlocked; persistMu is taken first, so lines still reach the database in order.
 func (job *streamJob) logMessage(message string) {
 	job.mu.Lock()
 	job.logs = append(job.logs, message)
+	var persisted = job.record != nil
 	for listener := range job.listeners {
 		select {
 		case listener <- message:
 		default:
 		}
 	}
+
+	if persisted {
+		job.persistMu.Lock()
+	}
 	job.mu.Unlock()
+
+	if persisted {
+		defer job.persistMu.Unlock()
+		if err := db.JobLogs.Insert(&db.JobLogLine{JobID: job.ID, Message: message, LoggedAt: time.Now()}); err != nil {
+			appLog.Errorf("failed to persist log line for job %s: %v\n", job.ID, err)
+		}
+	}
 }
 
 func (job *streamJob) subscribe() chan string {
@@ -79,6 +118,13 @@ func (job *streamJob) unsubscribe(ch chan string) {
 // 	return len(job.logs)
 // }
 
+// recordFailure marks the job as failed once it finishes. The latest failure wins.
+func (job *streamJob) recordFailure(message string) {
+	job.mu.Lock()
+	job.failure = message
+	job.mu.Unlock()
+}
+
 func (job *streamJob) markDone() {
 	job.mu.Lock()
 	if job.done {
@@ -90,7 +136,30 @@ func (job *streamJob) markDone() {
 		close(listener)
 		delete(job.listeners, listener)
 	}
+
+	var record *db.Job
+	if job.record != nil {
+		now := time.Now()
+		job.record.Status = db.JobStatusCompleted
+		if job.failure != "" {
+			job.record.Status = db.JobStatusFailed
+			job.record.Error = job.failure
+		}
+		job.record.FinishedAt = now
+		job.record.FinishedAtUnix = now.Unix()
+
+		var final = *job.record
+		record = &final
+		job.persistMu.Lock()
+	}
 	job.mu.Unlock()
+
+	if record != nil {
+		defer job.persistMu.Unlock()
+		if err := db.Jobs.Update(record); err != nil {
+			appLog.Errorf("failed to persist final status of job %s: %v\n", job.ID, err)
+		}
+	}
 }
 
 func sanitizeLogMessage(message string) string {
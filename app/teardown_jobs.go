@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/UNHCSC/pve-koth/auth"
+	"github.com/UNHCSC/pve-koth/db"
 	"github.com/UNHCSC/pve-koth/koth"
 )
 
@@ -23,6 +24,7 @@ func newTeardownJob(user *auth.AuthUser, compID string) *teardownJob {
 		streamJob: newStreamJob("teardown_job", uploadActor(user)),
 		compID:    compID,
 	}
+	job.persist(&db.Job{Kind: "teardown", CompetitionID: compID})
 	registerTeardownJob(job)
 	return job
 }
@@ -75,16 +77,19 @@ func startTeardownJob(job *teardownJob) {
 		comp, err := loadCompetitionByIdentifier(job.compID)
 		if err != nil {
 			job.Errorf("Failed to resolve competition %s: %v", job.compID, err)
+			job.recordFailure(err.Error())
 			appLog.Errorf("teardown[%s] failed to resolve competition %s: %v\n", job.Owner, job.compID, err)
 			return
 		}
 		if comp == nil {
 			job.Errorf("Competition %s not found", job.compID)
+			job.recordFailure("competition not found")
 			return
 		}
 
 		if err := koth.TeardownCompetitionWithLogger(comp, job); err != nil {
 			job.Errorf("Teardown failed: %v", err)
+			job.recordFailure(err.Error())
 			appLog.Errorf("teardown[%s] job %s failed: %v\n", job.Owner, job.ID, err)
 			return
 		}
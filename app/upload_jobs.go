@@ -23,12 +23,13 @@ var (
 	uploadJobsMu sync.RWMutex
 )
 
-func newUploadJob(user *auth.AuthUser) *uploadJob {
+func newUploadJob(user *auth.AuthUser, compID string) *uploadJob {
 	var job = &uploadJob{
 		streamJob: newStreamJob("job", uploadActor(user)),
 		status:    "pending",
 	}
 
+	job.persist(&db.Job{Kind: "provision", CompetitionID: compID})
 	registerUploadJob(job)
 	return job
 }
@@ -71,8 +72,10 @@ func (job *uploadJob) fail(message string, detail error) {
 	job.errorMsg = message
 	if detail != nil {
 		job.errorDetail = detail.Error()
+		message = fmt.Sprintf("%s: %v", message, detail)
 	}
 	job.metaMu.Unlock()
+	job.recordFailure(message)
 	job.markDone()
 }
 
@@ -138,3 +141,18 @@ func startProvisioningJob(job *uploadJob, req db.CreateCompetitionRequest) {
 		job.complete()
 	}()
 }
+
+func startProvisioningResumeJob(job *uploadJob, comp *db.Competition, enableAdvancedLogging bool) {
+	go func() {
+		job.setStatus("provisioning")
+		job.log("provisioning resume started")
+		if err := koth.ResumeCompetitionProvisioningWithLogger(comp, job, enableAdvancedLogging); err != nil {
+			job.log(fmt.Sprintf("Provisioning resume failed: %v", err))
+			job.fail("provisioning resume failed", err)
+			return
+		}
+
+		job.log("Provisioning resume completed successfully")
+		job.complete()
+	}()
+}
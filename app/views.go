@@ -4,8 +4,8 @@ import (
 	"github.com/UNHCSC/pve-koth/auth"
 	"github.com/UNHCSC/pve-koth/config"
 	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/koth"
 	"github.com/gofiber/fiber/v2"
-	"strings"
 )
 
 func showLanding(c *fiber.Ctx) (err error) {
@@ -100,82 +100,49 @@ func showDashboard(c *fiber.Ctx) (err error) {
 		displayName = "Guest"
 	}
 
-	comps, compsErr := db.Competitions.SelectAll()
-	if compsErr != nil {
-		appLog.Errorf("failed to load competitions for dashboard resources: %v\n", compsErr)
-		comps = nil
-	}
-
 	return c.Render("dashboard", bindWithLocals(c, fiber.Map{
 		"Title":        "Dashboard",
 		"User":         displayName,
 		"LoggedIn":     user != nil,
 		"CanManage":    canManage,
-		"ResourceInfo": fiber.Map{"Restrictions": config.Config.ContainerRestrictions, "Network": buildNetworkResourceStats(comps)},
+		"ResourceInfo": fiber.Map{"Restrictions": config.Config.ContainerRestrictions, "Network": buildNetworkResourceStats()},
 	}), "layout")
 }
 
-func buildNetworkResourceStats(comps []*db.Competition) fiber.Map {
+func buildNetworkResourceStats() fiber.Map {
 	network := config.Config.Network
 	info := fiber.Map{
 		"PoolCIDR":          network.PoolCIDR,
+		"PoolCIDR6":         network.PoolCIDR6,
 		"CompetitionPrefix": network.CompetitionSubnetPrefix,
+		"SmallestPrefix":    network.TeamSubnetPrefix - 1,
 		"TeamPrefix":        network.TeamSubnetPrefix,
 		"ContainerCIDR":     network.ContainerCIDR,
 		"Gateway":           network.ContainerGateway,
 		"Nameserver":        network.ContainerNameserver,
 		"SearchDomain":      network.ContainerSearchDomain,
-		"TotalSubnets":      0,
+		"Isolation":         network.Isolation,
+		"IsolationScope":    network.IsolationScope,
 		"UsedSubnets":       0,
-		"FreeSubnets":       0,
+		"TotalAddresses":    uint64(0),
+		"UsedAddresses":     uint64(0),
+		"LargestFreePrefix": 0,
+		"MaxTeamsAvailable": 0,
 		"UsagePercent":      0.0,
 	}
 
-	pool := network.ParsedPool()
-	if pool == nil {
+	usage, err := koth.IPv4PoolUsage()
+	if err != nil {
+		appLog.Errorf("failed to compute network pool usage: %v\n", err)
 		return info
 	}
 
-	maskOnes, _ := pool.Mask.Size()
-	diff := network.CompetitionSubnetPrefix - maskOnes
-	total := 0
-	if diff >= 0 && diff < 63 {
-		total = 1 << diff
-	}
-
-	seen := map[string]struct{}{}
-	for _, comp := range comps {
-		if comp == nil {
-			continue
-		}
-		cidr := strings.TrimSpace(comp.NetworkCIDR)
-		if cidr == "" {
-			continue
-		}
-		if _, ok := seen[cidr]; ok {
-			continue
-		}
-		seen[cidr] = struct{}{}
-	}
-
-	used := len(seen)
-	free := total - used
-	if free < 0 {
-		free = 0
-	}
-
-	usage := 0.0
-	if total > 0 {
-		usage = (float64(used) / float64(total)) * 100
-		if usage > 100 {
-			usage = 100
-		}
-	}
-
-	info["TotalSubnets"] = total
-	info["UsedSubnets"] = used
-	info["FreeSubnets"] = free
-	info["UsagePercent"] = usage
+	info["UsedSubnets"] = usage.Blocks
+	info["TotalAddresses"] = usage.TotalAddresses
+	info["UsedAddresses"] = usage.UsedAddresses
+	info["LargestFreePrefix"] = usage.LargestFreePrefix
+	info["MaxTeamsAvailable"] = usage.MaxTeamsAvailable
+	info["UsagePercent"] = usage.UsagePercent
 	return info
 }
 
@@ -212,3 +179,20 @@ func showScoreboard(c *fiber.Ctx) (err error) {
 		"SelectedCompetitionID": c.Params("competitionID"),
 	}), "layout")
 }
+
+func showTeam(c *fiber.Ctx) (err error) {
+	var user *auth.AuthUser = auth.IsAuthenticated(c, jwtSigningKey)
+
+	var displayName string
+	if user != nil {
+		if displayName, err = user.LDAPConn.DisplayName(); err != nil {
+			displayName = user.LDAPConn.Username
+		}
+	}
+
+	return c.Render("team", bindWithLocals(c, fiber.Map{
+		"Title":    "My Team",
+		"LoggedIn": user != nil,
+		"User":     displayName,
+	}), "layout")
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -17,6 +18,7 @@ type authPerms uint8
 const (
 	AuthPermsNone          authPerms = iota // No permissions, cannot log in
 	AuthPermsUser                           // Can view but not edit
+	AuthPermsPlayer                         // Can view plus see their own team's containers and credentials
 	AuthPermsAdministrator                  // Can do everything
 )
 
@@ -46,6 +48,18 @@ func (user *AuthUser) Permissions() authPerms {
 			}
 		}
 
+		for _, gName := range config.Config.LDAP.PlayerGroups {
+			if slices.Contains(groups, gName) {
+				user.perms = AuthPermsPlayer
+				return user.perms
+			}
+		}
+
+		if teamIDs, err := db.GetUserTeamIDs(user.LDAPConn.Username, groups); err == nil && len(teamIDs) > 0 {
+			user.perms = AuthPermsPlayer
+			return user.perms
+		}
+
 		for _, gName := range config.Config.LDAP.UserGroups {
 			if slices.Contains(groups, gName) {
 				user.perms = AuthPermsUser
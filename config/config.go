@@ -26,14 +26,15 @@ type Configuration struct {
 	} `toml:"database"` // Database configuration
 
 	LDAP struct {
-		Address     string   `toml:"address" default:"" validate:"required"`                   // LDAP server address (e.g. "ldaps://domain.cyber.lab:636")
-		DomainSLD   string   `toml:"domain_sld" default:"" validate:"required"`                // LDAP domain second-level domain (e.g. "cyber" for "domain.cyber.lab")
-		DomainTLD   string   `toml:"domain_tld" default:"" validate:"required"`                // LDAP domain top-level domain (e.g. "lab" for "domain.cyber.lab")
-		AccountsCN  string   `toml:"accounts_cn" default:"accounts" validate:"required"`       // LDAP container name for accounts (usually "accounts")
-		UsersCN     string   `toml:"users_cn" default:"users" validate:"required"`             // LDAP container name for users (usually "users")
-		GroupsCN    string   `toml:"groups_cn" default:"groups" validate:"required"`           // LDAP container name for groups (usually "groups")
-		AdminGroups []string `toml:"admin_groups" default:"[\"admins\"]" validate:"required"`  // LDAP groups whose members should have admin access to the web app
-		UserGroups  []string `toml:"user_groups" default:"[\"ipausers\"]" validate:"required"` // LDAP groups whose members should have user access to the web app
+		Address      string   `toml:"address" default:"" validate:"required"`                   // LDAP server address (e.g. "ldaps://domain.cyber.lab:636")
+		DomainSLD    string   `toml:"domain_sld" default:"" validate:"required"`                // LDAP domain second-level domain (e.g. "cyber" for "domain.cyber.lab")
+		DomainTLD    string   `toml:"domain_tld" default:"" validate:"required"`                // LDAP domain top-level domain (e.g. "lab" for "domain.cyber.lab")
+		AccountsCN   string   `toml:"accounts_cn" default:"accounts" validate:"required"`       // LDAP container name for accounts (usually "accounts")
+		UsersCN      string   `toml:"users_cn" default:"users" validate:"required"`             // LDAP container name for users (usually "users")
+		GroupsCN     string   `toml:"groups_cn" default:"groups" validate:"required"`           // LDAP container name for groups (usually "groups")
+		AdminGroups  []string `toml:"admin_groups" default:"[\"admins\"]" validate:"required"`  // LDAP groups whose members should have admin access to the web app
+		UserGroups   []string `toml:"user_groups" default:"[\"ipausers\"]" validate:"required"` // LDAP groups whose members should have user access to the web app
+		PlayerGroups []string `toml:"player_groups" default:"[]"`                               // LDAP groups whose members get the player role (rostered team members get it automatically)
 	} `toml:"ldap"` // LDAP configuration
 
 	Proxmox struct {
@@ -43,7 +44,10 @@ type Configuration struct {
 		Secret   string `toml:"secret" default:"" validate:"required"`                // Proxmox VE API token secret
 		Username string `toml:"username" default:""`                                  // Proxmox VE username (with realm) for ticket-based console auth, e.g. "root@pam"
 		Password string `toml:"password" default:""`                                  // Proxmox VE password for ticket-based console auth
-		Testing  struct {
+
+		ConsoleSessionIdleSeconds int `toml:"console_session_idle_seconds" default:"300" validate:"min=0"` // Keep logged-in container consoles open this long between commands (0 logs in for every command)
+
+		Testing struct {
 			Enabled        bool   `toml:"enabled" default:"false"`                                                                          // Enable Proxmox VE integration testing mode
 			SubnetCIDR     string `toml:"subnet_cidr" default:"10.255.0.0/16"`                                                              // Subnet CIDR to use for testing VMs
 			Storage        string `toml:"storage" default:"team"`                                                                           // Proxmox VE storage to use for testing VMs
@@ -52,12 +56,39 @@ type Configuration struct {
 			DNS            string `toml:"dns" default:"10.0.0.2"`                                                                           // DNS server IP for testing VMs
 			SearchDomain   string `toml:"search_domain" default:"cyber.lab"`                                                                // Search domain for testing VMs
 		} `toml:"testing"` // Proxmox VE integration testing configuration
+		NodeExec struct {
+			Enabled        bool              `toml:"enabled" default:"false"`                      // Run container commands with pct exec over SSH to the Proxmox node when asked for or when console login is rejected
+			User           string            `toml:"user" default:"root"`                          // SSH user on the nodes; must be able to run pct (non-root users go through sudo -n)
+			PrivateKeyPath string            `toml:"private_key_path" default:""`                  // Private key authorized for that user on every node
+			KnownHostsPath string            `toml:"known_hosts_path" default:""`                  // known_hosts file holding every node's host key, listed under the address koth dials
+			Port           int               `toml:"port" default:"22" validate:"min=1,max=65535"` // SSH port on the nodes
+			Hosts          map[string]string `toml:"hosts"`                                        // Node name to SSH address; unlisted nodes are dialed by name
+		} `toml:"node_exec"` // pct exec through the Proxmox nodes, independent of in-container passwords
 	} `toml:"proxmox"` // Proxmox VE integration configuration
 
 	Storage struct {
 		BasePath string `toml:"base_path" default:"./koth_live_data" validate:"required"` // Root directory where uploaded competition packages are stored
 	} `toml:"storage"`
 
+	Scoring struct {
+		ScorerContainerID  int    `toml:"scorer_container_id" default:"0" validate:"min=0"`   // CTID of an admin-owned container that runs checks for configs using scoringRunner "scorer" (0 disables)
+		ScorerUsername     string `toml:"scorer_username" default:"root"`                     // Console login user for the scorer container
+		ScorerPassword     string `toml:"scorer_password" default:""`                         // Console login password for the scorer container
+		HostShell          string `toml:"host_shell" default:"bash"`                          // Shell used to run checks for configs using scoringRunner "host"
+		HostTimeoutSeconds int    `toml:"host_timeout_seconds" default:"60" validate:"min=1"` // Maximum runtime of a single host-side scoring script
+	} `toml:"scoring"` // Scoring vantage point configuration
+
+	Provisioning struct {
+		MaxConcurrent int    `toml:"max_concurrent" default:"8" validate:"min=1"`                        // Containers provisioned or redeployed at the same time across all jobs
+		Placement     string `toml:"placement" default:"balanced" validate:"oneof=balanced round_robin"` // "balanced" weighs node free memory/CPU/storage, "round_robin" rotates through nodes
+		TeamAffinity  bool   `toml:"team_affinity" default:"false"`                                      // Keep each team's containers on one node (competitions can also opt in)
+	} `toml:"provisioning"` // Provisioning concurrency and node placement
+
+	Firewall struct {
+		ScorerSources []string `toml:"scorer_sources" default:"[]"` // IPs/CIDRs scoring traffic comes from (KotH server, scorer container); matched by firewall rules with peer "scorer"
+		AllowedEgress []string `toml:"allowed_egress" default:"[]"` // IPs/CIDRs containers can still reach when a competition blocks internet egress (e.g. the KotH server)
+	} `toml:"firewall"` // Managed Proxmox firewall settings
+
 	Network               NetworkConfig               `toml:"network"`
 	ContainerRestrictions ContainerRestrictionsConfig `toml:"container_restrictions"`
 }
@@ -65,19 +96,33 @@ type Configuration struct {
 var Config Configuration
 
 type NetworkConfig struct {
-	PoolCIDR                string `toml:"pool_cidr" default:"10.0.0.0/8" validate:"required"`
-	CompetitionSubnetPrefix int    `toml:"competition_subnet_prefix" default:"16" validate:"min=8,max=30"`
-	TeamSubnetPrefix        int    `toml:"team_subnet_prefix" default:"24" validate:"min=8,max=30"`
-	ContainerCIDR           int    `toml:"container_cidr" default:"8" validate:"min=1,max=30"`
-	ContainerGateway        string `toml:"container_gateway" default:"10.0.0.1" validate:"required,ipv4"`
-	ContainerNameserver     string `toml:"container_nameserver" default:"10.0.0.2" validate:"required,ipv4"`
-	ContainerSearchDomain   string `toml:"container_search_domain" default:"cyber.lab" validate:"required"`
-
-	parsedPool *net.IPNet `toml:"-"`
+	PoolCIDR                 string `toml:"pool_cidr" default:"10.0.0.0/8" validate:"required"`
+	CompetitionSubnetPrefix  int    `toml:"competition_subnet_prefix" default:"16" validate:"min=8,max=30"` // Largest block one competition may get; blocks are sized to the team count
+	TeamSubnetPrefix         int    `toml:"team_subnet_prefix" default:"24" validate:"min=8,max=30"`
+	ContainerCIDR            int    `toml:"container_cidr" default:"8" validate:"min=1,max=30"`
+	ContainerGateway         string `toml:"container_gateway" default:"10.0.0.1" validate:"required,ipv4"`
+	ContainerNameserver      string `toml:"container_nameserver" default:"10.0.0.2" validate:"required,ipv4"`
+	ContainerSearchDomain    string `toml:"container_search_domain" default:"cyber.lab" validate:"required"`
+	Bridge                   string `toml:"bridge" default:"vmbr0" validate:"required"`                       // Bridge containers attach to (VLAN tags are applied on it)
+	Isolation                string `toml:"isolation" default:"flat" validate:"oneof=flat vlan sdn"`          // "flat" shares one L2 network, "vlan" tags each segment, "sdn" creates a VNet per segment
+	IsolationScope           string `toml:"isolation_scope" default:"team" validate:"oneof=team competition"` // Isolate every team (and shared containers) or whole competitions from each other
+	VLANMin                  int    `toml:"vlan_min" default:"100" validate:"min=1,max=4094"`                 // First VLAN tag / VNet tag handed out to isolated segments
+	VLANMax                  int    `toml:"vlan_max" default:"3999" validate:"min=1,max=4094"`                // Last VLAN tag / VNet tag handed out to isolated segments
+	SDNZone                  string `toml:"sdn_zone" default:""`                                              // Existing SDN zone (VLAN, QinQ or VXLAN) VNets are created in when isolation is "sdn"
+	PoolCIDR6                string `toml:"pool_cidr6" default:""`                                            // IPv6 pool competitions get a block from; empty keeps networks IPv4-only
+	CompetitionSubnetPrefix6 int    `toml:"competition_subnet_prefix6" default:"56" validate:"oneof=48 56"`   // Size of each competition's IPv6 block; teams always get a /64
+	ContainerGateway6        string `toml:"container_gateway6" default:"" validate:"omitempty,ipv6"`          // IPv6 gateway for every container; empty uses the first host of the container's segment
+
+	parsedPool  *net.IPNet `toml:"-"`
+	parsedPool6 *net.IPNet `toml:"-"`
 }
 
+// TeamSubnetPrefix6 is the size of the IPv6 subnet every team (and the shared containers) get.
+const TeamSubnetPrefix6 = 64
+
 type ContainerRestrictionsConfig struct {
 	AllowedLXCTemplates []string `toml:"allowed_lxc_templates" default:"[]"`
+	AllowedVMTemplates  []int    `toml:"allowed_vm_templates" default:"[]"`
 	AllowedStoragePools []string `toml:"allowed_storage_pools" default:"[]"`
 	MaxCPUCores         int      `toml:"max_cpu_cores" default:"4" validate:"min=1"`
 	MaxMemoryMB         int      `toml:"max_memory_mb" default:"8192" validate:"min=1"`
@@ -107,19 +152,11 @@ func (n *NetworkConfig) initialize() error {
 		return fmt.Errorf("pool_cidr must be an IPv4 network")
 	}
 
-	if maskOnes > 16 {
-		return fmt.Errorf("pool_cidr %s is smaller than /16 and cannot supply competition networks", n.PoolCIDR)
-	}
-
-	if n.CompetitionSubnetPrefix != 16 {
-		return fmt.Errorf("competition_subnet_prefix must currently be 16")
-	}
-
 	if n.CompetitionSubnetPrefix < maskOnes {
 		return fmt.Errorf("competition_subnet_prefix (/ %d) must be equal to or larger than pool prefix (/ %d)", n.CompetitionSubnetPrefix, maskOnes)
 	}
 
-	if n.TeamSubnetPrefix < n.CompetitionSubnetPrefix {
+	if n.TeamSubnetPrefix <= n.CompetitionSubnetPrefix {
 		return fmt.Errorf("team_subnet_prefix (/ %d) must be larger than competition subnet (/ %d)", n.TeamSubnetPrefix, n.CompetitionSubnetPrefix)
 	}
 
@@ -127,11 +164,48 @@ func (n *NetworkConfig) initialize() error {
 		return fmt.Errorf("container_cidr (/ %d) must be less specific than competition subnet (/ %d)", n.ContainerCIDR, n.CompetitionSubnetPrefix)
 	}
 
+	if n.VLANMin > n.VLANMax {
+		return fmt.Errorf("vlan_min (%d) must not be larger than vlan_max (%d)", n.VLANMin, n.VLANMax)
+	}
+
+	if n.Isolation == "sdn" && strings.TrimSpace(n.SDNZone) == "" {
+		return fmt.Errorf("sdn_zone is required when isolation is \"sdn\"")
+	}
+
 	// Canonicalize stored IP reference
 	n.parsedPool.IP = ip.To4()
+
+	n.parsedPool6 = nil
+	if strings.TrimSpace(n.PoolCIDR6) == "" {
+		return nil
+	}
+
+	if ip, n.parsedPool6, err = net.ParseCIDR(strings.TrimSpace(n.PoolCIDR6)); err != nil {
+		return fmt.Errorf("invalid pool_cidr6 %q: %w", n.PoolCIDR6, err)
+	}
+
+	if ip.To4() != nil {
+		return fmt.Errorf("pool_cidr6 must be an IPv6 network")
+	}
+
+	if maskOnes, _ := n.parsedPool6.Mask.Size(); maskOnes > n.CompetitionSubnetPrefix6 {
+		return fmt.Errorf("pool_cidr6 %s is smaller than /%d and cannot supply competition networks", n.PoolCIDR6, n.CompetitionSubnetPrefix6)
+	}
+
 	return nil
 }
 
+// ParsedPool6 returns the IPv6 pool, or nil when competitions are IPv4-only.
+func (n *NetworkConfig) ParsedPool6() *net.IPNet {
+	if n == nil || n.parsedPool6 == nil {
+		return nil
+	}
+
+	var clone = *n.parsedPool6
+	clone.IP = append(net.IP(nil), n.parsedPool6.IP...)
+	return &clone
+}
+
 func (n *NetworkConfig) ParsedPool() *net.IPNet {
 	if n == nil || n.parsedPool == nil {
 		return nil
@@ -163,6 +237,13 @@ func loadConfig(path string) (err error) {
 
 	if err = Config.Network.initialize(); err != nil {
 		err = fmt.Errorf("network config: %w", err)
+		return
+	}
+
+	if Config.Proxmox.NodeExec.Enabled && strings.TrimSpace(Config.Proxmox.NodeExec.PrivateKeyPath) == "" {
+		err = fmt.Errorf("proxmox.node_exec: private_key_path is required when enabled")
+	} else if Config.Proxmox.NodeExec.Enabled && strings.TrimSpace(Config.Proxmox.NodeExec.KnownHostsPath) == "" {
+		err = fmt.Errorf("proxmox.node_exec: known_hosts_path is required when enabled")
 	}
 
 	return
@@ -1,6 +1,11 @@
 package db
 
 import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/UNHCSC/pve-koth/config"
 	"github.com/z46-dev/gomysql"
 )
@@ -11,8 +16,23 @@ var (
 	ScoreResults        *gomysql.RegisteredStruct[ScoreResult]
 	Competitions        *gomysql.RegisteredStruct[Competition]
 	CompetitionPackages *gomysql.RegisteredStruct[CompetitionPackage]
+	ScoreHistory        *gomysql.RegisteredStruct[ScoreHistoryEntry]
+	Ownership           *gomysql.RegisteredStruct[OwnershipRecord]
+	ScoreboardSnapshots *gomysql.RegisteredStruct[ScoreboardSnapshot]
+	TeamMembers         *gomysql.RegisteredStruct[TeamMember]
+	TeamRedeploys       *gomysql.RegisteredStruct[TeamRedeploy]
+	ScoreAdjustments    *gomysql.RegisteredStruct[ScoreAdjustment]
+	Jobs                *gomysql.RegisteredStruct[Job]
+	JobLogs             *gomysql.RegisteredStruct[JobLogLine]
+	NetworkAllocations  *gomysql.RegisteredStruct[NetworkAllocation]
+	VLANAllocations     *gomysql.RegisteredStruct[VLANAllocation]
+	ScoringIssues       *gomysql.RegisteredStruct[ScoringIssue]
 )
 
+// competitionWrites and teamWrites serialize UpdateCompetition and UpdateTeam so concurrent edits of different fields
+// all survive.
+var competitionWrites, teamWrites sync.Mutex
+
 func Init() (err error) {
 	if err = gomysql.Begin(config.Config.Database.File); err != nil {
 		return
@@ -38,6 +58,50 @@ func Init() (err error) {
 		return
 	}
 
+	if ScoreHistory, err = gomysql.Register(ScoreHistoryEntry{}); err != nil {
+		return
+	}
+
+	if Ownership, err = gomysql.Register(OwnershipRecord{}); err != nil {
+		return
+	}
+
+	if ScoreboardSnapshots, err = gomysql.Register(ScoreboardSnapshot{}); err != nil {
+		return
+	}
+
+	if TeamMembers, err = gomysql.Register(TeamMember{}); err != nil {
+		return
+	}
+
+	if TeamRedeploys, err = gomysql.Register(TeamRedeploy{}); err != nil {
+		return
+	}
+
+	if ScoreAdjustments, err = gomysql.Register(ScoreAdjustment{}); err != nil {
+		return
+	}
+
+	if Jobs, err = gomysql.Register(Job{}); err != nil {
+		return
+	}
+
+	if JobLogs, err = gomysql.Register(JobLogLine{}); err != nil {
+		return
+	}
+
+	if NetworkAllocations, err = gomysql.Register(NetworkAllocation{}); err != nil {
+		return
+	}
+
+	if VLANAllocations, err = gomysql.Register(VLANAllocation{}); err != nil {
+		return
+	}
+
+	if ScoringIssues, err = gomysql.Register(ScoringIssue{}); err != nil {
+		return
+	}
+
 	return
 }
 
@@ -63,6 +127,46 @@ func GetCompetitionBySystemID(systemID string) (comp *Competition, err error) {
 	return results[0], nil
 }
 
+// UpdateCompetition reloads competition id, lets mutate change it and saves it when mutate returns true. Writers that
+// go through it never overwrite each other's fields with a stale copy of the row. It returns the saved competition, or
+// nil when it no longer exists.
+func UpdateCompetition(id int64, mutate func(comp *Competition) bool) (comp *Competition, err error) {
+	competitionWrites.Lock()
+	defer competitionWrites.Unlock()
+
+	if comp, err = Competitions.Select(id); err != nil || comp == nil {
+		return nil, err
+	}
+
+	if mutate(comp) {
+		if err = Competitions.Update(comp); err != nil {
+			return nil, err
+		}
+	}
+
+	return comp, nil
+}
+
+// UpdateTeam reloads team id, lets mutate change it and saves it, the same way UpdateCompetition does for
+// competitions. Score changes go through it so scoring rounds, hill points and penalties never drop each other's
+// points.
+func UpdateTeam(id int64, mutate func(team *Team) bool) (team *Team, err error) {
+	teamWrites.Lock()
+	defer teamWrites.Unlock()
+
+	if team, err = Teams.Select(id); err != nil || team == nil {
+		return nil, err
+	}
+
+	if mutate(team) {
+		if err = Teams.Update(team); err != nil {
+			return nil, err
+		}
+	}
+
+	return team, nil
+}
+
 func GetCompetitionPackageBySystemID(systemID string) (pkg *CompetitionPackage, err error) {
 	var filter = gomysql.NewFilter().KeyCmp(CompetitionPackages.FieldBySQLName("competition_id"), gomysql.OpEqual, systemID)
 	var results []*CompetitionPackage
@@ -76,3 +180,371 @@ func GetCompetitionPackageBySystemID(systemID string) (pkg *CompetitionPackage,
 
 	return results[0], nil
 }
+
+// GetScoreHistory returns the recorded check results for a competition between from and to (inclusive).
+// A zero from or to leaves that side of the range open.
+func GetScoreHistory(systemID string, from, to time.Time) (entries []*ScoreHistoryEntry, err error) {
+	var filter = gomysql.NewFilter().KeyCmp(ScoreHistory.FieldBySQLName("competition_id"), gomysql.OpEqual, systemID)
+	if !from.IsZero() {
+		filter = filter.And().KeyCmp(ScoreHistory.FieldBySQLName("recorded_at_unix"), gomysql.OpGreaterThanOrEqual, from.Unix())
+	}
+
+	if !to.IsZero() {
+		filter = filter.And().KeyCmp(ScoreHistory.FieldBySQLName("recorded_at_unix"), gomysql.OpLessThanOrEqual, to.Unix())
+	}
+
+	if entries, err = ScoreHistory.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Round == entries[j].Round {
+			return entries[i].ID < entries[j].ID
+		}
+		return entries[i].Round < entries[j].Round
+	})
+
+	return entries, nil
+}
+
+// GetScoringIssues returns the scorer infrastructure errors recorded for a competition from round fromRound on,
+// oldest first.
+func GetScoringIssues(systemID string, fromRound int64) (issues []*ScoringIssue, err error) {
+	var filter = gomysql.NewFilter().
+		KeyCmp(ScoringIssues.FieldBySQLName("competition_id"), gomysql.OpEqual, systemID).And().
+		KeyCmp(ScoringIssues.FieldBySQLName("round"), gomysql.OpGreaterThanOrEqual, fromRound)
+	if issues, err = ScoringIssues.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Round == issues[j].Round {
+			return issues[i].ID < issues[j].ID
+		}
+		return issues[i].Round < issues[j].Round
+	})
+
+	return issues, nil
+}
+
+// GetOwnershipHistory returns every ownership record for a competition, oldest claim first.
+func GetOwnershipHistory(systemID string) (records []*OwnershipRecord, err error) {
+	var filter = gomysql.NewFilter().KeyCmp(Ownership.FieldBySQLName("competition_id"), gomysql.OpEqual, systemID)
+	if records, err = Ownership.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].ClaimedRound == records[j].ClaimedRound {
+			return records[i].ID < records[j].ID
+		}
+		return records[i].ClaimedRound < records[j].ClaimedRound
+	})
+
+	return records, nil
+}
+
+// GetCurrentOwnership returns the open ownership records (one per held hill) for a competition.
+func GetCurrentOwnership(systemID string) (records []*OwnershipRecord, err error) {
+	var filter = gomysql.NewFilter().
+		KeyCmp(Ownership.FieldBySQLName("competition_id"), gomysql.OpEqual, systemID).And().
+		KeyCmp(Ownership.FieldBySQLName("released_at_unix"), gomysql.OpEqual, 0)
+	if records, err = Ownership.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+
+	return records, nil
+}
+
+func GetScoreboardSnapshot(systemID string) (snapshot *ScoreboardSnapshot, err error) {
+	var filter = gomysql.NewFilter().KeyCmp(ScoreboardSnapshots.FieldBySQLName("competition_id"), gomysql.OpEqual, systemID)
+	var results []*ScoreboardSnapshot
+	if results, err = ScoreboardSnapshots.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	return results[0], nil
+}
+
+// SaveScoreboardSnapshot replaces the competition's stored scoreboard snapshot.
+func SaveScoreboardSnapshot(snapshot *ScoreboardSnapshot) (err error) {
+	if err = DeleteScoreboardSnapshot(snapshot.CompetitionID); err != nil {
+		return
+	}
+
+	return ScoreboardSnapshots.Insert(snapshot)
+}
+
+func DeleteScoreboardSnapshot(systemID string) (err error) {
+	var existing *ScoreboardSnapshot
+	if existing, err = GetScoreboardSnapshot(systemID); err != nil || existing == nil {
+		return
+	}
+
+	return ScoreboardSnapshots.Delete(existing.ID)
+}
+
+// NormalizeUsername canonicalizes an LDAP username for roster comparisons.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// GetTeamMembers returns a team's roster sorted by username.
+func GetTeamMembers(teamID int64) (members []*TeamMember, err error) {
+	var filter = gomysql.NewFilter().KeyCmp(TeamMembers.FieldBySQLName("team_id"), gomysql.OpEqual, teamID)
+	if members, err = TeamMembers.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].Username < members[j].Username
+	})
+
+	return members, nil
+}
+
+// SetTeamMembers replaces a team's roster with the given usernames.
+func SetTeamMembers(competitionID string, teamID int64, usernames []string) (err error) {
+	var existing []*TeamMember
+	if existing, err = GetTeamMembers(teamID); err != nil {
+		return
+	}
+
+	for _, member := range existing {
+		if err = TeamMembers.Delete(member.ID); err != nil {
+			return
+		}
+	}
+
+	var seen = make(map[string]bool, len(usernames))
+	for _, raw := range usernames {
+		username := NormalizeUsername(raw)
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+
+		if err = TeamMembers.Insert(&TeamMember{
+			CompetitionID: competitionID,
+			TeamID:        teamID,
+			Username:      username,
+		}); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+// GetUserTeamIDs returns the IDs of every team the user belongs to, either through the roster or through one of
+// their LDAP groups.
+func GetUserTeamIDs(username string, groups []string) (teamIDs []int64, err error) {
+	var seen = make(map[int64]bool)
+
+	username = NormalizeUsername(username)
+	if username != "" {
+		var filter = gomysql.NewFilter().KeyCmp(TeamMembers.FieldBySQLName("username"), gomysql.OpEqual, username)
+		var members []*TeamMember
+		if members, err = TeamMembers.SelectAllWithFilter(filter); err != nil {
+			return nil, err
+		}
+
+		for _, member := range members {
+			if !seen[member.TeamID] {
+				seen[member.TeamID] = true
+				teamIDs = append(teamIDs, member.TeamID)
+			}
+		}
+	}
+
+	if len(groups) > 0 {
+		var groupSet = make(map[string]bool, len(groups))
+		for _, group := range groups {
+			groupSet[strings.ToLower(strings.TrimSpace(group))] = true
+		}
+
+		var teams []*Team
+		if teams, err = Teams.SelectAll(); err != nil {
+			return nil, err
+		}
+
+		for _, team := range teams {
+			group := strings.ToLower(strings.TrimSpace(team.LDAPGroup))
+			if group != "" && groupSet[group] && !seen[team.ID] {
+				seen[team.ID] = true
+				teamIDs = append(teamIDs, team.ID)
+			}
+		}
+	}
+
+	sort.Slice(teamIDs, func(i, j int) bool {
+		return teamIDs[i] < teamIDs[j]
+	})
+
+	return teamIDs, nil
+}
+
+// GetTeamRedeploys returns a team's self-service redeploys, oldest first.
+func GetTeamRedeploys(teamID int64) (records []*TeamRedeploy, err error) {
+	var filter = gomysql.NewFilter().KeyCmp(TeamRedeploys.FieldBySQLName("team_id"), gomysql.OpEqual, teamID)
+	if records, err = TeamRedeploys.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].RequestedAtUnix == records[j].RequestedAtUnix {
+			return records[i].ID < records[j].ID
+		}
+		return records[i].RequestedAtUnix < records[j].RequestedAtUnix
+	})
+
+	return records, nil
+}
+
+// GetScoreAdjustments returns every manual or penalty score change for a competition, oldest first.
+func GetScoreAdjustments(systemID string) (adjustments []*ScoreAdjustment, err error) {
+	var filter = gomysql.NewFilter().KeyCmp(ScoreAdjustments.FieldBySQLName("competition_id"), gomysql.OpEqual, systemID)
+	if adjustments, err = ScoreAdjustments.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(adjustments, func(i, j int) bool {
+		return adjustments[i].ID < adjustments[j].ID
+	})
+
+	return adjustments, nil
+}
+
+// GetJobs returns every persisted job, newest first.
+func GetJobs() (jobs []*Job, err error) {
+	if jobs, err = Jobs.SelectAll(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].StartedAtUnix == jobs[j].StartedAtUnix {
+			return jobs[i].ID > jobs[j].ID
+		}
+		return jobs[i].StartedAtUnix > jobs[j].StartedAtUnix
+	})
+
+	return jobs, nil
+}
+
+// GetJobLogs returns a job's log lines in the order they were written.
+func GetJobLogs(jobID string) (lines []*JobLogLine, err error) {
+	var filter = gomysql.NewFilter().KeyCmp(JobLogs.FieldBySQLName("job_id"), gomysql.OpEqual, jobID)
+	if lines, err = JobLogs.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].ID < lines[j].ID
+	})
+
+	return lines, nil
+}
+
+// FailInterruptedJobs marks every job still recorded as running as failed. Jobs run in-process, so any job left
+// running when the server starts was cut off by a restart or crash.
+func FailInterruptedJobs(now time.Time) (interrupted []*Job, err error) {
+	var (
+		filter  = gomysql.NewFilter().KeyCmp(Jobs.FieldBySQLName("status"), gomysql.OpEqual, JobStatusRunning)
+		running []*Job
+	)
+
+	if running, err = Jobs.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	for _, job := range running {
+		job.Status = JobStatusFailed
+		job.Error = "interrupted by a server restart"
+		job.FinishedAt = now
+		job.FinishedAtUnix = now.Unix()
+		if err = Jobs.Update(job); err != nil {
+			return interrupted, err
+		}
+
+		if err = JobLogs.Insert(&JobLogLine{JobID: job.ID, Message: "ERROR: job interrupted by a server restart", LoggedAt: now}); err != nil {
+			return interrupted, err
+		}
+
+		interrupted = append(interrupted, job)
+	}
+
+	return interrupted, nil
+}
+
+// ResetInterruptedRedeploys marks every container still recorded as redeploying as unknown, so the status monitor
+// picks up its real state. Redeploys run in-process, so any left redeploying when the server starts were cut off.
+func ResetInterruptedRedeploys(now time.Time) (reset []*Container, err error) {
+	var (
+		filter      = gomysql.NewFilter().KeyCmp(Containers.FieldBySQLName("status"), gomysql.OpEqual, "redeploying")
+		redeploying []*Container
+	)
+
+	if redeploying, err = Containers.SelectAllWithFilter(filter); err != nil {
+		return nil, err
+	}
+
+	for _, container := range redeploying {
+		container.Status = "unknown"
+		container.LastUpdated = now
+		if err = Containers.Update(container); err != nil {
+			return reset, err
+		}
+
+		reset = append(reset, container)
+	}
+
+	return reset, nil
+}
+
+// GetNetworkAllocations returns the blocks allocated from the pool of an address family.
+func GetNetworkAllocations(family string) (allocations []*NetworkAllocation, err error) {
+	var filter = gomysql.NewFilter().KeyCmp(NetworkAllocations.FieldBySQLName("family"), gomysql.OpEqual, family)
+	return NetworkAllocations.SelectAllWithFilter(filter)
+}
+
+// ReleaseNetworkAllocations frees every block allocated to a competition.
+func ReleaseNetworkAllocations(competitionID string) (err error) {
+	var filter = gomysql.NewFilter().KeyCmp(NetworkAllocations.FieldBySQLName("competition_id"), gomysql.OpEqual, competitionID)
+	var allocations []*NetworkAllocation
+	if allocations, err = NetworkAllocations.SelectAllWithFilter(filter); err != nil {
+		return
+	}
+
+	for _, allocation := range allocations {
+		if err = NetworkAllocations.Delete(allocation.ID); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+// ReleaseVLANAllocations frees every VLAN tag allocated to a competition.
+func ReleaseVLANAllocations(competitionID string) (err error) {
+	var filter = gomysql.NewFilter().KeyCmp(VLANAllocations.FieldBySQLName("competition_id"), gomysql.OpEqual, competitionID)
+	var allocations []*VLANAllocation
+	if allocations, err = VLANAllocations.SelectAllWithFilter(filter); err != nil {
+		return
+	}
+
+	for _, allocation := range allocations {
+		if err = VLANAllocations.Delete(allocation.ID); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
@@ -85,20 +85,51 @@ type Team struct {
 	LastUpdated  time.Time `json:"lastUpdated" gomysql:"last_updated"`
 	CreatedAt    time.Time `json:"createdAt" gomysql:"created_at"`
 	NetworkCIDR  string    `json:"networkCIDR" gomysql:"network_cidr"`
+	NetworkCIDR6 string    `json:"networkCIDR6" gomysql:"network_cidr6"` // Empty when the competition is IPv4-only
+	ClaimToken   string    `json:"-" gomysql:"claim_token"`
+	LDAPGroup    string    `json:"ldapGroup" gomysql:"ldap_group"`
+}
+
+// TeamMember is an admin-assigned roster entry linking an LDAP username to a team.
+type TeamMember struct {
+	ID            int64  `json:"id" gomysql:"id,primary,increment"`
+	CompetitionID string `json:"competitionID" gomysql:"competition_id"`
+	TeamID        int64  `json:"teamID" gomysql:"team_id"`
+	Username      string `json:"username" gomysql:"username"`
+}
+
+// TeamRoster seeds a team's membership from config.json, matched to teams by position.
+type TeamRoster struct {
+	Name      string   `json:"name"`      // Display name for the team (defaults to "Team N")
+	LDAPGroup string   `json:"ldapGroup"` // Members of this LDAP group belong to the team
+	Members   []string `json:"members"`   // LDAP usernames assigned to the team directly
 }
 
 type Container struct {
 	PVEID       int64     `json:"id" gomysql:"id,primary,unique"`
 	IPAddress   string    `json:"ipAddress" gomysql:"ip_address,unique"`
+	IPv6Address string    `json:"ipv6Address" gomysql:"ipv6_address"` // Empty when the competition is IPv4-only
 	Status      string    `json:"status" gomysql:"status"`
 	TeamID      int64     `json:"teamID,omitempty" gomysql:"team_id"`
 	ConfigName  string    `json:"containerConfigName,omitempty" gomysql:"container_config_name"`
 	StoragePool string    `json:"storagePool" gomysql:"storage_pool"`
 	NodeName    string    `json:"nodeName" gomysql:"node_name"`
+	Kind        string    `json:"kind" gomysql:"kind"` // "lxc" or "vm"; empty for records created before VMs were supported
 	LastUpdated time.Time `json:"lastUpdated" gomysql:"last_updated"`
 	CreatedAt   time.Time `json:"createdAt" gomysql:"created_at"`
+
+	ExecCredentialError string    `json:"execCredentialError,omitempty" gomysql:"exec_credential_error"` // Why the guest last rejected koth's exec credentials; empty while they work
+	ExecCredentialSince time.Time `json:"execCredentialSince" gomysql:"exec_credential_since"`           // When ExecCredentialError was first seen
 }
 
+// Check states. A check is unknown when the scorer could not evaluate it (the script failed to run, exited non-zero
+// or printed garbage), which is not the team's fault.
+const (
+	CheckStatePass    = "pass"
+	CheckStateFail    = "fail"
+	CheckStateUnknown = "unknown"
+)
+
 type ScoreResult struct {
 	ID             int64     `json:"id" gomysql:"id,primary,increment"`
 	TeamID         int64     `json:"teamID" gomysql:"team_id"`
@@ -110,9 +141,57 @@ type ScoreResult struct {
 	PassPoints     int       `json:"passPoints" gomysql:"pass_points"`
 	FailPoints     int       `json:"failPoints" gomysql:"fail_points"`
 	Passed         bool      `json:"passed" gomysql:"passed"`
+	State          string    `json:"state" gomysql:"state"`                     // CheckStatePass, CheckStateFail or CheckStateUnknown; empty for results from older versions
+	LastKnownState string    `json:"lastKnownState" gomysql:"last_known_state"` // Latest pass or fail, kept through unknown results
+	Detail         string    `json:"detail,omitempty" gomysql:"detail"`         // Why the check is unknown
+	Source         string    `json:"source,omitempty" gomysql:"source"`         // Probe type or scoring script that reports the check
 	UpdatedAt      time.Time `json:"updatedAt" gomysql:"updated_at"`
 }
 
+type ScoreHistoryEntry struct {
+	ID             int64     `json:"id" gomysql:"id,primary,increment"`
+	Round          int64     `json:"round" gomysql:"round"`
+	CompetitionID  string    `json:"competitionID" gomysql:"competition_id"`
+	TeamID         int64     `json:"teamID" gomysql:"team_id"`
+	ContainerName  string    `json:"containerName" gomysql:"container_name"`
+	CheckID        string    `json:"checkID" gomysql:"check_id"`
+	CheckName      string    `json:"checkName" gomysql:"check_name"`
+	Passed         bool      `json:"passed" gomysql:"passed"`
+	State          string    `json:"state" gomysql:"state"` // Empty for entries from older versions and claim points
+	PointsAwarded  int       `json:"pointsAwarded" gomysql:"points_awarded"`
+	RecordedAt     time.Time `json:"recordedAt" gomysql:"recorded_at"`
+	RecordedAtUnix int64     `json:"-" gomysql:"recorded_at_unix"`
+}
+
+// ScoringIssue records a scorer-side failure during a scoring pass: something that left checks unknown instead of
+// passed or failed.
+type ScoringIssue struct {
+	ID             int64     `json:"id" gomysql:"id,primary,increment"`
+	CompetitionID  string    `json:"competitionID" gomysql:"competition_id"`
+	Round          int64     `json:"round" gomysql:"round"`
+	TeamID         int64     `json:"teamID" gomysql:"team_id"`
+	ContainerName  string    `json:"containerName" gomysql:"container_name"`
+	Source         string    `json:"source" gomysql:"source"` // The scoring script, or the step that failed before scripts could run
+	Message        string    `json:"message" gomysql:"message"`
+	UnknownChecks  int       `json:"unknownChecks" gomysql:"unknown_checks"`
+	RecordedAt     time.Time `json:"recordedAt" gomysql:"recorded_at"`
+	RecordedAtUnix int64     `json:"-" gomysql:"recorded_at_unix"`
+}
+
+// OwnershipRecord tracks a team holding a hill. ReleasedAt stays zero while the claim is current.
+type OwnershipRecord struct {
+	ID             int64     `json:"id" gomysql:"id,primary,increment"`
+	CompetitionID  string    `json:"competitionID" gomysql:"competition_id"`
+	HillTeamID     int64     `json:"hillTeamID" gomysql:"hill_team_id"`
+	ContainerName  string    `json:"containerName" gomysql:"container_name"`
+	OwnerTeamID    int64     `json:"ownerTeamID" gomysql:"owner_team_id"`
+	ClaimedRound   int64     `json:"claimedRound" gomysql:"claimed_round"`
+	ClaimedAt      time.Time `json:"claimedAt" gomysql:"claimed_at"`
+	ReleasedAt     time.Time `json:"releasedAt" gomysql:"released_at"`
+	ReleasedAtUnix int64     `json:"-" gomysql:"released_at_unix"`
+	PointsAwarded  int       `json:"pointsAwarded" gomysql:"points_awarded"`
+}
+
 type ContainerRestrictions struct {
 	HostnamePrefix string `json:"hostnamePrefix" gomysql:"hostname_prefix"`
 	RootPassword   string `json:"rootPassword" gomysql:"root_password"`
@@ -142,9 +221,173 @@ type Competition struct {
 	IsPrivate                bool                  `json:"isPrivate" gomysql:"is_private"`
 	PrivateLDAPAllowedGroups []string              `json:"privateLDAPAllowedGroups" gomysql:"private_ldap_allowed_groups"`
 	NetworkCIDR              string                `json:"networkCIDR" gomysql:"network_cidr"`
+	NetworkCIDR6             string                `json:"networkCIDR6" gomysql:"network_cidr6"` // IPv6 block of dual-stack competitions
 	SetupPublicFolder        string                `json:"setupPublicFolder" gomysql:"setup_public_folder"`
 	PackageStoragePath       string                `json:"packageStoragePath" gomysql:"package_storage_path"`
 	ScoringActive            bool                  `json:"scoringActive" gomysql:"scoring_active"`
+	ScoringRound             int64                 `json:"scoringRound" gomysql:"scoring_round"`
+	ScoringIntervalSeconds   int                   `json:"scoringIntervalSeconds" gomysql:"scoring_interval_seconds"`
+	ScoringJitterSeconds     int                   `json:"scoringJitterSeconds" gomysql:"scoring_jitter_seconds"`
+	StartsAt                 time.Time             `json:"startsAt" gomysql:"starts_at"`
+	EndsAt                   time.Time             `json:"endsAt" gomysql:"ends_at"`
+	FreezeMinutes            int                   `json:"freezeMinutes" gomysql:"freeze_minutes"`
+	PowerOnAtStart           bool                  `json:"powerOnAtStart" gomysql:"power_on_at_start"`
+	StartApplied             bool                  `json:"-" gomysql:"start_applied"`
+	EndApplied               bool                  `json:"-" gomysql:"end_applied"`
+	FrozenAt                 time.Time             `json:"frozenAt" gomysql:"frozen_at"`
+	SelfRedeploy             SelfRedeployPolicy    `json:"selfRedeploy" gomysql:"self_redeploy"`
+	ProvisioningStatus       string                `json:"provisioningStatus" gomysql:"provisioning_status"` // Empty for competitions created before it was tracked
+	ProvisioningError        string                `json:"provisioningError" gomysql:"provisioning_error"`
+	TemplateContainerIDs     []int64               `json:"templateContainerIDs" gomysql:"template_container_ids"` // Templates team containers were cloned from
+	NetworkIsolation         string                `json:"networkIsolation" gomysql:"network_isolation"`          // NetworkIsolation* mode the competition was provisioned with; empty means flat
+	NetworkSegments          []NetworkSegment      `json:"networkSegments" gomysql:"network_segments"`            // Isolated L2 segments allocated to the competition
+	Firewall                 FirewallPolicy        `json:"firewall" gomysql:"firewall"`
+}
+
+const (
+	NetworkIsolationFlat = "flat"
+	NetworkIsolationVLAN = "vlan"
+	NetworkIsolationSDN  = "sdn"
+)
+
+// NetworkSegment is an isolated L2 network a competition's containers attach to: a VLAN tag on a bridge, or an SDN
+// VNet carrying that tag.
+type NetworkSegment struct {
+	TeamID   int64  `json:"teamID"` // 0 for the shared containers' segment, or the whole competition's when isolating per competition
+	Tag      int    `json:"tag"`
+	Bridge   string `json:"bridge"` // Bridge the tag is applied on (vlan mode)
+	VNet     string `json:"vnet"`   // SDN VNet name (sdn mode)
+	Zone     string `json:"zone"`   // SDN zone the VNet lives in (sdn mode)
+	CIDR     string `json:"cidr"`
+	Gateway  string `json:"gateway"`
+	CIDR6    string `json:"cidr6"` // IPv6 subnet of dual-stack segments
+	Gateway6 string `json:"gateway6"`
+}
+
+const (
+	ProvisioningStatusRunning = "provisioning"
+	ProvisioningStatusFailed  = "provisioning_failed"
+	ProvisioningStatusReady   = "ready"
+)
+
+// FirewallPolicy is the Proxmox firewall configuration koth keeps on a competition's containers.
+type FirewallPolicy struct {
+	Enabled             bool           `json:"enabled"`
+	DefaultInbound      string         `json:"defaultInbound"`      // ACCEPT (default), DROP or REJECT for inbound traffic no rule matches
+	DefaultOutbound     string         `json:"defaultOutbound"`     // ACCEPT (default), DROP or REJECT for outbound traffic no rule matches
+	BlockInternetEgress bool           `json:"blockInternetEgress"` // Drop outbound traffic that leaves the competition network
+	Rules               []FirewallRule `json:"rules"`
+}
+
+const (
+	FirewallPeerAny         = "any"
+	FirewallPeerTeams       = "teams"       // Every team subnet of the competition
+	FirewallPeerOwnTeam     = "own_team"    // The container's own team subnet
+	FirewallPeerShared      = "shared"      // The shared containers' subnet
+	FirewallPeerScorer      = "scorer"      // [firewall] scorer_sources in config.toml
+	FirewallPeerCompetition = "competition" // The whole competition network
+)
+
+// FirewallRule allows or blocks traffic between a competition's containers and a peer.
+type FirewallRule struct {
+	Direction string `json:"direction"` // "in" (default) or "out"
+	Action    string `json:"action"`    // ACCEPT (default), DROP or REJECT
+	Peer      string `json:"peer"`      // One of the FirewallPeer* names, or an IP/CIDR; empty means any
+	Protocol  string `json:"protocol"`  // tcp, udp, icmp, ...; empty means any
+	Ports     string `json:"ports"`     // Destination ports such as "22", "80,443" or "8000:8100"; needs a protocol
+	Comment   string `json:"comment"`
+}
+
+// SelfRedeployPolicy controls whether team members may redeploy their own containers.
+type SelfRedeployPolicy struct {
+	Enabled         bool `json:"enabled"`
+	CooldownSeconds int  `json:"cooldownSeconds"` // Minimum wait between two redeploys by the same team
+	MaxPerTeam      int  `json:"maxPerTeam"`      // Redeploys each team may request during the competition (0 = unlimited)
+	PenaltyPoints   int  `json:"penaltyPoints"`   // Points deducted from the team for every redeploy
+}
+
+// TeamRedeploy records a redeploy a team member requested for one of their containers.
+type TeamRedeploy struct {
+	ID              int64     `json:"id" gomysql:"id,primary,increment"`
+	CompetitionID   string    `json:"competitionID" gomysql:"competition_id"`
+	TeamID          int64     `json:"teamID" gomysql:"team_id"`
+	ContainerID     int64     `json:"containerID" gomysql:"container_id"`
+	Requester       string    `json:"requester" gomysql:"requester"`
+	JobID           string    `json:"jobID" gomysql:"job_id"`
+	PenaltyPoints   int       `json:"penaltyPoints" gomysql:"penalty_points"`
+	RequestedAt     time.Time `json:"requestedAt" gomysql:"requested_at"`
+	RequestedAtUnix int64     `json:"-" gomysql:"requested_at_unix"`
+}
+
+// ScoreAdjustment records a score change made outside of scoring rounds.
+type ScoreAdjustment struct {
+	ID            int64     `json:"id" gomysql:"id,primary,increment"`
+	CompetitionID string    `json:"competitionID" gomysql:"competition_id"`
+	TeamID        int64     `json:"teamID" gomysql:"team_id"`
+	Points        int       `json:"points" gomysql:"points"`
+	Reason        string    `json:"reason" gomysql:"reason"`
+	Actor         string    `json:"actor" gomysql:"actor"`
+	CreatedAt     time.Time `json:"createdAt" gomysql:"created_at"`
+}
+
+const (
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+const (
+	AddressFamilyIPv4 = "ipv4"
+	AddressFamilyIPv6 = "ipv6"
+)
+
+// NetworkAllocation is an address block handed out from one of the network pools.
+type NetworkAllocation struct {
+	ID            int64     `json:"id" gomysql:"id,primary,increment"`
+	CIDR          string    `json:"cidr" gomysql:"cidr,unique"`
+	Family        string    `json:"family" gomysql:"family"` // AddressFamilyIPv4 or AddressFamilyIPv6
+	CompetitionID string    `json:"competitionID" gomysql:"competition_id"`
+	AllocatedAt   time.Time `json:"allocatedAt" gomysql:"allocated_at"`
+}
+
+// VLANAllocation is a VLAN tag handed out to one of a competition's isolated network segments.
+type VLANAllocation struct {
+	ID            int64     `json:"id" gomysql:"id,primary,increment"`
+	Tag           int       `json:"tag" gomysql:"tag,unique"`
+	CompetitionID string    `json:"competitionID" gomysql:"competition_id"`
+	AllocatedAt   time.Time `json:"allocatedAt" gomysql:"allocated_at"`
+}
+
+// Job is the persisted record of a streamed background job (provisioning, redeploy or teardown).
+type Job struct {
+	ID             string    `json:"id" gomysql:"id,primary,unique"`
+	Kind           string    `json:"kind" gomysql:"kind"`
+	Owner          string    `json:"owner" gomysql:"owner"`
+	CompetitionID  string    `json:"competitionID" gomysql:"competition_id"`
+	TeamID         int64     `json:"teamID" gomysql:"team_id"`
+	ContainerIDs   []int64   `json:"containerIDs" gomysql:"container_ids"`
+	Status         string    `json:"status" gomysql:"status"`
+	Error          string    `json:"error" gomysql:"error"`
+	StartedAt      time.Time `json:"startedAt" gomysql:"started_at"`
+	StartedAtUnix  int64     `json:"-" gomysql:"started_at_unix"`
+	FinishedAt     time.Time `json:"finishedAt" gomysql:"finished_at"`
+	FinishedAtUnix int64     `json:"-" gomysql:"finished_at_unix"`
+}
+
+// JobLogLine is one line of a job's log, in the order it was written.
+type JobLogLine struct {
+	ID       int64     `json:"id" gomysql:"id,primary,increment"`
+	JobID    string    `json:"jobID" gomysql:"job_id"`
+	Message  string    `json:"message" gomysql:"message"`
+	LoggedAt time.Time `json:"loggedAt" gomysql:"logged_at"`
+}
+
+// ScoreboardSnapshot holds the public scoreboard captured when a competition's freeze window began.
+type ScoreboardSnapshot struct {
+	ID            int64     `json:"id" gomysql:"id,primary,increment"`
+	CompetitionID string    `json:"competitionID" gomysql:"competition_id,unique"`
+	Payload       []byte    `json:"payload" gomysql:"payload"`
+	TakenAt       time.Time `json:"takenAt" gomysql:"taken_at"`
 }
 
 type CompetitionPackage struct {
@@ -159,10 +402,31 @@ type CompetitionPackage struct {
 }
 
 type ScoringCheck struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	PassPoints int    `json:"passPoints"`
-	FailPoints int    `json:"failPoints"`
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	PassPoints int           `json:"passPoints"`
+	FailPoints int           `json:"failPoints"`
+	Type       string        `json:"type,omitempty"`   // "" or "script" for script-reported checks, otherwise a built-in probe (tcp, http, dns, icmp, ssh, smtp, ftp)
+	Script     string        `json:"script,omitempty"` // Scoring script that reports the check, when a container has several (optional)
+	Probe      *ScoringProbe `json:"probe,omitempty"`  // Built-in probe parameters; required for tcp and dns, optional for the other probes
+}
+
+// ScoringProbe configures a built-in check executed from the koth host.
+type ScoringProbe struct {
+	Target             string `json:"target,omitempty"`             // Host to probe; a container name in the same team or a literal host (defaults to the scored container)
+	Port               int    `json:"port,omitempty"`               // Port to connect to (defaults per probe type)
+	TimeoutSeconds     int    `json:"timeoutSeconds,omitempty"`     // Per-probe timeout (defaults to 5 seconds)
+	TLS                bool   `json:"tls,omitempty"`                // http: use https; smtp: require STARTTLS
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"` // Skip TLS certificate verification
+	Path               string `json:"path,omitempty"`               // http: request path
+	HostHeader         string `json:"hostHeader,omitempty"`         // http: Host header override
+	ExpectStatus       int    `json:"expectStatus,omitempty"`       // http: required status code (defaults to any 2xx/3xx)
+	BodyRegex          string `json:"bodyRegex,omitempty"`          // http: regex the response body must match
+	RecordName         string `json:"recordName,omitempty"`         // dns: name to resolve
+	RecordType         string `json:"recordType,omitempty"`         // dns: A, AAAA, CNAME, MX, NS, TXT or PTR (defaults to A)
+	Expect             string `json:"expect,omitempty"`             // dns: regex one of the answers must match; ssh/ftp: regex the banner must match; smtp: regex the greeting or EHLO reply must match
+	Username           string `json:"username,omitempty"`           // ssh/smtp/ftp: login username (banner-only check when empty)
+	Password           string `json:"password,omitempty"`           // ssh/smtp/ftp: login password
 }
 
 type ContainerSpecTemplate struct {
@@ -172,29 +436,76 @@ type ContainerSpecTemplate struct {
 	StorageSizeGB int    `json:"storageSizeGB"`
 	MemoryMB      int    `json:"memoryMB"`
 	Cores         int    `json:"cores"`
+	VMTemplateID  int    `json:"vmTemplateID"` // Template VM cloned for kind "vm" configs (needs cloud-init, or cloudbase-init on Windows, and the guest agent)
 }
 
 type TeamContainerConfig struct {
-	Name                   string         `json:"name"`
-	LastOctetValue         int            `json:"lastOctetValue"`
-	SetupScript            []string       `json:"setupScript"`
-	ScoringScript          []string       `json:"scoringScript"`
-	ScoringSchema          []ScoringCheck `json:"scoringSchema"`
-	ContainerSpecsTemplate string         `json:"containerSpecsTemplate"`
+	Name                   string               `json:"name"`
+	Kind                   string               `json:"kind"` // "lxc" (default) or "vm"
+	LastOctetValue         int                  `json:"lastOctetValue"`
+	SetupScript            []string             `json:"setupScript"`
+	ScoringScript          []string             `json:"scoringScript"`
+	ScoringSchema          []ScoringCheck       `json:"scoringSchema"`
+	ContainerSpecsTemplate string               `json:"containerSpecsTemplate"`
+	ScoringRunner          string               `json:"scoringRunner"`
+	ExecTransport          string               `json:"execTransport"` // "console" (default) or "ssh"
+	Claim                  *ClaimConfig         `json:"claim,omitempty"`
+	Node                   string               `json:"node"`              // Pin this container to a Proxmox node (optional)
+	PersonalizeScript      []string             `json:"personalizeScript"` // Run on each team's clone when cloneTeamContainers is set
+	Interfaces             []ContainerInterface `json:"interfaces"`        // Extra interfaces after the team network's eth0
+}
+
+const (
+	InterfaceNetworkTeam   = "team"
+	InterfaceNetworkShared = "shared"
+)
+
+// ContainerInterface is an extra network interface of a container config, attached as eth1, eth2, ... in order.
+type ContainerInterface struct {
+	Name    string `json:"name"`    // Label used in script variables; defaults to eth1, eth2, ...
+	Network string `json:"network"` // "team" (default), "shared" or a CIDR the address is taken from
+	Bridge  string `json:"bridge"`  // Bridge or VNet; defaults to the network's own. Required for CIDR networks
+	VLAN    int    `json:"vlan"`    // 802.1Q tag on the bridge (optional)
+	Offset  int    `json:"offset"`  // Host offset in the network. Outside the team network, team N uses offset+N-1
+	DHCP    bool   `json:"dhcp"`    // Ask for an address instead of using offset
+	Gateway bool   `json:"gateway"` // Route the default gateway through this interface instead of eth0
+}
+
+// ClaimConfig turns a container into a hill that teams capture by writing their claim token into Path.
+type ClaimConfig struct {
+	Path   string `json:"path"`   // Claim file read every scoring tick (defaults to /root/king.txt, or C:\Users\Administrator\king.txt on Windows VMs)
+	Points int    `json:"points"` // Points awarded to the owning team each tick
 }
 
 type CreateCompetitionRequest struct {
-	CompetitionID          string `json:"competitionID"`
-	CompetitionName        string `json:"competitionName"`
-	CompetitionDescription string `json:"competitionDescription"`
-	CompetitionHost        string `json:"competitionHost"`
-	NumTeams               int    `json:"numTeams"`
-	Privacy                struct {
+	CompetitionID          string             `json:"competitionID"`
+	CompetitionName        string             `json:"competitionName"`
+	CompetitionDescription string             `json:"competitionDescription"`
+	CompetitionHost        string             `json:"competitionHost"`
+	NumTeams               int                `json:"numTeams"`
+	TeamRosters            []TeamRoster       `json:"teamRosters"`
+	SelfRedeploy           SelfRedeployPolicy `json:"selfRedeploy"`
+	ScoringIntervalSeconds int                `json:"scoringIntervalSeconds"` // Seconds between scoring rounds (defaults to 60)
+	ScoringJitterSeconds   int                `json:"scoringJitterSeconds"`   // Each round fires up to this many seconds early or late
+	UnknownCheckPolicy     string             `json:"unknownCheckPolicy"`     // "none" (default), "carry" or "retry": how checks the scorer could not evaluate are scored
+	Schedule               struct {
+		StartsAt       time.Time `json:"startsAt"`       // Scoring turns on automatically at this time (optional)
+		EndsAt         time.Time `json:"endsAt"`         // Scoring turns off automatically at this time (optional)
+		FreezeMinutes  int       `json:"freezeMinutes"`  // Public scoreboard freezes this many minutes before endsAt
+		PowerOnAtStart bool      `json:"powerOnAtStart"` // Start every competition container at startsAt
+	} `json:"schedule"`
+	Placement struct {
+		TeamAffinity bool `json:"teamAffinity"` // Keep each team's containers on one node
+	} `json:"placement"`
+	CloneTeamContainers bool           `json:"cloneTeamContainers"` // Build each team container config once, then linked-clone it for every team
+	Firewall            FirewallPolicy `json:"firewall"`
+	Privacy             struct {
 		Public                  bool               `json:"public"`
 		LDAPAllowedGroupsFilter flexibleStringList `json:"ldapAllowedGroupsFilter"`
 	} `json:"privacy"`
 	ContainerSpecsTemplates map[string]ContainerSpecTemplate `json:"containerSpecsTemplates"`
 	TeamContainerConfigs    []TeamContainerConfig            `json:"teamContainerConfigs"`
+	SharedContainerConfigs  []TeamContainerConfig            `json:"sharedContainerConfigs"`
 	TemplateLookup          map[string]ContainerSpecTemplate `json:"-"`
 	SetupPublicFolder       string                           `json:"setupPublicFolder"`
 	WriteupFilePath         string                           `json:"writeupFilePath"`
@@ -204,4 +515,5 @@ type CreateCompetitionRequest struct {
 	} `json:"attachedFiles"`
 	PackagePath           string `json:"-"`
 	EnableAdvancedLogging bool   `json:"enableAdvancedLogging"`
+	KeepPartialOnFailure  bool   `json:"keepPartialOnFailure"` // Keep containers that provisioned when others fail, so provisioning can be resumed
 }
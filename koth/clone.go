@@ -0,0 +1,281 @@
+package koth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/luthermonson/go-proxmox"
+)
+
+// configTemplate is the template every team's copy of one team container config is cloned from. It is built once
+// from the config's first plan, set up without any team identity, and converted to a Proxmox template.
+type configTemplate struct {
+	source *containerPlan
+	ready  chan struct{}
+	ct     *proxmox.Container
+	err    error
+	reused bool // Built by an earlier run of a resumed competition
+}
+
+// markClonePlans switches every team container plan to clone provisioning. Shared containers exist once per
+// competition and VMs are already cloned from a template VM, so both are always built directly.
+func markClonePlans(plans []*containerPlan) {
+	for _, plan := range plans {
+		if plan.team != nil && plan.kind != proxmoxAPI.GuestKindVM {
+			plan.clone = true
+		}
+	}
+}
+
+func templateHostname(comp *db.Competition, name string) string {
+	return fmt.Sprintf("%s-template-%s", comp.ContainerRestrictions.HostnamePrefix, name)
+}
+
+func templateKey(plan *containerPlan) string {
+	return strings.ToLower(strings.TrimSpace(plan.name))
+}
+
+// planTemplates returns one template per team container config that has clone plans, keyed by config name.
+func planTemplates(plans []*containerPlan) map[string]*configTemplate {
+	templates := make(map[string]*configTemplate)
+	for _, plan := range plans {
+		if !plan.clone {
+			continue
+		}
+
+		if _, ok := templates[templateKey(plan)]; !ok {
+			templates[templateKey(plan)] = &configTemplate{
+				source: plan,
+				ready:  make(chan struct{}),
+			}
+		}
+	}
+
+	return templates
+}
+
+// reuseTemplates marks the templates an earlier provisioning run already built, found by hostname among the
+// competition's template containers, as ready so resumed clones use them instead of building new ones.
+func reuseTemplates(log ProgressLogger, comp *db.Competition, templates map[string]*configTemplate) {
+	if len(templates) == 0 || len(comp.TemplateContainerIDs) == 0 {
+		return
+	}
+
+	var ids []int
+	for _, id := range comp.TemplateContainerIDs {
+		ids = append(ids, int(id))
+	}
+
+	guests, err := api.GetGuests(ids)
+	if err != nil {
+		log.Errorf("Failed to look up existing templates; building new ones: %v\n", err)
+		return
+	}
+
+	for _, template := range templates {
+		hostname := templateHostname(comp, template.source.name)
+		for _, guest := range guests {
+			if guest.Container != nil && strings.EqualFold(guest.Container.Name, hostname) {
+				template.ct, template.reused = guest.Container, true
+				close(template.ready)
+				log.Statusf("Reusing template %s (CTID: %d) for %s.", hostname, guest.Container.VMID, template.source.name)
+				break
+			}
+		}
+	}
+}
+
+// build creates, sets up and templates the source container. The returned entry lets the caller clean the template
+// up if provisioning fails; its err is set when the template itself could not be built.
+func (t *configTemplate) build(ctx context.Context, log ProgressLogger, comp *db.Competition, network *teamNetwork, publicFolderURL, artifactBaseURL string, compLock *sync.Mutex, enableAdvancedLogging bool) (entry *provisionedContainer) {
+	defer close(t.ready)
+
+	var (
+		options = *t.source.options
+		plan    = *t.source
+	)
+	options.Hostname = templateHostname(comp, t.source.name)
+	plan.options = &options
+	plan.template = true
+
+	placement := plan.placementRequest()
+	placement.AffinityKey = ""
+
+	var release func()
+	if release, t.err = acquireProvisionSlot(ctx); t.err != nil {
+		return nil
+	}
+	defer release()
+
+	log.Statusf("Building template %s for %s...", options.Hostname, t.source.name)
+
+	var createResult *proxmoxAPI.ProxmoxAPICreateResult
+	if t.err = retryWithDelay(ctx, containerCreateRetries, containerRetryDelay, func(attempt int) error {
+		node, placeErr := api.PlaceContainer(placement)
+		if placeErr != nil {
+			return placeErr
+		}
+
+		result, createErr := api.CreateContainer(node, &options)
+		if createErr != nil {
+			log.Errorf("Failed to create template %s on attempt %d: %v\n", options.Hostname, attempt+1, createErr)
+			return createErr
+		}
+
+		createResult = result
+		return nil
+	}); t.err != nil {
+		t.err = fmt.Errorf("create template %s: %w", options.Hostname, t.err)
+		return nil
+	}
+
+	entry = &provisionedContainer{result: createResult}
+	defer func() {
+		entry.err = t.err
+	}()
+
+	if t.err = retryWithDelay(ctx, containerStartRetries, containerRetryDelay, func(attempt int) error {
+		return api.StartContainer(createResult.Container)
+	}); t.err != nil {
+		t.err = fmt.Errorf("start template %s: %w", options.Hostname, t.err)
+		return
+	}
+
+	if t.err = waitForPlanReady(api, comp, &plan, createResult.Guest()); t.err != nil {
+		t.err = fmt.Errorf("template %s console not ready: %w", options.Hostname, t.err)
+		return
+	}
+
+	if t.err = runSetupScripts(log, api, createResult.Guest(), comp, &plan, network, publicFolderURL, artifactBaseURL, enableAdvancedLogging); t.err != nil {
+		t.err = fmt.Errorf("set up template %s: %w", options.Hostname, t.err)
+		return
+	}
+
+	if t.err = api.StopContainer(createResult.Container); t.err != nil {
+		t.err = fmt.Errorf("stop template %s: %w", options.Hostname, t.err)
+		return
+	}
+
+	if t.err = api.CreateTemplate(createResult.Container); t.err != nil {
+		t.err = fmt.Errorf("convert %s to a template: %w", options.Hostname, t.err)
+		return
+	}
+
+	compLock.Lock()
+	comp.TemplateContainerIDs = append(comp.TemplateContainerIDs, int64(createResult.CTID))
+	compLock.Unlock()
+
+	t.ct = createResult.Container
+	log.Statusf("Template %s (CTID: %d) is ready; cloning it for every team.", options.Hostname, createResult.CTID)
+	return entry
+}
+
+// cloneContainerPlan provisions a team container by linked-cloning its config's template onto the node placement
+// picks for it, applying the team's network settings and running the config's personalization scripts.
+func cloneContainerPlan(ctx context.Context, log ProgressLogger, plan *containerPlan, template *configTemplate, comp *db.Competition, network *teamNetwork, publicFolderURL, artifactBaseURL string, teamLock, compLock *sync.Mutex, enableAdvancedLogging bool) (entry *provisionedContainer, err error) {
+	// Wait before taking a worker slot so waiting clones never starve the template build.
+	select {
+	case <-template.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if template.err != nil {
+		return nil, fmt.Errorf("template for %s is unavailable: %w", plan.name, template.err)
+	}
+
+	var release func()
+	if release, err = acquireProvisionSlot(ctx); err != nil {
+		return nil, err
+	}
+	defer release()
+
+	log.Statusf("Cloning container %s for %s...", plan.options.Hostname, plan.ownerLabel())
+
+	var clone *proxmox.Container
+	if err = retryWithDelay(ctx, containerCreateRetries, containerRetryDelay, func(attempt int) error {
+		node, placeErr := api.PlaceContainer(plan.placementRequest())
+		if placeErr != nil {
+			log.Errorf("Failed to place container %s on attempt %d: %v\n", plan.options.Hostname, attempt+1, placeErr)
+			return placeErr
+		}
+
+		ct, cloneErr := api.CloneTemplate(template.ct, node, plan.options.Hostname, false)
+		if cloneErr != nil && strings.Contains(strings.ToLower(cloneErr.Error()), "linked clone") {
+			log.Statusf("Storage for %s does not support linked clones; making a full clone instead.", plan.options.Hostname)
+			ct, cloneErr = api.CloneTemplate(template.ct, node, plan.options.Hostname, true)
+		}
+		if cloneErr != nil {
+			log.Errorf("Failed to clone container %s on attempt %d: %v\n", plan.options.Hostname, attempt+1, cloneErr)
+			return cloneErr
+		}
+
+		clone = ct
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	entry = &provisionedContainer{
+		plan: plan,
+		result: &proxmoxAPI.ProxmoxAPICreateResult{
+			Container: clone,
+			CTID:      int(clone.VMID),
+		},
+	}
+
+	if err = api.ChangeContainerNetworking(clone, plan.options); err != nil {
+		log.Errorf("Failed to apply networking to %s: %v\n", plan.options.Hostname, err)
+		return entry, err
+	}
+
+	if err = retryWithDelay(ctx, containerStartRetries, containerRetryDelay, func(attempt int) error {
+		startErr := api.StartContainer(clone)
+		if startErr != nil {
+			log.Errorf("Failed to start container %d on attempt %d: %v\n", clone.VMID, attempt+1, startErr)
+		}
+		return startErr
+	}); err != nil {
+		return entry, err
+	}
+
+	if err = waitForPlanReady(api, comp, plan, proxmoxAPI.ContainerGuest(clone)); err != nil {
+		log.Errorf("Container %d console not ready: %v\n", clone.VMID, err)
+		return entry, err
+	}
+
+	if err = runContainerScripts(log, api, proxmoxAPI.ContainerGuest(clone), comp, plan, network, plan.personalize, "personalization", publicFolderURL, artifactBaseURL, enableAdvancedLogging); err != nil {
+		return entry, err
+	}
+
+	if err = finishProvisionedContainer(log, comp, plan, entry, teamLock, compLock); err != nil {
+		return entry, err
+	}
+
+	return entry, nil
+}
+
+// deleteTemplateContainers removes the templates a competition's team containers were cloned from. Linked clones
+// depend on their template, so this must run after the clones are deleted.
+func deleteTemplateContainers(comp *db.Competition, log ProgressLogger) error {
+	if len(comp.TemplateContainerIDs) == 0 {
+		return nil
+	}
+
+	var ids []int
+	for _, id := range comp.TemplateContainerIDs {
+		ids = append(ids, int(id))
+	}
+
+	log.Status("Deleting container templates...")
+	if err := api.BulkCTActionWithRetries(api.BulkDelete, ids, 1+len(ids)/4); err != nil {
+		log.Errorf("Failed to delete container templates: %v\n", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package koth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
+)
+
+const competitionScheduleInterval = 5 * time.Second
+
+var (
+	competitionSchedulerOnce sync.Once
+	scoreboardFreezeLock     sync.RWMutex
+	scoreboardFreezeHandler  func(comp *db.Competition)
+)
+
+// SetScoreboardFreezeHandler registers the callback that captures the public scoreboard when a competition's freeze
+// window begins.
+func SetScoreboardFreezeHandler(handler func(comp *db.Competition)) {
+	scoreboardFreezeLock.Lock()
+	scoreboardFreezeHandler = handler
+	scoreboardFreezeLock.Unlock()
+}
+
+// ValidateCompetitionWindow checks a competition's start/end times and freeze length.
+func ValidateCompetitionWindow(startsAt, endsAt time.Time, freezeMinutes int) error {
+	if freezeMinutes < 0 {
+		return fmt.Errorf("freezeMinutes must not be negative")
+	}
+
+	if freezeMinutes > 0 && endsAt.IsZero() {
+		return fmt.Errorf("freezeMinutes requires endsAt")
+	}
+
+	if !startsAt.IsZero() && !endsAt.IsZero() {
+		if !endsAt.After(startsAt) {
+			return fmt.Errorf("endsAt must be after startsAt")
+		}
+
+		if freezeMinutes > 0 && endsAt.Add(-time.Duration(freezeMinutes)*time.Minute).Before(startsAt) {
+			return fmt.Errorf("freeze window of %d minutes starts before the competition does", freezeMinutes)
+		}
+	}
+
+	return nil
+}
+
+// ScoreboardFreezeStart returns when the competition's public scoreboard freezes, or the zero time when it never does.
+func ScoreboardFreezeStart(comp *db.Competition) time.Time {
+	if comp == nil || comp.FreezeMinutes <= 0 || comp.EndsAt.IsZero() {
+		return time.Time{}
+	}
+
+	return comp.EndsAt.Add(-time.Duration(comp.FreezeMinutes) * time.Minute)
+}
+
+// ScoreboardFrozen reports whether the public scoreboard should be frozen at the given time. The freeze lifts once
+// the competition ends so the final standings are revealed.
+func ScoreboardFrozen(comp *db.Competition, now time.Time) bool {
+	var freezeStart = ScoreboardFreezeStart(comp)
+	if freezeStart.IsZero() {
+		return false
+	}
+
+	return !now.Before(freezeStart) && now.Before(comp.EndsAt)
+}
+
+func StartCompetitionScheduler() {
+	competitionSchedulerOnce.Do(func() {
+		go competitionSchedulerLoop()
+	})
+}
+
+func competitionSchedulerLoop() {
+	scoringLog.Basicf("competition scheduler started (interval %s)\n", competitionScheduleInterval)
+	runCompetitionSchedule(time.Now())
+
+	ticker := time.NewTicker(competitionScheduleInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		runCompetitionSchedule(now)
+	}
+}
+
+func runCompetitionSchedule(now time.Time) {
+	comps, err := db.Competitions.SelectAll()
+	if err != nil {
+		scoringLog.Errorf("failed to load competitions for scheduling: %v\n", err)
+		return
+	}
+
+	for _, comp := range comps {
+		if comp == nil {
+			continue
+		}
+
+		applyCompetitionSchedule(comp, now)
+	}
+}
+
+// applyCompetitionSchedule performs any start, end or freeze transition that is due. Each transition fires once so
+// manual scoring toggles made afterwards are respected.
+func applyCompetitionSchedule(comp *db.Competition, now time.Time) {
+	var started, freezing, thawing bool
+
+	updated, err := db.UpdateCompetition(comp.ID, func(current *db.Competition) (changed bool) {
+		started, freezing, thawing = false, false, false
+
+		if !current.StartsAt.IsZero() && !current.StartApplied && !now.Before(current.StartsAt) {
+			current.StartApplied = true
+			changed = true
+
+			if current.EndsAt.IsZero() || now.Before(current.EndsAt) {
+				current.ScoringActive = true
+				started = true
+			}
+		}
+
+		if !current.EndsAt.IsZero() && !current.EndApplied && !now.Before(current.EndsAt) {
+			current.EndApplied = true
+			current.ScoringActive = false
+			changed = true
+			scoringLog.Statusf("%s: competition ended, scoring stopped\n", current.SystemID)
+		}
+
+		var frozen = ScoreboardFrozen(current, now)
+		if frozen && current.FrozenAt.IsZero() {
+			current.FrozenAt = now
+			changed = true
+			freezing = true
+		} else if !frozen && !current.FrozenAt.IsZero() {
+			current.FrozenAt = time.Time{}
+			changed = true
+			thawing = true
+		}
+
+		return
+	})
+	if err != nil {
+		scoringLog.Errorf("failed to apply schedule for %s: %v\n", comp.SystemID, err)
+		return
+	}
+	if updated == nil {
+		return
+	}
+	comp = updated
+
+	if started {
+		scoringLog.Statusf("%s: competition started, scoring activated\n", comp.SystemID)
+
+		if comp.PowerOnAtStart && len(comp.ContainerIDs) > 0 {
+			if err := BulkStartContainers(comp.ContainerIDs); err != nil {
+				scoringLog.Errorf("failed to power on containers for %s: %v\n", comp.SystemID, err)
+			}
+		}
+
+		ReconfigureScoringSchedule(comp.SystemID)
+	}
+
+	if freezing {
+		scoringLog.Statusf("%s: public scoreboard frozen until %s\n", comp.SystemID, comp.EndsAt.Format(time.RFC3339))
+
+		scoreboardFreezeLock.RLock()
+		handler := scoreboardFreezeHandler
+		scoreboardFreezeLock.RUnlock()
+
+		if handler != nil {
+			handler(comp)
+		}
+	}
+
+	if thawing {
+		scoringLog.Statusf("%s: public scoreboard unfrozen\n", comp.SystemID)
+		if err := db.DeleteScoreboardSnapshot(comp.SystemID); err != nil {
+			scoringLog.Errorf("failed to remove scoreboard snapshot for %s: %v\n", comp.SystemID, err)
+		}
+	}
+}
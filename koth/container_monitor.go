@@ -115,28 +115,24 @@ func ContainerRuntimeSnapshot(ids []int64) (map[int64]ContainerRuntime, error) {
 		return nil, err
 	}
 
-	containers, err := api.GetContainers(intIDs)
+	guests, err := api.GetGuests(intIDs)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, ct := range containers {
-		var id = int64(ct.VMID)
-		var name = strings.TrimSpace(ct.Name)
-		if name == "" && ct.ContainerConfig != nil {
-			name = strings.TrimSpace(ct.ContainerConfig.Hostname)
-		}
+	for _, guest := range guests {
+		var id = int64(guest.ID())
 
-		var status = strings.ToLower(strings.TrimSpace(ct.Status))
+		var status = strings.ToLower(strings.TrimSpace(guest.Status()))
 		if status == "" {
 			status = "unknown"
 		}
 
 		result[id] = ContainerRuntime{
 			ID:     id,
-			Name:   name,
+			Name:   guest.Name(),
 			Status: status,
-			Node:   strings.TrimSpace(ct.Node),
+			Node:   strings.TrimSpace(guest.NodeName()),
 		}
 	}
 
@@ -0,0 +1,312 @@
+package koth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/UNHCSC/pve-koth/ssh"
+)
+
+// Exec transports select how koth runs setup and scoring commands inside a container.
+const (
+	ExecTransportConsole = "console" // the Proxmox termproxy console (containers) or guest agent (VMs)
+	ExecTransportSSH     = "ssh"     // SSH to the container's address with the competition keypair
+	ExecTransportNode    = "node"    // pct exec over SSH to the Proxmox node, needing no guest credentials
+)
+
+const (
+	sshPort           = 22
+	sshConnectRetries = 2
+	sshCommandTimeout = 5 * time.Minute
+	planReadyAttempts = 10
+)
+
+// NormalizeExecTransport validates an execTransport value from config.json, defaulting to the console.
+func NormalizeExecTransport(raw string) (string, error) {
+	switch transport := strings.ToLower(strings.TrimSpace(raw)); transport {
+	case "", ExecTransportConsole:
+		return ExecTransportConsole, nil
+	case ExecTransportSSH:
+		return transport, nil
+	case ExecTransportNode:
+		if !config.Config.Proxmox.NodeExec.Enabled {
+			return "", fmt.Errorf("execTransport %q requires proxmox.node_exec to be enabled in config.toml", transport)
+		}
+		return transport, nil
+	default:
+		return "", fmt.Errorf("unknown execTransport %q (expected %q, %q or %q)", raw, ExecTransportConsole, ExecTransportSSH, ExecTransportNode)
+	}
+}
+
+// execTransport normalizes a config's transport, falling back to the console for values that slipped past validation.
+func execTransport(raw string) string {
+	if transport, err := NormalizeExecTransport(raw); err == nil {
+		return transport
+	}
+
+	return ExecTransportConsole
+}
+
+// execInGuest runs a command inside a plan's guest over its config's transport. With SSH, output is also streamed to
+// stream line by line as it arrives when stream is not nil. When the guest rejects the console password or the
+// competition key, the command is retried through node exec if it is enabled, and the container's record notes the
+// rejection until the transport's credentials work again.
+func execInGuest(api proxmoxAPI.Backend, comp *db.Competition, plan *containerPlan, guest *proxmoxAPI.Guest, username, password, command string, stream ProgressLogger) (stdout, stderr string, exitCode int, err error) {
+	if err = checkGuestTransport(plan, guest); err != nil {
+		return "", "", -1, err
+	}
+
+	switch plan.transport {
+	case ExecTransportNode:
+		stdout, stderr, exitCode, err = api.NodeExecute(guest, command)
+	case ExecTransportSSH:
+		stdout, stderr, exitCode, err = sshExecute(comp, plan.ipAddress, plan.options.Hostname, command, sshConnectRetries, stream)
+	default:
+		stdout, stderr, exitCode, err = api.ExecuteGuestWithRetries(guest, username, password, command, 2)
+	}
+
+	if err == nil {
+		noteExecCredentials(int64(guest.ID()), plan.options.Hostname, "")
+	} else if errors.Is(err, proxmoxAPI.ErrGuestCredentials) {
+		return nodeExecFallback(api, guest, plan.options.Hostname, command, err)
+	}
+
+	return
+}
+
+// checkGuestTransport rejects transports a guest cannot be reached over. Windows VMs have no root login for SSH, so
+// their commands go through the guest agent.
+func checkGuestTransport(plan *containerPlan, guest *proxmoxAPI.Guest) error {
+	if plan.transport == ExecTransportSSH && guest.IsWindows() {
+		return fmt.Errorf("%s is a Windows VM and cannot use execTransport %q; use %q or %q", plan.options.Hostname, ExecTransportSSH, ExecTransportConsole, ExecTransportNode)
+	}
+
+	return nil
+}
+
+// nodeExecFallback reruns a command whose guest credentials were rejected through node exec, when it is enabled, and
+// records the rejection against the container either way.
+func nodeExecFallback(api proxmoxAPI.Backend, guest *proxmoxAPI.Guest, hostname, command string, credErr error) (stdout, stderr string, exitCode int, err error) {
+	var id = int64(guest.ID())
+	if !config.Config.Proxmox.NodeExec.Enabled {
+		noteExecCredentials(id, hostname, credErr.Error())
+		return "", "", -1, credErr
+	}
+
+	if stdout, stderr, exitCode, err = api.NodeExecute(guest, command); err != nil {
+		noteExecCredentials(id, hostname, fmt.Sprintf("%v; node exec fallback also failed", credErr))
+		return "", "", -1, fmt.Errorf("%w (node exec fallback failed: %v)", credErr, err)
+	}
+
+	noteExecCredentials(id, hostname, fmt.Sprintf("%v; commands are running through node exec instead", credErr))
+	return
+}
+
+// execCredentialState caches each container's last recorded credential problem so the database is only written when
+// it changes, not on every scoring pass.
+var execCredentialState = struct {
+	sync.Mutex
+	byID map[int64]string
+}{byID: make(map[int64]string)}
+
+// noteExecCredentials records why a container's exec credentials were rejected, or clears the note when problem is
+// empty. Changes are logged and persisted on the container record for the admin dashboard.
+func noteExecCredentials(id int64, hostname, problem string) {
+	execCredentialState.Lock()
+	defer execCredentialState.Unlock()
+
+	if last, known := execCredentialState.byID[id]; known && last == problem {
+		return
+	}
+
+	record, err := db.Containers.Select(id)
+	if err != nil || record == nil {
+		return
+	}
+
+	execCredentialState.byID[id] = problem
+	if record.ExecCredentialError == problem {
+		return
+	}
+
+	if problem != "" {
+		containerLog.Errorf("Exec credentials for %s (CTID %d) stopped working: %s\n", hostname, id, problem)
+		if record.ExecCredentialError == "" {
+			record.ExecCredentialSince = time.Now()
+		}
+	} else {
+		containerLog.Successf("Exec credentials for %s (CTID %d) work again.", hostname, id)
+		record.ExecCredentialSince = time.Time{}
+	}
+
+	record.ExecCredentialError = problem
+	if err = db.Containers.Update(record); err != nil {
+		containerLog.Errorf("failed to record exec credential state for %s: %v\n", hostname, err)
+	}
+}
+
+// forgetExecCredentials drops the cached credential state of a CTID that now belongs to a fresh container record.
+func forgetExecCredentials(id int64) {
+	execCredentialState.Lock()
+	defer execCredentialState.Unlock()
+
+	delete(execCredentialState.byID, id)
+}
+
+// sshExecute runs a command as root on host, authenticating with the competition's private key.
+func sshExecute(comp *db.Competition, host, hostname, command string, numRetries int, stream ProgressLogger) (stdout, stderr string, exitCode int, err error) {
+	if strings.TrimSpace(host) == "" {
+		return "", "", -1, fmt.Errorf("%s has no address to connect to", hostname)
+	}
+
+	var key []byte
+	if key, err = os.ReadFile(comp.SSHPrivKeyPath); err != nil {
+		return "", "", -1, fmt.Errorf("read competition SSH key: %w", err)
+	}
+
+	auth, err := ssh.PrivateKeyAuth(key)
+	if err != nil {
+		return "", "", -1, err
+	}
+
+	var conn *ssh.SSHConnection
+	for i := range numRetries + 1 {
+		if conn, err = ssh.Connect("root", host, sshPort, auth); err == nil {
+			break
+		}
+
+		// The team removed the competition key; retrying cannot help.
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			return "", "", -1, fmt.Errorf("%w: %s no longer accepts the competition SSH key", proxmoxAPI.ErrGuestCredentials, hostname)
+		}
+
+		if i < numRetries {
+			time.Sleep(time.Second * (time.Duration(i) + 1))
+		}
+	}
+	if err != nil {
+		return "", "", -1, fmt.Errorf("connect to %s over SSH: %w", hostname, err)
+	}
+
+	var (
+		timedOut  atomic.Bool
+		closeOnce sync.Once
+		closeConn = func() { closeOnce.Do(func() { _ = conn.Close() }) }
+		timer     = time.AfterFunc(sshCommandTimeout, func() {
+			timedOut.Store(true)
+			closeConn()
+		})
+	)
+	defer closeConn()
+
+	var (
+		stdoutBuf, stderrBuf bytes.Buffer
+		stdoutW, stderrW     io.Writer = &stdoutBuf, &stderrBuf
+		stdoutLines          *lineLogger
+		stderrLines          *lineLogger
+	)
+
+	if stream != nil {
+		stdoutLines = &lineLogger{log: stream, prefix: hostname}
+		stderrLines = &lineLogger{log: stream, prefix: hostname + " stderr"}
+		stdoutW = io.MultiWriter(&stdoutBuf, stdoutLines)
+		stderrW = io.MultiWriter(&stderrBuf, stderrLines)
+	}
+
+	exitCode, err = conn.SendWithStreams(command, stdoutW, stderrW)
+	timer.Stop()
+
+	if stream != nil {
+		stdoutLines.Flush()
+		stderrLines.Flush()
+	}
+
+	if timedOut.Load() {
+		err = fmt.Errorf("command on %s timed out after %s", hostname, sshCommandTimeout)
+	}
+
+	return stdoutBuf.String(), stderrBuf.String(), exitCode, err
+}
+
+// waitForPlanReady waits until commands can be run in a plan's freshly started guest over its transport. SSH waits
+// for sshd to accept the competition key, and node exec for pct exec to work, instead of the console or guest agent.
+func waitForPlanReady(api proxmoxAPI.Backend, comp *db.Competition, plan *containerPlan, guest *proxmoxAPI.Guest) error {
+	if err := checkGuestTransport(plan, guest); err != nil {
+		return err
+	}
+
+	var probe func() (int, error)
+	switch plan.transport {
+	case ExecTransportSSH:
+		probe = func() (int, error) {
+			_, _, exitCode, err := sshExecute(comp, plan.ipAddress, plan.options.Hostname, "echo KOTH_READY", 0, nil)
+			return exitCode, err
+		}
+	case ExecTransportNode:
+		probe = func() (int, error) {
+			_, _, exitCode, err := api.NodeExecute(guest, "echo KOTH_READY")
+			return exitCode, err
+		}
+	default:
+		return waitForGuestReady(api, guest, plan.options.RootPassword)
+	}
+
+	var lastErr error
+	for i := range planReadyAttempts {
+		exitCode, err := probe()
+		if err == nil && exitCode == 0 {
+			return nil
+		} else if err == nil {
+			err = fmt.Errorf("readiness check exited with code %d", exitCode)
+		}
+
+		lastErr = err
+		time.Sleep(time.Second * time.Duration(i+1))
+	}
+
+	return fmt.Errorf("container not reachable over %s: %w", plan.transport, lastErr)
+}
+
+// lineLogger forwards complete lines written to it to a progress log.
+type lineLogger struct {
+	log     ProgressLogger
+	prefix  string
+	pending []byte
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.pending = append(l.pending, p...)
+	for {
+		idx := bytes.IndexByte(l.pending, '\n')
+		if idx < 0 {
+			break
+		}
+
+		l.emit(l.pending[:idx])
+		l.pending = l.pending[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush logs a trailing line that did not end in a newline.
+func (l *lineLogger) Flush() {
+	if len(l.pending) > 0 {
+		l.emit(l.pending)
+		l.pending = nil
+	}
+}
+
+func (l *lineLogger) emit(line []byte) {
+	l.log.Statusf("[%s] %s", l.prefix, strings.TrimRight(string(line), "\r"))
+}
@@ -0,0 +1,387 @@
+package koth
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/luthermonson/go-proxmox"
+)
+
+// firewallCommentPrefix marks the guest firewall rules koth owns, so rules added by hand are left alone.
+const firewallCommentPrefix = "koth-managed"
+
+// firewallIPSetKinds are the suffixes of the cluster IPSets every firewalled competition gets.
+var firewallIPSetKinds = []string{"net", "teams", "shared", "scorer", "egress"}
+
+var firewallPortsPattern = regexp.MustCompile(`^[0-9]+(:[0-9]+)?(,[0-9]+(:[0-9]+)?)*$`)
+
+// NormalizeFirewallPolicy validates a competition's firewall policy and fills in its defaults.
+func NormalizeFirewallPolicy(policy db.FirewallPolicy) (db.FirewallPolicy, error) {
+	var err error
+	if policy.DefaultInbound, err = normalizeFirewallAction(policy.DefaultInbound); err != nil {
+		return policy, fmt.Errorf("firewall defaultInbound: %w", err)
+	}
+	if policy.DefaultOutbound, err = normalizeFirewallAction(policy.DefaultOutbound); err != nil {
+		return policy, fmt.Errorf("firewall defaultOutbound: %w", err)
+	}
+
+	var rules = make([]db.FirewallRule, 0, len(policy.Rules))
+	for idx, rule := range policy.Rules {
+		switch rule.Direction = strings.ToLower(strings.TrimSpace(rule.Direction)); rule.Direction {
+		case "":
+			rule.Direction = "in"
+		case "in", "out":
+		default:
+			return policy, fmt.Errorf("firewall rule %d: direction must be \"in\" or \"out\"", idx+1)
+		}
+
+		if rule.Action, err = normalizeFirewallAction(rule.Action); err != nil {
+			return policy, fmt.Errorf("firewall rule %d: %w", idx+1, err)
+		}
+
+		rule.Peer = strings.TrimSpace(rule.Peer)
+		switch strings.ToLower(rule.Peer) {
+		case "", db.FirewallPeerAny:
+			rule.Peer = db.FirewallPeerAny
+		case db.FirewallPeerTeams, db.FirewallPeerOwnTeam, db.FirewallPeerShared, db.FirewallPeerScorer, db.FirewallPeerCompetition:
+			rule.Peer = strings.ToLower(rule.Peer)
+		default:
+			if net.ParseIP(rule.Peer) == nil {
+				if _, _, cidrErr := net.ParseCIDR(rule.Peer); cidrErr != nil {
+					return policy, fmt.Errorf("firewall rule %d: unknown peer %q", idx+1, rule.Peer)
+				}
+			}
+		}
+
+		rule.Protocol = strings.ToLower(strings.TrimSpace(rule.Protocol))
+		rule.Ports = strings.ReplaceAll(strings.TrimSpace(rule.Ports), " ", "")
+		if rule.Ports != "" {
+			if rule.Protocol != "tcp" && rule.Protocol != "udp" {
+				return policy, fmt.Errorf("firewall rule %d: ports need protocol tcp or udp", idx+1)
+			}
+			if !firewallPortsPattern.MatchString(rule.Ports) {
+				return policy, fmt.Errorf("firewall rule %d: invalid ports %q", idx+1, rule.Ports)
+			}
+		}
+
+		rule.Comment = strings.TrimSpace(rule.Comment)
+		rules = append(rules, rule)
+	}
+	policy.Rules = rules
+
+	return policy, nil
+}
+
+func normalizeFirewallAction(raw string) (string, error) {
+	switch action := strings.ToUpper(strings.TrimSpace(raw)); action {
+	case "":
+		return "ACCEPT", nil
+	case "ACCEPT", "DROP", "REJECT":
+		return action, nil
+	default:
+		return "", fmt.Errorf("unknown action %q (expected ACCEPT, DROP or REJECT)", raw)
+	}
+}
+
+// Security group names are limited to 18 characters, so everything is keyed by the competition's database ID.
+func firewallGroupName(comp *db.Competition) string {
+	return fmt.Sprintf("koth%d", comp.ID)
+}
+
+func firewallIPSetName(comp *db.Competition, set string) string {
+	return fmt.Sprintf("koth%d-%s", comp.ID, set)
+}
+
+// firewallIPSets returns the cluster IPSets a competition's rules refer to, keyed by set name.
+func firewallIPSets(comp *db.Competition) (map[string][]string, error) {
+	var teams []string
+	for _, teamID := range comp.TeamIDs {
+		team, err := db.Teams.Select(teamID)
+		if err != nil {
+			return nil, fmt.Errorf("load team %d: %w", teamID, err)
+		}
+		if team != nil {
+			teams = append(teams, nonEmpty(team.NetworkCIDR, team.NetworkCIDR6)...)
+		}
+	}
+
+	shared, err := SharedSubnetCIDR(comp)
+	if err != nil {
+		return nil, err
+	}
+
+	shared6, err := SharedSubnetCIDR6(comp)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]string{
+		firewallIPSetName(comp, "net"):    nonEmpty(comp.NetworkCIDR, comp.NetworkCIDR6),
+		firewallIPSetName(comp, "teams"):  teams,
+		firewallIPSetName(comp, "shared"): nonEmpty(shared, shared6),
+		firewallIPSetName(comp, "scorer"): config.Config.Firewall.ScorerSources,
+		firewallIPSetName(comp, "egress"): config.Config.Firewall.AllowedEgress,
+	}, nil
+}
+
+// firewallPeerAddress returns the rule address for a peer, or "" for any address.
+func firewallPeerAddress(comp *db.Competition, peer, teamCIDR string) string {
+	switch peer {
+	case db.FirewallPeerAny:
+		return ""
+	case db.FirewallPeerOwnTeam:
+		return teamCIDR
+	case db.FirewallPeerTeams:
+		return "+" + firewallIPSetName(comp, "teams")
+	case db.FirewallPeerShared:
+		return "+" + firewallIPSetName(comp, "shared")
+	case db.FirewallPeerScorer:
+		return "+" + firewallIPSetName(comp, "scorer")
+	case db.FirewallPeerCompetition:
+		return "+" + firewallIPSetName(comp, "net")
+	default:
+		return peer
+	}
+}
+
+func proxmoxFirewallRule(comp *db.Competition, rule db.FirewallRule, teamCIDR string) *proxmox.FirewallRule {
+	var result = &proxmox.FirewallRule{
+		Type:    rule.Direction,
+		Action:  rule.Action,
+		Proto:   rule.Protocol,
+		Dport:   rule.Ports,
+		Enable:  1,
+		Comment: strings.TrimSpace(fmt.Sprintf("%s %s", firewallCommentPrefix, rule.Comment)),
+	}
+
+	if rule.Direction == "out" {
+		result.Dest = firewallPeerAddress(comp, rule.Peer, teamCIDR)
+	} else {
+		result.Source = firewallPeerAddress(comp, rule.Peer, teamCIDR)
+	}
+
+	return result
+}
+
+// firewallGroupRules translates the policy into the competition's security group. Rules that depend on the
+// container's own team are written on each guest instead.
+func firewallGroupRules(comp *db.Competition) []*proxmox.FirewallRule {
+	var rules []*proxmox.FirewallRule
+	for _, rule := range comp.Firewall.Rules {
+		if rule.Peer != db.FirewallPeerOwnTeam {
+			rules = append(rules, proxmoxFirewallRule(comp, rule, ""))
+		}
+	}
+
+	if comp.Firewall.BlockInternetEgress {
+		var allow = func(dest, comment string) {
+			rules = append(rules, &proxmox.FirewallRule{Type: "out", Action: "ACCEPT", Dest: dest, Enable: 1, Comment: firewallCommentPrefix + " " + comment})
+		}
+
+		allow("+"+firewallIPSetName(comp, "net"), "competition network")
+		allow("+"+firewallIPSetName(comp, "egress"), "allowed egress")
+		allow(config.Config.Network.ContainerNameserver, "nameserver")
+		rules = append(rules, &proxmox.FirewallRule{Type: "out", Action: "DROP", Enable: 1, Comment: firewallCommentPrefix + " block internet egress"})
+	}
+
+	return rules
+}
+
+// guestFirewallRules returns the rules written on a single container: its own-team rules, once per address family
+// of the team's subnets, then the competition group.
+func guestFirewallRules(comp *db.Competition, teamCIDRs []string) []*proxmox.FirewallRule {
+	var rules []*proxmox.FirewallRule
+	for _, rule := range comp.Firewall.Rules {
+		if rule.Peer != db.FirewallPeerOwnTeam {
+			continue
+		}
+		for _, teamCIDR := range teamCIDRs {
+			rules = append(rules, proxmoxFirewallRule(comp, rule, teamCIDR))
+		}
+	}
+
+	return append(rules, &proxmox.FirewallRule{
+		Type:    "group",
+		Action:  firewallGroupName(comp),
+		Enable:  1,
+		Comment: firewallCommentPrefix + " competition policy",
+	})
+}
+
+// syncFirewallObjects writes the competition's IPSets and security group.
+func syncFirewallObjects(comp *db.Competition) error {
+	sets, err := firewallIPSets(comp)
+	if err != nil {
+		return err
+	}
+
+	for name, cidrs := range sets {
+		if err = api.EnsureIPSet(name, fmt.Sprintf("KotH %s", comp.SystemID), cidrs); err != nil {
+			return err
+		}
+	}
+
+	return api.ReplaceSecurityGroup(firewallGroupName(comp), fmt.Sprintf("KotH %s", comp.SystemID), firewallGroupRules(comp))
+}
+
+func deleteFirewallObjects(comp *db.Competition) error {
+	var combinedErr = api.DeleteSecurityGroup(firewallGroupName(comp))
+	for _, kind := range firewallIPSetKinds {
+		combinedErr = errors.Join(combinedErr, api.DeleteIPSet(firewallIPSetName(comp, kind)))
+	}
+
+	return combinedErr
+}
+
+// setupCompetitionFirewall creates the firewall objects of a new competition whose policy is enabled.
+func setupCompetitionFirewall(log ProgressLogger, comp *db.Competition) error {
+	if !comp.Firewall.Enabled {
+		return nil
+	}
+
+	log.Status("Writing firewall security group and IPSets...")
+	if err := syncFirewallObjects(comp); err != nil {
+		return fmt.Errorf("set up firewall: %w", err)
+	}
+
+	return nil
+}
+
+// teamFirewallCIDRs returns the subnets a team's containers live in, IPv4 first; team 0 is the shared subnet.
+func teamFirewallCIDRs(comp *db.Competition, teamID int64) ([]string, error) {
+	if teamID == 0 {
+		cidr, err := SharedSubnetCIDR(comp)
+		if err != nil {
+			return nil, err
+		}
+
+		cidr6, err := SharedSubnetCIDR6(comp)
+		if err != nil {
+			return nil, err
+		}
+
+		return nonEmpty(cidr, cidr6), nil
+	}
+
+	team, err := db.Teams.Select(teamID)
+	if err != nil {
+		return nil, fmt.Errorf("load team %d: %w", teamID, err)
+	}
+	if team == nil {
+		return nil, fmt.Errorf("team %d not found", teamID)
+	}
+
+	return nonEmpty(team.NetworkCIDR, team.NetworkCIDR6), nil
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
+// applyGuestFirewall points a container at the competition's security group and applies the default policies. With
+// the policy disabled it removes koth's rules again.
+func applyGuestFirewall(comp *db.Competition, teamID int64, guest *proxmoxAPI.Guest) error {
+	if !comp.Firewall.Enabled {
+		return api.SetGuestFirewall(guest, proxmoxAPI.GuestFirewallOptions{PolicyIn: "ACCEPT", PolicyOut: "ACCEPT"}, firewallCommentPrefix, nil)
+	}
+
+	teamCIDRs, err := teamFirewallCIDRs(comp, teamID)
+	if err != nil {
+		return err
+	}
+
+	return api.SetGuestFirewall(guest, proxmoxAPI.GuestFirewallOptions{
+		Enable:    true,
+		PolicyIn:  comp.Firewall.DefaultInbound,
+		PolicyOut: comp.Firewall.DefaultOutbound,
+	}, firewallCommentPrefix, guestFirewallRules(comp, teamCIDRs))
+}
+
+// UpdateCompetitionFirewall stores a new firewall policy and applies it to the competition's running containers
+// immediately. Disabling the policy removes everything koth created.
+func UpdateCompetitionFirewall(comp *db.Competition, policy db.FirewallPolicy) (err error) {
+	if comp == nil {
+		return fmt.Errorf("competition is nil")
+	}
+
+	if api == nil {
+		return fmt.Errorf("proxmox API is not initialized")
+	}
+
+	if policy, err = NormalizeFirewallPolicy(policy); err != nil {
+		return err
+	}
+
+	var wasEnabled bool
+	if comp, err = db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+		wasEnabled = current.Firewall.Enabled
+		current.Firewall = policy
+		return true
+	}); err != nil {
+		return fmt.Errorf("update competition: %w", err)
+	} else if comp == nil {
+		return fmt.Errorf("competition no longer exists")
+	}
+
+	if !policy.Enabled && !wasEnabled {
+		return nil
+	}
+
+	if policy.Enabled {
+		if err = syncFirewallObjects(comp); err != nil {
+			return err
+		}
+	}
+
+	var combinedErr error
+	for _, id := range comp.ContainerIDs {
+		record, selectErr := db.Containers.Select(id)
+		if selectErr != nil || record == nil {
+			continue
+		}
+
+		guest, guestErr := api.Guest(int(record.PVEID))
+		if guestErr != nil {
+			combinedErr = errors.Join(combinedErr, fmt.Errorf("container %d: %w", record.PVEID, guestErr))
+			continue
+		}
+
+		if applyErr := applyGuestFirewall(comp, record.TeamID, guest); applyErr != nil {
+			combinedErr = errors.Join(combinedErr, applyErr)
+		}
+	}
+
+	if !policy.Enabled {
+		combinedErr = errors.Join(combinedErr, deleteFirewallObjects(comp))
+	}
+
+	return combinedErr
+}
+
+// teardownCompetitionFirewall removes the security group and IPSets once the competition's containers are gone.
+func teardownCompetitionFirewall(comp *db.Competition, log ProgressLogger) error {
+	if comp == nil || !comp.Firewall.Enabled {
+		return nil
+	}
+
+	log.Status("Deleting firewall security group and IPSets...")
+	if err := deleteFirewallObjects(comp); err != nil {
+		log.Errorf("Failed to delete firewall objects: %v\n", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package koth
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+)
+
+// maxExtraInterfaces is how many interfaces a config may add after eth0; Proxmox numbers guest NICs net0 to net31.
+const maxExtraInterfaces = 31
+
+// resolvedInterface is a container config interface with its network and, unless it uses DHCP, its address.
+type resolvedInterface struct {
+	spec    db.ContainerInterface
+	label   string
+	network string // db.InterfaceNetworkTeam, db.InterfaceNetworkShared or a CIDR
+	address net.IP
+}
+
+func interfaceNetwork(raw string) string {
+	switch network := strings.ToLower(strings.TrimSpace(raw)); network {
+	case "", db.InterfaceNetworkTeam:
+		return db.InterfaceNetworkTeam
+	case db.InterfaceNetworkShared:
+		return network
+	default:
+		return strings.TrimSpace(raw)
+	}
+}
+
+func interfaceLabel(iface db.ContainerInterface, idx int) string {
+	if strings.TrimSpace(iface.Name) == "" {
+		return fmt.Sprintf("eth%d", idx+1)
+	}
+
+	return sanitizeContainerName(iface.Name)
+}
+
+// ValidateContainerInterfaces checks the extra interfaces of a container config.
+func ValidateContainerInterfaces(cfg db.TeamContainerConfig) error {
+	if len(cfg.Interfaces) > maxExtraInterfaces {
+		return fmt.Errorf("container %s declares %d interfaces; at most %d are supported", cfg.Name, len(cfg.Interfaces), maxExtraInterfaces)
+	}
+
+	var (
+		labels   = make(map[string]struct{}, len(cfg.Interfaces))
+		gateways int
+	)
+
+	for idx, iface := range cfg.Interfaces {
+		var label = interfaceLabel(iface, idx)
+		if _, dup := labels[label]; dup {
+			return fmt.Errorf("container %s has more than one interface named %s", cfg.Name, label)
+		}
+		labels[label] = struct{}{}
+
+		var network = interfaceNetwork(iface.Network)
+		if network != db.InterfaceNetworkTeam && network != db.InterfaceNetworkShared {
+			ip, _, err := net.ParseCIDR(network)
+			if err != nil || ip.To4() == nil {
+				return fmt.Errorf("interface %s of container %s: network must be team, shared or an IPv4 CIDR", label, cfg.Name)
+			}
+			if strings.TrimSpace(iface.Bridge) == "" {
+				return fmt.Errorf("interface %s of container %s: a bridge is required for network %s", label, cfg.Name, network)
+			}
+		}
+
+		if iface.VLAN < 0 || iface.VLAN > 4094 {
+			return fmt.Errorf("interface %s of container %s: vlan %d is out of range", label, cfg.Name, iface.VLAN)
+		}
+
+		if iface.DHCP {
+			if iface.Gateway {
+				return fmt.Errorf("interface %s of container %s: dhcp interfaces take their gateway from DHCP", label, cfg.Name)
+			}
+		} else if iface.Offset <= 0 {
+			return fmt.Errorf("interface %s of container %s needs an offset or dhcp", label, cfg.Name)
+		}
+
+		if iface.Gateway {
+			gateways++
+		}
+	}
+
+	if gateways > 1 {
+		return fmt.Errorf("container %s routes its gateway through %d interfaces; pick one", cfg.Name, gateways)
+	}
+
+	return nil
+}
+
+// resolveContainerInterfaces assigns addresses to a config's extra interfaces. teamIndex is -1 for shared containers.
+// Team interfaces use the owner's own subnet; on the shared subnet or a custom CIDR every team gets its own address,
+// offset by its index.
+func resolveContainerInterfaces(compSubnet *net.IPNet, teamIndex int, ifaces []db.ContainerInterface) ([]resolvedInterface, error) {
+	var resolved = make([]resolvedInterface, 0, len(ifaces))
+	for idx, iface := range ifaces {
+		var entry = resolvedInterface{
+			spec:    iface,
+			label:   interfaceLabel(iface, idx),
+			network: interfaceNetwork(iface.Network),
+		}
+
+		if !iface.DHCP {
+			var (
+				base   uint32
+				prefix = config.Config.Network.TeamSubnetPrefix
+				offset = iface.Offset
+				err    error
+			)
+
+			switch entry.network {
+			case db.InterfaceNetworkTeam:
+				if teamIndex < 0 {
+					base, err = sharedSubnetBaseIP(compSubnet)
+				} else {
+					base, err = teamSubnetBaseIP(compSubnet, teamIndex)
+				}
+			case db.InterfaceNetworkShared:
+				base, err = sharedSubnetBaseIP(compSubnet)
+				offset += max(teamIndex, 0)
+			default:
+				var block *net.IPNet
+				if _, block, err = net.ParseCIDR(entry.network); err == nil {
+					base = ipToUint32(block.IP)
+					prefix, _ = block.Mask.Size()
+					offset += max(teamIndex, 0)
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("interface %s: %w", entry.label, err)
+			}
+
+			if entry.address, err = hostIPWithinSubnet(base, prefix, offset); err != nil {
+				return nil, fmt.Errorf("interface %s: %w", entry.label, err)
+			}
+		}
+
+		resolved = append(resolved, entry)
+	}
+
+	return resolved, nil
+}
+
+// addInterfaces records the addresses of a container's extra interfaces for its scripts' environment.
+func (n *teamNetwork) addInterfaces(sanitizedName string, resolved []resolvedInterface) {
+	for _, iface := range resolved {
+		if iface.address == nil {
+			continue
+		}
+
+		if n.interfaceIPs == nil {
+			n.interfaceIPs = make(map[string]map[string]string)
+		}
+		if n.interfaceIPs[sanitizedName] == nil {
+			n.interfaceIPs[sanitizedName] = make(map[string]string)
+		}
+		n.interfaceIPs[sanitizedName][iface.label] = iface.address.String()
+	}
+}
+
+// attachInterfaces fills in the plan's extra interfaces. Interfaces on the team or shared network default to that
+// network's bridge, VLAN and gateway; an explicit bridge or vlan replaces them.
+func attachInterfaces(comp *db.Competition, plan *containerPlan) error {
+	plan.options.Interfaces = nil
+	if len(plan.interfaces) == 0 {
+		return nil
+	}
+
+	_, compSubnet, err := net.ParseCIDR(strings.TrimSpace(comp.NetworkCIDR))
+	if err != nil {
+		return fmt.Errorf("parse competition network: %w", err)
+	}
+
+	var teamIndex = -1
+	if plan.team != nil {
+		if teamIndex = findTeamIndex(comp.TeamIDs, plan.team.ID); teamIndex < 0 {
+			return fmt.Errorf("team %d is not part of competition %s", plan.team.ID, comp.SystemID)
+		}
+	}
+
+	resolved, err := resolveContainerInterfaces(compSubnet, teamIndex, plan.interfaces)
+	if err != nil {
+		return fmt.Errorf("container %s: %w", plan.name, err)
+	}
+
+	for _, entry := range resolved {
+		var iface = proxmoxAPI.NetworkInterface{
+			Bridge:      plan.options.Bridge,
+			VLANTag:     plan.options.VLANTag,
+			DHCP:        entry.spec.DHCP,
+			CIDRBlock:   plan.options.CIDRBlock,
+			GatewayIPv4: plan.options.GatewayIPv4,
+		}
+
+		switch entry.network {
+		case db.InterfaceNetworkTeam:
+		case db.InterfaceNetworkShared:
+			if isolated(comp) {
+				if segment, ok := networkSegmentFor(comp, 0); ok {
+					iface.Bridge, iface.VLANTag, iface.CIDRBlock, iface.GatewayIPv4 = segmentAttachment(comp, segment)
+				}
+			}
+		default:
+			_, block, _ := net.ParseCIDR(entry.network)
+			iface.CIDRBlock, _ = block.Mask.Size()
+			iface.GatewayIPv4 = uint32ToIP(ipToUint32(block.IP) + 1).String()
+		}
+
+		if strings.TrimSpace(entry.spec.Bridge) != "" || entry.spec.VLAN != 0 {
+			iface.Bridge = strings.TrimSpace(entry.spec.Bridge)
+			if iface.Bridge == "" {
+				iface.Bridge = config.Config.Network.Bridge
+			}
+			iface.VLANTag = entry.spec.VLAN
+		}
+
+		if entry.address != nil {
+			iface.IPv4Address = entry.address.String()
+		}
+		if !entry.spec.Gateway {
+			iface.GatewayIPv4 = ""
+		}
+
+		plan.options.Interfaces = append(plan.options.Interfaces, iface)
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package koth
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
+)
+
+// isolatedGatewayOffset is the host offset of the gateway inside every isolated segment, so containers may not use it.
+const isolatedGatewayOffset = 1
+
+func isolationMode() string {
+	switch mode := strings.ToLower(strings.TrimSpace(config.Config.Network.Isolation)); mode {
+	case db.NetworkIsolationVLAN, db.NetworkIsolationSDN:
+		return mode
+	default:
+		return db.NetworkIsolationFlat
+	}
+}
+
+// ValidateNetworkIsolation rejects container configs that would collide with the gateway of an isolated segment, or
+// with the default IPv6 gateway of dual-stack networks.
+func ValidateNetworkIsolation(req *db.CreateCompetitionRequest) error {
+	var defaultGateway6 = dualStack() && strings.TrimSpace(config.Config.Network.ContainerGateway6) == ""
+	if req == nil || (isolationMode() == db.NetworkIsolationFlat && !defaultGateway6) {
+		return nil
+	}
+
+	for _, cfg := range append(append([]db.TeamContainerConfig(nil), req.TeamContainerConfigs...), req.SharedContainerConfigs...) {
+		if cfg.LastOctetValue == isolatedGatewayOffset {
+			return fmt.Errorf("container %s uses lastOctetValue %d, which is reserved for the segment gateway", cfg.Name, isolatedGatewayOffset)
+		}
+	}
+
+	return nil
+}
+
+// NextFreeVLANTags returns the count lowest tags in [min, max] that are not in used.
+func NextFreeVLANTags(used map[int]struct{}, min, max, count int) ([]int, error) {
+	var tags = make([]int, 0, count)
+	for tag := min; tag <= max && len(tags) < count; tag++ {
+		if _, taken := used[tag]; !taken {
+			tags = append(tags, tag)
+		}
+	}
+
+	if len(tags) < count {
+		return nil, fmt.Errorf("only %d of %d VLAN tags are free between %d and %d", len(tags), count, min, max)
+	}
+
+	return tags, nil
+}
+
+// allocateVLANTags reserves the count lowest free tags of [network] vlanMin-vlanMax for competitionID. Like
+// allocateBlock it holds allocationMu and records the tags, so competitions created at once never share one.
+func allocateVLANTags(competitionID string, count int) ([]int, error) {
+	allocationMu.Lock()
+	defer allocationMu.Unlock()
+
+	allocations, err := db.VLANAllocations.SelectAll()
+	if err != nil {
+		return nil, fmt.Errorf("fetch VLAN allocations: %w", err)
+	}
+
+	var used = make(map[int]struct{}, len(allocations))
+	for _, allocation := range allocations {
+		used[allocation.Tag] = struct{}{}
+	}
+
+	tags, err := NextFreeVLANTags(used, config.Config.Network.VLANMin, config.Config.Network.VLANMax, count)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range tags {
+		if err = db.VLANAllocations.Insert(&db.VLANAllocation{
+			Tag:           tag,
+			CompetitionID: competitionID,
+			AllocatedAt:   time.Now(),
+		}); err != nil {
+			return nil, fmt.Errorf("record VLAN allocation %d: %w", tag, err)
+		}
+	}
+
+	return tags, nil
+}
+
+// newNetworkSegment describes an isolated segment covering cidr and, for dual-stack competitions, cidr6. Its gateways
+// are the first hosts of the blocks.
+func newNetworkSegment(mode string, teamID int64, cidr, cidr6 string, tag int) (db.NetworkSegment, error) {
+	var _, block, err = net.ParseCIDR(cidr)
+	if err != nil {
+		return db.NetworkSegment{}, fmt.Errorf("parse segment network %q: %w", cidr, err)
+	}
+
+	var segment = db.NetworkSegment{
+		TeamID:  teamID,
+		Tag:     tag,
+		CIDR:    block.String(),
+		Gateway: uint32ToIP(ipToUint32(block.IP) + isolatedGatewayOffset).String(),
+	}
+
+	if cidr6 != "" {
+		var block6 *net.IPNet
+		if _, block6, err = net.ParseCIDR(cidr6); err != nil {
+			return db.NetworkSegment{}, fmt.Errorf("parse segment network %q: %w", cidr6, err)
+		}
+
+		var gateway6 net.IP
+		if gateway6, err = hostIP6WithinSubnet(block6, isolatedGatewayOffset); err != nil {
+			return db.NetworkSegment{}, err
+		}
+		segment.CIDR6, segment.Gateway6 = block6.String(), gateway6.String()
+	}
+
+	if mode == db.NetworkIsolationSDN {
+		// VNet names are limited to 8 characters; tags are unique, so they make the name unique too.
+		segment.VNet = fmt.Sprintf("koth%d", tag)
+		segment.Zone = strings.TrimSpace(config.Config.Network.SDNZone)
+	} else {
+		segment.Bridge = strings.TrimSpace(config.Config.Network.Bridge)
+	}
+
+	return segment, nil
+}
+
+// setupNetworkIsolation allocates the competition's isolated segments according to [network] isolation and, for SDN,
+// creates their VNets. Segments are stored on the competition before anything is created so teardown can always find
+// them.
+func setupNetworkIsolation(log ProgressLogger, comp *db.Competition, teams []*db.Team, hasShared bool) (err error) {
+	var mode = isolationMode()
+	comp.NetworkIsolation = mode
+	if mode == db.NetworkIsolationFlat {
+		return nil
+	}
+
+	type segmentBlock struct {
+		teamID int64
+		cidr   string
+		cidr6  string
+	}
+
+	var blocks []segmentBlock
+	if strings.EqualFold(config.Config.Network.IsolationScope, "competition") {
+		blocks = append(blocks, segmentBlock{cidr: comp.NetworkCIDR, cidr6: comp.NetworkCIDR6})
+	} else {
+		for _, team := range teams {
+			blocks = append(blocks, segmentBlock{teamID: team.ID, cidr: team.NetworkCIDR, cidr6: team.NetworkCIDR6})
+		}
+
+		if hasShared {
+			var sharedCIDR, sharedCIDR6 string
+			if sharedCIDR, err = SharedSubnetCIDR(comp); err != nil {
+				return err
+			}
+			if sharedCIDR6, err = SharedSubnetCIDR6(comp); err != nil {
+				return err
+			}
+			blocks = append(blocks, segmentBlock{cidr: sharedCIDR, cidr6: sharedCIDR6})
+		}
+	}
+
+	var tags []int
+	if tags, err = allocateVLANTags(comp.SystemID, len(blocks)); err != nil {
+		return err
+	}
+
+	comp.NetworkSegments = make([]db.NetworkSegment, 0, len(blocks))
+	for idx, block := range blocks {
+		var segment db.NetworkSegment
+		if segment, err = newNetworkSegment(mode, block.teamID, block.cidr, block.cidr6, tags[idx]); err != nil {
+			return err
+		}
+		comp.NetworkSegments = append(comp.NetworkSegments, segment)
+	}
+
+	if _, err = db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+		current.NetworkIsolation = comp.NetworkIsolation
+		current.NetworkSegments = comp.NetworkSegments
+		return true
+	}); err != nil {
+		return fmt.Errorf("record network segments: %w", err)
+	}
+
+	if mode == db.NetworkIsolationSDN {
+		log.Statusf("Creating %d SDN VNets in zone %s...", len(comp.NetworkSegments), config.Config.Network.SDNZone)
+		for _, segment := range comp.NetworkSegments {
+			if err = api.CreateVNet(segment.VNet, segment.Zone, segment.Tag, segment.CIDR, segment.Gateway, fmt.Sprintf("KotH %s", comp.SystemID)); err != nil {
+				return err
+			}
+			if segment.CIDR6 != "" {
+				if err = api.CreateVNetSubnet(segment.VNet, segment.CIDR6, segment.Gateway6); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err = api.ApplySDN(); err != nil {
+			return err
+		}
+	}
+
+	log.Statusf("Isolated the competition into %d %s network segments.", len(comp.NetworkSegments), mode)
+	return nil
+}
+
+// teardownNetworkIsolation removes the SDN VNets created for a competition. VLAN segments are only tags on the bridge,
+// so there is nothing to remove for them.
+func teardownNetworkIsolation(comp *db.Competition, log ProgressLogger) error {
+	if comp == nil || comp.NetworkIsolation != db.NetworkIsolationSDN || len(comp.NetworkSegments) == 0 {
+		return nil
+	}
+
+	log.Statusf("Deleting %d SDN VNets...", len(comp.NetworkSegments))
+
+	var combinedErr error
+	for _, segment := range comp.NetworkSegments {
+		var cidrs = []string{segment.CIDR}
+		if segment.CIDR6 != "" {
+			cidrs = append(cidrs, segment.CIDR6)
+		}
+
+		if err := api.DeleteVNet(segment.VNet, segment.Zone, cidrs...); err != nil {
+			log.Errorf("Failed to delete VNet %s: %v\n", segment.VNet, err)
+			combinedErr = errors.Join(combinedErr, err)
+		}
+	}
+
+	if err := api.ApplySDN(); err != nil {
+		log.Errorf("Failed to apply SDN changes: %v\n", err)
+		combinedErr = errors.Join(combinedErr, err)
+	}
+
+	return combinedErr
+}
+
+// networkSegmentFor returns the segment a team's containers attach to. Shared containers, and every container when
+// isolating per competition, use the segment with team ID 0.
+func networkSegmentFor(comp *db.Competition, teamID int64) (db.NetworkSegment, bool) {
+	var fallback *db.NetworkSegment
+	for idx := range comp.NetworkSegments {
+		if comp.NetworkSegments[idx].TeamID == teamID {
+			return comp.NetworkSegments[idx], true
+		}
+		if comp.NetworkSegments[idx].TeamID == 0 {
+			fallback = &comp.NetworkSegments[idx]
+		}
+	}
+
+	if fallback != nil {
+		return *fallback, true
+	}
+
+	return db.NetworkSegment{}, false
+}
+
+func isolated(comp *db.Competition) bool {
+	return comp.NetworkIsolation != "" && comp.NetworkIsolation != db.NetworkIsolationFlat
+}
+
+// segmentAttachment returns the bridge, VLAN tag, prefix and gateway of an interface attached to segment.
+func segmentAttachment(comp *db.Competition, segment db.NetworkSegment) (bridge string, tag, prefix int, gateway string) {
+	if comp.NetworkIsolation == db.NetworkIsolationSDN {
+		bridge = segment.VNet
+	} else {
+		bridge, tag = segment.Bridge, segment.Tag
+	}
+
+	if _, block, err := net.ParseCIDR(segment.CIDR); err == nil {
+		prefix, _ = block.Mask.Size()
+		gateway = segment.Gateway
+	}
+
+	return
+}
+
+// applyNetworkAttachment points a plan's network interfaces at the bridge, VLAN or VNet of their segment. Isolated
+// containers use their segment's gateway and prefix instead of the flat network's.
+func applyNetworkAttachment(comp *db.Competition, plan *containerPlan) error {
+	if !isolated(comp) {
+		plan.options.Bridge = config.Config.Network.Bridge
+	} else if segment, ok := networkSegmentFor(comp, plan.teamID()); ok {
+		var bridge, tag, prefix, gateway = segmentAttachment(comp, segment)
+		plan.options.Bridge, plan.options.VLANTag = bridge, tag
+		if gateway != "" {
+			plan.options.GatewayIPv4, plan.options.CIDRBlock = gateway, prefix
+		}
+	}
+
+	attachIPv6(comp, plan)
+	return attachInterfaces(comp, plan)
+}
@@ -2,6 +2,7 @@ package koth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
@@ -23,7 +24,7 @@ import (
 )
 
 var (
-	api *proxmoxAPI.ProxmoxAPI
+	api proxmoxAPI.Backend
 )
 
 const (
@@ -74,10 +75,29 @@ func wrapLoggerSafe(log ProgressLogger) ProgressLogger {
 }
 
 func Init() (err error) {
-	api, err = proxmoxAPI.InitProxmox()
+	var live *proxmoxAPI.ProxmoxAPI
+	if live, err = proxmoxAPI.InitProxmox(); err != nil {
+		return
+	}
+
+	api = live
 	return
 }
 
+// SetBackend replaces the Proxmox backend koth drives, e.g. with a proxmoxfake.Cluster in tests.
+func SetBackend(backend proxmoxAPI.Backend) {
+	api = backend
+}
+
+// ConsoleStats reports how often container console execution reused tickets and logged-in consoles.
+func ConsoleStats() proxmoxAPI.ConsoleStats {
+	if api == nil {
+		return proxmoxAPI.ConsoleStats{}
+	}
+
+	return api.ConsoleStats()
+}
+
 type ProgressLogger interface {
 	Status(message string)
 	Statusf(format string, args ...any)
@@ -86,24 +106,59 @@ type ProgressLogger interface {
 }
 
 type containerPlan struct {
-	team          *db.Team
+	team          *db.Team // nil for shared competition containers
 	name          string
 	sanitizedName string
 	order         int
 	ipAddress     string
+	ipAddress6    string // Empty for IPv4-only competitions
 	setupScripts  []string
 	options       *proxmoxAPI.ContainerCreateOptions
+	node          string // Pinned Proxmox node, empty to let placement decide
+	affinityKey   string // Plans sharing a key are placed on the same node
+	clone         bool   // Linked-clone this container from its config's template instead of building it
+	template      bool   // This is the config's template, which every team is cloned from
+	personalize   []string
+	kind          string // proxmoxAPI.GuestKindContainer or proxmoxAPI.GuestKindVM
+	transport     string // ExecTransportConsole, ExecTransportSSH or ExecTransportNode
+	vmTemplateID  int
+	interfaces    []db.ContainerInterface // Extra interfaces, resolved into options by applyNetworkAttachment
 }
 
 type teamNetwork struct {
-	ipsByName map[string]string
-	ipOrder   []string
+	ipsByName        map[string]string
+	ipOrder          []string
+	sharedIPsByName  map[string]string
+	sharedIPOrder    []string
+	interfaceIPs     map[string]map[string]string // Container name -> interface label -> address of extra interfaces
+	sharedIfaceIPs   map[string]map[string]string
+	ip6sByName       map[string]string // IPv6 addresses of dual-stack competitions; empty otherwise
+	ip6Order         []string
+	sharedIP6sByName map[string]string
+	sharedIP6Order   []string
+}
+
+// teamID returns the owning team's ID, or 0 for shared competition containers.
+func (p *containerPlan) teamID() int64 {
+	if p == nil || p.team == nil {
+		return 0
+	}
+	return p.team.ID
+}
+
+// ownerLabel names the owner of the container in progress logs.
+func (p *containerPlan) ownerLabel() string {
+	if p == nil || p.team == nil {
+		return "the competition (shared)"
+	}
+	return p.team.Name
 }
 
 type provisionedContainer struct {
 	plan     *containerPlan
 	result   *proxmoxAPI.ProxmoxAPICreateResult
 	recorded bool
+	err      error
 }
 
 func CreateNewComp(request *db.CreateCompetitionRequest) (comp *db.Competition, err error) {
@@ -177,13 +232,55 @@ func CreateNewCompWithLogger(request *db.CreateCompetitionRequest, logSink Progr
 		return
 	}
 
+	var scoringInterval, scoringJitter int
+	if scoringInterval, scoringJitter, err = NormalizeScoringSchedule(request.ScoringIntervalSeconds, request.ScoringJitterSeconds); err != nil {
+		localLog.Errorf("Invalid scoring schedule: %v\n", err)
+		return
+	}
+
+	if err = ValidateCompetitionWindow(request.Schedule.StartsAt, request.Schedule.EndsAt, request.Schedule.FreezeMinutes); err != nil {
+		localLog.Errorf("Invalid competition schedule: %v\n", err)
+		return
+	}
+
+	if err = ValidateSelfRedeployPolicy(request.SelfRedeploy); err != nil {
+		localLog.Errorf("Invalid self-service redeploy policy: %v\n", err)
+		return
+	}
+
+	if _, err = NormalizeUnknownCheckPolicy(request.UnknownCheckPolicy); err != nil {
+		localLog.Errorf("Invalid unknown check policy: %v\n", err)
+		return
+	}
+
 	localLog.Status("Allocating network resources...")
 	var compSubnet *net.IPNet
-	if compSubnet, err = allocateCompetitionSubnet(); err != nil {
+	if compSubnet, err = allocateCompetitionSubnet(request.CompetitionID, request.NumTeams); err != nil {
 		localLog.Errorf("Failed to allocate competition subnet: %v\n", err)
 		return
 	}
 
+	var (
+		provisioned  []*provisionedContainer
+		createdTeams []*db.Team
+		keptPartial  bool
+	)
+
+	// From here on a failure undoes everything created so far: containers, teams, the competition record, its data
+	// directory and package, and the address blocks. Keep-partial failures are left for a resume instead.
+	defer func() {
+		if err != nil && !keptPartial {
+			cleanupProvisionedContainers(localLog, comp, provisioned)
+			cleanupFailedCompetitionResources(localLog, comp, createdTeams, dataDir, request.CompetitionID, request.PackagePath)
+		}
+	}()
+
+	var compSubnet6 *net.IPNet
+	if compSubnet6, err = allocateCompetitionSubnet6(request.CompetitionID); err != nil {
+		localLog.Errorf("Failed to allocate competition IPv6 subnet: %v\n", err)
+		return
+	}
+
 	localLog.Status("Creating competition record...")
 
 	comp = &db.Competition{
@@ -209,10 +306,23 @@ func CreateNewCompWithLogger(request *db.CreateCompetitionRequest, logSink Progr
 
 			return []string(request.Privacy.LDAPAllowedGroupsFilter)
 		}(),
-		NetworkCIDR:        compSubnet.String(),
-		SetupPublicFolder:  publicFolderRel,
-		PackageStoragePath: packageRoot,
-		ScoringActive:      false,
+		NetworkCIDR:            compSubnet.String(),
+		SetupPublicFolder:      publicFolderRel,
+		PackageStoragePath:     packageRoot,
+		ScoringActive:          false,
+		ScoringIntervalSeconds: scoringInterval,
+		ScoringJitterSeconds:   scoringJitter,
+		StartsAt:               request.Schedule.StartsAt,
+		EndsAt:                 request.Schedule.EndsAt,
+		FreezeMinutes:          request.Schedule.FreezeMinutes,
+		PowerOnAtStart:         request.Schedule.PowerOnAtStart,
+		SelfRedeploy:           request.SelfRedeploy,
+		Firewall:               request.Firewall,
+		ProvisioningStatus:     db.ProvisioningStatusRunning,
+	}
+
+	if compSubnet6 != nil {
+		comp.NetworkCIDR6 = compSubnet6.String()
 	}
 
 	if err = db.Competitions.Insert(comp); err != nil {
@@ -236,19 +346,24 @@ func CreateNewCompWithLogger(request *db.CreateCompetitionRequest, logSink Progr
 
 	// 3. Create containers for each team
 	localLog.Status("Creating container templates...")
-	var maxTeams = maxTeamsPerCompetition()
-	if maxTeams == 0 || request.NumTeams > maxTeams {
-		localLog.Errorf("Requested %d teams exceeds available /24 subnets (%d) in %s\n", request.NumTeams, maxTeams, comp.NetworkCIDR)
+	if maxTeams := teamCapacity(compSubnet); request.NumTeams > maxTeams {
+		err = fmt.Errorf("requested %d teams exceeds available /%d subnets (%d) in %s", request.NumTeams, config.Config.Network.TeamSubnetPrefix, maxTeams, comp.NetworkCIDR)
+		localLog.Errorf("%v\n", err)
 		return
 	}
 
 	var (
-		plans        []*containerPlan
-		teamNetworks = make(map[int64]*teamNetwork)
-		teamLocks    = make(map[int64]*sync.Mutex)
-		createdTeams []*db.Team
+		plans         []*containerPlan
+		teamNetworks  = make(map[int64]*teamNetwork)
+		teamLocks     = make(map[int64]*sync.Mutex)
+		sharedNetwork *teamNetwork
 	)
 
+	if sharedNetwork, err = buildSharedNetwork(compSubnet, compSubnet6, request.SharedContainerConfigs); err != nil {
+		localLog.Errorf("Failed to allocate shared container network: %v\n", err)
+		return
+	}
+
 	for teamIndex := 0; teamIndex < request.NumTeams; teamIndex++ {
 		var teamSubnetBase uint32
 		if teamSubnetBase, err = teamSubnetBaseIP(compSubnet, teamIndex); err != nil {
@@ -258,18 +373,51 @@ func CreateNewCompWithLogger(request *db.CreateCompetitionRequest, logSink Progr
 
 		teamSubnet := buildSubnet(teamSubnetBase, config.Config.Network.TeamSubnetPrefix)
 		if teamSubnet == nil {
-			localLog.Errorf("Failed to determine subnet for team %d\n", teamIndex+1)
+			err = fmt.Errorf("failed to determine subnet for team %d", teamIndex+1)
+			localLog.Errorf("%v\n", err)
 			return
 		}
 
+		var teamBlock6 *net.IPNet
+		if compSubnet6 != nil {
+			if teamBlock6, err = teamSubnet6(compSubnet6, teamIndex); err != nil {
+				localLog.Errorf("Failed to allocate IPv6 subnet for team %d: %v\n", teamIndex+1, err)
+				return
+			}
+		}
+
+		var claimToken string
+		if claimToken, err = newClaimToken(); err != nil {
+			localLog.Errorf("Failed to generate claim token for team %d: %v\n", teamIndex+1, err)
+			return
+		}
+
+		var roster db.TeamRoster
+		if teamIndex < len(request.TeamRosters) {
+			roster = request.TeamRosters[teamIndex]
+		}
+
+		teamName := strings.TrimSpace(roster.Name)
+		if teamName == "" {
+			teamName = fmt.Sprintf("Team %d", teamIndex+1)
+		}
+
 		var team *db.Team = &db.Team{
 			ID:           0,
-			Name:         fmt.Sprintf("Team %d", teamIndex+1),
+			Name:         teamName,
 			Score:        0,
 			ContainerIDs: []int64{},
 			LastUpdated:  time.Now(),
 			CreatedAt:    time.Now(),
 			NetworkCIDR:  teamSubnet.String(),
+			NetworkCIDR6: func() string {
+				if teamBlock6 == nil {
+					return ""
+				}
+				return teamBlock6.String()
+			}(),
+			ClaimToken: claimToken,
+			LDAPGroup:  strings.TrimSpace(roster.LDAPGroup),
 		}
 
 		if err = db.Teams.Insert(team); err != nil {
@@ -277,12 +425,21 @@ func CreateNewCompWithLogger(request *db.CreateCompetitionRequest, logSink Progr
 			return
 		}
 
+		if len(roster.Members) > 0 {
+			if err = db.SetTeamMembers(comp.SystemID, team.ID, roster.Members); err != nil {
+				localLog.Errorf("Failed to store roster for %s: %v\n", team.Name, err)
+				return
+			}
+		}
+
 		createdTeams = append(createdTeams, team)
 		comp.TeamIDs = append(comp.TeamIDs, team.ID)
 		teamNetworks[team.ID] = &teamNetwork{
-			ipsByName: make(map[string]string),
-			ipOrder:   make([]string, 0),
+			ipsByName:  make(map[string]string),
+			ipOrder:    make([]string, 0),
+			ip6sByName: make(map[string]string),
 		}
+		teamNetworks[team.ID].attachShared(sharedNetwork)
 		teamLocks[team.ID] = &sync.Mutex{}
 
 		for templateOrder, templateCfg := range request.TeamContainerConfigs {
@@ -292,9 +449,28 @@ func CreateNewCompWithLogger(request *db.CreateCompetitionRequest, logSink Progr
 				return
 			}
 
+			var resolved []resolvedInterface
+			if resolved, err = resolveContainerInterfaces(compSubnet, teamIndex, templateCfg.Interfaces); err != nil {
+				localLog.Errorf("Failed to allocate interfaces for %s (team %d): %v\n", templateCfg.Name, teamIndex+1, err)
+				return
+			}
+
 			var sanitizedName = sanitizeContainerName(templateCfg.Name)
 			teamNetworks[team.ID].ipsByName[sanitizedName] = hostIP.String()
 			teamNetworks[team.ID].ipOrder = append(teamNetworks[team.ID].ipOrder, hostIP.String())
+			teamNetworks[team.ID].addInterfaces(sanitizedName, resolved)
+
+			var hostIP6 string
+			if teamBlock6 != nil {
+				var addr6 net.IP
+				if addr6, err = hostIP6WithinSubnet(teamBlock6, templateCfg.LastOctetValue); err != nil {
+					localLog.Errorf("Failed to allocate container IPv6 for %s (team %d): %v\n", templateCfg.Name, teamIndex+1, err)
+					return
+				}
+				hostIP6 = addr6.String()
+				teamNetworks[team.ID].ip6sByName[sanitizedName] = hostIP6
+				teamNetworks[team.ID].ip6Order = append(teamNetworks[team.ID].ip6Order, hostIP6)
+			}
 
 			var templateSpec db.ContainerSpecTemplate
 			if templateSpec, err = ResolveContainerSpecTemplate(templateLookup, templateCfg.ContainerSpecsTemplate); err != nil {
@@ -302,116 +478,238 @@ func CreateNewCompWithLogger(request *db.CreateCompetitionRequest, logSink Progr
 				return
 			}
 
-			var plan = &containerPlan{
-				team:          team,
-				name:          templateCfg.Name,
-				sanitizedName: sanitizedName,
-				order:         templateOrder,
-				ipAddress:     hostIP.String(),
-				setupScripts:  append([]string(nil), templateCfg.SetupScript...),
-				options: &proxmoxAPI.ContainerCreateOptions{
-					TemplatePath:     templateSpec.TemplatePath,
-					StoragePool:      templateSpec.StoragePool,
-					Hostname:         fmt.Sprintf("%s-team-%d-%s", comp.ContainerRestrictions.HostnamePrefix, teamIndex+1, templateCfg.Name),
-					RootPassword:     templateSpec.RootPassword,
-					RootSSHPublicKey: publicKey,
-					StorageSizeGB:    templateSpec.StorageSizeGB,
-					MemoryMB:         templateSpec.MemoryMB,
-					Cores:            templateSpec.Cores,
-					GatewayIPv4:      config.Config.Network.ContainerGateway,
-					IPv4Address:      hostIP.String(),
-					CIDRBlock:        config.Config.Network.ContainerCIDR,
-					NameServer:       config.Config.Network.ContainerNameserver,
-					SearchDomain:     config.Config.Network.ContainerSearchDomain,
-				},
-			}
-
+			plan := newTeamContainerPlan(comp, team, teamIndex, templateOrder, templateCfg, hostIP.String(), hostIP6, templateSpec, publicKey)
 			plans = append(plans, plan)
 		}
 	}
 
+	if len(request.SharedContainerConfigs) > 0 {
+		var sharedPlans []*containerPlan
+		if sharedPlans, err = buildSharedContainerPlans(comp, request.SharedContainerConfigs, sharedNetwork, templateLookup, publicKey); err != nil {
+			localLog.Errorf("Failed to plan shared containers: %v\n", err)
+			return
+		}
+
+		teamNetworks[0] = sharedNetwork
+		teamLocks[0] = &sync.Mutex{}
+		plans = append(plans, sharedPlans...)
+	}
+
 	if len(plans) == 0 {
 		localLog.Status("No team container configurations provided; skipping container provisioning.")
-		if err = db.Competitions.Update(comp); err != nil {
+		// The teams are normally stored with the networks below, so store them with the status here.
+		comp.ProvisioningStatus = db.ProvisioningStatusReady
+		if _, err = db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+			current.TeamIDs = comp.TeamIDs
+			return true
+		}); err == nil {
+			err = saveProvisioningState(comp)
+		}
+		if err != nil {
 			localLog.Errorf("Failed to update competition record: %v\n", err)
 		}
 		localLog.Successf("Successfully created competition: %s\n", request.CompetitionName)
 		return
 	}
 
+	applyTeamAffinity(comp, request, plans)
+	if request.CloneTeamContainers {
+		markClonePlans(plans)
+	}
+
+	if err = setupNetworkIsolation(localLog, comp, createdTeams, len(request.SharedContainerConfigs) > 0); err != nil {
+		localLog.Errorf("Failed to set up isolated networks: %v\n", err)
+		return
+	}
+
+	for _, plan := range plans {
+		if err = applyNetworkAttachment(comp, plan); err != nil {
+			localLog.Errorf("Failed to attach networks: %v\n", err)
+			return
+		}
+	}
+
+	if err = setupCompetitionFirewall(localLog, comp); err != nil {
+		localLog.Errorf("%v\n", err)
+		return
+	}
+
+	// Later writes only touch provisioning fields, so the teams, keys and networks must be stored before containers are.
+	if err = db.Competitions.Update(comp); err != nil {
+		localLog.Errorf("Failed to update competition record: %v\n", err)
+		return
+	}
+
+	var failures []error
+	provisioned, failures = provisionContainerPlans(localLog, comp, plans, teamNetworks, teamLocks, privateKey, request.KeepPartialOnFailure, request.EnableAdvancedLogging)
+	if len(failures) > 0 {
+		if !request.KeepPartialOnFailure {
+			err = failures[0]
+			return
+		}
+
+		keptPartial = true
+		err = keepPartialProvisioning(localLog, comp, provisioned, failures, len(plans))
+		return
+	}
+
+	comp.ProvisioningStatus = db.ProvisioningStatusReady
+	if err = saveProvisioningState(comp); err != nil {
+		localLog.Errorf("Failed to update competition record: %v\n", err)
+		return
+	}
+
+	// 4. Store in DB
+
+	localLog.Successf("Successfully created competition: %s\n", request.CompetitionName)
+	return
+}
+
+// newTeamContainerPlan builds the provisioning plan for one of a team's containers.
+func newTeamContainerPlan(comp *db.Competition, team *db.Team, teamIndex, order int, cfg db.TeamContainerConfig, ip, ip6 string, templateSpec db.ContainerSpecTemplate, publicKey string) *containerPlan {
+	return &containerPlan{
+		team:          team,
+		name:          cfg.Name,
+		sanitizedName: sanitizeContainerName(cfg.Name),
+		order:         order,
+		ipAddress:     ip,
+		ipAddress6:    ip6,
+		setupScripts:  append([]string(nil), cfg.SetupScript...),
+		personalize:   append([]string(nil), cfg.PersonalizeScript...),
+		node:          strings.TrimSpace(cfg.Node),
+		kind:          guestKind(cfg.Kind),
+		transport:     execTransport(cfg.ExecTransport),
+		vmTemplateID:  templateSpec.VMTemplateID,
+		interfaces:    append([]db.ContainerInterface(nil), cfg.Interfaces...),
+		options: &proxmoxAPI.ContainerCreateOptions{
+			TemplatePath:     templateSpec.TemplatePath,
+			StoragePool:      templateSpec.StoragePool,
+			Hostname:         fmt.Sprintf("%s-team-%d-%s", comp.ContainerRestrictions.HostnamePrefix, teamIndex+1, cfg.Name),
+			RootPassword:     templateSpec.RootPassword,
+			RootSSHPublicKey: publicKey,
+			StorageSizeGB:    templateSpec.StorageSizeGB,
+			MemoryMB:         templateSpec.MemoryMB,
+			Cores:            templateSpec.Cores,
+			GatewayIPv4:      config.Config.Network.ContainerGateway,
+			IPv4Address:      ip,
+			CIDRBlock:        config.Config.Network.ContainerCIDR,
+			IPv6Address:      ip6,
+			NameServer:       config.Config.Network.ContainerNameserver,
+			SearchDomain:     config.Config.Network.ContainerSearchDomain,
+		},
+	}
+}
+
+// provisionContainerPlans provisions every plan concurrently. Unless keepPartial is set, the first failure cancels
+// the plans still in flight. It returns every container that was created, successful or not, and the failures.
+func provisionContainerPlans(log ProgressLogger, comp *db.Competition, plans []*containerPlan, networks map[int64]*teamNetwork, locks map[int64]*sync.Mutex, privateKey string, keepPartial, enableAdvancedLogging bool) (provisioned []*provisionedContainer, failures []error) {
 	var (
-		provisioned         []*provisionedContainer
 		provisionedMu       sync.Mutex
 		totalContainers     = len(plans)
 		completedContainers int32
+		compLock            sync.Mutex
+		wg                  sync.WaitGroup
+		publicFolderURL     = competitionPublicFolderURL(comp)
+		artifactBaseURL     = buildCompetitionArtifactBase(externalBaseURL(), comp.SystemID)
 	)
 
-	defer func() {
-		if err != nil {
-			cleanupProvisionedContainers(localLog, comp, provisioned)
-			cleanupFailedCompetitionResources(localLog, comp, createdTeams, dataDir, request.CompetitionID, request.PackagePath)
-		}
-	}()
-
-	var compLock sync.Mutex
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	log.Statusf("Provisioning progress: (0/%d) containers complete.", totalContainers)
+
 	var (
-		publicFolderURL = competitionPublicFolderURL(comp)
-		artifactBaseURL = buildCompetitionArtifactBase(externalBaseURL(), comp.SystemID)
+		templates       = planTemplates(plans)
+		templateEntries []*provisionedContainer
 	)
 
-	localLog.Statusf("Provisioning progress: (0/%d) containers complete.", totalContainers)
+	reuseTemplates(log, comp, templates)
+	for _, template := range templates {
+		if template.reused {
+			continue
+		}
+
+		wg.Add(1)
+		go func(template *configTemplate) {
+			defer wg.Done()
+			entry := template.build(ctx, log, comp, networks[template.source.teamID()], publicFolderURL, artifactBaseURL, &compLock, enableAdvancedLogging)
+			if entry == nil {
+				return
+			}
 
-	errCh := make(chan error, len(plans))
-	var wg sync.WaitGroup
+			provisionedMu.Lock()
+			defer provisionedMu.Unlock()
+			templateEntries = append(templateEntries, entry)
+		}(template)
+	}
 
 	for _, plan := range plans {
 		wg.Add(1)
-		network := teamNetworks[plan.team.ID]
-		teamLock := teamLocks[plan.team.ID]
+		network := networks[plan.teamID()]
+		teamLock := locks[plan.teamID()]
 
 		go func(plan *containerPlan, network *teamNetwork, teamLock *sync.Mutex) {
 			defer wg.Done()
-			entry, perr := provisionContainerPlan(ctx, localLog, plan, comp, network, privateKey, publicFolderURL, artifactBaseURL, teamLock, &compLock, request.EnableAdvancedLogging)
+			var (
+				entry *provisionedContainer
+				perr  error
+			)
+			if template := templates[templateKey(plan)]; plan.clone && template != nil {
+				entry, perr = cloneContainerPlan(ctx, log, plan, template, comp, network, publicFolderURL, artifactBaseURL, teamLock, &compLock, enableAdvancedLogging)
+			} else {
+				entry, perr = provisionContainerPlan(ctx, log, plan, comp, network, privateKey, publicFolderURL, artifactBaseURL, teamLock, &compLock, enableAdvancedLogging)
+			}
+			if entry != nil {
+				entry.err = perr
+			}
+
+			provisionedMu.Lock()
+			defer provisionedMu.Unlock()
+
 			if entry != nil {
-				provisionedMu.Lock()
 				provisioned = append(provisioned, entry)
-				provisionedMu.Unlock()
 			}
 			if perr == nil && entry != nil && entry.recorded {
 				current := atomic.AddInt32(&completedContainers, 1)
-				localLog.Statusf("Provisioning progress: (%d/%d) containers complete.", current, totalContainers)
+				log.Statusf("Provisioning progress: (%d/%d) containers complete.", current, totalContainers)
 			}
 			if perr != nil {
-				select {
-				case errCh <- perr:
-				default:
+				failures = append(failures, fmt.Errorf("%s: %w", plan.options.Hostname, perr))
+				if !keepPartial {
+					cancel()
 				}
-				cancel()
 			}
 		}(plan, network, teamLock)
 	}
 
 	wg.Wait()
 
-	select {
-	case provisionErr := <-errCh:
-		err = provisionErr
-		return
-	default:
-	}
+	// Templates go first so cleanup, which walks the list backwards, deletes linked clones before their template.
+	return append(templateEntries, provisioned...), failures
+}
 
-	if err = db.Competitions.Update(comp); err != nil {
-		localLog.Errorf("Failed to update competition record: %v\n", err)
-		return
+// keepPartialProvisioning handles a failed provisioning run in keep-partial mode: containers that finished stay
+// recorded, containers that failed before being recorded are destroyed, and the competition is marked
+// provisioning_failed so the missing containers can be provisioned later with a resume.
+func keepPartialProvisioning(log ProgressLogger, comp *db.Competition, provisioned []*provisionedContainer, failures []error, total int) error {
+	var unrecorded []*provisionedContainer
+	for _, entry := range provisioned {
+		if entry != nil && entry.err != nil && !entry.recorded {
+			unrecorded = append(unrecorded, entry)
+		}
 	}
+	cleanupProvisionedContainers(log, comp, unrecorded)
 
-	// 4. Store in DB
+	failed := errors.Join(failures...)
+	comp.ProvisioningStatus = db.ProvisioningStatusFailed
+	comp.ProvisioningError = failed.Error()
+	if err := saveProvisioningState(comp); err != nil {
+		log.Errorf("Failed to update competition record: %v\n", err)
+		return errors.Join(failed, err)
+	}
 
-	localLog.Successf("Successfully created competition: %s\n", request.CompetitionName)
-	return
+	log.Errorf("%d of %d containers failed to provision. Successful containers were kept; resume provisioning to retry the rest.\n", len(failures), total)
+	return fmt.Errorf("%d of %d containers failed to provision: %w", len(failures), total, failed)
 }
 
 func provisionContainerPlan(ctx context.Context, log ProgressLogger, plan *containerPlan, comp *db.Competition, network *teamNetwork, privateKey, publicFolderURL, artifactBaseURL string, teamLock *sync.Mutex, compLock *sync.Mutex, enableAdvancedLogging bool) (entry *provisionedContainer, err error) {
@@ -419,11 +717,22 @@ func provisionContainerPlan(ctx context.Context, log ProgressLogger, plan *conta
 		return nil, fmt.Errorf("container plan is nil")
 	}
 
-	log.Statusf("Provisioning container %s for %s...", plan.options.Hostname, plan.team.Name)
+	var release func()
+	if release, err = acquireProvisionSlot(ctx); err != nil {
+		return nil, err
+	}
+	defer release()
+
+	log.Statusf("Provisioning container %s for %s...", plan.options.Hostname, plan.ownerLabel())
 	var createResult *proxmoxAPI.ProxmoxAPICreateResult
 	if err = retryWithDelay(ctx, containerCreateRetries, containerRetryDelay, func(attempt int) error {
 		log.Statusf("Creating container %s (attempt %d/%d)...", plan.options.Hostname, attempt+1, containerCreateRetries)
-		result, createErr := api.CreateContainer(api.NextNode(), plan.options)
+		node, placeErr := api.PlaceContainer(plan.placementRequest())
+		if placeErr != nil {
+			log.Errorf("Failed to place container %s on attempt %d: %v\n", plan.options.Hostname, attempt+1, placeErr)
+			return placeErr
+		}
+		result, createErr := createPlanGuest(node, plan, 0)
 		if createErr != nil {
 			log.Errorf("Failed to create container %s on attempt %d: %v\n", plan.options.Hostname, attempt+1, createErr)
 			return createErr
@@ -441,7 +750,7 @@ func provisionContainerPlan(ctx context.Context, log ProgressLogger, plan *conta
 
 	if err = retryWithDelay(ctx, containerStartRetries, containerRetryDelay, func(attempt int) error {
 		log.Statusf("Starting container %s (CTID: %d) attempt %d/%d...", plan.options.Hostname, createResult.CTID, attempt+1, containerStartRetries)
-		startErr := api.StartContainer(createResult.Container)
+		startErr := api.StartGuest(createResult.Guest())
 		if startErr != nil {
 			log.Errorf("Failed to start container %d on attempt %d: %v\n", createResult.CTID, attempt+1, startErr)
 		}
@@ -462,41 +771,61 @@ func provisionContainerPlan(ctx context.Context, log ProgressLogger, plan *conta
 		}
 	}
 
-	if err = waitForConsoleReady(api, createResult.Container, plan.options.RootPassword); err != nil {
+	if err = waitForPlanReady(api, comp, plan, createResult.Guest()); err != nil {
 		log.Errorf("Container %d console not ready: %v\n", createResult.CTID, err)
 		return entry, err
 	}
 
-	if err = runSetupScripts(log, api, createResult.Container, comp, plan, network, publicFolderURL, artifactBaseURL, enableAdvancedLogging); err != nil {
+	if err = runSetupScripts(log, api, createResult.Guest(), comp, plan, network, publicFolderURL, artifactBaseURL, enableAdvancedLogging); err != nil {
 		return entry, err
 	}
 
-	var record *db.Container
-	if record, err = recordProvisionedContainer(comp, plan.team, plan, createResult, plan.ipAddress, plan.options.StoragePool, createResult.Container.Node, teamLock, compLock); err != nil {
-		log.Errorf("Failed to record container %d: %v\n", createResult.CTID, err)
+	if err = finishProvisionedContainer(log, comp, plan, entry, teamLock, compLock); err != nil {
 		return entry, err
 	}
-	entry.recorded = true
+
+	return entry, nil
+}
+
+// finishProvisionedContainer firewalls, snapshots and stops a container whose scripts have run, then records it.
+// Recording comes last so a container that failed halfway is never mistaken for a finished one by a resume.
+func finishProvisionedContainer(log ProgressLogger, comp *db.Competition, plan *containerPlan, entry *provisionedContainer, teamLock, compLock *sync.Mutex) (err error) {
+	var createResult = entry.result
+
+	if comp.Firewall.Enabled {
+		if err = applyGuestFirewall(comp, plan.teamID(), createResult.Guest()); err != nil {
+			log.Errorf("Failed to apply firewall rules to container %d: %v\n", createResult.CTID, err)
+			return err
+		}
+	}
+
+	takeBaselineSnapshot(log, createResult.Guest())
 
 	log.Statusf("Stopping container %s (CTID: %d) after provisioning...", plan.options.Hostname, createResult.CTID)
-	if err = api.StopContainer(createResult.Container); err != nil {
+	if err = api.StopGuest(createResult.Guest()); err != nil {
 		log.Errorf("Failed to stop container %d after provisioning: %v\n", createResult.CTID, err)
-		return entry, err
+		return err
 	}
 
-	record.Status = "stopped"
-	record.LastUpdated = time.Now()
-	if updateErr := db.Containers.Update(record); updateErr != nil {
-		log.Errorf("Failed to update container %d metadata: %v\n", record.PVEID, updateErr)
+	if _, err = recordProvisionedContainer(comp, plan.team, plan, createResult, plan.ipAddress, plan.options.StoragePool, createResult.Guest().NodeName(), teamLock, compLock); err != nil {
+		log.Errorf("Failed to record container %d: %v\n", createResult.CTID, err)
+		return err
 	}
+	entry.recorded = true
 
 	log.Statusf("Container %s (CTID: %d) provisioned successfully.", plan.options.Hostname, createResult.CTID)
-	return entry, nil
+	return nil
 }
 
-func runSetupScripts(log ProgressLogger, api *proxmoxAPI.ProxmoxAPI, ct *proxmox.Container, comp *db.Competition, plan *containerPlan, network *teamNetwork, publicFolderURL, artifactBaseURL string, logEnv bool) (err error) {
-	if len(plan.setupScripts) == 0 {
-		log.Statusf("No setup scripts defined for %s; skipping.", plan.options.Hostname)
+func runSetupScripts(log ProgressLogger, api proxmoxAPI.Backend, ct *proxmoxAPI.Guest, comp *db.Competition, plan *containerPlan, network *teamNetwork, publicFolderURL, artifactBaseURL string, logEnv bool) (err error) {
+	return runContainerScripts(log, api, ct, comp, plan, network, plan.setupScripts, "setup", publicFolderURL, artifactBaseURL, logEnv)
+}
+
+// runContainerScripts downloads and runs each script inside the container in order, stopping at the first failure.
+// kind names the scripts in progress logs ("setup", "personalization").
+func runContainerScripts(log ProgressLogger, api proxmoxAPI.Backend, ct *proxmoxAPI.Guest, comp *db.Competition, plan *containerPlan, network *teamNetwork, scripts []string, kind, publicFolderURL, artifactBaseURL string, logEnv bool) (err error) {
+	if len(scripts) == 0 {
+		log.Statusf("No %s scripts defined for %s; skipping.", kind, plan.options.Hostname)
 		return nil
 	}
 
@@ -510,34 +839,43 @@ func runSetupScripts(log ProgressLogger, api *proxmoxAPI.ProxmoxAPI, ct *proxmox
 		log.Statusf("Script environment: %s", formatScriptEnv(envs))
 	}
 
-	for _, scriptPath := range plan.setupScripts {
+	for _, scriptPath := range scripts {
 		var scriptURL = buildArtifactFileURL(artifactBaseURL, scriptPath)
 
 		var (
 			exitCode int
-			command  = ssh.LoadAndRunScript(scriptURL, token, envs)
+			command  = scriptCommand(ct, scriptURL, token, envs)
 		)
 
 		if logEnv {
-			log.Statusf("Executing setup script %s on %s with command: %s", scriptPath, plan.options.Hostname, command)
+			log.Statusf("Executing %s script %s on %s with command: %s", kind, scriptPath, plan.options.Hostname, command)
 		} else {
-			log.Statusf("Executing setup script %s on %s...", scriptPath, plan.options.Hostname)
+			log.Statusf("Executing %s script %s on %s...", kind, scriptPath, plan.options.Hostname)
+		}
+
+		// Over SSH, advanced logging streams the script's output as it runs instead of dumping it at the end.
+		var (
+			stream   ProgressLogger
+			streamed = logEnv && plan.transport == ExecTransportSSH
+		)
+		if streamed {
+			stream = log
 		}
 
 		var stderr, stdout string
-		if stdout, stderr, exitCode, err = api.RawExecuteWithRetries(ct, "root", plan.options.RootPassword, command, 2); err != nil {
-			log.Errorf("Failed to execute setup script %s on %s: %v\n", scriptPath, plan.options.Hostname, err)
+		if stdout, stderr, exitCode, err = execInGuest(api, comp, plan, ct, "root", plan.options.RootPassword, command, stream); err != nil {
+			log.Errorf("Failed to execute %s script %s on %s: %v\n", kind, scriptPath, plan.options.Hostname, err)
 			return
 		}
 
-		if logEnv {
-			log.Statusf("Setup script %s exited with code %d, stdout: %s, stderr: %s", scriptPath, exitCode, stdout, stderr)
+		if logEnv && !streamed {
+			log.Statusf("Script %s (%s) exited with code %d, stdout: %s, stderr: %s", scriptPath, kind, exitCode, stdout, stderr)
 		} else {
-			log.Statusf("Setup script %s exited with code %d.", scriptPath, exitCode)
+			log.Statusf("Script %s (%s) exited with code %d.", scriptPath, kind, exitCode)
 		}
 
 		if exitCode != 0 {
-			err = fmt.Errorf("setup script %s exited with code %d", scriptPath, exitCode)
+			err = fmt.Errorf("%s script %s exited with code %d", kind, scriptPath, exitCode)
 			log.Errorf("%v\n", err)
 			return
 		}
@@ -547,7 +885,7 @@ func runSetupScripts(log ProgressLogger, api *proxmoxAPI.ProxmoxAPI, ct *proxmox
 	return nil
 }
 
-func waitForConsoleReady(api *proxmoxAPI.ProxmoxAPI, ct *proxmox.Container, rootPassword string) error {
+func waitForConsoleReady(api proxmoxAPI.Backend, ct *proxmox.Container, rootPassword string) error {
 	const attempts = 5
 	for i := 0; i < attempts; i++ {
 		if _, _, exitCode, err := api.RawExecuteWithRetries(ct, "root", rootPassword, "echo KOTH_READY", 0); err == nil && exitCode == 0 {
@@ -558,15 +896,28 @@ func waitForConsoleReady(api *proxmoxAPI.ProxmoxAPI, ct *proxmox.Container, root
 	return fmt.Errorf("container console not ready for raw execution")
 }
 
+// buildScriptEnv returns the KOTH_* environment of a plan's scripts. Templates are cloned for every team, so their
+// setup scripts only get the competition-wide variables and KOTH_TEMPLATE=1; anything team-specific is left to the
+// clones' personalization scripts.
 func buildScriptEnv(comp *db.Competition, plan *containerPlan, network *teamNetwork, publicFolderURL string) map[string]any {
 	var envs = map[string]any{
 		"KOTH_COMP_ID":       comp.SystemID,
-		"KOTH_TEAM_ID":       fmt.Sprintf("%d", plan.team.ID),
 		"KOTH_HOSTNAME":      plan.options.Hostname,
-		"KOTH_IP":            plan.ipAddress,
 		"KOTH_PUBLIC_FOLDER": publicFolderURL,
 	}
 
+	if plan.template {
+		envs["KOTH_TEMPLATE"] = "1"
+		addSharedScriptEnv(envs, network)
+		return envs
+	}
+
+	envs["KOTH_TEAM_ID"] = fmt.Sprintf("%d", plan.teamID())
+	envs["KOTH_IP"] = plan.ipAddress
+	if plan.ipAddress6 != "" {
+		envs["KOTH_IP6"] = plan.ipAddress6
+	}
+
 	if network != nil {
 		envs["KOTH_CONTAINER_IPS"] = strings.Join(network.ipOrder, ",")
 
@@ -579,14 +930,53 @@ func buildScriptEnv(comp *db.Competition, plan *containerPlan, network *teamNetw
 		for _, name := range names {
 			envs[fmt.Sprintf("KOTH_CONTAINER_IPS_%s", name)] = network.ipsByName[name]
 		}
+
+		for name, ips := range network.interfaceIPs {
+			for label, ip := range ips {
+				envs[fmt.Sprintf("KOTH_CONTAINER_IPS_%s_%s", name, label)] = ip
+			}
+		}
+
+		for label, ip := range network.interfaceIPs[plan.sanitizedName] {
+			envs[fmt.Sprintf("KOTH_IP_%s", label)] = ip
+		}
+
+		if len(network.ip6Order) > 0 {
+			envs["KOTH_CONTAINER_IP6S"] = strings.Join(network.ip6Order, ",")
+			for name, ip := range network.ip6sByName {
+				envs[fmt.Sprintf("KOTH_CONTAINER_IP6S_%s", name)] = ip
+			}
+		}
 	} else {
 		envs["KOTH_CONTAINER_IPS"] = plan.ipAddress
 		envs[fmt.Sprintf("KOTH_CONTAINER_IPS_%s", plan.sanitizedName)] = plan.ipAddress
 	}
 
+	addSharedScriptEnv(envs, network)
 	return envs
 }
 
+// addSharedScriptEnv adds the addresses of the competition's shared containers, which are the same for every team.
+func addSharedScriptEnv(envs map[string]any, network *teamNetwork) {
+	if network != nil && len(network.sharedIPOrder) > 0 {
+		envs["KOTH_SHARED_IPS"] = strings.Join(network.sharedIPOrder, ",")
+		for name, ip := range network.sharedIPsByName {
+			envs[fmt.Sprintf("KOTH_SHARED_IPS_%s", name)] = ip
+		}
+		for name, ip := range network.sharedIP6sByName {
+			envs[fmt.Sprintf("KOTH_SHARED_IP6S_%s", name)] = ip
+		}
+		if len(network.sharedIP6Order) > 0 {
+			envs["KOTH_SHARED_IP6S"] = strings.Join(network.sharedIP6Order, ",")
+		}
+		for name, ips := range network.sharedIfaceIPs {
+			for label, ip := range ips {
+				envs[fmt.Sprintf("KOTH_SHARED_IPS_%s_%s", name, label)] = ip
+			}
+		}
+	}
+}
+
 func formatScriptEnv(envs map[string]any) string {
 	if len(envs) == 0 {
 		return ""
@@ -618,11 +1008,13 @@ func recordProvisionedContainer(comp *db.Competition, team *db.Team, plan *conta
 	record = &db.Container{
 		PVEID:       int64(result.CTID),
 		IPAddress:   ip,
-		Status:      "running",
-		TeamID:      plan.team.ID,
+		IPv6Address: plan.ipAddress6,
+		Status:      "stopped",
+		TeamID:      plan.teamID(),
 		ConfigName:  plan.name,
 		StoragePool: storagePool,
 		NodeName:    nodeName,
+		Kind:        plan.kind,
 		LastUpdated: time.Now(),
 		CreatedAt:   time.Now(),
 	}
@@ -630,10 +1022,26 @@ func recordProvisionedContainer(comp *db.Competition, team *db.Team, plan *conta
 	if err = db.Containers.Insert(record); err != nil {
 		return nil, err
 	}
+	forgetExecCredentials(record.PVEID)
+
+	if team != nil {
+		var updated *db.Team
+		if updated, err = db.UpdateTeam(team.ID, func(current *db.Team) bool {
+			current.ContainerIDs = append(current.ContainerIDs, record.PVEID)
+			current.LastUpdated = time.Now()
+			return true
+		}); err != nil {
+			return nil, err
+		} else if updated == nil {
+			return nil, fmt.Errorf("team %d not found", team.ID)
+		}
+		*team = *updated
+	}
 
-	team.ContainerIDs = append(team.ContainerIDs, record.PVEID)
-	team.LastUpdated = time.Now()
-	if err = db.Teams.Update(team); err != nil {
+	if _, err = db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+		current.ContainerIDs = append(current.ContainerIDs, record.PVEID)
+		return true
+	}); err != nil {
 		return nil, err
 	}
 
@@ -644,16 +1052,16 @@ func recordProvisionedContainer(comp *db.Competition, team *db.Team, plan *conta
 func cleanupProvisionedContainers(log ProgressLogger, comp *db.Competition, provisioned []*provisionedContainer) {
 	for i := len(provisioned) - 1; i >= 0; i-- {
 		var entry = provisioned[i]
-		if entry == nil || entry.result == nil || entry.result.Container == nil {
+		if entry == nil || entry.result == nil || (entry.result.Container == nil && entry.result.VM == nil) {
 			continue
 		}
 
 		log.Errorf("Cleaning up container %d after failure...\n", entry.result.CTID)
-		if err := api.StopContainer(entry.result.Container); err != nil {
+		if err := api.StopGuest(entry.result.Guest()); err != nil {
 			log.Errorf("Failed to stop container %d: %v\n", entry.result.CTID, err)
 		}
 
-		if err := api.DeleteContainer(entry.result.Container); err != nil {
+		if err := api.DeleteGuest(entry.result.Guest()); err != nil {
 			log.Errorf("Failed to delete container %d: %v\n", entry.result.CTID, err)
 		}
 
@@ -664,20 +1072,37 @@ func cleanupProvisionedContainers(log ProgressLogger, comp *db.Competition, prov
 			}
 
 			if entry.plan != nil && entry.plan.team != nil {
-				entry.plan.team.ContainerIDs = removeIDFromSlice(entry.plan.team.ContainerIDs, ctID)
-				entry.plan.team.LastUpdated = time.Now()
-				if err := db.Teams.Update(entry.plan.team); err != nil {
+				updated, err := db.UpdateTeam(entry.plan.team.ID, func(current *db.Team) bool {
+					current.ContainerIDs = removeIDFromSlice(current.ContainerIDs, ctID)
+					current.LastUpdated = time.Now()
+					return true
+				})
+				if err != nil {
 					log.Errorf("Failed to update team %d during cleanup: %v\n", entry.plan.team.ID, err)
+				} else if updated != nil {
+					*entry.plan.team = *updated
 				}
 			}
 
 			if comp != nil {
+				if _, err := db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+					current.ContainerIDs = removeIDFromSlice(current.ContainerIDs, ctID)
+					return true
+				}); err != nil {
+					log.Errorf("Failed to update competition %s during cleanup: %v\n", comp.SystemID, err)
+				}
 				comp.ContainerIDs = removeIDFromSlice(comp.ContainerIDs, ctID)
 			}
 		}
 	}
 }
 
+// touchTeam marks a team as updated, for use with db.UpdateTeam.
+func touchTeam(team *db.Team) bool {
+	team.LastUpdated = time.Now()
+	return true
+}
+
 func removeIDFromSlice(source []int64, target int64) []int64 {
 	if len(source) == 0 {
 		return source
@@ -694,6 +1119,9 @@ func removeIDFromSlice(source []int64, target int64) []int64 {
 }
 
 func cleanupFailedCompetitionResources(log ProgressLogger, comp *db.Competition, teams []*db.Team, dataDir, compID, packagePath string) {
+	_ = teardownCompetitionFirewall(comp, log)
+	_ = teardownNetworkIsolation(comp, log)
+
 	for _, team := range teams {
 		if team == nil || team.ID == 0 {
 			continue
@@ -715,6 +1143,10 @@ func cleanupFailedCompetitionResources(log ProgressLogger, comp *db.Competition,
 		}
 	}
 
+	if compID != "" {
+		_ = releaseCompetitionNetworks(log, compID)
+	}
+
 	cleanupCompetitionPackageOnFailure(log, compID, packagePath)
 }
 
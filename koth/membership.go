@@ -0,0 +1,65 @@
+package koth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/UNHCSC/pve-koth/db"
+)
+
+// ContainerCredential is the login a team uses for one of its containers.
+type ContainerCredential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ValidateTeamRosters checks that config.json rosters line up with the requested teams.
+func ValidateTeamRosters(req *db.CreateCompetitionRequest) error {
+	if req == nil {
+		return fmt.Errorf("competition request is nil")
+	}
+
+	if len(req.TeamRosters) > req.NumTeams {
+		return fmt.Errorf("teamRosters lists %d teams but numTeams is %d", len(req.TeamRosters), req.NumTeams)
+	}
+
+	var owners = make(map[string]int)
+	for idx, roster := range req.TeamRosters {
+		for _, member := range roster.Members {
+			username := db.NormalizeUsername(member)
+			if username == "" {
+				continue
+			}
+			if previous, exists := owners[username]; exists && previous != idx {
+				return fmt.Errorf("user %q is listed on teams %d and %d", username, previous+1, idx+1)
+			}
+			owners[username] = idx
+		}
+	}
+
+	return nil
+}
+
+// TeamContainerCredentials returns the root login for each team container config in the competition, keyed by
+// lowercase config name. Shared containers are left out because no single team owns them.
+func TeamContainerCredentials(comp *db.Competition) (map[string]ContainerCredential, error) {
+	req, err := loadCompetitionDefinition(comp)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials = make(map[string]ContainerCredential, len(req.TeamContainerConfigs))
+	for _, cfg := range req.TeamContainerConfigs {
+		templateSpec, specErr := ResolveContainerSpecTemplate(req.TemplateLookup, cfg.ContainerSpecsTemplate)
+		if specErr != nil {
+			return nil, specErr
+		}
+
+		credentials[strings.ToLower(strings.TrimSpace(cfg.Name))] = ContainerCredential{
+			Username: "root",
+			Password: templateSpec.RootPassword,
+		}
+	}
+
+	return credentials, nil
+}
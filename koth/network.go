@@ -2,72 +2,338 @@ package koth
 
 import (
 	"fmt"
+	"math/big"
 	"net"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/UNHCSC/pve-koth/config"
 	"github.com/UNHCSC/pve-koth/db"
 )
 
-func allocateCompetitionSubnet() (*net.IPNet, error) {
+// allocationMu serializes allocations so two competitions created at once never get the same block.
+var allocationMu sync.Mutex
+
+// CompetitionPrefixForTeams returns the prefix of the smallest block that holds numTeams team subnets plus the
+// reserved shared one. Blocks never grow beyond [network] competition_subnet_prefix.
+func CompetitionPrefixForTeams(numTeams int) (int, error) {
+	if numTeams < 0 {
+		return 0, fmt.Errorf("invalid team count %d", numTeams)
+	}
+
+	var (
+		teamPrefix = config.Config.Network.TeamSubnetPrefix
+		bits       = 1
+	)
+	for 1<<bits < numTeams+1 {
+		bits++
+	}
+
+	if prefix := teamPrefix - bits; prefix >= config.Config.Network.CompetitionSubnetPrefix {
+		return prefix, nil
+	}
+
+	return 0, fmt.Errorf("%d teams need more /%d subnets than a /%d competition block (competition_subnet_prefix) holds", numTeams, teamPrefix, config.Config.Network.CompetitionSubnetPrefix)
+}
+
+// NextFreeBlock returns the lowest block of the given prefix inside pool that overlaps none of allocated. Blocks are
+// aligned to their size, so mixed sizes pack like a buddy allocator.
+func NextFreeBlock(pool *net.IPNet, prefix int, allocated []*net.IPNet) (*net.IPNet, error) {
+	var poolPrefix, bits = pool.Mask.Size()
+	if prefix < poolPrefix || prefix > bits {
+		return nil, fmt.Errorf("cannot carve a /%d out of pool %s", prefix, pool.String())
+	}
+
+	type span struct{ first, last *big.Int }
+
+	var spans []span
+	for _, block := range allocated {
+		if block == nil || !pool.Contains(block.IP) && !block.Contains(pool.IP) {
+			continue
+		}
+		ones, _ := block.Mask.Size()
+		first := addressToBig(block.IP, bits)
+		spans = append(spans, span{first, new(big.Int).Sub(new(big.Int).Add(first, blockSize(ones, bits)), big.NewInt(1))})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].first.Cmp(spans[j].first) < 0 })
+
+	var (
+		size    = blockSize(prefix, bits)
+		poolEnd = new(big.Int).Add(addressToBig(pool.IP, bits), blockSize(poolPrefix, bits))
+		cursor  = addressToBig(pool.IP, bits)
+	)
+
+	for {
+		// Round the cursor up to the next multiple of the block size.
+		candidate := new(big.Int).Mul(new(big.Int).Div(new(big.Int).Add(cursor, new(big.Int).Sub(size, big.NewInt(1))), size), size)
+		candidateEnd := new(big.Int).Add(candidate, size)
+		if candidateEnd.Cmp(poolEnd) > 0 {
+			return nil, fmt.Errorf("no available /%d blocks remain in pool %s", prefix, pool.String())
+		}
+
+		var blocker *span
+		for idx := range spans {
+			if spans[idx].first.Cmp(candidateEnd) < 0 && spans[idx].last.Cmp(candidate) >= 0 {
+				blocker = &spans[idx]
+				break
+			}
+		}
+
+		if blocker == nil {
+			return &net.IPNet{IP: bigToAddress(candidate, bits), Mask: net.CIDRMask(prefix, bits)}, nil
+		}
+
+		cursor = new(big.Int).Add(blocker.last, big.NewInt(1))
+	}
+}
+
+func blockSize(prefix, bits int) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(bits-prefix))
+}
+
+func addressToBig(ip net.IP, bits int) *big.Int {
+	if bits == 32 {
+		return new(big.Int).SetBytes(ip.To4())
+	}
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+func bigToAddress(value *big.Int, bits int) net.IP {
+	var ip = make(net.IP, bits/8)
+	value.FillBytes(ip)
+	return ip
+}
+
+func allocatedBlocks(family string) ([]*net.IPNet, error) {
+	allocations, err := db.GetNetworkAllocations(family)
+	if err != nil {
+		return nil, fmt.Errorf("fetch network allocations: %w", err)
+	}
+
+	var blocks []*net.IPNet
+	for _, allocation := range allocations {
+		if _, block, parseErr := net.ParseCIDR(allocation.CIDR); parseErr == nil {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks, nil
+}
+
+// allocateBlock carves a block out of pool and records it for competitionID.
+func allocateBlock(family string, pool *net.IPNet, prefix int, competitionID string) (*net.IPNet, error) {
+	allocationMu.Lock()
+	defer allocationMu.Unlock()
+
+	allocated, err := allocatedBlocks(family)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := NextFreeBlock(pool, prefix, allocated)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.NetworkAllocations.Insert(&db.NetworkAllocation{
+		CIDR:          block.String(),
+		Family:        family,
+		CompetitionID: competitionID,
+		AllocatedAt:   time.Now(),
+	}); err != nil {
+		return nil, fmt.Errorf("record network allocation %s: %w", block.String(), err)
+	}
+
+	return block, nil
+}
+
+// allocateCompetitionSubnet reserves an IPv4 block just large enough for numTeams teams.
+func allocateCompetitionSubnet(competitionID string, numTeams int) (*net.IPNet, error) {
 	var pool *net.IPNet = config.Config.Network.ParsedPool()
 	if pool == nil {
 		return nil, fmt.Errorf("network pool not configured")
 	}
 
-	var existing, err = db.Competitions.SelectAll()
+	prefix, err := CompetitionPrefixForTeams(numTeams)
 	if err != nil {
-		return nil, fmt.Errorf("fetch competitions: %w", err)
+		return nil, err
 	}
 
-	var used = make(map[string]struct{})
-	for _, competition := range existing {
-		if competition.NetworkCIDR == "" {
+	return allocateBlock(db.AddressFamilyIPv4, pool, prefix, competitionID)
+}
+
+// ReconcileNetworkAllocations records blocks and VLAN tags of competitions created before allocations were tracked and
+// frees the ones whose competition no longer exists, e.g. after a crash mid-provisioning.
+func ReconcileNetworkAllocations() (recorded, released int, err error) {
+	allocationMu.Lock()
+	defer allocationMu.Unlock()
+
+	competitions, err := db.Competitions.SelectAll()
+	if err != nil {
+		return 0, 0, fmt.Errorf("fetch competitions: %w", err)
+	}
+
+	allocations, err := db.NetworkAllocations.SelectAll()
+	if err != nil {
+		return 0, 0, fmt.Errorf("fetch network allocations: %w", err)
+	}
+
+	var (
+		known  = make(map[string]bool, len(allocations))
+		owners = make(map[string]bool, len(competitions))
+	)
+	for _, allocation := range allocations {
+		known[allocation.CIDR] = true
+	}
+
+	for _, comp := range competitions {
+		owners[comp.SystemID] = true
+		for family, cidr := range map[string]string{db.AddressFamilyIPv4: comp.NetworkCIDR, db.AddressFamilyIPv6: comp.NetworkCIDR6} {
+			_, block, parseErr := net.ParseCIDR(strings.TrimSpace(cidr))
+			if parseErr != nil || known[block.String()] {
+				continue
+			}
+
+			if err = db.NetworkAllocations.Insert(&db.NetworkAllocation{
+				CIDR:          block.String(),
+				Family:        family,
+				CompetitionID: comp.SystemID,
+				AllocatedAt:   comp.CreatedAt,
+			}); err != nil {
+				return recorded, released, fmt.Errorf("record network allocation %s: %w", block.String(), err)
+			}
+			known[block.String()] = true
+			recorded++
+		}
+	}
+
+	for _, allocation := range allocations {
+		if owners[allocation.CompetitionID] {
 			continue
 		}
 
-		if _, netblock, parseErr := net.ParseCIDR(competition.NetworkCIDR); parseErr == nil && netblock != nil {
-			used[netblock.String()] = struct{}{}
+		if err = db.NetworkAllocations.Delete(allocation.ID); err != nil {
+			return recorded, released, fmt.Errorf("release network allocation %s: %w", allocation.CIDR, err)
 		}
+		released++
 	}
 
-	var (
-		baseIP           = ipToUint32(pool.IP)
-		poolPrefix, _    = pool.Mask.Size()
-		compPrefix       = config.Config.Network.CompetitionSubnetPrefix
-		subnetSize       = uint32(1) << uint32(32-compPrefix)
-		availableSubnets = uint32(1) << uint32(compPrefix-poolPrefix)
-		allocatedSubnets uint32
-	)
+	tags, err := db.VLANAllocations.SelectAll()
+	if err != nil {
+		return recorded, released, fmt.Errorf("fetch VLAN allocations: %w", err)
+	}
 
-	for allocatedSubnets < availableSubnets {
-		var subnetStart = baseIP + allocatedSubnets*subnetSize
-		var subnet = buildSubnet(subnetStart, compPrefix)
-		if subnet == nil {
-			break
+	var knownTags = make(map[int]bool, len(tags))
+	for _, allocation := range tags {
+		knownTags[allocation.Tag] = true
+	}
+
+	for _, comp := range competitions {
+		for _, segment := range comp.NetworkSegments {
+			if segment.Tag == 0 || knownTags[segment.Tag] {
+				continue
+			}
+
+			if err = db.VLANAllocations.Insert(&db.VLANAllocation{Tag: segment.Tag, CompetitionID: comp.SystemID, AllocatedAt: comp.CreatedAt}); err != nil {
+				return recorded, released, fmt.Errorf("record VLAN allocation %d: %w", segment.Tag, err)
+			}
+			knownTags[segment.Tag] = true
+			recorded++
+		}
+	}
+
+	for _, allocation := range tags {
+		if owners[allocation.CompetitionID] {
+			continue
 		}
 
-		if _, taken := used[subnet.String()]; !taken {
-			return subnet, nil
+		if err = db.VLANAllocations.Delete(allocation.ID); err != nil {
+			return recorded, released, fmt.Errorf("release VLAN allocation %d: %w", allocation.Tag, err)
 		}
+		released++
+	}
+
+	return recorded, released, nil
+}
+
+// releaseCompetitionNetworks returns a competition's blocks to the pools and frees its VLAN tags.
+func releaseCompetitionNetworks(log ProgressLogger, competitionID string) error {
+	allocationMu.Lock()
+	defer allocationMu.Unlock()
+
+	if err := db.ReleaseNetworkAllocations(competitionID); err != nil {
+		log.Errorf("Failed to release network allocations of %s: %v\n", competitionID, err)
+		return err
+	}
 
-		allocatedSubnets++
+	if err := db.ReleaseVLANAllocations(competitionID); err != nil {
+		log.Errorf("Failed to release VLAN tags of %s: %v\n", competitionID, err)
+		return err
 	}
 
-	return nil, fmt.Errorf("no available /%d subnets remain in pool %s", compPrefix, pool.String())
+	return nil
 }
 
-func maxTeamsPerCompetition() int {
-	var diff = config.Config.Network.TeamSubnetPrefix - config.Config.Network.CompetitionSubnetPrefix
-	if diff <= 0 {
-		return 0
+// PoolUsage summarizes how much of the IPv4 pool is allocated.
+type PoolUsage struct {
+	Blocks            int     // Allocated competition blocks
+	TotalAddresses    uint64  // Addresses in the pool
+	UsedAddresses     uint64  // Addresses inside allocated blocks
+	UsagePercent      float64 // UsedAddresses as a share of TotalAddresses
+	LargestFreePrefix int     // Prefix of the largest block still free, or 0 when none of the allowed sizes fits
+	MaxTeamsAvailable int     // Teams the next competition can have
+}
+
+// IPv4PoolUsage reports the allocation state of the IPv4 pool.
+func IPv4PoolUsage() (usage PoolUsage, err error) {
+	var pool = config.Config.Network.ParsedPool()
+	if pool == nil {
+		return usage, fmt.Errorf("network pool not configured")
+	}
+
+	allocated, err := allocatedBlocks(db.AddressFamilyIPv4)
+	if err != nil {
+		return usage, err
 	}
 
-	var total = 1 << diff
-	if total <= 1 {
+	poolPrefix, _ := pool.Mask.Size()
+	usage.TotalAddresses = 1 << uint(32-poolPrefix)
+	for _, block := range allocated {
+		if !pool.Contains(block.IP) {
+			continue
+		}
+		ones, _ := block.Mask.Size()
+		usage.Blocks++
+		usage.UsedAddresses += 1 << uint(32-ones)
+	}
+
+	if usage.TotalAddresses > 0 {
+		usage.UsagePercent = min(float64(usage.UsedAddresses)/float64(usage.TotalAddresses)*100, 100)
+	}
+
+	var teamPrefix = config.Config.Network.TeamSubnetPrefix
+	for prefix := max(config.Config.Network.CompetitionSubnetPrefix, poolPrefix); prefix < teamPrefix; prefix++ {
+		if _, fitErr := NextFreeBlock(pool, prefix, allocated); fitErr == nil {
+			usage.LargestFreePrefix = prefix
+			usage.MaxTeamsAvailable = (1 << uint(teamPrefix-prefix)) - 1
+			break
+		}
+	}
+
+	return usage, nil
+}
+
+// teamCapacity returns how many team subnets fit in a competition block next to the reserved shared subnet.
+func teamCapacity(compSubnet *net.IPNet) int {
+	var compPrefix, _ = compSubnet.Mask.Size()
+	var diff = config.Config.Network.TeamSubnetPrefix - compPrefix
+	if diff <= 0 || diff > 30 {
 		return 0
 	}
-	return total - 1
+
+	return (1 << diff) - 1
 }
 
 func teamSubnetBaseIP(compSubnet *net.IPNet, teamIndex int) (uint32, error) {
@@ -79,7 +345,7 @@ func teamSubnetBaseIP(compSubnet *net.IPNet, teamIndex int) (uint32, error) {
 		return 0, fmt.Errorf("invalid team index %d", teamIndex)
 	}
 
-	var capacity = maxTeamsPerCompetition()
+	var capacity = teamCapacity(compSubnet)
 	if capacity == 0 || teamIndex >= capacity {
 		return 0, fmt.Errorf("team index %d exceeds available /%d subnets in %s", teamIndex, config.Config.Network.TeamSubnetPrefix, compSubnet.String())
 	}
@@ -0,0 +1,149 @@
+package koth
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
+)
+
+// dualStack reports whether new competitions get an IPv6 block next to their IPv4 one.
+func dualStack() bool {
+	return config.Config.Network.ParsedPool6() != nil
+}
+
+// allocateCompetitionSubnet6 reserves an IPv6 block for a competition, or returns nil when dual-stack networking is
+// off.
+func allocateCompetitionSubnet6(competitionID string) (*net.IPNet, error) {
+	var pool = config.Config.Network.ParsedPool6()
+	if pool == nil {
+		return nil, nil
+	}
+
+	return allocateBlock(db.AddressFamilyIPv6, pool, config.Config.Network.CompetitionSubnetPrefix6, competitionID)
+}
+
+// subnet6At returns the idx-th block of the given prefix length counting from base.
+func subnet6At(base net.IP, prefix int, idx *big.Int) *net.IPNet {
+	var offset = new(big.Int).Lsh(idx, uint(128-prefix))
+	return &net.IPNet{
+		IP:   bigToIP6(new(big.Int).Add(ip6ToBig(base), offset)),
+		Mask: net.CIDRMask(prefix, 128),
+	}
+}
+
+// teamSubnet6 returns a team's /64 inside the competition's IPv6 block. Like the IPv4 layout, the first /64 belongs
+// to the shared containers (teamIndex -1) and teams start at the second.
+func teamSubnet6(compSubnet6 *net.IPNet, teamIndex int) (*net.IPNet, error) {
+	if compSubnet6 == nil {
+		return nil, fmt.Errorf("competition has no IPv6 network")
+	}
+
+	var compPrefix, _ = compSubnet6.Mask.Size()
+	var capacity = new(big.Int).Lsh(big.NewInt(1), uint(config.TeamSubnetPrefix6-compPrefix))
+	var idx = big.NewInt(int64(teamIndex + 1))
+	if teamIndex < -1 || idx.Cmp(capacity) >= 0 {
+		return nil, fmt.Errorf("team index %d exceeds available /%d subnets in %s", teamIndex, config.TeamSubnetPrefix6, compSubnet6.String())
+	}
+
+	return subnet6At(compSubnet6.IP, config.TeamSubnetPrefix6, idx), nil
+}
+
+// hostIP6WithinSubnet returns the address hostOffset hosts into subnet, mirroring hostIPWithinSubnet's offsets so a
+// container's IPv6 interface ID matches its IPv4 offset.
+func hostIP6WithinSubnet(subnet *net.IPNet, hostOffset int) (net.IP, error) {
+	if hostOffset <= 0 {
+		return nil, fmt.Errorf("host offset %d is invalid for %s", hostOffset, subnet.String())
+	}
+
+	return bigToIP6(new(big.Int).Add(ip6ToBig(subnet.IP), big.NewInt(int64(hostOffset)))), nil
+}
+
+// competitionSubnet6 parses the competition's IPv6 block, returning nil for IPv4-only competitions.
+func competitionSubnet6(comp *db.Competition) (*net.IPNet, error) {
+	if comp == nil || strings.TrimSpace(comp.NetworkCIDR6) == "" {
+		return nil, nil
+	}
+
+	_, block, err := net.ParseCIDR(strings.TrimSpace(comp.NetworkCIDR6))
+	if err != nil {
+		return nil, fmt.Errorf("parse competition IPv6 network: %w", err)
+	}
+
+	return block, nil
+}
+
+// SharedSubnetCIDR6 returns the IPv6 /64 reserved for a competition's shared containers, or "" when it has none.
+func SharedSubnetCIDR6(comp *db.Competition) (string, error) {
+	compSubnet6, err := competitionSubnet6(comp)
+	if err != nil || compSubnet6 == nil {
+		return "", err
+	}
+
+	subnet, err := teamSubnet6(compSubnet6, -1)
+	if err != nil {
+		return "", err
+	}
+
+	return subnet.String(), nil
+}
+
+func ip6ToBig(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+func bigToIP6(value *big.Int) net.IP {
+	var ip = make(net.IP, net.IPv6len)
+	value.FillBytes(ip)
+	return ip
+}
+
+// addIPv6 assigns every config its IPv6 address in the /64 of teamIndex (-1 for the shared containers), using the same
+// host offsets as IPv4.
+func (n *teamNetwork) addIPv6(compSubnet6 *net.IPNet, teamIndex int, configs []db.TeamContainerConfig) error {
+	subnet, err := teamSubnet6(compSubnet6, teamIndex)
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range configs {
+		hostIP, hostErr := hostIP6WithinSubnet(subnet, cfg.LastOctetValue)
+		if hostErr != nil {
+			return fmt.Errorf("container %s: %w", cfg.Name, hostErr)
+		}
+
+		n.ip6sByName[sanitizeContainerName(cfg.Name)] = hostIP.String()
+		n.ip6Order = append(n.ip6Order, hostIP.String())
+	}
+
+	return nil
+}
+
+// attachIPv6 sets the prefix and gateway of a dual-stack plan. Containers use their /64, or their isolated segment's
+// IPv6 block, with its first host as the gateway unless [network] container_gateway6 names one.
+func attachIPv6(comp *db.Competition, plan *containerPlan) {
+	var address = net.ParseIP(plan.options.IPv6Address)
+	if address == nil {
+		return
+	}
+
+	var block = &net.IPNet{IP: address.Mask(net.CIDRMask(config.TeamSubnetPrefix6, 128)), Mask: net.CIDRMask(config.TeamSubnetPrefix6, 128)}
+	if isolated(comp) {
+		if segment, ok := networkSegmentFor(comp, plan.teamID()); ok && segment.CIDR6 != "" {
+			if _, segmentBlock, err := net.ParseCIDR(segment.CIDR6); err == nil {
+				block = segmentBlock
+			}
+		}
+	}
+
+	plan.options.CIDRBlock6, _ = block.Mask.Size()
+	plan.options.GatewayIPv6 = strings.TrimSpace(config.Config.Network.ContainerGateway6)
+	if plan.options.GatewayIPv6 == "" {
+		if gateway, err := hostIP6WithinSubnet(block, isolatedGatewayOffset); err == nil {
+			plan.options.GatewayIPv6 = gateway.String()
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package koth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/UNHCSC/pve-koth/ssh"
+)
+
+const (
+	defaultClaimPath   = "/root/king.txt"
+	windowsClaimPath   = `C:\Users\Administrator\king.txt`
+	claimTokenPrefix   = "koth-"
+	maxClaimTokenBytes = 256
+	claimCheckID       = "claim"
+)
+
+// hillClaim is the outcome of reading one hill's claim file during a scoring round.
+type hillClaim struct {
+	hillTeamID    int64
+	containerName string
+	points        int
+	ownerTeamID   int64
+	readOK        bool
+}
+
+// newClaimToken returns a random secret a team writes into a hill's claim file to take ownership.
+func newClaimToken() (string, error) {
+	var buf = make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return claimTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// EnsureTeamClaimToken returns the team's claim token, generating and persisting one for teams created before
+// ownership scoring existed.
+func EnsureTeamClaimToken(team *db.Team) (string, error) {
+	if team == nil {
+		return "", fmt.Errorf("team is nil")
+	}
+	if team.ClaimToken != "" {
+		return team.ClaimToken, nil
+	}
+
+	token, err := newClaimToken()
+	if err != nil {
+		return "", err
+	}
+
+	updated, err := db.UpdateTeam(team.ID, func(current *db.Team) bool {
+		if current.ClaimToken != "" {
+			return false
+		}
+		current.ClaimToken = token
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+	if updated == nil {
+		return "", fmt.Errorf("team %d not found", team.ID)
+	}
+
+	*team = *updated
+	return team.ClaimToken, nil
+}
+
+// claimPath returns the sanitized absolute claim file path for a hill.
+func claimPath(cfg *db.ClaimConfig) string {
+	if cfg == nil || strings.TrimSpace(cfg.Path) == "" {
+		return defaultClaimPath
+	}
+	return path.Clean("/" + strings.TrimSpace(cfg.Path))
+}
+
+// readClaimCommand builds the console command that prints the first line of a claim file.
+func readClaimCommand(claimFile string) string {
+	var quoted = "'" + strings.ReplaceAll(claimFile, "'", `'"'"'`) + "'"
+	return fmt.Sprintf("head -c %d -- %s 2>/dev/null | head -n 1; true", maxClaimTokenBytes, quoted)
+}
+
+// guestClaimCommand builds the command that prints the first line of a hill's claim file on guest. Windows paths are
+// taken as configured, defaulting to Administrator's profile, and read with PowerShell.
+func guestClaimCommand(guest *proxmoxAPI.Guest, cfg *db.ClaimConfig) string {
+	if !guest.IsWindows() {
+		return readClaimCommand(claimPath(cfg))
+	}
+
+	var claimFile = windowsClaimPath
+	if cfg != nil && strings.TrimSpace(cfg.Path) != "" {
+		claimFile = strings.TrimSpace(cfg.Path)
+	}
+
+	return fmt.Sprintf(`try {
+	$reader = New-Object IO.StreamReader(%s)
+	$buffer = New-Object char[] %d
+	$read = $reader.Read($buffer, 0, $buffer.Length)
+	$reader.Close()
+	[string]::new($buffer, 0, $read).Split([char[]]@([char]13, [char]10))[0]
+} catch {}
+exit 0`, ssh.PowerShellQuote(claimFile), maxClaimTokenBytes)
+}
+
+// scoreOwnership reads every hill's claim file, updates the ownership history and awards hill points to the
+// owning teams.
+func scoreOwnership(comp *db.Competition, req *db.CreateCompetitionRequest, round int64, roundTime time.Time) {
+	var hills, sharedHills []db.TeamContainerConfig
+	for _, cfg := range req.TeamContainerConfigs {
+		if cfg.Claim != nil {
+			hills = append(hills, cfg)
+		}
+	}
+	for _, cfg := range req.SharedContainerConfigs {
+		if cfg.Claim != nil {
+			sharedHills = append(sharedHills, cfg)
+		}
+	}
+	if len(hills) == 0 && len(sharedHills) == 0 {
+		return
+	}
+
+	var (
+		teamsByToken = make(map[string]*db.Team)
+		teamsByID    = make(map[int64]*db.Team)
+	)
+
+	for _, teamID := range comp.TeamIDs {
+		team, err := db.Teams.Select(teamID)
+		if err != nil {
+			scoringLog.Errorf("failed to load team %d for ownership scoring: %v\n", teamID, err)
+			continue
+		}
+		if team == nil {
+			continue
+		}
+
+		teamsByID[team.ID] = team
+		if token := strings.TrimSpace(team.ClaimToken); token != "" {
+			teamsByToken[token] = team
+		}
+	}
+
+	var (
+		claims []hillClaim
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+	)
+
+	var read = func(hillTeamID int64, hostname string, cfg db.TeamContainerConfig) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			claim := readHillClaim(comp, req, hillTeamID, hostname, cfg, teamsByToken)
+
+			mu.Lock()
+			claims = append(claims, claim)
+			mu.Unlock()
+		}()
+	}
+
+	for teamIndex, teamID := range comp.TeamIDs {
+		if teamsByID[teamID] == nil {
+			continue
+		}
+
+		for _, cfg := range hills {
+			read(teamID, fmt.Sprintf("%s-team-%d-%s", comp.ContainerRestrictions.HostnamePrefix, teamIndex+1, cfg.Name), cfg)
+		}
+	}
+
+	for _, cfg := range sharedHills {
+		read(0, sharedContainerHostname(comp, cfg.Name), cfg)
+	}
+
+	wg.Wait()
+
+	current, err := db.GetCurrentOwnership(comp.SystemID)
+	if err != nil {
+		scoringLog.Errorf("failed to load ownership for %s: %v\n", comp.SystemID, err)
+		return
+	}
+
+	var openByHill = make(map[string]*db.OwnershipRecord, len(current))
+	for _, record := range current {
+		openByHill[hillKey(record.HillTeamID, record.ContainerName)] = record
+	}
+
+	var awarded = make(map[int64]int)
+	for _, claim := range claims {
+		if !claim.readOK {
+			continue
+		}
+
+		var (
+			key  = hillKey(claim.hillTeamID, claim.containerName)
+			open = openByHill[key]
+		)
+
+		if open != nil && open.OwnerTeamID == claim.ownerTeamID {
+			open.PointsAwarded += claim.points
+			if err := db.Ownership.Update(open); err != nil {
+				scoringLog.Errorf("failed to update ownership record %d: %v\n", open.ID, err)
+			}
+		} else {
+			if open != nil {
+				open.ReleasedAt = roundTime
+				open.ReleasedAtUnix = roundTime.Unix()
+				if err := db.Ownership.Update(open); err != nil {
+					scoringLog.Errorf("failed to release ownership record %d: %v\n", open.ID, err)
+				}
+			}
+
+			if claim.ownerTeamID != 0 {
+				record := &db.OwnershipRecord{
+					CompetitionID: comp.SystemID,
+					HillTeamID:    claim.hillTeamID,
+					ContainerName: claim.containerName,
+					OwnerTeamID:   claim.ownerTeamID,
+					ClaimedRound:  round,
+					ClaimedAt:     roundTime,
+					PointsAwarded: claim.points,
+				}
+				if err := db.Ownership.Insert(record); err != nil {
+					scoringLog.Errorf("failed to record ownership of %s for team %d: %v\n", claim.containerName, claim.ownerTeamID, err)
+				}
+				scoringLog.Statusf("%s: team %d claimed %s (hill team %d)\n", comp.SystemID, claim.ownerTeamID, claim.containerName, claim.hillTeamID)
+			}
+		}
+
+		if claim.ownerTeamID == 0 {
+			continue
+		}
+
+		awarded[claim.ownerTeamID] += claim.points
+		entry := &db.ScoreHistoryEntry{
+			Round:          round,
+			CompetitionID:  comp.SystemID,
+			TeamID:         claim.ownerTeamID,
+			ContainerName:  claim.containerName,
+			CheckID:        claimCheckID,
+			CheckName:      hillLabel(claim.hillTeamID, claim.containerName),
+			Passed:         true,
+			State:          db.CheckStatePass,
+			PointsAwarded:  claim.points,
+			RecordedAt:     roundTime,
+			RecordedAtUnix: roundTime.Unix(),
+		}
+		if err := db.ScoreHistory.Insert(entry); err != nil {
+			scoringLog.Errorf("failed to persist ownership history for team %d: %v\n", claim.ownerTeamID, err)
+		}
+	}
+
+	for teamID, points := range awarded {
+		team, err := db.UpdateTeam(teamID, func(current *db.Team) bool {
+			current.Score += points
+			current.LastUpdated = time.Now()
+			return true
+		})
+		if err != nil {
+			scoringLog.Errorf("failed to update team %d: %v\n", teamID, err)
+		} else if team == nil {
+			scoringLog.Errorf("team %d disappeared before its ownership points were awarded\n", teamID)
+		}
+	}
+}
+
+// readHillClaim reads a single hill's claim file and resolves the token to its owning team. Shared hills use a
+// hillTeamID of 0.
+func readHillClaim(comp *db.Competition, req *db.CreateCompetitionRequest, hillTeamID int64, hostname string, cfg db.TeamContainerConfig, teamsByToken map[string]*db.Team) hillClaim {
+	var claim = hillClaim{
+		hillTeamID:    hillTeamID,
+		containerName: cfg.Name,
+		points:        cfg.Claim.Points,
+	}
+
+	status, statusErr := containerStatusForTeam(comp, hillTeamID, cfg.Name)
+	if statusErr != nil {
+		scoringLog.Errorf("failed to fetch status for %s: %v\n", hostname, statusErr)
+	}
+	if strings.EqualFold(status, "redeploying") {
+		return claim
+	}
+
+	templateSpec, specErr := ResolveContainerSpecTemplate(req.TemplateLookup, cfg.ContainerSpecsTemplate)
+	if specErr != nil {
+		scoringLog.Errorf("failed to resolve template %s for %s: %v\n", cfg.ContainerSpecsTemplate, cfg.Name, specErr)
+		return claim
+	}
+
+	record, recErr := containerRecordForTeam(comp, hillTeamID, cfg.Name)
+	if recErr != nil || record == nil {
+		if recErr != nil {
+			scoringLog.Errorf("failed to load container record for %s: %v\n", hostname, recErr)
+		}
+		return claim
+	}
+
+	var (
+		stdout   string
+		exitCode int
+		execErr  error
+	)
+
+	ct, ctErr := api.Guest(int(record.PVEID))
+	if ctErr != nil {
+		scoringLog.Errorf("failed to load container %s (CTID %d): %v\n", hostname, record.PVEID, ctErr)
+		return claim
+	}
+
+	plan := &containerPlan{
+		name:      cfg.Name,
+		ipAddress: record.IPAddress,
+		transport: execTransport(cfg.ExecTransport),
+		options:   &proxmoxAPI.ContainerCreateOptions{Hostname: hostname, RootPassword: templateSpec.RootPassword},
+	}
+
+	stdout, _, exitCode, execErr = execInGuest(api, comp, plan, ct, "root", templateSpec.RootPassword, guestClaimCommand(ct, cfg.Claim), nil)
+	if execErr != nil || exitCode != 0 {
+		scoringLog.Errorf("failed to read claim file on %s: exit %d: %v\n", hostname, exitCode, execErr)
+		return claim
+	}
+
+	claim.readOK = true
+	if owner, ok := teamsByToken[strings.TrimSpace(stdout)]; ok {
+		claim.ownerTeamID = owner.ID
+	}
+
+	return claim
+}
+
+// hillLabel describes a hill in score history entries.
+func hillLabel(hillTeamID int64, containerName string) string {
+	if hillTeamID == 0 {
+		return fmt.Sprintf("Holding %s (shared)", containerName)
+	}
+	return fmt.Sprintf("Holding %s (team %d)", containerName, hillTeamID)
+}
+
+func hillKey(hillTeamID int64, containerName string) string {
+	return fmt.Sprintf("%d/%s", hillTeamID, strings.ToLower(strings.TrimSpace(containerName)))
+}
@@ -0,0 +1,99 @@
+package koth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+)
+
+var (
+	provisionSlots     chan struct{}
+	provisionSlotsOnce sync.Once
+)
+
+// acquireProvisionSlot blocks until one of the provisioning.max_concurrent worker slots is free. Every provisioning
+// and redeploy job shares the same slots, so a large upload cannot starve the cluster for everyone else.
+func acquireProvisionSlot(ctx context.Context) (release func(), err error) {
+	provisionSlotsOnce.Do(func() {
+		limit := config.Config.Provisioning.MaxConcurrent
+		if limit < 1 {
+			limit = 1
+		}
+		provisionSlots = make(chan struct{}, limit)
+	})
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	select {
+	case provisionSlots <- struct{}{}:
+		return func() { <-provisionSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// placementRequest describes what a plan needs from the node it is created on.
+func (p *containerPlan) placementRequest() proxmoxAPI.PlacementRequest {
+	return proxmoxAPI.PlacementRequest{
+		MemoryMB:      p.options.MemoryMB,
+		Cores:         p.options.Cores,
+		StoragePool:   p.options.StoragePool,
+		StorageSizeGB: p.options.StorageSizeGB,
+		PinnedNode:    p.node,
+		AffinityKey:   p.affinityKey,
+	}
+}
+
+// teamAffinityEnabled reports whether a competition keeps each team's containers on a single node.
+func teamAffinityEnabled(req *db.CreateCompetitionRequest) bool {
+	return config.Config.Provisioning.TeamAffinity || (req != nil && req.Placement.TeamAffinity)
+}
+
+func teamAffinityKey(comp *db.Competition, teamID int64) string {
+	return fmt.Sprintf("%s/team/%d", comp.SystemID, teamID)
+}
+
+// applyTeamAffinity tags every team plan with its team's affinity key when team affinity is enabled.
+func applyTeamAffinity(comp *db.Competition, req *db.CreateCompetitionRequest, plans []*containerPlan) {
+	if !teamAffinityEnabled(req) {
+		return
+	}
+
+	for _, plan := range plans {
+		if plan.team != nil {
+			plan.affinityKey = teamAffinityKey(comp, plan.team.ID)
+		}
+	}
+}
+
+// seedTeamAffinity points each team's affinity key at the node its existing containers already run on, so resumed
+// containers join them.
+func seedTeamAffinity(comp *db.Competition, req *db.CreateCompetitionRequest) {
+	if api == nil || !teamAffinityEnabled(req) {
+		return
+	}
+
+	for _, teamID := range comp.TeamIDs {
+		team, err := db.Teams.Select(teamID)
+		if err != nil || team == nil {
+			continue
+		}
+
+		for _, id := range team.ContainerIDs {
+			record, err := db.Containers.Select(id)
+			if err != nil || record == nil || strings.TrimSpace(record.NodeName) == "" {
+				continue
+			}
+
+			api.SetPlacementAffinity(teamAffinityKey(comp, teamID), record.NodeName)
+			break
+		}
+	}
+}
@@ -0,0 +1,523 @@
+package koth
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	checkTypeScript = "script"
+	checkTypeTCP    = "tcp"
+	checkTypeHTTP   = "http"
+	checkTypeDNS    = "dns"
+	checkTypeICMP   = "icmp"
+	checkTypeSSH    = "ssh"
+	checkTypeSMTP   = "smtp"
+	checkTypeFTP    = "ftp"
+
+	defaultProbeTimeout = 5 * time.Second
+	maxProbeBodyBytes   = 1 << 20
+)
+
+var defaultProbePorts = map[string]int{
+	checkTypeHTTP: 80,
+	checkTypeDNS:  53,
+	checkTypeSSH:  22,
+	checkTypeSMTP: 25,
+	checkTypeFTP:  21,
+}
+
+// normalizeCheckType returns the canonical check type, treating an empty type as a script check.
+func normalizeCheckType(raw string) string {
+	var checkType = strings.ToLower(strings.TrimSpace(raw))
+	if checkType == "" {
+		return checkTypeScript
+	}
+	return checkType
+}
+
+// isProbeCheck reports whether the check is evaluated by a built-in probe instead of a scoring script.
+func isProbeCheck(check db.ScoringCheck) bool {
+	return normalizeCheckType(check.Type) != checkTypeScript
+}
+
+// ValidateScoringCheck verifies that a scoring check's type and probe parameters are usable. tcp and dns checks need a
+// probe block; the other probes fall back to their defaults without one.
+func ValidateScoringCheck(check db.ScoringCheck) error {
+	var checkType = normalizeCheckType(check.Type)
+	if checkType == checkTypeScript {
+		return nil
+	}
+
+	switch checkType {
+	case checkTypeTCP, checkTypeHTTP, checkTypeDNS, checkTypeICMP, checkTypeSSH, checkTypeSMTP, checkTypeFTP:
+	default:
+		return fmt.Errorf("check %s has unknown type %q", check.ID, check.Type)
+	}
+
+	var probe = check.Probe
+	if probe == nil {
+		if checkType == checkTypeTCP || checkType == checkTypeDNS {
+			return fmt.Errorf("check %s (%s) requires a probe block", check.ID, checkType)
+		}
+		return nil
+	}
+
+	if probe.Port < 0 || probe.Port > 65535 {
+		return fmt.Errorf("check %s has invalid port %d", check.ID, probe.Port)
+	}
+	if checkType == checkTypeTCP && probe.Port == 0 {
+		return fmt.Errorf("check %s (tcp) requires a port", check.ID)
+	}
+	if probe.TimeoutSeconds < 0 {
+		return fmt.Errorf("check %s has invalid timeoutSeconds %d", check.ID, probe.TimeoutSeconds)
+	}
+
+	if probe.BodyRegex != "" {
+		if _, err := regexp.Compile(probe.BodyRegex); err != nil {
+			return fmt.Errorf("check %s has invalid bodyRegex: %w", check.ID, err)
+		}
+	}
+	if probe.Expect != "" {
+		if _, err := regexp.Compile(probe.Expect); err != nil {
+			return fmt.Errorf("check %s has invalid expect pattern: %w", check.ID, err)
+		}
+	}
+
+	if checkType == checkTypeDNS {
+		if strings.TrimSpace(probe.RecordName) == "" {
+			return fmt.Errorf("check %s (dns) requires recordName", check.ID)
+		}
+		switch strings.ToUpper(strings.TrimSpace(probe.RecordType)) {
+		case "", "A", "AAAA", "CNAME", "MX", "NS", "TXT", "PTR":
+		default:
+			return fmt.Errorf("check %s (dns) has unsupported recordType %q", check.ID, probe.RecordType)
+		}
+	}
+
+	return nil
+}
+
+// RunProbe executes a built-in check against the given host and reports whether it passed. A non-nil error
+// describes why the probe failed; it is informational and always accompanies a false result.
+func RunProbe(check db.ScoringCheck, host string) (bool, error) {
+	var probe db.ScoringProbe
+	if check.Probe != nil {
+		probe = *check.Probe
+	}
+
+	if strings.TrimSpace(host) == "" {
+		return false, fmt.Errorf("no probe target")
+	}
+
+	var timeout = defaultProbeTimeout
+	if probe.TimeoutSeconds > 0 {
+		timeout = time.Duration(probe.TimeoutSeconds) * time.Second
+	}
+
+	var (
+		checkType = normalizeCheckType(check.Type)
+		port      = probe.Port
+	)
+
+	if port == 0 {
+		port = defaultProbePorts[checkType]
+		if checkType == checkTypeHTTP && probe.TLS {
+			port = 443
+		}
+	}
+
+	var address = net.JoinHostPort(host, strconv.Itoa(port))
+
+	var err error
+	switch checkType {
+	case checkTypeTCP:
+		err = probeTCP(address, timeout)
+	case checkTypeHTTP:
+		err = probeHTTP(host, port, probe, timeout)
+	case checkTypeDNS:
+		err = probeDNS(address, probe, timeout)
+	case checkTypeICMP:
+		err = probeICMP(host, timeout)
+	case checkTypeSSH:
+		err = probeSSH(address, probe, timeout)
+	case checkTypeSMTP:
+		err = probeSMTP(host, address, probe, timeout)
+	case checkTypeFTP:
+		err = probeFTP(address, probe, timeout)
+	default:
+		err = fmt.Errorf("unsupported check type %q", check.Type)
+	}
+
+	return err == nil, err
+}
+
+// resolveProbeTarget maps a probe target to an address, resolving container names within the team network.
+func resolveProbeTarget(probe *db.ScoringProbe, plan *containerPlan, network *teamNetwork) string {
+	if probe == nil || strings.TrimSpace(probe.Target) == "" {
+		return plan.ipAddress
+	}
+
+	if network != nil {
+		if ip, ok := network.ipsByName[sanitizeContainerName(probe.Target)]; ok {
+			return ip
+		}
+	}
+
+	return strings.TrimSpace(probe.Target)
+}
+
+func probeTCP(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func probeHTTP(host string, port int, probe db.ScoringProbe, timeout time.Duration) error {
+	var scheme = "http"
+	if probe.TLS {
+		scheme = "https"
+	}
+
+	var path = probe.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)), path), nil)
+	if err != nil {
+		return err
+	}
+	if probe.HostHeader != "" {
+		req.Host = probe.HostHeader
+	}
+
+	var client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: probe.InsecureSkipVerify, ServerName: probe.HostHeader},
+			DisableKeepAlives: true,
+		},
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if probe.ExpectStatus != 0 {
+		if resp.StatusCode != probe.ExpectStatus {
+			return fmt.Errorf("status %d, expected %d", resp.StatusCode, probe.ExpectStatus)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	if probe.BodyRegex == "" {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodyBytes))
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	pattern, err := regexp.Compile(probe.BodyRegex)
+	if err != nil {
+		return fmt.Errorf("invalid bodyRegex: %w", err)
+	}
+	if !pattern.Match(body) {
+		return fmt.Errorf("body does not match %q", probe.BodyRegex)
+	}
+
+	return nil
+}
+
+func probeDNS(address string, probe db.ScoringProbe, timeout time.Duration) error {
+	var resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		name    = strings.TrimSpace(probe.RecordName)
+		answers []string
+		err     error
+	)
+
+	switch strings.ToUpper(strings.TrimSpace(probe.RecordType)) {
+	case "", "A", "AAAA":
+		var ips []net.IP
+		var network = "ip4"
+		if strings.EqualFold(strings.TrimSpace(probe.RecordType), "AAAA") {
+			network = "ip6"
+		}
+		if ips, err = resolver.LookupIP(ctx, network, name); err == nil {
+			for _, ip := range ips {
+				answers = append(answers, ip.String())
+			}
+		}
+	case "CNAME":
+		var cname string
+		if cname, err = resolver.LookupCNAME(ctx, name); err == nil {
+			answers = append(answers, cname)
+		}
+	case "MX":
+		var records []*net.MX
+		if records, err = resolver.LookupMX(ctx, name); err == nil {
+			for _, record := range records {
+				answers = append(answers, record.Host)
+			}
+		}
+	case "NS":
+		var records []*net.NS
+		if records, err = resolver.LookupNS(ctx, name); err == nil {
+			for _, record := range records {
+				answers = append(answers, record.Host)
+			}
+		}
+	case "TXT":
+		answers, err = resolver.LookupTXT(ctx, name)
+	case "PTR":
+		answers, err = resolver.LookupAddr(ctx, name)
+	default:
+		return fmt.Errorf("unsupported record type %q", probe.RecordType)
+	}
+
+	if err != nil {
+		return err
+	}
+	if len(answers) == 0 {
+		return fmt.Errorf("no answers for %s", name)
+	}
+	if probe.Expect == "" {
+		return nil
+	}
+
+	pattern, err := regexp.Compile(probe.Expect)
+	if err != nil {
+		return fmt.Errorf("invalid expect pattern: %w", err)
+	}
+	for _, answer := range answers {
+		if pattern.MatchString(answer) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no answer for %s matches %q (got %s)", name, probe.Expect, strings.Join(answers, ", "))
+}
+
+func probeICMP(host string, timeout time.Duration) error {
+	var seconds = int(timeout / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+time.Second)
+	defer cancel()
+
+	if output, err := exec.CommandContext(ctx, "ping", "-c", "1", "-W", strconv.Itoa(seconds), host).CombinedOutput(); err != nil {
+		return fmt.Errorf("ping %s: %v (%s)", host, err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+func probeSSH(address string, probe db.ScoringProbe, timeout time.Duration) error {
+	if probe.Username == "" {
+		banner, err := readBannerLine(address, timeout)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(banner, "SSH-") {
+			return fmt.Errorf("unexpected ssh banner %q", banner)
+		}
+		return matchBanner(banner, probe.Expect)
+	}
+
+	var clientConfig = &ssh.ClientConfig{
+		User: probe.Username,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(probe.Password),
+			ssh.KeyboardInteractive(func(_, _ string, questions []string, _ []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = probe.Password
+				}
+				return answers, nil
+			}),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         timeout,
+	}
+
+	client, err := ssh.Dial("tcp", address, clientConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return matchBanner(string(client.ServerVersion()), probe.Expect)
+}
+
+// recordingConn keeps a copy of everything read from the connection until recording is turned off.
+type recordingConn struct {
+	net.Conn
+	recording bool
+	read      strings.Builder
+}
+
+func (c *recordingConn) Read(p []byte) (n int, err error) {
+	n, err = c.Conn.Read(p)
+	if c.recording {
+		c.read.Write(p[:n])
+	}
+	return
+}
+
+// probeSMTP passes when the server greets and accepts EHLO. Expect must match the greeting or the EHLO reply.
+func probeSMTP(host, address string, probe db.ScoringProbe, timeout time.Duration) error {
+	dialed, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	defer dialed.Close()
+	dialed.SetDeadline(time.Now().Add(timeout))
+
+	var conn = &recordingConn{Conn: dialed, recording: probe.Expect != ""}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// The server says nothing between its greeting and the EHLO reply, so everything read so far is the greeting.
+	var greeting = strings.TrimSpace(conn.read.String())
+	conn.read.Reset()
+
+	if err = client.Hello("koth-scorer"); err != nil {
+		return err
+	}
+
+	conn.recording = false
+	if probe.Expect != "" && matchBanner(greeting, probe.Expect) != nil && matchBanner(strings.TrimSpace(conn.read.String()), probe.Expect) != nil {
+		return fmt.Errorf("neither the greeting %q nor the EHLO reply matches %q", greeting, probe.Expect)
+	}
+
+	if probe.TLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("server does not offer STARTTLS")
+		}
+		if err = client.StartTLS(&tls.Config{ServerName: host, InsecureSkipVerify: probe.InsecureSkipVerify}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if probe.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", probe.Username, probe.Password, host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	return client.Quit()
+}
+
+func probeFTP(address string, probe db.ScoringProbe, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var text = textproto.NewConn(conn)
+
+	_, banner, err := text.ReadResponse(220)
+	if err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+	if err = matchBanner(banner, probe.Expect); err != nil {
+		return err
+	}
+
+	if probe.Username != "" {
+		if _, err = text.Cmd("USER %s", probe.Username); err != nil {
+			return err
+		}
+
+		code, message, respErr := text.ReadResponse(0)
+		if respErr != nil {
+			return fmt.Errorf("USER: %w", respErr)
+		}
+
+		if code == 331 {
+			if _, err = text.Cmd("PASS %s", probe.Password); err != nil {
+				return err
+			}
+			if _, _, respErr = text.ReadResponse(230); respErr != nil {
+				return fmt.Errorf("PASS: %w", respErr)
+			}
+		} else if code != 230 {
+			return fmt.Errorf("USER: unexpected response %d %s", code, message)
+		}
+	}
+
+	text.Cmd("QUIT")
+	return nil
+}
+
+// readBannerLine connects to address and returns the first line the server sends.
+func readBannerLine(address string, timeout time.Duration) (string, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	line, err := textproto.NewReader(bufio.NewReader(conn)).ReadLine()
+	if err != nil {
+		return "", fmt.Errorf("read banner: %w", err)
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+func matchBanner(banner, expect string) error {
+	if expect == "" {
+		return nil
+	}
+
+	pattern, err := regexp.Compile(expect)
+	if err != nil {
+		return fmt.Errorf("invalid expect pattern: %w", err)
+	}
+	if pattern.MatchString(banner) {
+		return nil
+	}
+	return fmt.Errorf("banner %q does not match %q", banner, expect)
+}
@@ -0,0 +1,312 @@
+package koth
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/UNHCSC/pve-koth/db"
+)
+
+// ErrProvisioningNotResumable is returned when a resume is requested for a competition that is not in the
+// provisioning_failed state.
+var ErrProvisioningNotResumable = errors.New("competition provisioning is not resumable")
+
+var provisioningStatusMu sync.Mutex
+
+// ClaimProvisioningResume moves a provisioning_failed competition back to provisioning so only one resume runs at a
+// time.
+func ClaimProvisioningResume(comp *db.Competition) error {
+	if comp == nil {
+		return fmt.Errorf("competition is nil")
+	}
+
+	provisioningStatusMu.Lock()
+	defer provisioningStatusMu.Unlock()
+
+	var claimErr error
+	current, err := db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+		if current.ProvisioningStatus != db.ProvisioningStatusFailed {
+			claimErr = fmt.Errorf("%w: %s is %s", ErrProvisioningNotResumable, comp.SystemID, provisioningStatusLabel(current.ProvisioningStatus))
+			return false
+		}
+
+		current.ProvisioningStatus = db.ProvisioningStatusRunning
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("competition %s not found", comp.SystemID)
+	}
+	if claimErr != nil {
+		return claimErr
+	}
+
+	*comp = *current
+	return nil
+}
+
+// FailInterruptedProvisioning marks competitions that were still provisioning when the server stopped as
+// provisioning_failed, so their missing containers can be provisioned with a resume.
+func FailInterruptedProvisioning() (systemIDs []string, err error) {
+	provisioningStatusMu.Lock()
+	defer provisioningStatusMu.Unlock()
+
+	comps, err := db.Competitions.SelectAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, comp := range comps {
+		if comp == nil || comp.ProvisioningStatus != db.ProvisioningStatusRunning {
+			continue
+		}
+
+		if _, err = db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+			current.ProvisioningStatus = db.ProvisioningStatusFailed
+			current.ProvisioningError = "provisioning was interrupted by a server restart"
+			return true
+		}); err != nil {
+			return systemIDs, err
+		}
+
+		systemIDs = append(systemIDs, comp.SystemID)
+	}
+
+	return systemIDs, nil
+}
+
+// ResumeCompetitionProvisioningWithLogger provisions only the containers of a competition that have no record yet,
+// reusing its teams, networks and SSH keys. The competition must have been claimed with ClaimProvisioningResume.
+func ResumeCompetitionProvisioningWithLogger(comp *db.Competition, logSink ProgressLogger, enableAdvancedLogging bool) (err error) {
+	if comp == nil {
+		return fmt.Errorf("competition is nil")
+	}
+
+	var localLog = wrapLoggerSafe(logSink)
+	if localLog == nil {
+		localLog = wrapLoggerSafe(containerLog)
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		// Anything that fails before containers are provisioned leaves the competition resumable again.
+		if comp.ProvisioningStatus == db.ProvisioningStatusRunning {
+			comp.ProvisioningStatus = db.ProvisioningStatusFailed
+			comp.ProvisioningError = err.Error()
+			if updateErr := saveProvisioningState(comp); updateErr != nil {
+				localLog.Errorf("Failed to update competition record: %v\n", updateErr)
+			}
+		}
+	}()
+
+	if api == nil {
+		return fmt.Errorf("proxmox API is not initialized")
+	}
+
+	localLog.Statusf("Resuming provisioning for %s...", comp.Name)
+
+	var req *db.CreateCompetitionRequest
+	if req, err = loadCompetitionDefinition(comp); err != nil {
+		return fmt.Errorf("load competition definition: %w", err)
+	}
+
+	var publicKeyData, privateKeyData []byte
+	if publicKeyData, err = os.ReadFile(comp.SSHPubKeyPath); err != nil {
+		return fmt.Errorf("read ssh public key: %w", err)
+	}
+	if privateKeyData, err = os.ReadFile(comp.SSHPrivKeyPath); err != nil {
+		return fmt.Errorf("read ssh private key: %w", err)
+	}
+
+	var compNet *net.IPNet
+	if _, compNet, err = net.ParseCIDR(comp.NetworkCIDR); err != nil {
+		return fmt.Errorf("parse competition network: %w", err)
+	}
+
+	var compNet6 *net.IPNet
+	if compNet6, err = competitionSubnet6(comp); err != nil {
+		return err
+	}
+
+	var plans []*containerPlan
+	if plans, err = missingContainerPlans(comp, req, compNet, compNet6, strings.TrimSpace(string(publicKeyData))); err != nil {
+		return err
+	}
+
+	applyTeamAffinity(comp, req, plans)
+	seedTeamAffinity(comp, req)
+	if req.CloneTeamContainers {
+		markClonePlans(plans)
+	}
+	for _, plan := range plans {
+		if err = applyNetworkAttachment(comp, plan); err != nil {
+			return err
+		}
+	}
+
+	var (
+		networks = make(map[int64]*teamNetwork)
+		locks    = make(map[int64]*sync.Mutex)
+	)
+
+	for _, plan := range plans {
+		if _, ok := networks[plan.teamID()]; ok {
+			continue
+		}
+
+		var network *teamNetwork
+		if plan.team == nil {
+			network, err = buildSharedNetwork(compNet, compNet6, req.SharedContainerConfigs)
+		} else {
+			network, err = buildTeamNetwork(compNet, compNet6, findTeamIndex(comp.TeamIDs, plan.team.ID), req.TeamContainerConfigs, req.SharedContainerConfigs)
+		}
+		if err != nil {
+			return fmt.Errorf("build network for %s: %w", plan.ownerLabel(), err)
+		}
+
+		networks[plan.teamID()] = network
+		locks[plan.teamID()] = &sync.Mutex{}
+	}
+
+	if len(plans) == 0 {
+		localLog.Status("Every planned container already exists; nothing to resume.")
+	} else {
+		localLog.Statusf("%d containers are missing and will be provisioned.", len(plans))
+
+		provisioned, failures := provisionContainerPlans(localLog, comp, plans, networks, locks, string(privateKeyData), true, enableAdvancedLogging)
+		if len(failures) > 0 {
+			return keepPartialProvisioning(localLog, comp, provisioned, failures, len(plans))
+		}
+	}
+
+	comp.ProvisioningStatus = db.ProvisioningStatusReady
+	comp.ProvisioningError = ""
+	if err = saveProvisioningState(comp); err != nil {
+		localLog.Errorf("Failed to update competition record: %v\n", err)
+		return err
+	}
+
+	localLog.Successf("Provisioning for %s is complete.\n", comp.Name)
+	return nil
+}
+
+// saveProvisioningState writes comp's provisioning status and container IDs onto the stored competition, keeping
+// scoring and schedule edits made while it was provisioning, and refreshes comp from the saved row.
+func saveProvisioningState(comp *db.Competition) error {
+	updated, err := db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+		current.ProvisioningStatus = comp.ProvisioningStatus
+		current.ProvisioningError = comp.ProvisioningError
+		current.ContainerIDs = comp.ContainerIDs
+		current.TemplateContainerIDs = comp.TemplateContainerIDs
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if updated == nil {
+		return fmt.Errorf("competition %s not found", comp.SystemID)
+	}
+
+	*comp = *updated
+	return nil
+}
+
+// missingContainerPlans rebuilds the provisioning plans of every team and shared container that has no record.
+func missingContainerPlans(comp *db.Competition, req *db.CreateCompetitionRequest, compNet, compNet6 *net.IPNet, publicKey string) ([]*containerPlan, error) {
+	var plans []*containerPlan
+
+	for teamIndex, teamID := range comp.TeamIDs {
+		team, err := db.Teams.Select(teamID)
+		if err != nil {
+			return nil, fmt.Errorf("load team %d: %w", teamID, err)
+		}
+		if team == nil {
+			return nil, fmt.Errorf("team %d not found", teamID)
+		}
+
+		existing, err := recordedConfigNames(team.ContainerIDs, teamID)
+		if err != nil {
+			return nil, err
+		}
+
+		network, err := buildTeamNetwork(compNet, compNet6, teamIndex, req.TeamContainerConfigs, req.SharedContainerConfigs)
+		if err != nil {
+			return nil, fmt.Errorf("build network for %s: %w", team.Name, err)
+		}
+
+		for order, cfg := range req.TeamContainerConfigs {
+			if existing[strings.ToLower(strings.TrimSpace(cfg.Name))] {
+				continue
+			}
+
+			templateSpec, err := ResolveContainerSpecTemplate(req.TemplateLookup, cfg.ContainerSpecsTemplate)
+			if err != nil {
+				return nil, fmt.Errorf("resolve template for %s: %w", cfg.Name, err)
+			}
+
+			sanitizedName := sanitizeContainerName(cfg.Name)
+			plans = append(plans, newTeamContainerPlan(comp, team, teamIndex, order, cfg, network.ipsByName[sanitizedName], network.ip6sByName[sanitizedName], templateSpec, publicKey))
+		}
+	}
+
+	if len(req.SharedContainerConfigs) == 0 {
+		return plans, nil
+	}
+
+	existing, err := recordedConfigNames(comp.ContainerIDs, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedNetwork, err := buildSharedNetwork(compNet, compNet6, req.SharedContainerConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("build shared network: %w", err)
+	}
+
+	sharedPlans, err := buildSharedContainerPlans(comp, req.SharedContainerConfigs, sharedNetwork, req.TemplateLookup, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, plan := range sharedPlans {
+		if !existing[strings.ToLower(strings.TrimSpace(plan.name))] {
+			plans = append(plans, plan)
+		}
+	}
+
+	return plans, nil
+}
+
+// recordedConfigNames returns the lowercased config names of the recorded containers owned by teamID (0 for shared).
+func recordedConfigNames(ids []int64, teamID int64) (map[string]bool, error) {
+	names := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		record, err := db.Containers.Select(id)
+		if err != nil {
+			return nil, fmt.Errorf("load container %d: %w", id, err)
+		}
+		if record == nil || record.TeamID != teamID {
+			continue
+		}
+
+		names[strings.ToLower(strings.TrimSpace(record.ConfigName))] = true
+	}
+
+	return names, nil
+}
+
+func provisioningStatusLabel(status string) string {
+	if status == "" {
+		return db.ProvisioningStatusReady
+	}
+	return status
+}
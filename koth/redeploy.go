@@ -1,6 +1,7 @@
 package koth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -65,19 +66,26 @@ func redeployContainer(log ProgressLogger, id int64, startAfter, enableAdvancedL
 	if req, err = loadCompetitionDefinition(comp); err != nil {
 		return fmt.Errorf("load competition definition: %w", err)
 	}
-	if len(req.TeamContainerConfigs) == 0 {
+	if len(req.TeamContainerConfigs) == 0 && len(req.SharedContainerConfigs) == 0 {
 		return fmt.Errorf("competition %s has no container configurations", comp.SystemID)
 	}
 
-	var team *db.Team
-	var teamIndex int
-	if team, teamIndex, err = resolveTeamForContainer(comp, record); err != nil {
+	var (
+		team      *db.Team
+		teamIndex int
+		shared    = isSharedContainerRecord(comp, record, req)
+		configs   = req.TeamContainerConfigs
+	)
+
+	if shared {
+		configs = req.SharedContainerConfigs
+	} else if team, teamIndex, err = resolveTeamForContainer(comp, record); err != nil {
 		return err
 	}
 
 	var cfg db.TeamContainerConfig
 	var cfgIndex int
-	if cfg, cfgIndex, err = resolveContainerConfig(req.TeamContainerConfigs, record); err != nil {
+	if cfg, cfgIndex, err = resolveContainerConfig(configs, record); err != nil {
 		return err
 	}
 
@@ -103,11 +111,31 @@ func redeployContainer(log ProgressLogger, id int64, startAfter, enableAdvancedL
 		return fmt.Errorf("parse competition network: %w", err)
 	}
 
-	var network *teamNetwork
-	if network, err = buildTeamNetwork(compNet, teamIndex, req.TeamContainerConfigs); err != nil {
+	var compNet6 *net.IPNet
+	if compNet6, err = competitionSubnet6(comp); err != nil {
+		return err
+	}
+
+	var (
+		network  *teamNetwork
+		hostname = fmt.Sprintf("%s-team-%d-%s", comp.ContainerRestrictions.HostnamePrefix, teamIndex+1, cfg.Name)
+	)
+
+	if shared {
+		hostname = sharedContainerHostname(comp, cfg.Name)
+		if network, err = buildSharedNetwork(compNet, compNet6, req.SharedContainerConfigs); err != nil {
+			return fmt.Errorf("build shared network: %w", err)
+		}
+	} else if network, err = buildTeamNetwork(compNet, compNet6, teamIndex, req.TeamContainerConfigs, req.SharedContainerConfigs); err != nil {
 		return fmt.Errorf("build team network: %w", err)
 	}
 
+	// Records from before the competition went dual-stack have no IPv6 address; give them the one their config maps to.
+	var ip6 = strings.TrimSpace(record.IPv6Address)
+	if ip6 == "" {
+		ip6 = network.ip6sByName[sanitizeContainerName(cfg.Name)]
+	}
+
 	var templateSpec db.ContainerSpecTemplate
 	if templateSpec, err = ResolveContainerSpecTemplate(req.TemplateLookup, cfg.ContainerSpecsTemplate); err != nil {
 		return fmt.Errorf("resolve template for %s: %w", cfg.Name, err)
@@ -119,11 +147,17 @@ func redeployContainer(log ProgressLogger, id int64, startAfter, enableAdvancedL
 		sanitizedName: sanitizeContainerName(cfg.Name),
 		order:         cfgIndex,
 		ipAddress:     record.IPAddress,
+		ipAddress6:    ip6,
 		setupScripts:  append([]string(nil), cfg.SetupScript...),
+		node:          strings.TrimSpace(cfg.Node),
+		kind:          guestKind(cfg.Kind),
+		transport:     execTransport(cfg.ExecTransport),
+		vmTemplateID:  templateSpec.VMTemplateID,
+		interfaces:    append([]db.ContainerInterface(nil), cfg.Interfaces...),
 		options: &proxmoxAPI.ContainerCreateOptions{
 			TemplatePath:     templateSpec.TemplatePath,
 			StoragePool:      templateSpec.StoragePool,
-			Hostname:         fmt.Sprintf("%s-team-%d-%s", comp.ContainerRestrictions.HostnamePrefix, teamIndex+1, cfg.Name),
+			Hostname:         hostname,
 			RootPassword:     templateSpec.RootPassword,
 			RootSSHPublicKey: publicKey,
 			StorageSizeGB:    templateSpec.StorageSizeGB,
@@ -131,24 +165,34 @@ func redeployContainer(log ProgressLogger, id int64, startAfter, enableAdvancedL
 			Cores:            templateSpec.Cores,
 			GatewayIPv4:      config.Config.Network.ContainerGateway,
 			IPv4Address:      record.IPAddress,
+			IPv6Address:      ip6,
 			CIDRBlock:        config.Config.Network.ContainerCIDR,
 			NameServer:       config.Config.Network.ContainerNameserver,
 			SearchDomain:     config.Config.Network.ContainerSearchDomain,
 		},
 	}
 
+	if err = applyNetworkAttachment(comp, plan); err != nil {
+		return err
+	}
+
 	var publicFolderURL = competitionPublicFolderURL(comp)
 	var artifactBaseURL = buildCompetitionArtifactBase(externalBaseURL(), comp.SystemID)
 
+	var release func()
+	if release, err = acquireProvisionSlot(context.Background()); err != nil {
+		return err
+	}
+	defer release()
+
 	var node *proxmox.Node
-	if trimmedNode := strings.TrimSpace(record.NodeName); trimmedNode != "" {
+	if trimmedNode := strings.TrimSpace(record.NodeName); trimmedNode != "" && plan.node == "" {
 		node = api.NodeByName(trimmedNode)
 	}
 	if node == nil {
-		node = api.NextNode()
-	}
-	if node == nil {
-		return fmt.Errorf("no proxmox nodes available for redeploy")
+		if node, err = api.PlaceContainer(plan.placementRequest()); err != nil {
+			return fmt.Errorf("place container: %w", err)
+		}
 	}
 
 	if err = deleteExistingContainer(record.PVEID); err != nil {
@@ -156,10 +200,10 @@ func redeployContainer(log ProgressLogger, id int64, startAfter, enableAdvancedL
 	}
 
 	var createResult *proxmoxAPI.ProxmoxAPICreateResult
-	if createResult, err = api.CreateContainerWithID(node, plan.options, int(record.PVEID)); err != nil {
+	if createResult, err = createPlanGuest(node, plan, int(record.PVEID)); err != nil {
 		return fmt.Errorf("create container: %w", err)
 	}
-	var newContainer = createResult.Container
+	var newContainer = createResult.Guest()
 
 	defer func() {
 		if newContainer == nil {
@@ -168,19 +212,19 @@ func redeployContainer(log ProgressLogger, id int64, startAfter, enableAdvancedL
 		if err == nil {
 			return
 		}
-		if stopErr := api.StopContainer(newContainer); stopErr != nil {
+		if stopErr := api.StopGuest(newContainer); stopErr != nil {
 			log.Errorf("Failed to stop container %d after failed redeploy: %v\n", record.PVEID, stopErr)
 		}
-		if delErr := api.DeleteContainer(newContainer); delErr != nil {
+		if delErr := api.DeleteGuest(newContainer); delErr != nil {
 			log.Errorf("Failed to clean up container %d after failed redeploy: %v\n", record.PVEID, delErr)
 		}
 	}()
 
-	if err = api.StartContainer(newContainer); err != nil {
+	if err = api.StartGuest(newContainer); err != nil {
 		return fmt.Errorf("failed to start container: %w", err)
 	}
 
-	if err = waitForConsoleReady(api, newContainer, plan.options.RootPassword); err != nil {
+	if err = waitForPlanReady(api, comp, plan, newContainer); err != nil {
 		return fmt.Errorf("container %d console not ready: %w", record.PVEID, err)
 	}
 
@@ -188,27 +232,38 @@ func redeployContainer(log ProgressLogger, id int64, startAfter, enableAdvancedL
 		return err
 	}
 
-	if stopErr := api.StopContainer(newContainer); stopErr != nil {
+	if comp.Firewall.Enabled {
+		if err = applyGuestFirewall(comp, plan.teamID(), newContainer); err != nil {
+			return fmt.Errorf("apply firewall rules: %w", err)
+		}
+	}
+
+	takeBaselineSnapshot(log, newContainer)
+
+	if stopErr := api.StopGuest(newContainer); stopErr != nil {
 		return fmt.Errorf("failed to stop container after redeploy: %w", stopErr)
 	}
 
-	record.NodeName = newContainer.Node
+	record.NodeName = newContainer.NodeName()
+	record.Kind = plan.kind
+	record.IPv6Address = plan.ipAddress6
 	record.StoragePool = plan.options.StoragePool
 	record.Status = "stopped"
-	record.TeamID = team.ID
+	record.TeamID = plan.teamID()
 	record.ConfigName = strings.TrimSpace(cfg.Name)
 	record.LastUpdated = time.Now()
 	if updateErr := db.Containers.Update(record); updateErr != nil {
 		log.Errorf("failed to update container %d metadata: %v\n", record.PVEID, updateErr)
 	}
 
-	team.LastUpdated = time.Now()
-	if updateErr := db.Teams.Update(team); updateErr != nil {
-		log.Errorf("failed to update team %d metadata: %v\n", team.ID, updateErr)
+	if team != nil {
+		if _, updateErr := db.UpdateTeam(team.ID, touchTeam); updateErr != nil {
+			log.Errorf("failed to update team %d metadata: %v\n", team.ID, updateErr)
+		}
 	}
 
 	if startAfter {
-		if err = api.StartContainer(newContainer); err != nil {
+		if err = api.StartGuest(newContainer); err != nil {
 			return fmt.Errorf("failed to start container after redeploy: %w", err)
 		}
 
@@ -218,9 +273,10 @@ func redeployContainer(log ProgressLogger, id int64, startAfter, enableAdvancedL
 			log.Errorf("failed to update container %d metadata after start: %v\n", record.PVEID, updateErr)
 		}
 
-		team.LastUpdated = time.Now()
-		if updateErr := db.Teams.Update(team); updateErr != nil {
-			log.Errorf("failed to update team %d metadata after start: %v\n", team.ID, updateErr)
+		if team != nil {
+			if _, updateErr := db.UpdateTeam(team.ID, touchTeam); updateErr != nil {
+				log.Errorf("failed to update team %d metadata after start: %v\n", team.ID, updateErr)
+			}
 		}
 	}
 
@@ -232,17 +288,17 @@ func deleteExistingContainer(ctID int64) error {
 		return fmt.Errorf("proxmox API is not initialized")
 	}
 
-	var existing *proxmox.Container
+	var existing *proxmoxAPI.Guest
 	var err error
-	if existing, err = api.Container(int(ctID)); err != nil {
+	if existing, err = api.Guest(int(ctID)); err != nil {
 		if !errors.Is(err, proxmox.ErrNotFound) {
 			return fmt.Errorf("lookup existing container: %w", err)
 		}
 		return nil
 	}
 
-	_ = api.StopContainer(existing)
-	if err = api.DeleteContainer(existing); err != nil {
+	_ = api.StopGuest(existing)
+	if err = api.DeleteGuest(existing); err != nil {
 		return fmt.Errorf("delete existing container %d: %w", ctID, err)
 	}
 
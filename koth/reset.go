@@ -0,0 +1,101 @@
+package koth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+)
+
+// BaselineSnapshotName is the Proxmox snapshot taken once a container's setup scripts finish. Resets roll back to it.
+const BaselineSnapshotName = "koth-baseline"
+
+// takeBaselineSnapshot snapshots a freshly set up container. Failing to snapshot is not fatal: resets of the container
+// fall back to a full redeploy.
+func takeBaselineSnapshot(log ProgressLogger, guest *proxmoxAPI.Guest) {
+	if err := api.SnapshotGuest(guest, BaselineSnapshotName); err != nil {
+		log.Errorf("Failed to take baseline snapshot of container %d; resets will fall back to a full redeploy: %v\n", guest.ID(), err)
+		return
+	}
+
+	log.Statusf("Baseline snapshot taken for container %d.", guest.ID())
+}
+
+// ResetContainersWithLogger rolls the requested containers back to their baseline snapshot. Containers without one
+// are redeployed from scratch instead. The startAfter flag starts each container once it has been reset.
+func ResetContainersWithLogger(ids []int64, log ProgressLogger, startAfter, enableAdvancedLogging bool) error {
+	normalized := normalizeContainerIDs(ids)
+	if len(normalized) == 0 {
+		return fmt.Errorf("no container IDs supplied")
+	}
+
+	if api == nil {
+		return fmt.Errorf("proxmox API is not initialized")
+	}
+
+	var localLog = wrapLoggerSafe(log)
+	if localLog == nil {
+		localLog = wrapLoggerSafe(containerLog)
+	}
+
+	for _, id := range normalized {
+		localLog.Statusf("Resetting container %d...", id)
+		if err := resetContainer(localLog, id, startAfter, enableAdvancedLogging); err != nil {
+			return fmt.Errorf("container %d: %w", id, err)
+		}
+		localLog.Successf("Container %d reset successfully.", id)
+	}
+
+	return nil
+}
+
+func resetContainer(log ProgressLogger, id int64, startAfter, enableAdvancedLogging bool) (err error) {
+	var record *db.Container
+	if record, err = db.Containers.Select(id); err != nil {
+		return fmt.Errorf("lookup container: %w", err)
+	} else if record == nil {
+		return fmt.Errorf("container %d not found", id)
+	}
+
+	var ct *proxmoxAPI.Guest
+	if ct, err = api.Guest(int(record.PVEID)); err != nil {
+		log.Statusf("Container %d is missing from Proxmox; falling back to a full redeploy.", record.PVEID)
+		return redeployContainer(log, id, startAfter, enableAdvancedLogging)
+	}
+
+	var hasBaseline bool
+	if hasBaseline, err = api.HasSnapshot(ct, BaselineSnapshotName); err != nil {
+		return err
+	}
+
+	if !hasBaseline {
+		log.Statusf("Container %d has no baseline snapshot; falling back to a full redeploy.", record.PVEID)
+		return redeployContainer(log, id, startAfter, enableAdvancedLogging)
+	}
+
+	log.Statusf("Stopping container %d...", record.PVEID)
+	if err = api.StopGuest(ct); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+
+	log.Statusf("Rolling container %d back to its baseline snapshot...", record.PVEID)
+	if err = api.RollbackGuest(ct, BaselineSnapshotName); err != nil {
+		return err
+	}
+
+	record.Status = "stopped"
+	if startAfter {
+		if err = api.StartGuest(ct); err != nil {
+			return fmt.Errorf("failed to start container after reset: %w", err)
+		}
+		record.Status = "running"
+	}
+
+	record.LastUpdated = time.Now()
+	if updateErr := db.Containers.Update(record); updateErr != nil {
+		log.Errorf("failed to update container %d metadata: %v\n", record.PVEID, updateErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package koth
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
+)
+
+const (
+	DefaultScoringIntervalSeconds = 60
+	MinScoringIntervalSeconds     = 10
+
+	scheduleReconcileInterval = 15 * time.Second
+)
+
+// scoringWorker owns the scoring timer for a single competition.
+type scoringWorker struct {
+	reconfigure chan struct{}
+	stop        chan struct{}
+}
+
+var (
+	scoringWorkersLock sync.Mutex
+	scoringWorkers     = make(map[string]*scoringWorker)
+)
+
+// NormalizeScoringSchedule validates a competition's scoring interval and jitter, defaulting an unset interval.
+func NormalizeScoringSchedule(intervalSeconds, jitterSeconds int) (int, int, error) {
+	if intervalSeconds == 0 {
+		intervalSeconds = DefaultScoringIntervalSeconds
+	}
+
+	if intervalSeconds < MinScoringIntervalSeconds {
+		return 0, 0, fmt.Errorf("scoringIntervalSeconds must be at least %d", MinScoringIntervalSeconds)
+	}
+
+	if jitterSeconds < 0 {
+		return 0, 0, fmt.Errorf("scoringJitterSeconds must not be negative")
+	}
+
+	if jitterSeconds >= intervalSeconds {
+		return 0, 0, fmt.Errorf("scoringJitterSeconds (%d) must be smaller than scoringIntervalSeconds (%d)", jitterSeconds, intervalSeconds)
+	}
+
+	return intervalSeconds, jitterSeconds, nil
+}
+
+// ScoringDelay returns the wait before a competition's next scoring round: the interval shifted by a uniformly
+// random offset in [-jitter, +jitter]. roll must return a value in [0, n).
+func ScoringDelay(intervalSeconds, jitterSeconds int, roll func(n int) int) time.Duration {
+	intervalSeconds, jitterSeconds, err := NormalizeScoringSchedule(intervalSeconds, jitterSeconds)
+	if err != nil {
+		intervalSeconds, jitterSeconds = DefaultScoringIntervalSeconds, 0
+	}
+
+	var offset int
+	if jitterSeconds > 0 && roll != nil {
+		offset = roll(2*jitterSeconds+1) - jitterSeconds
+	}
+
+	return time.Duration(intervalSeconds+offset) * time.Second
+}
+
+// ReconfigureScoringSchedule makes a competition's scoring worker pick up its current interval and jitter
+// immediately instead of after the pending round.
+func ReconfigureScoringSchedule(systemID string) {
+	scoringWorkersLock.Lock()
+	worker := scoringWorkers[systemID]
+	scoringWorkersLock.Unlock()
+
+	if worker == nil {
+		go reconcileScoringWorkers()
+		return
+	}
+
+	select {
+	case worker.reconfigure <- struct{}{}:
+	default:
+	}
+}
+
+func scoringLoop() {
+	scoringLog.Basicf("scoring scheduler started (reconcile every %s)\n", scheduleReconcileInterval)
+	reconcileScoringWorkers()
+
+	ticker := time.NewTicker(scheduleReconcileInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		reconcileScoringWorkers()
+	}
+}
+
+// reconcileScoringWorkers starts a worker for every competition that lacks one and stops workers whose competition
+// has been torn down.
+func reconcileScoringWorkers() {
+	comps, err := db.Competitions.SelectAll()
+	if err != nil {
+		scoringLog.Errorf("failed to load competitions for scoring: %v\n", err)
+		return
+	}
+
+	scoringWorkersLock.Lock()
+	defer scoringWorkersLock.Unlock()
+
+	var seen = make(map[string]bool, len(comps))
+	for _, comp := range comps {
+		if comp == nil || comp.SystemID == "" {
+			continue
+		}
+
+		seen[comp.SystemID] = true
+		if scoringWorkers[comp.SystemID] != nil {
+			continue
+		}
+
+		worker := &scoringWorker{
+			reconfigure: make(chan struct{}, 1),
+			stop:        make(chan struct{}),
+		}
+		scoringWorkers[comp.SystemID] = worker
+		go runScoringWorker(comp.SystemID, worker)
+	}
+
+	for systemID, worker := range scoringWorkers {
+		if !seen[systemID] {
+			close(worker.stop)
+			delete(scoringWorkers, systemID)
+		}
+	}
+}
+
+// runScoringWorker scores one competition on its own interval until the competition disappears. Rounds for the
+// same competition never overlap because scoring runs on the worker goroutine.
+func runScoringWorker(systemID string, worker *scoringWorker) {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		comp, err := db.GetCompetitionBySystemID(systemID)
+		if err != nil {
+			scoringLog.Errorf("failed to load competition %s for scheduling: %v\n", systemID, err)
+		}
+
+		var delay = ScoringDelay(DefaultScoringIntervalSeconds, 0, nil)
+		if comp != nil {
+			delay = ScoringDelay(comp.ScoringIntervalSeconds, comp.ScoringJitterSeconds, rand.IntN)
+		}
+
+		timer.Reset(delay)
+
+		select {
+		case <-worker.stop:
+			return
+		case <-worker.reconfigure:
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+
+		if comp, err = db.GetCompetitionBySystemID(systemID); err != nil {
+			scoringLog.Errorf("failed to reload competition %s for scoring: %v\n", systemID, err)
+			continue
+		}
+
+		if comp == nil || !comp.ScoringActive {
+			continue
+		}
+
+		var (
+			started = time.Now()
+			before  = ConsoleStats()
+		)
+
+		if err = scoreCompetition(comp); err != nil {
+			scoringLog.Errorf("scoring failed for %s: %v\n", comp.SystemID, err)
+		}
+
+		var (
+			elapsed  = time.Since(started)
+			after    = ConsoleStats()
+			interval = ScoringDelay(comp.ScoringIntervalSeconds, 0, nil)
+		)
+
+		// Console counters are shared by every competition, so overlapping passes show up in each other's numbers.
+		scoringLog.Statusf("Scoring pass for %s took %s (consoles: %d reused, %d opened, %d ticket logins)\n", comp.SystemID, elapsed.Round(time.Millisecond),
+			after.SessionsReused-before.SessionsReused, after.SessionsOpened-before.SessionsOpened, after.TicketLogins-before.TicketLogins)
+		if elapsed > interval {
+			scoringLog.Errorf("scoring pass for %s took %s, longer than its %s interval\n", comp.SystemID, elapsed.Round(time.Second), interval)
+		}
+	}
+}
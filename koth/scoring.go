@@ -3,10 +3,12 @@ package koth
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -14,13 +16,10 @@ import (
 	"github.com/UNHCSC/pve-koth/config"
 	"github.com/UNHCSC/pve-koth/db"
 	"github.com/UNHCSC/pve-koth/proxmoxAPI"
-	"github.com/UNHCSC/pve-koth/ssh"
 	"github.com/z46-dev/go-logger"
 	"github.com/z46-dev/gomysql"
 )
 
-const scoringInterval = time.Minute
-
 var (
 	scoringLog      *logger.Logger = logger.NewLogger().SetPrefix("[SCORE]", logger.BoldYellow).IncludeTimestamp()
 	scoringLoopOnce sync.Once
@@ -30,6 +29,7 @@ type containerScoreResult struct {
 	Name   string
 	Order  int
 	Checks []checkScoreResult
+	Issues []scoringIssue
 }
 
 type checkScoreResult struct {
@@ -38,7 +38,11 @@ type checkScoreResult struct {
 	Order      int
 	PassPoints int
 	FailPoints int
-	Passed     bool
+	State      string // db.CheckStatePass, db.CheckStateFail or db.CheckStateUnknown
+	LastKnown  string // Latest pass or fail, carried through unknown results
+	Detail     string // Why the check is unknown
+	Source     string // Probe type or scoring script that reports the check; empty when no script is known to
+	Points     int    // Awarded under the competition's unknown check policy
 }
 
 func StartScoringLoop() {
@@ -47,45 +51,14 @@ func StartScoringLoop() {
 	})
 }
 
-func scoringLoop() {
-	scoringLog.Basicf("scoring loop started (interval %s)\n", scoringInterval)
-	runScoringPass()
-
-	ticker := time.NewTicker(scoringInterval)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		runScoringPass()
-	}
-}
-
-func runScoringPass() {
-	comps, err := db.Competitions.SelectAll()
-	if err != nil {
-		scoringLog.Errorf("failed to load competitions for scoring: %v\n", err)
-		return
-	}
-
-	if len(comps) == 0 {
-		return
-	}
-
-	var wg sync.WaitGroup
-	for _, comp := range comps {
-		if comp == nil || !comp.ScoringActive {
-			continue
-		}
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if err := scoreCompetition(comp); err != nil {
-				scoringLog.Errorf("scoring failed for %s: %v\n", comp.SystemID, err)
-			}
-		}()
+// ScoreCompetitionOnce runs a single scoring pass for the competition right away, outside its schedule. Competitions
+// with scoring turned off are skipped.
+func ScoreCompetitionOnce(comp *db.Competition) error {
+	if comp == nil {
+		return fmt.Errorf("competition is nil")
 	}
 
-	wg.Wait()
+	return scoreCompetition(comp)
 }
 
 func loadCompetitionDefinition(comp *db.Competition) (*db.CreateCompetitionRequest, error) {
@@ -143,7 +116,7 @@ func scoreCompetition(comp *db.Competition) (err error) {
 		return fmt.Errorf("%s: %w", logPrefix, err)
 	}
 
-	if len(req.TeamContainerConfigs) == 0 || len(comp.TeamIDs) == 0 {
+	if (len(req.TeamContainerConfigs) == 0 && len(req.SharedContainerConfigs) == 0) || len(comp.TeamIDs) == 0 {
 		return nil
 	}
 
@@ -155,9 +128,20 @@ func scoreCompetition(comp *db.Competition) (err error) {
 		return fmt.Errorf("%s network invalid: %w", logPrefix, err)
 	}
 
+	var compNet6 *net.IPNet
+	if compNet6, err = competitionSubnet6(comp); err != nil {
+		return fmt.Errorf("%s: %w", logPrefix, err)
+	}
+
 	publicFolderURL := competitionPublicFolderURL(comp)
 	artifactBaseURL := buildCompetitionArtifactBase(externalBaseURL(), comp.SystemID)
 
+	round, roundErr := nextScoringRound(comp)
+	if roundErr != nil {
+		return fmt.Errorf("%s: advance scoring round: %w", logPrefix, roundErr)
+	}
+	roundTime := time.Now()
+
 	var wg sync.WaitGroup
 	for idx, teamID := range comp.TeamIDs {
 		wg.Add(1)
@@ -173,7 +157,7 @@ func scoreCompetition(comp *db.Competition) (err error) {
 				return
 			}
 
-			network, netErr := buildTeamNetwork(compNet, teamIndex, req.TeamContainerConfigs)
+			network, netErr := buildTeamNetwork(compNet, compNet6, teamIndex, req.TeamContainerConfigs, req.SharedContainerConfigs)
 			if netErr != nil {
 				scoringLog.Errorf("failed to build network for %s team %d: %v\n", comp.SystemID, team.ID, netErr)
 				return
@@ -185,10 +169,14 @@ func scoreCompetition(comp *db.Competition) (err error) {
 			}
 
 			persistScoreResults(team.ID, containerResults)
-
-			team.Score += teamScore
-			team.LastUpdated = time.Now()
-			if dbErr := db.Teams.Update(team); dbErr != nil {
+			persistScoreHistory(comp.SystemID, round, roundTime, team.ID, containerResults)
+			persistScoringIssues(comp.SystemID, round, roundTime, team.ID, containerResults)
+
+			if _, dbErr := db.UpdateTeam(team.ID, func(current *db.Team) bool {
+				current.Score += teamScore
+				current.LastUpdated = time.Now()
+				return true
+			}); dbErr != nil {
 				scoringLog.Errorf("failed to update team %d: %v\n", team.ID, dbErr)
 			}
 		}(idx, teamID)
@@ -196,13 +184,24 @@ func scoreCompetition(comp *db.Competition) (err error) {
 
 	wg.Wait()
 
+	scoreOwnership(comp, req, round, roundTime)
+
 	return nil
 }
 
-func buildTeamNetwork(compSubnet *net.IPNet, teamIndex int, configs []db.TeamContainerConfig) (*teamNetwork, error) {
+func buildTeamNetwork(compSubnet, compSubnet6 *net.IPNet, teamIndex int, configs, sharedConfigs []db.TeamContainerConfig) (*teamNetwork, error) {
 	network := &teamNetwork{
-		ipsByName: make(map[string]string),
-		ipOrder:   make([]string, 0),
+		ipsByName:  make(map[string]string),
+		ipOrder:    make([]string, 0),
+		ip6sByName: make(map[string]string),
+	}
+
+	if len(sharedConfigs) > 0 {
+		shared, err := buildSharedNetwork(compSubnet, compSubnet6, sharedConfigs)
+		if err != nil {
+			return nil, err
+		}
+		network.attachShared(shared)
 	}
 
 	teamSubnetBase, err := teamSubnetBaseIP(compSubnet, teamIndex)
@@ -216,9 +215,21 @@ func buildTeamNetwork(compSubnet *net.IPNet, teamIndex int, configs []db.TeamCon
 			return nil, hostErr
 		}
 
+		resolved, ifaceErr := resolveContainerInterfaces(compSubnet, teamIndex, cfg.Interfaces)
+		if ifaceErr != nil {
+			return nil, fmt.Errorf("container %s: %w", cfg.Name, ifaceErr)
+		}
+
 		sanitizedName := sanitizeContainerName(cfg.Name)
 		network.ipsByName[sanitizedName] = hostIP.String()
 		network.ipOrder = append(network.ipOrder, hostIP.String())
+		network.addInterfaces(sanitizedName, resolved)
+	}
+
+	if compSubnet6 != nil {
+		if err = network.addIPv6(compSubnet6, teamIndex, configs); err != nil {
+			return nil, err
+		}
 	}
 
 	return network, nil
@@ -230,10 +241,12 @@ func scoreTeam(comp *db.Competition, team *db.Team, teamIndex int, configs []db.
 	}
 
 	var (
-		total   int
-		wg      sync.WaitGroup
-		mu      sync.Mutex
-		results []containerScoreResult
+		total    int
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		results  []containerScoreResult
+		policy   = unknownCheckPolicy(req.UnknownCheckPolicy)
+		previous = previousCheckResults(team.ID)
 	)
 
 	for order, containerCfg := range configs {
@@ -255,13 +268,14 @@ func scoreTeam(comp *db.Competition, team *db.Team, teamIndex int, configs []db.
 			sanitizedName: sanitized,
 			order:         order,
 			ipAddress:     ipAddress,
+			transport:     execTransport(containerCfg.ExecTransport),
 			options: &proxmoxAPI.ContainerCreateOptions{
 				Hostname:     fmt.Sprintf("%s-team-%d-%s", comp.ContainerRestrictions.HostnamePrefix, teamIndex+1, containerCfg.Name),
 				RootPassword: templateSpec.RootPassword,
 			},
 		}
 
-		status, statusErr := containerStatusForTeam(team.ID, containerCfg.Name)
+		status, statusErr := containerStatusForTeam(comp, team.ID, containerCfg.Name)
 		if statusErr != nil {
 			scoringLog.Errorf("failed to fetch status for %s (%s): %v\n", plan.options.Hostname, containerCfg.Name, statusErr)
 		}
@@ -273,7 +287,7 @@ func scoreTeam(comp *db.Competition, team *db.Team, teamIndex int, configs []db.
 		wg.Add(1)
 		go func(cfg db.TeamContainerConfig, plan *containerPlan) {
 			defer wg.Done()
-			score, detail := scoreContainer(comp, plan, network, publicFolderURL, artifactBaseURL, cfg.ScoringScript, cfg.ScoringSchema)
+			score, detail := scoreContainer(comp, plan, network, publicFolderURL, artifactBaseURL, cfg, policy, previous)
 			mu.Lock()
 			total += score
 			results = append(results, detail)
@@ -285,8 +299,14 @@ func scoreTeam(comp *db.Competition, team *db.Team, teamIndex int, configs []db.
 	return total, results, nil
 }
 
-func scoreContainer(comp *db.Competition, plan *containerPlan, network *teamNetwork, publicFolderURL, artifactBaseURL string, scoringScripts []string, checks []db.ScoringCheck) (int, containerScoreResult) {
-	var result containerScoreResult
+// scoreContainer runs a container's checks. Checks the scorer could not evaluate end up unknown, with the reason
+// recorded as an issue, and are scored by policy instead of being charged FailPoints.
+func scoreContainer(comp *db.Competition, plan *containerPlan, network *teamNetwork, publicFolderURL, artifactBaseURL string, cfg db.TeamContainerConfig, policy string, previous map[string]previousCheck) (int, containerScoreResult) {
+	var (
+		result         containerScoreResult
+		scoringScripts = cfg.ScoringScript
+		checks         = cfg.ScoringSchema
+	)
 	if plan != nil {
 		result.Name = plan.name
 		result.Order = plan.order
@@ -299,6 +319,7 @@ func scoreContainer(comp *db.Competition, plan *containerPlan, network *teamNetw
 	var (
 		schemaIndex = make(map[string]int)
 		reported    = make(map[string]bool)
+		probeChecks = make(map[int]db.ScoringCheck)
 	)
 
 	for idx, check := range checks {
@@ -318,7 +339,12 @@ func scoreContainer(comp *db.Competition, plan *containerPlan, network *teamNetw
 			Order:      idx,
 			PassPoints: check.PassPoints,
 			FailPoints: check.FailPoints,
+			Source:     checkSource(check, scoringScripts, previous[plan.name+"/"+id]),
 		})
+
+		if isProbeCheck(check) {
+			probeChecks[schemaIndex[id]] = check
+		}
 	}
 
 	if len(result.Checks) == 0 {
@@ -338,38 +364,46 @@ func scoreContainer(comp *db.Competition, plan *containerPlan, network *teamNetw
 		defer RevokeAccessToken(token)
 	}
 
-	record, recErr := containerRecordForTeam(plan.team.ID, plan.name)
+	record, recErr := containerRecordForTeam(comp, plan.team.ID, plan.name)
 	if recErr != nil {
 		scoringLog.Errorf("failed to load container record for %s: %v\n", plan.options.Hostname, recErr)
-		return 0, result
+		result.Issues = append(result.Issues, scoringIssue{Source: "container record", Message: fmt.Sprintf("load container record: %v", recErr), container: true})
+		return finishContainerScore(&result, reported, probeChecks, policy, previous), result
 	}
 	if record == nil {
-		scoringLog.Statusf("Container %s not provisioned; treating checks as failed\n", plan.options.Hostname)
-		return 0, result
+		scoringLog.Statusf("Container %s not provisioned; treating checks as unknown\n", plan.options.Hostname)
+		result.Issues = append(result.Issues, scoringIssue{Source: "container record", Message: "container is not provisioned", container: true})
+		return finishContainerScore(&result, reported, probeChecks, policy, previous), result
 	}
 
-	ct, ctErr := api.Container(int(record.PVEID))
-	if ctErr != nil {
-		scoringLog.Errorf("failed to load container %s (CTID %d): %v\n", plan.options.Hostname, record.PVEID, ctErr)
-		return 0, result
+	for index, passed := range runProbeChecks(plan, network, probeChecks) {
+		reported[result.Checks[index].ID] = true
+		result.Checks[index].State = checkState(passed)
+	}
+
+	var execute scoringExecutor
+	if len(scoringScripts) > 0 {
+		runner, runnerErr := NormalizeScoringRunner(cfg.ScoringRunner)
+		if runnerErr != nil {
+			scoringLog.Errorf("invalid scoring runner for %s: %v\n", plan.options.Hostname, runnerErr)
+			result.Issues = append(result.Issues, scoringIssue{Source: "scoring runner", Message: runnerErr.Error(), scripts: true})
+		} else if execute, runnerErr = buildScoringExecutor(runner, comp, plan, record, envs, token, artifactBaseURL); runnerErr != nil {
+			scoringLog.Errorf("failed to prepare %s scoring runner for %s: %v\n", runner, plan.options.Hostname, runnerErr)
+			result.Issues = append(result.Issues, scoringIssue{Source: "scoring runner", Message: runnerErr.Error(), scripts: true})
+		}
 	}
 
 	for _, scriptPath := range scoringScripts {
 		scriptPath = strings.TrimSpace(scriptPath)
-		if scriptPath == "" {
+		if scriptPath == "" || execute == nil {
 			continue
 		}
 
-		scriptURL := buildArtifactFileURL(artifactBaseURL, scriptPath)
-		command := ssh.LoadAndRunScript(scriptURL, token, envs)
-
-		stdout, stderr, exitCode, execErr := api.RawExecuteWithRetries(ct, "root", plan.options.RootPassword, command, 2)
-		if execErr != nil {
-			scoringLog.Errorf("failed to execute scoring script %s on %s: %v\n", scriptPath, plan.options.Hostname, execErr)
-		} else if exitCode != 0 {
-			scoringLog.Errorf("scoring script %s exited %d on %s\nStdout:\n%s\nStderr:\n%s\n", scriptPath, exitCode, plan.options.Hostname, summarizeScriptOutput(stdout), summarizeScriptOutput(stderr))
-		} else if payload, parseErr := parseCheckPayload([]byte(stdout)); parseErr != nil {
-			scoringLog.Errorf("invalid scoring payload from %s (%s): %v\nStdout:\n%s\nStderr:\n%s\n", plan.options.Hostname, scriptPath, parseErr, summarizeScriptOutput(stdout), summarizeScriptOutput(stderr))
+		payload, scriptErr := runScoringScript(execute, plan.options.Hostname, scriptPath, policy)
+		if scriptErr != nil {
+			if !errors.Is(scriptErr, proxmoxAPI.ErrGuestCredentials) {
+				result.Issues = append(result.Issues, scoringIssue{Source: scriptPath, Message: scriptErr.Error()})
+			}
 		} else {
 			for rawID, passed := range payload {
 				id := strings.TrimSpace(rawID)
@@ -381,26 +415,51 @@ func scoreContainer(comp *db.Competition, plan *containerPlan, network *teamNetw
 					scoringLog.Statusf("scoring script %s reported unknown check %s on %s; ignoring\n", scriptPath, id, plan.options.Hostname)
 					continue
 				}
+				if _, isProbe := probeChecks[index]; isProbe {
+					scoringLog.Statusf("scoring script %s reported built-in check %s on %s; ignoring\n", scriptPath, id, plan.options.Hostname)
+					continue
+				}
 				if reported[id] {
 					scoringLog.Statusf("scoring script %s reported duplicate result for check %s on %s; keeping first result\n", scriptPath, id, plan.options.Hostname)
 					continue
 				}
 				reported[id] = true
-				result.Checks[index].Passed = passed
+				result.Checks[index].State = checkState(passed)
+				result.Checks[index].Source = scriptPath
 			}
 		}
 	}
 
-	var total int
-	for _, check := range result.Checks {
-		if reported[check.ID] && check.Passed {
-			total += check.PassPoints
-		} else {
-			total += check.FailPoints
-		}
+	return finishContainerScore(&result, reported, probeChecks, policy, previous), result
+}
+
+// runProbeChecks executes built-in probe checks in parallel, keyed by their index in the container's results.
+func runProbeChecks(plan *containerPlan, network *teamNetwork, checks map[int]db.ScoringCheck) map[int]bool {
+	var (
+		results = make(map[int]bool, len(checks))
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+	)
+
+	for index, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			target := resolveProbeTarget(check.Probe, plan, network)
+			passed, err := RunProbe(check, target)
+			if err != nil {
+				scoringLog.Statusf("%s check %s failed against %s for %s: %v\n", normalizeCheckType(check.Type), check.ID, target, plan.options.Hostname, err)
+			}
+
+			mu.Lock()
+			results[index] = passed
+			mu.Unlock()
+		}()
 	}
 
-	return total, result
+	wg.Wait()
+	return results
 }
 
 func persistScoreResults(teamID int64, containers []containerScoreResult) {
@@ -425,7 +484,11 @@ func persistScoreResults(teamID int64, containers []containerScoreResult) {
 				CheckOrder:     check.Order,
 				PassPoints:     check.PassPoints,
 				FailPoints:     check.FailPoints,
-				Passed:         check.Passed,
+				Passed:         check.State == db.CheckStatePass,
+				State:          check.State,
+				LastKnownState: check.LastKnown,
+				Detail:         check.Detail,
+				Source:         check.Source,
 				UpdatedAt:      timestamp,
 			}
 
@@ -436,6 +499,47 @@ func persistScoreResults(teamID int64, containers []containerScoreResult) {
 	}
 }
 
+// nextScoringRound increments and persists the competition's round counter, returning the new round number.
+func nextScoringRound(comp *db.Competition) (int64, error) {
+	current, err := db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+		current.ScoringRound++
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if current == nil {
+		return 0, fmt.Errorf("competition %s no longer exists", comp.SystemID)
+	}
+
+	comp.ScoringRound = current.ScoringRound
+	return current.ScoringRound, nil
+}
+
+func persistScoreHistory(compID string, round int64, timestamp time.Time, teamID int64, containers []containerScoreResult) {
+	for _, container := range containers {
+		for _, check := range container.Checks {
+			entry := &db.ScoreHistoryEntry{
+				Round:          round,
+				CompetitionID:  compID,
+				TeamID:         teamID,
+				ContainerName:  container.Name,
+				CheckID:        check.ID,
+				CheckName:      check.Name,
+				Passed:         check.State == db.CheckStatePass,
+				State:          check.State,
+				PointsAwarded:  check.Points,
+				RecordedAt:     timestamp,
+				RecordedAtUnix: timestamp.Unix(),
+			}
+
+			if err := db.ScoreHistory.Insert(entry); err != nil {
+				scoringLog.Errorf("failed to persist score history for team %d: %v\n", teamID, err)
+			}
+		}
+	}
+}
+
 func parseCheckPayload(raw []byte) (map[string]bool, error) {
 	raw = bytes.TrimSpace(raw)
 	if len(raw) == 0 {
@@ -465,25 +569,20 @@ func parseCheckPayload(raw []byte) (map[string]bool, error) {
 	return nil, fmt.Errorf("payload missing boolean check data")
 }
 
-func containerStatusForTeam(teamID int64, configName string) (string, error) {
-	filter := gomysql.NewFilter().
-		KeyCmp(db.Containers.FieldBySQLName("team_id"), gomysql.OpEqual, teamID).
-		And().
-		KeyCmp(db.Containers.FieldBySQLName("container_config_name"), gomysql.OpEqual, strings.TrimSpace(configName))
-
-	results, err := db.Containers.SelectAllWithFilter(filter)
-	if err != nil {
+// containerStatusForTeam returns the recorded status of comp's container for configName, or "" when it has none.
+func containerStatusForTeam(comp *db.Competition, teamID int64, configName string) (string, error) {
+	record, err := containerRecordForTeam(comp, teamID, configName)
+	if err != nil || record == nil {
 		return "", err
 	}
 
-	if len(results) == 0 {
-		return "", nil
-	}
-
-	return strings.TrimSpace(results[0].Status), nil
+	return strings.TrimSpace(record.Status), nil
 }
 
-func containerRecordForTeam(teamID int64, configName string) (*db.Container, error) {
+// containerRecordForTeam returns comp's container record for configName. Shared containers all have team ID 0, so
+// only records listed in comp.ContainerIDs are considered, keeping competitions with the same shared config names
+// apart.
+func containerRecordForTeam(comp *db.Competition, teamID int64, configName string) (*db.Container, error) {
 	filter := gomysql.NewFilter().
 		KeyCmp(db.Containers.FieldBySQLName("team_id"), gomysql.OpEqual, teamID).
 		And().
@@ -493,8 +592,12 @@ func containerRecordForTeam(teamID int64, configName string) (*db.Container, err
 	if err != nil {
 		return nil, err
 	}
-	if len(results) == 0 {
-		return nil, nil
+
+	for _, record := range results {
+		if record != nil && slices.Contains(comp.ContainerIDs, record.PVEID) {
+			return record, nil
+		}
 	}
-	return results[0], nil
+
+	return nil, nil
 }
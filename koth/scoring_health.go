@@ -0,0 +1,294 @@
+package koth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/z46-dev/gomysql"
+)
+
+// Unknown check policies decide how checks the scorer could not evaluate, because of an exec error, a failing or
+// garbled scoring script or a missing container record, are scored.
+const (
+	UnknownCheckNone  = "none"  // award no points either way
+	UnknownCheckCarry = "carry" // award the points of the check's last pass or fail result
+	UnknownCheckRetry = "retry" // rerun the failing script once, then award no points
+)
+
+const scriptRetryDelay = 3 * time.Second
+
+// scoringIssue is a scorer infrastructure error that left some of a container's checks unknown. A script's issue only
+// covers the checks that script reports, plus any check no script is known to report.
+type scoringIssue struct {
+	Source        string // The scoring script, or the step that failed before scripts could run
+	Message       string
+	UnknownChecks int
+	container     bool // Nothing, probes included, could be evaluated
+	scripts       bool // No scoring script could run
+}
+
+// previousCheck is what the last scoring pass recorded for a check.
+type previousCheck struct {
+	LastKnown string // Latest pass or fail result
+	Source    string // Probe type or scoring script that reported it
+}
+
+// NormalizeUnknownCheckPolicy validates an unknownCheckPolicy value from config.json, defaulting to no points.
+func NormalizeUnknownCheckPolicy(raw string) (string, error) {
+	switch policy := strings.ToLower(strings.TrimSpace(raw)); policy {
+	case "", UnknownCheckNone:
+		return UnknownCheckNone, nil
+	case UnknownCheckCarry, UnknownCheckRetry:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown unknownCheckPolicy %q (expected %q, %q or %q)", raw, UnknownCheckNone, UnknownCheckCarry, UnknownCheckRetry)
+	}
+}
+
+// unknownCheckPolicy normalizes a competition's policy, falling back to no points for values that slipped past
+// validation.
+func unknownCheckPolicy(raw string) string {
+	if policy, err := NormalizeUnknownCheckPolicy(raw); err == nil {
+		return policy
+	}
+
+	return UnknownCheckNone
+}
+
+// UnknownCheckPolicyFor returns the unknown check policy a competition's package configures.
+func UnknownCheckPolicyFor(comp *db.Competition) (string, error) {
+	req, err := loadCompetitionDefinition(comp)
+	if err != nil {
+		return "", err
+	}
+
+	return unknownCheckPolicy(req.UnknownCheckPolicy), nil
+}
+
+func checkState(passed bool) string {
+	if passed {
+		return db.CheckStatePass
+	}
+
+	return db.CheckStateFail
+}
+
+// runScoringScript runs one scoring script and parses its payload. Exec errors, non-zero exits and unparseable output
+// are scorer problems rather than team failures; under the retry policy the script gets a second attempt before one
+// is reported. Rejected guest credentials are returned as is, since the team changed them.
+func runScoringScript(execute scoringExecutor, hostname, scriptPath, policy string) (payload map[string]bool, err error) {
+	for attempt := 0; ; attempt++ {
+		stdout, stderr, exitCode, execErr := execute(scriptPath)
+		switch {
+		case execErr != nil:
+			scoringLog.Errorf("failed to execute scoring script %s on %s: %v\n", scriptPath, hostname, execErr)
+			if errors.Is(execErr, proxmoxAPI.ErrGuestCredentials) {
+				return nil, execErr
+			}
+			err = fmt.Errorf("exec failed: %w", execErr)
+		case exitCode != 0:
+			scoringLog.Errorf("scoring script %s exited %d on %s\nStdout:\n%s\nStderr:\n%s\n", scriptPath, exitCode, hostname, summarizeScriptOutput(stdout), summarizeScriptOutput(stderr))
+			err = fmt.Errorf("exited with code %d: %s", exitCode, summarizeScriptOutput(stderr))
+		default:
+			if payload, err = parseCheckPayload([]byte(stdout)); err == nil {
+				return payload, nil
+			}
+			scoringLog.Errorf("invalid scoring payload from %s (%s): %v\nStdout:\n%s\nStderr:\n%s\n", hostname, scriptPath, err, summarizeScriptOutput(stdout), summarizeScriptOutput(stderr))
+			err = fmt.Errorf("invalid payload: %w", err)
+		}
+
+		if policy != UnknownCheckRetry || attempt > 0 {
+			return nil, err
+		}
+
+		scoringLog.Statusf("Retrying scoring script %s on %s\n", scriptPath, hostname)
+		time.Sleep(scriptRetryDelay)
+	}
+}
+
+// ValidateCheckScript verifies that the script a check names, if any, is one of its container's scoring scripts.
+func ValidateCheckScript(check db.ScoringCheck, scripts []string) error {
+	var script = strings.TrimSpace(check.Script)
+	if script == "" {
+		return nil
+	}
+
+	if isProbeCheck(check) {
+		return fmt.Errorf("check %s is a %s probe and cannot name a script", check.ID, normalizeCheckType(check.Type))
+	}
+
+	for _, path := range scripts {
+		if strings.TrimSpace(path) == script {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("check %s names script %q, which is not one of the container's scoringScript entries", check.ID, script)
+}
+
+// checkSource returns who reports a check: its probe type, the script the schema names, the container's only script,
+// or whichever script reported it last pass. It is empty when none of those is known.
+func checkSource(check db.ScoringCheck, scripts []string, previous previousCheck) string {
+	if isProbeCheck(check) {
+		return normalizeCheckType(check.Type)
+	}
+
+	if script := strings.TrimSpace(check.Script); script != "" {
+		return script
+	}
+
+	var only string
+	for _, script := range scripts {
+		if script = strings.TrimSpace(script); script == "" {
+			continue
+		} else if only != "" {
+			return previous.Source
+		}
+		only = script
+	}
+
+	return only
+}
+
+// covers reports whether an issue kept check from being evaluated.
+func (issue scoringIssue) covers(check checkScoreResult, probe bool) bool {
+	switch {
+	case issue.container:
+		return true
+	case probe:
+		return false
+	case issue.scripts:
+		return true
+	default:
+		return check.Source == "" || check.Source == issue.Source
+	}
+}
+
+// finishContainerScore settles every check that no probe or script reported and totals the container's points. A
+// check is unknown when an issue kept the scorer from running its script, and fails otherwise, since a script that
+// ran cleanly and left it out did not pass it.
+func finishContainerScore(result *containerScoreResult, reported map[string]bool, probes map[int]db.ScoringCheck, policy string, previous map[string]previousCheck) (total int) {
+	for index := range result.Checks {
+		check := &result.Checks[index]
+		if !reported[check.ID] {
+			var (
+				_, probe = probes[index]
+				detail   []string
+			)
+
+			for issueIndex, issue := range result.Issues {
+				if issue.covers(*check, probe) {
+					detail = append(detail, fmt.Sprintf("%s: %s", issue.Source, issue.Message))
+					result.Issues[issueIndex].UnknownChecks++
+				}
+			}
+
+			if len(detail) > 0 {
+				check.State = db.CheckStateUnknown
+				check.Detail = strings.Join(detail, "; ")
+			} else {
+				check.State = db.CheckStateFail
+			}
+		}
+
+		check.LastKnown = check.State
+		if check.State == db.CheckStateUnknown {
+			check.LastKnown = previous[result.Name+"/"+check.ID].LastKnown
+		}
+
+		check.Points = checkPoints(*check, policy)
+		total += check.Points
+	}
+
+	return
+}
+
+// checkPoints returns the points a check earns this round under policy.
+func checkPoints(check checkScoreResult, policy string) int {
+	state := check.State
+	if state == db.CheckStateUnknown && policy == UnknownCheckCarry {
+		state = check.LastKnown
+	}
+
+	switch state {
+	case db.CheckStatePass:
+		return check.PassPoints
+	case db.CheckStateFail:
+		return check.FailPoints
+	default:
+		return 0
+	}
+}
+
+// previousCheckResults returns the latest pass or fail result of each of a team's checks and who reported it, keyed by
+// container name and check ID, for carrying results and script ownership through unknown results.
+func previousCheckResults(teamID int64) map[string]previousCheck {
+	states := make(map[string]previousCheck)
+
+	filter := gomysql.NewFilter().KeyCmp(db.ScoreResults.FieldBySQLName("team_id"), gomysql.OpEqual, teamID)
+	results, err := db.ScoreResults.SelectAllWithFilter(filter)
+	if err != nil {
+		scoringLog.Errorf("failed to load previous score results for team %d: %v\n", teamID, err)
+		return states
+	}
+
+	for _, result := range results {
+		state := result.LastKnownState
+		if result.State == "" {
+			// Results from before check states were recorded.
+			state = checkState(result.Passed)
+		}
+
+		states[result.ContainerName+"/"+result.CheckID] = previousCheck{LastKnown: state, Source: result.Source}
+	}
+
+	return states
+}
+
+func persistScoringIssues(compID string, round int64, timestamp time.Time, teamID int64, containers []containerScoreResult) {
+	for _, container := range containers {
+		for _, issue := range container.Issues {
+			entry := &db.ScoringIssue{
+				CompetitionID:  compID,
+				Round:          round,
+				TeamID:         teamID,
+				ContainerName:  container.Name,
+				Source:         issue.Source,
+				Message:        issue.Message,
+				UnknownChecks:  issue.UnknownChecks,
+				RecordedAt:     timestamp,
+				RecordedAtUnix: timestamp.Unix(),
+			}
+
+			if err := db.ScoringIssues.Insert(entry); err != nil {
+				scoringLog.Errorf("failed to persist scoring issue for team %d: %v\n", teamID, err)
+			}
+		}
+	}
+}
+
+func purgeScoringIssues(comp *db.Competition, log ProgressLogger) error {
+	if comp.SystemID == "" {
+		return nil
+	}
+
+	filter := gomysql.NewFilter().KeyCmp(db.ScoringIssues.FieldBySQLName("competition_id"), gomysql.OpEqual, comp.SystemID)
+	entries, err := db.ScoringIssues.SelectAllWithFilter(filter)
+	if err != nil {
+		log.Errorf("Failed to load scoring issues for %s: %v\n", comp.SystemID, err)
+		return err
+	}
+
+	var combined error
+	for _, entry := range entries {
+		if err := db.ScoringIssues.Delete(entry.ID); err != nil {
+			log.Errorf("Failed to delete scoring issue %d: %v\n", entry.ID, err)
+			combined = errors.Join(combined, err)
+		}
+	}
+	return combined
+}
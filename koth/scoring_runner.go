@@ -0,0 +1,154 @@
+package koth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+)
+
+// Scoring runners select where a container's scoring scripts execute.
+const (
+	ScoringRunnerContainer = "container" // inside the scored container (legacy behaviour)
+	ScoringRunnerScorer    = "scorer"    // inside the admin-owned scorer container from config.toml
+	ScoringRunnerHost      = "host"      // on the koth host itself
+)
+
+// hostScriptWaitDelay is how long a host scoring script's output pipes may stay open after the script exits or times
+// out, e.g. held by a child it left running in the background.
+const hostScriptWaitDelay = 2 * time.Second
+
+// scoringExecutor runs a single scoring script and returns its raw output.
+type scoringExecutor func(scriptPath string) (stdout, stderr string, exitCode int, err error)
+
+// NormalizeScoringRunner validates a scoringRunner value from config.json, defaulting to the container runner.
+func NormalizeScoringRunner(raw string) (string, error) {
+	switch runner := strings.ToLower(strings.TrimSpace(raw)); runner {
+	case "", ScoringRunnerContainer:
+		return ScoringRunnerContainer, nil
+	case ScoringRunnerScorer:
+		if config.Config.Scoring.ScorerContainerID <= 0 {
+			return "", fmt.Errorf("scoringRunner %q requires scoring.scorer_container_id in config.toml", runner)
+		}
+		return runner, nil
+	case ScoringRunnerHost:
+		return runner, nil
+	default:
+		return "", fmt.Errorf("unknown scoringRunner %q (expected %q, %q or %q)", raw, ScoringRunnerContainer, ScoringRunnerScorer, ScoringRunnerHost)
+	}
+}
+
+// buildScoringExecutor resolves the execution target for a container's scoring scripts.
+func buildScoringExecutor(runner string, comp *db.Competition, plan *containerPlan, record *db.Container, envs map[string]any, token, artifactBaseURL string) (scoringExecutor, error) {
+	var remote = func(guest *proxmoxAPI.Guest, username, password string) scoringExecutor {
+		return func(scriptPath string) (string, string, int, error) {
+			command := scriptCommand(guest, buildArtifactFileURL(artifactBaseURL, scriptPath), token, envs)
+			return api.ExecuteGuestWithRetries(guest, username, password, command, 2)
+		}
+	}
+
+	switch runner {
+	case ScoringRunnerHost:
+		return func(scriptPath string) (string, string, int, error) {
+			return runHostScoringScript(comp, scriptPath, envs)
+		}, nil
+	case ScoringRunnerScorer:
+		ct, err := api.Guest(config.Config.Scoring.ScorerContainerID)
+		if err != nil {
+			return nil, fmt.Errorf("load scorer container %d: %w", config.Config.Scoring.ScorerContainerID, err)
+		}
+		return remote(ct, config.Config.Scoring.ScorerUsername, config.Config.Scoring.ScorerPassword), nil
+	default:
+		ct, err := api.Guest(int(record.PVEID))
+		if err != nil {
+			return nil, fmt.Errorf("load container %s (CTID %d): %w", plan.options.Hostname, record.PVEID, err)
+		}
+		return func(scriptPath string) (string, string, int, error) {
+			command := scriptCommand(ct, buildArtifactFileURL(artifactBaseURL, scriptPath), token, envs)
+			return execInGuest(api, comp, plan, ct, "root", plan.options.RootPassword, command, nil)
+		}, nil
+	}
+}
+
+// runHostScoringScript executes a packaged scoring script on the koth host, feeding it the same environment
+// variables a container-side run would receive and nothing else from koth's environment.
+func runHostScoringScript(comp *db.Competition, scriptPath string, envs map[string]any) (stdout, stderr string, exitCode int, err error) {
+	var relative = sanitizeRelativePath(scriptPath)
+	if relative == "" || comp.PackageStoragePath == "" {
+		return "", "", -1, fmt.Errorf("scoring script %q cannot be resolved", scriptPath)
+	}
+
+	var script []byte
+	if script, err = os.ReadFile(filepath.Join(comp.PackageStoragePath, relative)); err != nil {
+		return "", "", -1, fmt.Errorf("read scoring script: %w", err)
+	}
+
+	var shell = strings.TrimSpace(config.Config.Scoring.HostShell)
+	if shell == "" {
+		shell = "bash"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Config.Scoring.HostTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	var (
+		cmd                  = exec.CommandContext(ctx, shell, "-s", "--")
+		stdoutBuf, stderrBuf bytes.Buffer
+	)
+
+	cmd.Stdin = bytes.NewReader(script)
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+	cmd.Env = hostScriptEnv(envs)
+	cmd.WaitDelay = hostScriptWaitDelay
+
+	err = cmd.Run()
+	stdout, stderr = stdoutBuf.String(), stderrBuf.String()
+
+	// The script exited cleanly, but a background child kept its output open past the wait delay.
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil
+	} else if ctx.Err() != nil {
+		return stdout, stderr, -1, fmt.Errorf("scoring script timed out after %ds", config.Config.Scoring.HostTimeoutSeconds)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout, stderr, exitErr.ExitCode(), nil
+	}
+
+	if err != nil {
+		return stdout, stderr, -1, err
+	}
+
+	return stdout, stderr, 0, nil
+}
+
+// hostScriptEnv is the whole environment of a host scoring script: the koth server's PATH and HOME and the script's
+// KOTH_* variables. Nothing else from koth's own environment, such as credentials, reaches competition code.
+func hostScriptEnv(envs map[string]any) []string {
+	var path = os.Getenv("PATH")
+	if path == "" {
+		path = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	}
+
+	var env = []string{"PATH=" + path}
+	if home := os.Getenv("HOME"); home != "" {
+		env = append(env, "HOME="+home)
+	}
+
+	for key, value := range envs {
+		env = append(env, fmt.Sprintf("%s=%v", key, value))
+	}
+
+	return env
+}
@@ -0,0 +1,186 @@
+package koth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
+)
+
+// ErrSelfRedeployDenied is wrapped by every reason a team redeploy request is refused.
+var ErrSelfRedeployDenied = errors.New("redeploy not allowed")
+
+// ErrContainerRedeploying is returned when a redeploy is requested for a container that is already being redeployed.
+var ErrContainerRedeploying = errors.New("container is already being redeployed")
+
+var selfRedeployMu sync.Mutex
+
+// SelfRedeployStatus summarizes what a team may still do under its competition's self-service redeploy policy.
+type SelfRedeployStatus struct {
+	Enabled                  bool `json:"enabled"`
+	Used                     int  `json:"used"`
+	Remaining                int  `json:"remaining"` // -1 when unlimited
+	CooldownRemainingSeconds int  `json:"cooldownRemainingSeconds"`
+	PenaltyPoints            int  `json:"penaltyPoints"`
+}
+
+// ValidateSelfRedeployPolicy checks the selfRedeploy block of config.json.
+func ValidateSelfRedeployPolicy(policy db.SelfRedeployPolicy) error {
+	if policy.CooldownSeconds < 0 {
+		return fmt.Errorf("selfRedeploy.cooldownSeconds must not be negative")
+	}
+
+	if policy.MaxPerTeam < 0 {
+		return fmt.Errorf("selfRedeploy.maxPerTeam must not be negative")
+	}
+
+	if policy.PenaltyPoints < 0 {
+		return fmt.Errorf("selfRedeploy.penaltyPoints must not be negative (it is deducted)")
+	}
+
+	return nil
+}
+
+// EvaluateSelfRedeploy applies a policy to a team's previous redeploys.
+func EvaluateSelfRedeploy(policy db.SelfRedeployPolicy, history []*db.TeamRedeploy, now time.Time) SelfRedeployStatus {
+	status := SelfRedeployStatus{
+		Enabled:       policy.Enabled,
+		Used:          len(history),
+		Remaining:     -1,
+		PenaltyPoints: policy.PenaltyPoints,
+	}
+
+	if policy.MaxPerTeam > 0 {
+		status.Remaining = max(policy.MaxPerTeam-status.Used, 0)
+	}
+
+	if policy.CooldownSeconds > 0 && len(history) > 0 {
+		var latest time.Time
+		for _, record := range history {
+			if record != nil && record.RequestedAt.After(latest) {
+				latest = record.RequestedAt
+			}
+		}
+
+		if wait := latest.Add(time.Duration(policy.CooldownSeconds) * time.Second).Sub(now); wait > 0 {
+			status.CooldownRemainingSeconds = int((wait + time.Second - 1) / time.Second)
+		}
+	}
+
+	return status
+}
+
+// SelfRedeployStatusFor returns the team's current self-service redeploy allowance.
+func SelfRedeployStatusFor(comp *db.Competition, teamID int64, now time.Time) (SelfRedeployStatus, error) {
+	if comp == nil {
+		return SelfRedeployStatus{}, fmt.Errorf("competition is nil")
+	}
+
+	history, err := db.GetTeamRedeploys(teamID)
+	if err != nil {
+		return SelfRedeployStatus{}, err
+	}
+
+	return EvaluateSelfRedeploy(comp.SelfRedeploy, history, now), nil
+}
+
+// ClaimSelfRedeploy checks the policy for a team's redeploy request and, when allowed, marks the container as
+// redeploying, records the request and deducts the penalty as a score adjustment. Checks and bookkeeping happen under
+// one lock so concurrent requests cannot both slip past the cooldown or limit, or start two redeploys of one
+// container.
+func ClaimSelfRedeploy(comp *db.Competition, team *db.Team, containerID int64, requester string, now time.Time) (*db.TeamRedeploy, error) {
+	if comp == nil || team == nil {
+		return nil, fmt.Errorf("competition and team are required")
+	}
+
+	selfRedeployMu.Lock()
+	defer selfRedeployMu.Unlock()
+
+	container, err := db.Containers.Select(containerID)
+	if err != nil {
+		return nil, err
+	}
+	if container == nil {
+		return nil, fmt.Errorf("container %d not found", containerID)
+	}
+	if strings.EqualFold(strings.TrimSpace(container.Status), "redeploying") {
+		return nil, ErrContainerRedeploying
+	}
+
+	status, err := SelfRedeployStatusFor(comp, team.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !status.Enabled:
+		return nil, fmt.Errorf("%w: self-service redeploys are disabled for %s", ErrSelfRedeployDenied, comp.Name)
+	case status.Remaining == 0:
+		return nil, fmt.Errorf("%w: %s has used all %d redeploys", ErrSelfRedeployDenied, team.Name, comp.SelfRedeploy.MaxPerTeam)
+	case status.CooldownRemainingSeconds > 0:
+		return nil, fmt.Errorf("%w: %s must wait %ds before redeploying again", ErrSelfRedeployDenied, team.Name, status.CooldownRemainingSeconds)
+	}
+
+	record := &db.TeamRedeploy{
+		CompetitionID:   comp.SystemID,
+		TeamID:          team.ID,
+		ContainerID:     containerID,
+		Requester:       requester,
+		PenaltyPoints:   comp.SelfRedeploy.PenaltyPoints,
+		RequestedAt:     now,
+		RequestedAtUnix: now.Unix(),
+	}
+	var previous = *container
+	container.Status = "redeploying"
+	container.LastUpdated = now
+	if err = db.Containers.Update(container); err != nil {
+		return nil, err
+	}
+
+	if err = db.TeamRedeploys.Insert(record); err != nil {
+		if restoreErr := db.Containers.Update(&previous); restoreErr != nil {
+			err = errors.Join(err, restoreErr)
+		}
+		return nil, err
+	}
+
+	if comp.SelfRedeploy.PenaltyPoints > 0 {
+		if err = AdjustTeamScore(comp, team, -comp.SelfRedeploy.PenaltyPoints, fmt.Sprintf("Redeploy of CT %d", containerID), requester); err != nil {
+			return record, err
+		}
+	}
+
+	return record, nil
+}
+
+// AdjustTeamScore changes a team's score outside of a scoring round and records the change as an adjustment.
+func AdjustTeamScore(comp *db.Competition, team *db.Team, points int, reason, actor string) error {
+	if comp == nil || team == nil {
+		return fmt.Errorf("competition and team are required")
+	}
+
+	updated, err := db.UpdateTeam(team.ID, func(current *db.Team) bool {
+		current.Score += points
+		current.LastUpdated = time.Now().UTC()
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if updated == nil {
+		return fmt.Errorf("team %d not found", team.ID)
+	}
+	*team = *updated
+
+	return db.ScoreAdjustments.Insert(&db.ScoreAdjustment{
+		CompetitionID: comp.SystemID,
+		TeamID:        team.ID,
+		Points:        points,
+		Reason:        reason,
+		Actor:         actor,
+		CreatedAt:     team.LastUpdated,
+	})
+}
@@ -0,0 +1,185 @@
+package koth
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+)
+
+// sharedSubnetBaseIP returns the base address of the reserved subnet that hosts shared competition containers.
+// Team subnets start at index 1 of the competition block, so the first team-sized block is always free.
+func sharedSubnetBaseIP(compSubnet *net.IPNet) (uint32, error) {
+	if compSubnet == nil {
+		return 0, fmt.Errorf("competition subnet is nil")
+	}
+
+	if teamCapacity(compSubnet) == 0 {
+		return 0, fmt.Errorf("competition subnet %s has no room for a shared /%d", compSubnet.String(), config.Config.Network.TeamSubnetPrefix)
+	}
+
+	return ipToUint32(compSubnet.IP), nil
+}
+
+// SharedSubnetCIDR returns the CIDR block reserved for a competition's shared containers.
+func SharedSubnetCIDR(comp *db.Competition) (string, error) {
+	if comp == nil {
+		return "", fmt.Errorf("competition is nil")
+	}
+
+	_, compNet, err := net.ParseCIDR(strings.TrimSpace(comp.NetworkCIDR))
+	if err != nil {
+		return "", fmt.Errorf("parse competition network: %w", err)
+	}
+
+	base, err := sharedSubnetBaseIP(compNet)
+	if err != nil {
+		return "", err
+	}
+
+	return buildSubnet(base, config.Config.Network.TeamSubnetPrefix).String(), nil
+}
+
+// buildSharedNetwork assigns addresses to the shared containers inside the reserved subnet.
+func buildSharedNetwork(compSubnet, compSubnet6 *net.IPNet, configs []db.TeamContainerConfig) (*teamNetwork, error) {
+	network := &teamNetwork{
+		ipsByName:       make(map[string]string),
+		ipOrder:         make([]string, 0),
+		sharedIPsByName: make(map[string]string),
+		sharedIPOrder:   make([]string, 0),
+		ip6sByName:      make(map[string]string),
+	}
+
+	if len(configs) == 0 {
+		return network, nil
+	}
+
+	base, err := sharedSubnetBaseIP(compSubnet)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cfg := range configs {
+		hostIP, hostErr := hostIPWithinSubnet(base, config.Config.Network.TeamSubnetPrefix, cfg.LastOctetValue)
+		if hostErr != nil {
+			return nil, fmt.Errorf("shared container %s: %w", cfg.Name, hostErr)
+		}
+
+		resolved, ifaceErr := resolveContainerInterfaces(compSubnet, -1, cfg.Interfaces)
+		if ifaceErr != nil {
+			return nil, fmt.Errorf("shared container %s: %w", cfg.Name, ifaceErr)
+		}
+
+		sanitizedName := sanitizeContainerName(cfg.Name)
+		network.ipsByName[sanitizedName] = hostIP.String()
+		network.ipOrder = append(network.ipOrder, hostIP.String())
+		network.addInterfaces(sanitizedName, resolved)
+	}
+
+	if compSubnet6 != nil {
+		if err = network.addIPv6(compSubnet6, -1, configs); err != nil {
+			return nil, err
+		}
+	}
+
+	network.attachShared(network)
+	return network, nil
+}
+
+// attachShared exposes the shared containers' addresses to scripts running with this network.
+func (n *teamNetwork) attachShared(shared *teamNetwork) {
+	if n == nil || shared == nil {
+		return
+	}
+
+	n.sharedIPsByName = make(map[string]string, len(shared.ipsByName))
+	for name, ip := range shared.ipsByName {
+		n.sharedIPsByName[name] = ip
+	}
+	n.sharedIPOrder = append([]string(nil), shared.ipOrder...)
+
+	n.sharedIP6sByName = make(map[string]string, len(shared.ip6sByName))
+	for name, ip := range shared.ip6sByName {
+		n.sharedIP6sByName[name] = ip
+	}
+	n.sharedIP6Order = append([]string(nil), shared.ip6Order...)
+
+	n.sharedIfaceIPs = make(map[string]map[string]string, len(shared.interfaceIPs))
+	for name, ips := range shared.interfaceIPs {
+		n.sharedIfaceIPs[name] = ips
+	}
+}
+
+// sharedContainerHostname returns the hostname used for a shared competition container.
+func sharedContainerHostname(comp *db.Competition, name string) string {
+	return fmt.Sprintf("%s-shared-%s", comp.ContainerRestrictions.HostnamePrefix, name)
+}
+
+// buildSharedContainerPlans prepares provisioning plans for the competition's shared containers.
+func buildSharedContainerPlans(comp *db.Competition, configs []db.TeamContainerConfig, network *teamNetwork, templateLookup map[string]db.ContainerSpecTemplate, publicKey string) ([]*containerPlan, error) {
+	var plans []*containerPlan
+	for order, cfg := range configs {
+		sanitizedName := sanitizeContainerName(cfg.Name)
+		ip, ip6 := network.ipsByName[sanitizedName], network.ip6sByName[sanitizedName]
+		if ip == "" {
+			return nil, fmt.Errorf("shared container %s has no address", cfg.Name)
+		}
+
+		templateSpec, err := ResolveContainerSpecTemplate(templateLookup, cfg.ContainerSpecsTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("resolve template for shared container %s: %w", cfg.Name, err)
+		}
+
+		plans = append(plans, &containerPlan{
+			name:          cfg.Name,
+			sanitizedName: sanitizedName,
+			order:         order,
+			ipAddress:     ip,
+			ipAddress6:    ip6,
+			setupScripts:  append([]string(nil), cfg.SetupScript...),
+			node:          strings.TrimSpace(cfg.Node),
+			kind:          guestKind(cfg.Kind),
+			transport:     execTransport(cfg.ExecTransport),
+			vmTemplateID:  templateSpec.VMTemplateID,
+			interfaces:    append([]db.ContainerInterface(nil), cfg.Interfaces...),
+			options: &proxmoxAPI.ContainerCreateOptions{
+				TemplatePath:     templateSpec.TemplatePath,
+				StoragePool:      templateSpec.StoragePool,
+				Hostname:         sharedContainerHostname(comp, cfg.Name),
+				RootPassword:     templateSpec.RootPassword,
+				RootSSHPublicKey: publicKey,
+				StorageSizeGB:    templateSpec.StorageSizeGB,
+				MemoryMB:         templateSpec.MemoryMB,
+				Cores:            templateSpec.Cores,
+				GatewayIPv4:      config.Config.Network.ContainerGateway,
+				IPv4Address:      ip,
+				IPv6Address:      ip6,
+				CIDRBlock:        config.Config.Network.ContainerCIDR,
+				NameServer:       config.Config.Network.ContainerNameserver,
+				SearchDomain:     config.Config.Network.ContainerSearchDomain,
+			},
+		})
+	}
+
+	return plans, nil
+}
+
+// isSharedContainerRecord reports whether a container record is one of comp's containers for one of its shared configs.
+func isSharedContainerRecord(comp *db.Competition, record *db.Container, req *db.CreateCompetitionRequest) bool {
+	if comp == nil || record == nil || req == nil || record.TeamID != 0 || !slices.Contains(comp.ContainerIDs, record.PVEID) {
+		return false
+	}
+
+	name := strings.TrimSpace(record.ConfigName)
+	for _, cfg := range req.SharedContainerConfigs {
+		if strings.EqualFold(strings.TrimSpace(cfg.Name), name) {
+			return true
+		}
+	}
+
+	return false
+}
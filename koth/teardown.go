@@ -39,6 +39,18 @@ func TeardownCompetitionWithLogger(comp *db.Competition, logSink ProgressLogger)
 		combinedErr = errors.Join(combinedErr, err)
 	}
 
+	if err := deleteTemplateContainers(comp, log); err != nil {
+		combinedErr = errors.Join(combinedErr, err)
+	}
+
+	if err := teardownCompetitionFirewall(comp, log); err != nil {
+		combinedErr = errors.Join(combinedErr, err)
+	}
+
+	if err := teardownNetworkIsolation(comp, log); err != nil {
+		combinedErr = errors.Join(combinedErr, err)
+	}
+
 	if err := purgeContainerRecords(comp, log); err != nil {
 		combinedErr = errors.Join(combinedErr, err)
 	}
@@ -51,9 +63,32 @@ func TeardownCompetitionWithLogger(comp *db.Competition, logSink ProgressLogger)
 		combinedErr = errors.Join(combinedErr, err)
 	}
 
+	if err := purgeScoreHistory(comp, log); err != nil {
+		combinedErr = errors.Join(combinedErr, err)
+	}
+
+	if err := purgeScoringIssues(comp, log); err != nil {
+		combinedErr = errors.Join(combinedErr, err)
+	}
+
+	if err := purgeOwnershipHistory(comp, log); err != nil {
+		combinedErr = errors.Join(combinedErr, err)
+	}
+
+	if err := purgeRedeployHistory(comp, log); err != nil {
+		combinedErr = errors.Join(combinedErr, err)
+	}
+
+	if err := db.DeleteScoreboardSnapshot(comp.SystemID); err != nil {
+		log.Errorf("Failed to delete scoreboard snapshot for %s: %v\n", comp.SystemID, err)
+		combinedErr = errors.Join(combinedErr, err)
+	}
+
 	if err := db.Competitions.Delete(comp.ID); err != nil {
 		log.Errorf("Failed to delete competition record %d: %v\n", comp.ID, err)
 		combinedErr = errors.Join(combinedErr, err)
+	} else if err := releaseCompetitionNetworks(log, comp.SystemID); err != nil {
+		combinedErr = errors.Join(combinedErr, err)
 	}
 
 	if err := removeCompetitionData(comp, log); err != nil {
@@ -111,6 +146,10 @@ func purgeContainerRecords(comp *db.Competition, log ProgressLogger) error {
 func purgeTeamRecords(comp *db.Competition, log ProgressLogger) error {
 	var combined error
 	for _, teamID := range comp.TeamIDs {
+		if err := db.SetTeamMembers(comp.SystemID, teamID, nil); err != nil {
+			log.Errorf("Failed to remove roster for team %d: %v\n", teamID, err)
+			combined = errors.Join(combined, err)
+		}
 		if err := db.Teams.Delete(teamID); err != nil {
 			log.Errorf("Failed to remove team record %d: %v\n", teamID, err)
 			combined = errors.Join(combined, err)
@@ -139,6 +178,85 @@ func purgeScoreResults(comp *db.Competition, log ProgressLogger) error {
 	return combined
 }
 
+func purgeScoreHistory(comp *db.Competition, log ProgressLogger) error {
+	if comp.SystemID == "" {
+		return nil
+	}
+
+	filter := gomysql.NewFilter().KeyCmp(db.ScoreHistory.FieldBySQLName("competition_id"), gomysql.OpEqual, comp.SystemID)
+	entries, err := db.ScoreHistory.SelectAllWithFilter(filter)
+	if err != nil {
+		log.Errorf("Failed to load score history for %s: %v\n", comp.SystemID, err)
+		return err
+	}
+
+	var combined error
+	for _, entry := range entries {
+		if err := db.ScoreHistory.Delete(entry.ID); err != nil {
+			log.Errorf("Failed to delete score history entry %d: %v\n", entry.ID, err)
+			combined = errors.Join(combined, err)
+		}
+	}
+	return combined
+}
+
+func purgeOwnershipHistory(comp *db.Competition, log ProgressLogger) error {
+	if comp.SystemID == "" {
+		return nil
+	}
+
+	records, err := db.GetOwnershipHistory(comp.SystemID)
+	if err != nil {
+		log.Errorf("Failed to load ownership history for %s: %v\n", comp.SystemID, err)
+		return err
+	}
+
+	var combined error
+	for _, record := range records {
+		if err := db.Ownership.Delete(record.ID); err != nil {
+			log.Errorf("Failed to delete ownership record %d: %v\n", record.ID, err)
+			combined = errors.Join(combined, err)
+		}
+	}
+	return combined
+}
+
+func purgeRedeployHistory(comp *db.Competition, log ProgressLogger) error {
+	if comp.SystemID == "" {
+		return nil
+	}
+
+	var combined error
+	for _, teamID := range comp.TeamIDs {
+		records, err := db.GetTeamRedeploys(teamID)
+		if err != nil {
+			log.Errorf("Failed to load redeploy history for team %d: %v\n", teamID, err)
+			combined = errors.Join(combined, err)
+			continue
+		}
+		for _, record := range records {
+			if err := db.TeamRedeploys.Delete(record.ID); err != nil {
+				log.Errorf("Failed to delete redeploy record %d: %v\n", record.ID, err)
+				combined = errors.Join(combined, err)
+			}
+		}
+	}
+
+	adjustments, err := db.GetScoreAdjustments(comp.SystemID)
+	if err != nil {
+		log.Errorf("Failed to load score adjustments for %s: %v\n", comp.SystemID, err)
+		return errors.Join(combined, err)
+	}
+	for _, adjustment := range adjustments {
+		if err := db.ScoreAdjustments.Delete(adjustment.ID); err != nil {
+			log.Errorf("Failed to delete score adjustment %d: %v\n", adjustment.ID, err)
+			combined = errors.Join(combined, err)
+		}
+	}
+
+	return combined
+}
+
 func removeCompetitionData(comp *db.Competition, log ProgressLogger) error {
 	if comp.SystemID == "" {
 		return nil
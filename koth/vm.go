@@ -0,0 +1,111 @@
+package koth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/UNHCSC/pve-koth/ssh"
+	"github.com/luthermonson/go-proxmox"
+)
+
+const guestAgentTimeout = 5 * time.Minute
+
+// guestKind normalizes a container config's kind, treating anything unrecognized as an LXC container. Requests are
+// validated before they reach provisioning, so this only matters for records written by older versions.
+func guestKind(raw string) string {
+	if kind, err := proxmoxAPI.NormalizeGuestKind(raw); err == nil {
+		return kind
+	}
+
+	return proxmoxAPI.GuestKindContainer
+}
+
+// ValidateGuestKind checks that a container config's kind is known and that its spec template can build it: VMs need
+// a vmTemplateID and containers need a templatePath.
+func ValidateGuestKind(rawKind string, spec db.ContainerSpecTemplate) error {
+	kind, err := proxmoxAPI.NormalizeGuestKind(rawKind)
+	if err != nil {
+		return err
+	}
+
+	if kind == proxmoxAPI.GuestKindVM && spec.VMTemplateID <= 0 {
+		return fmt.Errorf("kind %q requires a template with vmTemplateID", kind)
+	}
+	if kind == proxmoxAPI.GuestKindContainer && strings.TrimSpace(spec.TemplatePath) == "" {
+		return fmt.Errorf("kind %q requires a template with templatePath", kind)
+	}
+
+	return nil
+}
+
+// vmCreateOptions maps a plan's container options onto the cloud-init settings of a cloned VM.
+func vmCreateOptions(plan *containerPlan) *proxmoxAPI.VMCreateOptions {
+	return &proxmoxAPI.VMCreateOptions{
+		TemplateID:       plan.vmTemplateID,
+		StoragePool:      plan.options.StoragePool,
+		Name:             plan.options.Hostname,
+		RootPassword:     plan.options.RootPassword,
+		RootSSHPublicKey: plan.options.RootSSHPublicKey,
+		MemoryMB:         plan.options.MemoryMB,
+		Cores:            plan.options.Cores,
+		GatewayIPv4:      plan.options.GatewayIPv4,
+		IPv4Address:      plan.options.IPv4Address,
+		CIDRBlock:        plan.options.CIDRBlock,
+		NameServer:       plan.options.NameServer,
+		SearchDomain:     plan.options.SearchDomain,
+		Bridge:           plan.options.Bridge,
+		VLANTag:          plan.options.VLANTag,
+		GatewayIPv6:      plan.options.GatewayIPv6,
+		IPv6Address:      plan.options.IPv6Address,
+		CIDRBlock6:       plan.options.CIDRBlock6,
+		Interfaces:       plan.options.Interfaces,
+	}
+}
+
+// scriptCommand builds the command that downloads and runs a packaged script inside guest: a PowerShell command on
+// Windows guests and a bash pipeline everywhere else.
+func scriptCommand(guest *proxmoxAPI.Guest, scriptURL, token string, envs map[string]any) string {
+	if guest.IsWindows() {
+		return ssh.LoadAndRunPowerShellScript(scriptURL, token, envs)
+	}
+
+	return ssh.LoadAndRunScript(scriptURL, token, envs)
+}
+
+// createPlanGuest creates the container or VM a plan describes on node. VMs are full-cloned there from their template,
+// which must sit on storage that node can read. An id of 0 picks the next free ID.
+func createPlanGuest(node *proxmox.Node, plan *containerPlan, id int) (*proxmoxAPI.ProxmoxAPICreateResult, error) {
+	if plan.kind == proxmoxAPI.GuestKindVM {
+		return api.CreateVM(node, vmCreateOptions(plan), id)
+	}
+
+	if id > 0 {
+		return api.CreateContainerWithID(node, plan.options, id)
+	}
+
+	return api.CreateContainer(node, plan.options)
+}
+
+// waitForGuestReady waits until commands can be run in a freshly started guest. VMs boot and run cloud-init before
+// their guest agent answers, so they get a much longer grace period than containers.
+func waitForGuestReady(api proxmoxAPI.Backend, guest *proxmoxAPI.Guest, rootPassword string) error {
+	if guest.Kind() != proxmoxAPI.GuestKindVM {
+		return waitForConsoleReady(api, guest.Container, rootPassword)
+	}
+
+	if err := api.WaitForGuestAgent(guest.VM, guestAgentTimeout); err != nil {
+		return fmt.Errorf("guest agent did not start: %w", err)
+	}
+
+	const attempts = 5
+	for i := 0; i < attempts; i++ {
+		if _, _, exitCode, err := api.ExecuteGuestWithRetries(guest, "root", rootPassword, "echo KOTH_READY", 0); err == nil && exitCode == 0 {
+			return nil
+		}
+		time.Sleep(time.Second * time.Duration(i+1))
+	}
+	return fmt.Errorf("guest agent not ready for command execution")
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"time"
+
 	"github.com/UNHCSC/pve-koth/app"
 	"github.com/UNHCSC/pve-koth/config"
 	"github.com/UNHCSC/pve-koth/db"
@@ -23,12 +25,54 @@ func main() {
 		return
 	}
 
+	var interrupted []*db.Job
+	if interrupted, err = db.FailInterruptedJobs(time.Now()); err != nil {
+		mainLog.Errorf("failed to reconcile interrupted jobs: %v\n", err)
+		return
+	}
+
+	for _, job := range interrupted {
+		mainLog.Warningf("%s job %s (owner %s) was interrupted by the last shutdown and has been marked failed\n", job.Kind, job.ID, job.Owner)
+	}
+
+	var redeploys []*db.Container
+	if redeploys, err = db.ResetInterruptedRedeploys(time.Now()); err != nil {
+		mainLog.Errorf("failed to reconcile interrupted redeploys: %v\n", err)
+		return
+	}
+
+	for _, record := range redeploys {
+		mainLog.Warningf("redeploy of container %d was interrupted by the last shutdown; check it before scoring resumes\n", record.PVEID)
+	}
+
+	var resumable []string
+	if resumable, err = koth.FailInterruptedProvisioning(); err != nil {
+		mainLog.Errorf("failed to reconcile interrupted provisioning: %v\n", err)
+		return
+	}
+
+	for _, systemID := range resumable {
+		mainLog.Warningf("provisioning of competition %s was interrupted and can be resumed from the dashboard\n", systemID)
+	}
+
+	var recorded, released int
+	if recorded, released, err = koth.ReconcileNetworkAllocations(); err != nil {
+		mainLog.Errorf("failed to reconcile network allocations: %v\n", err)
+		return
+	}
+
+	if recorded > 0 || released > 0 {
+		mainLog.Warningf("network allocations reconciled: %d recorded, %d released\n", recorded, released)
+	}
+
 	if err = koth.Init(); err != nil {
 		mainLog.Errorf("failed to initialize koth module: %v\n", err)
 		return
 	}
 
+	koth.SetScoreboardFreezeHandler(app.CaptureScoreboardSnapshot)
 	koth.StartScoringLoop()
+	koth.StartCompetitionScheduler()
 	koth.StartContainerStatusMonitor()
 
 	mainLog.Errorf("fiber log: %v\n", app.StartApp())
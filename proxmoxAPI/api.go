@@ -20,6 +20,7 @@ type ProxmoxAPI struct {
 	Nodes              []*proxmox.Node
 	Cluster            *proxmox.Cluster
 	nodeRotator        int
+	placement          placementState
 	tokenUser          string
 	apiHost            string
 	apiPort            string
@@ -29,13 +30,28 @@ type ProxmoxAPI struct {
 	httpClient         *http.Client
 	ConnectTimeout     time.Duration
 	CommandTimeout     time.Duration
+	SessionIdleTimeout time.Duration // How long a logged-in console stays pooled between commands; 0 disables pooling
+	SessionMaxAge      time.Duration // How long a pooled console is reused before logging in again; 0 uses 10 minutes
+	TicketRenewAfter   time.Duration // How long a PVE ticket is reused before it is renewed; 0 uses 90 minutes
+	tickets            ticketCache
+	consoles           consolePool
+	consoleCounters    consoleCounters
 }
 
 type ProxmoxAPICreateResult struct {
 	Container *proxmox.Container
+	VM        *proxmox.VirtualMachine // Set instead of Container for virtual machines
 	CTID      int
 }
 
+// Guest returns the created container or VM.
+func (r *ProxmoxAPICreateResult) Guest() *Guest {
+	if r.VM != nil {
+		return VMGuest(r.VM)
+	}
+	return ContainerGuest(r.Container)
+}
+
 type ProxmoxAPIBulkCreateResult struct {
 	Result *ProxmoxAPICreateResult
 	Error  error
@@ -69,6 +85,7 @@ func InitProxmox() (api *ProxmoxAPI, err error) {
 		httpClient:         httpClient,
 		ConnectTimeout:     30 * time.Second,
 		CommandTimeout:     5 * time.Minute,
+		SessionIdleTimeout: time.Duration(config.Config.Proxmox.ConsoleSessionIdleSeconds) * time.Second,
 	}
 	if token := config.Config.Proxmox.TokenID; token != "" {
 		if parts := strings.SplitN(token, "!", 2); len(parts) > 0 {
@@ -103,6 +120,9 @@ func InitProxmox() (api *ProxmoxAPI, err error) {
 }
 
 func (api *ProxmoxAPI) NextNode() *proxmox.Node {
+	api.placement.mu.Lock()
+	defer api.placement.mu.Unlock()
+
 	if len(api.Nodes) == 0 {
 		return nil
 	}
@@ -0,0 +1,62 @@
+package proxmoxAPI
+
+import (
+	"time"
+
+	"github.com/luthermonson/go-proxmox"
+)
+
+// Backend is the part of ProxmoxAPI that koth provisions, scores and tears competitions down through. ProxmoxAPI
+// talks to a live cluster; proxmoxfake.Cluster simulates one in memory for tests.
+type Backend interface {
+	// Placement
+	NodeByName(name string) *proxmox.Node
+	PlaceContainer(req PlacementRequest) (*proxmox.Node, error)
+	SetPlacementAffinity(key, nodeName string)
+
+	// Creation
+	CreateContainer(node *proxmox.Node, conf *ContainerCreateOptions) (*ProxmoxAPICreateResult, error)
+	CreateContainerWithID(node *proxmox.Node, conf *ContainerCreateOptions, ctID int) (*ProxmoxAPICreateResult, error)
+	CreateVM(node *proxmox.Node, conf *VMCreateOptions, vmID int) (*ProxmoxAPICreateResult, error)
+	CreateTemplate(ct *proxmox.Container) error
+	CloneTemplate(ct *proxmox.Container, node *proxmox.Node, hostname string, full bool) (*proxmox.Container, error)
+	ChangeContainerNetworking(ct *proxmox.Container, conf *ContainerCreateOptions) error
+
+	// Lifecycle
+	Guest(id int) (*Guest, error)
+	GetGuests(ids []int) ([]*Guest, error)
+	StartContainer(ct *proxmox.Container) error
+	StopContainer(ct *proxmox.Container) error
+	StartGuest(g *Guest) error
+	StopGuest(g *Guest) error
+	DeleteGuest(g *Guest) error
+	BulkStart(ids []int) error
+	BulkStop(ids []int) error
+	BulkDelete(ids []int) error
+	BulkCTActionWithRetries(action func(ids []int) error, ids []int, numRetries int) error
+	SnapshotGuest(g *Guest, name string) error
+	HasSnapshot(g *Guest, name string) (bool, error)
+	RollbackGuest(g *Guest, name string) error
+
+	// Execution
+	RawExecuteWithRetries(ct *proxmox.Container, username, password, command string, numRetries int) (stdout, stderr string, exitCode int, err error)
+	ExecuteGuestWithRetries(g *Guest, username, password, command string, numRetries int) (stdout, stderr string, exitCode int, err error)
+	NodeExecute(g *Guest, command string) (stdout, stderr string, exitCode int, err error)
+	ConsoleStats() ConsoleStats
+	WaitForGuestAgent(vm *proxmox.VirtualMachine, timeout time.Duration) error
+
+	// Firewall
+	EnsureIPSet(name, comment string, cidrs []string) error
+	DeleteIPSet(name string) error
+	ReplaceSecurityGroup(name, comment string, rules []*proxmox.FirewallRule) error
+	DeleteSecurityGroup(name string) error
+	SetGuestFirewall(g *Guest, options GuestFirewallOptions, managedPrefix string, rules []*proxmox.FirewallRule) error
+
+	// SDN
+	CreateVNet(name, zone string, tag int, cidr, gateway, alias string) error
+	CreateVNetSubnet(name, cidr, gateway string) error
+	DeleteVNet(name, zone string, cidrs ...string) error
+	ApplySDN() error
+}
+
+var _ Backend = (*ProxmoxAPI)(nil)
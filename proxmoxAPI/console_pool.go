@@ -0,0 +1,355 @@
+package proxmoxAPI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ticketLifetime    = 2 * time.Hour    // PVE auth tickets expire two hours after they are issued
+	ticketRenewAfter  = 90 * time.Minute // Renew well before expiry so in-flight requests never carry a stale ticket
+	consoleProbeAfter = 15 * time.Second // Pooled consoles idle longer than this are health-checked before reuse
+	consoleProbeLimit = 5 * time.Second
+	consoleMaxAge     = 10 * time.Minute // Pooled consoles are logged into again this often, so changed guest passwords are noticed
+)
+
+// errTicketRejected marks Proxmox refusing a cached ticket, e.g. after the cluster's auth key rotated.
+var errTicketRejected = errors.New("proxmox rejected the auth ticket")
+
+// ConsoleStats counts how often console execution reused a cached ticket or a logged-in console instead of logging in
+// again. Counters are cumulative since koth started.
+type ConsoleStats struct {
+	TicketLogins      int64 `json:"ticketLogins"`      // Full username/password logins
+	TicketRenewals    int64 `json:"ticketRenewals"`    // Tickets renewed with the previous ticket
+	TicketReuses      int64 `json:"ticketReuses"`      // Console opens served by the cached ticket
+	SessionsOpened    int64 `json:"sessionsOpened"`    // Consoles opened and logged into
+	SessionsReused    int64 `json:"sessionsReused"`    // Commands run on an already logged-in console
+	HealthChecks      int64 `json:"healthChecks"`      // Idle consoles probed before reuse
+	SessionsDiscarded int64 `json:"sessionsDiscarded"` // Consoles dropped after a failed health check or command
+	SessionsExpired   int64 `json:"sessionsExpired"`   // Consoles closed after sitting idle too long
+	IdleSessions      int   `json:"idleSessions"`      // Consoles currently pooled
+}
+
+type consoleCounters struct {
+	ticketLogins, ticketRenewals, ticketReuses        atomic.Int64
+	opened, reused, discarded, expired, healthChecked atomic.Int64
+}
+
+type cachedTicket struct {
+	ticket, csrf string
+	issued       time.Time
+}
+
+// ticketCache holds one ticket for the whole cluster; PVE tickets are valid on every node.
+type ticketCache struct {
+	mu      sync.Mutex
+	current *cachedTicket
+}
+
+// consoleKey identifies a pooled console: one container logged into as one user.
+type consoleKey struct {
+	node string
+	vmid int
+	user string
+	pass string
+}
+
+type consoleSession struct {
+	key      consoleKey
+	conn     *consoleConn
+	opened   time.Time
+	lastUsed time.Time
+	stop     context.CancelFunc // Stops the session's keepalive
+}
+
+func (s *consoleSession) close() {
+	s.stop()
+	_ = s.conn.Close()
+}
+
+type consolePool struct {
+	mu          sync.Mutex
+	idle        map[consoleKey]*consoleSession
+	stopJanitor chan struct{} // Closed to stop the janitor; nil until it is started
+}
+
+// ConsoleStats reports ticket and console session reuse.
+func (api *ProxmoxAPI) ConsoleStats() ConsoleStats {
+	api.consoles.mu.Lock()
+	idle := len(api.consoles.idle)
+	api.consoles.mu.Unlock()
+
+	return ConsoleStats{
+		TicketLogins:      api.consoleCounters.ticketLogins.Load(),
+		TicketRenewals:    api.consoleCounters.ticketRenewals.Load(),
+		TicketReuses:      api.consoleCounters.ticketReuses.Load(),
+		SessionsOpened:    api.consoleCounters.opened.Load(),
+		SessionsReused:    api.consoleCounters.reused.Load(),
+		HealthChecks:      api.consoleCounters.healthChecked.Load(),
+		SessionsDiscarded: api.consoleCounters.discarded.Load(),
+		SessionsExpired:   api.consoleCounters.expired.Load(),
+		IdleSessions:      idle,
+	}
+}
+
+// authTicket returns a valid ticket, logging in only when there is none or it is about to expire. Tickets past
+// TicketRenewAfter are renewed with themselves, falling back to a full login if renewal fails.
+func (api *ProxmoxAPI) authTicket(ctx context.Context, host string) (ticket, csrf string, err error) {
+	api.tickets.mu.Lock()
+	defer api.tickets.mu.Unlock()
+
+	if cached := api.tickets.current; cached != nil {
+		age := time.Since(cached.issued)
+		if age < api.getTicketRenewAfter() {
+			api.consoleCounters.ticketReuses.Add(1)
+			return cached.ticket, cached.csrf, nil
+		}
+
+		if age < ticketLifetime {
+			if ticket, csrf, err = api.requestTicket(ctx, host, cached.ticket); err == nil {
+				api.consoleCounters.ticketRenewals.Add(1)
+				api.tickets.current = &cachedTicket{ticket: ticket, csrf: csrf, issued: time.Now()}
+				return
+			}
+		}
+	}
+
+	api.tickets.current = nil
+	if ticket, csrf, err = api.requestTicket(ctx, host, api.password); err != nil {
+		return
+	}
+
+	api.consoleCounters.ticketLogins.Add(1)
+	api.tickets.current = &cachedTicket{ticket: ticket, csrf: csrf, issued: time.Now()}
+	return
+}
+
+// dropTicket forgets ticket if it is still the cached one, so the next caller logs in again.
+func (api *ProxmoxAPI) dropTicket(ticket string) {
+	api.tickets.mu.Lock()
+	defer api.tickets.mu.Unlock()
+
+	if api.tickets.current != nil && api.tickets.current.ticket == ticket {
+		api.tickets.current = nil
+	}
+}
+
+// checkoutConsole takes a logged-in console for key out of the pool, or opens a new one. Consoles that sat idle for
+// a while must answer a trivial command before they are handed out; those that do not are closed and replaced, as are
+// consoles past their maximum age.
+func (api *ProxmoxAPI) checkoutConsole(key consoleKey, host string) (*consoleSession, error) {
+	if session := api.takeIdleConsole(key); session != nil {
+		switch {
+		case time.Since(session.opened) >= api.getSessionMaxAge():
+			api.consoleCounters.expired.Add(1)
+			session.close()
+		case time.Since(session.lastUsed) < consoleProbeAfter || api.probeConsole(session) == nil:
+			api.consoleCounters.reused.Add(1)
+			return session, nil
+		default:
+			api.consoleCounters.discarded.Add(1)
+			session.close()
+		}
+	}
+
+	return api.openConsole(key, host)
+}
+
+// takeIdleConsole removes and returns the pooled console for key.
+func (api *ProxmoxAPI) takeIdleConsole(key consoleKey) *consoleSession {
+	api.consoles.mu.Lock()
+	defer api.consoles.mu.Unlock()
+
+	session := api.consoles.idle[key]
+	delete(api.consoles.idle, key)
+	return session
+}
+
+// expireIdleConsoles closes pooled consoles idle past the timeout or older than the maximum age, including those of
+// containers that were stopped or deleted since. They are closed after the pool is unlocked, so slow connections
+// never hold up other commands.
+func (api *ProxmoxAPI) expireIdleConsoles() {
+	var expired []*consoleSession
+
+	api.consoles.mu.Lock()
+	for key, session := range api.consoles.idle {
+		if time.Since(session.lastUsed) > api.SessionIdleTimeout || time.Since(session.opened) >= api.getSessionMaxAge() {
+			delete(api.consoles.idle, key)
+			expired = append(expired, session)
+		}
+	}
+	api.consoles.mu.Unlock()
+
+	for _, session := range expired {
+		api.consoleCounters.expired.Add(1)
+		session.close()
+	}
+}
+
+// releaseConsole returns a console to the pool after a successful command. Without pooling, or when a concurrent
+// caller already pooled a console for the same key, it is closed instead.
+func (api *ProxmoxAPI) releaseConsole(session *consoleSession) {
+	session.lastUsed = time.Now()
+	if api.SessionIdleTimeout <= 0 {
+		session.close()
+		return
+	}
+
+	api.consoles.mu.Lock()
+	if api.consoles.idle == nil {
+		api.consoles.idle = make(map[consoleKey]*consoleSession)
+	}
+
+	if api.consoles.stopJanitor == nil {
+		api.consoles.stopJanitor = make(chan struct{})
+		go api.consoleJanitor(api.consoles.stopJanitor, max(api.SessionIdleTimeout/2, time.Second))
+	}
+
+	var duplicate = api.consoles.idle[session.key] != nil
+	if !duplicate {
+		api.consoles.idle[session.key] = session
+	}
+	api.consoles.mu.Unlock()
+
+	if duplicate {
+		session.close()
+	}
+}
+
+// consoleJanitor expires idle consoles every interval until stop is closed.
+func (api *ProxmoxAPI) consoleJanitor(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			api.expireIdleConsoles()
+		}
+	}
+}
+
+// Close stops the console janitor and closes every pooled console. Commands run afterwards log in again and restart
+// the janitor.
+func (api *ProxmoxAPI) Close() {
+	api.consoles.mu.Lock()
+	var idle = api.consoles.idle
+	api.consoles.idle = nil
+	if api.consoles.stopJanitor != nil {
+		close(api.consoles.stopJanitor)
+		api.consoles.stopJanitor = nil
+	}
+	api.consoles.mu.Unlock()
+
+	for _, session := range idle {
+		session.close()
+	}
+}
+
+func (api *ProxmoxAPI) probeConsole(session *consoleSession) error {
+	ctx, cancel := context.WithTimeout(api.baseContext(), consoleProbeLimit)
+	defer cancel()
+
+	api.consoleCounters.healthChecked.Add(1)
+	_, _, exitCode, err := api.executeAndCollect(ctx, session.conn, "true")
+	if err == nil && exitCode != 0 {
+		err = fmt.Errorf("console health check exited with code %d", exitCode)
+	}
+
+	return err
+}
+
+// openConsole opens the container's termproxy console and logs in. A cached ticket Proxmox no longer accepts is
+// dropped and the open retried once with a fresh login.
+func (api *ProxmoxAPI) openConsole(key consoleKey, host string) (session *consoleSession, err error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		if session, err = api.dialConsole(key, host); !errors.Is(err, errTicketRejected) {
+			return
+		}
+	}
+
+	return
+}
+
+func (api *ProxmoxAPI) dialConsole(key consoleKey, host string) (session *consoleSession, err error) {
+	connectCtx, cancelConnect := context.WithTimeout(api.baseContext(), api.getConnectTimeout())
+	defer cancelConnect()
+
+	var authTicket, csrfToken string
+	if authTicket, csrfToken, err = api.authTicket(connectCtx, host); err != nil {
+		err = fmt.Errorf("failed to obtain PVE ticket: %w", err)
+		return
+	}
+
+	var proxy *termProxySession
+	if proxy, err = api.openTermProxy(connectCtx, host, key.node, key.vmid, authTicket, csrfToken); err != nil {
+		if errors.Is(err, errTicketRejected) {
+			api.dropTicket(authTicket)
+		}
+		err = fmt.Errorf("failed to open termproxy: %w", err)
+		return
+	}
+
+	var conn *consoleConn
+	if conn, err = api.dialVNCWebSocket(connectCtx, host, key.node, key.vmid, proxy, authTicket); err != nil {
+		err = fmt.Errorf("failed to dial console websocket: %w", err)
+		return
+	}
+
+	terminalUser := determineTerminalUser(proxy.User, api.username, api.tokenUser)
+	if terminalUser == "" {
+		_ = conn.Close()
+		err = fmt.Errorf("failed to determine terminal user for handshake")
+		return
+	}
+
+	keepAliveCtx, stop := context.WithCancel(api.baseContext())
+	session = &consoleSession{key: key, conn: conn, opened: time.Now(), stop: stop}
+
+	loginCtx, cancelLogin := context.WithTimeout(api.baseContext(), api.getCommandTimeout())
+	defer cancelLogin()
+
+	if err = api.performHandshake(loginCtx, conn, terminalUser, proxy.Ticket); err == nil {
+		go api.keepAlive(keepAliveCtx, conn)
+		err = api.ensureConsoleLogin(loginCtx, conn, key.user, key.pass)
+	}
+
+	if err != nil {
+		session.close()
+		session = nil
+		return
+	}
+
+	api.consoleCounters.opened.Add(1)
+	return
+}
+
+func (api *ProxmoxAPI) getTicketRenewAfter() time.Duration {
+	if api.TicketRenewAfter > 0 {
+		return api.TicketRenewAfter
+	}
+
+	return ticketRenewAfter
+}
+
+func (api *ProxmoxAPI) getSessionMaxAge() time.Duration {
+	if api.SessionMaxAge > 0 {
+		return api.SessionMaxAge
+	}
+
+	return consoleMaxAge
+}
+
+func (api *ProxmoxAPI) baseContext() context.Context {
+	if api.bg == nil {
+		return context.Background()
+	}
+
+	return api.bg
+}
@@ -1,6 +1,7 @@
 package proxmoxAPI
 
 import (
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
@@ -71,16 +72,31 @@ func (api *ProxmoxAPI) CreateTemplate(ct *proxmox.Container) (err error) {
 	return
 }
 
-func (api *ProxmoxAPI) CloneTemplate(ct *proxmox.Container, hostname string) (newCT *proxmox.Container, err error) {
+// CloneTemplate clones a template container onto node, or onto the template's own node when node is nil. Linked
+// clones (full = false) share the template's disk and are nearly instant, but need storage that supports them
+// (LVM-thin, ZFS, Ceph); cloning onto another node needs the template on shared storage.
+func (api *ProxmoxAPI) CloneTemplate(ct *proxmox.Container, node *proxmox.Node, hostname string, full bool) (newCT *proxmox.Container, err error) {
 	var (
-		newID int
-		task  *proxmox.Task
+		newID   int
+		task    *proxmox.Task
+		options = &proxmox.ContainerCloneOptions{
+			Hostname: hostname,
+		}
 	)
 
-	if newID, task, err = ct.Clone(api.bg, &proxmox.ContainerCloneOptions{
-		Full:     1,
-		Hostname: hostname,
-	}); err != nil {
+	if full {
+		options.Full = 1
+	}
+
+	if node != nil && node.Name != ct.Node {
+		options.Target = node.Name
+	}
+
+	// Clone picks the next free ID itself, so serialize it with container creation.
+	api.createLock.Lock()
+	defer api.createLock.Unlock()
+
+	if newID, task, err = ct.Clone(api.bg, options); err != nil {
 		err = fmt.Errorf("failed to clone container: %w", err)
 		return
 	} else if err = task.Wait(api.bg, time.Second, time.Minute*10); err != nil {
@@ -96,13 +112,10 @@ func (api *ProxmoxAPI) CloneTemplate(ct *proxmox.Container, hostname string) (ne
 	return
 }
 
-func (api *ProxmoxAPI) ChangeContainerNetworking(ct *proxmox.Container, gatewayIPv4, IPv4Address string, CIDRBlock int) (err error) {
+func (api *ProxmoxAPI) ChangeContainerNetworking(ct *proxmox.Container, conf *ContainerCreateOptions) (err error) {
 	var task *proxmox.Task
 
-	if task, err = ct.Config(api.bg, proxmox.ContainerOption{
-		Name:  "net0",
-		Value: fmt.Sprintf("name=eth0,bridge=vmbr0,firewall=1,gw=%s,ip=%s/%d", gatewayIPv4, IPv4Address, CIDRBlock),
-	}); err != nil {
+	if task, err = ct.Config(api.bg, conf.NetworkOptions()...); err != nil {
 		err = fmt.Errorf("failed to change container networking: %w", err)
 	} else if task != nil {
 		if err = task.Wait(api.bg, time.Second, time.Minute*3); err != nil {
@@ -168,15 +181,21 @@ func (api *ProxmoxAPI) bulkJob(tasks []*proxmox.Task, bucketSize int) (err error
 func (api *ProxmoxAPI) BulkStart(ids []int) (err error) {
 	var tasks []*proxmox.Task
 
-	var containers []*proxmox.Container
-	if containers, err = api.GetContainers(ids); err != nil {
+	var guests []*Guest
+	if guests, err = api.GetGuests(ids); err != nil {
 		return
 	}
 
-	for _, ct := range containers {
+	for _, guest := range guests {
 		var task *proxmox.Task
-		if task, err = ct.Start(api.bg); err != nil && !strings.Contains(strings.ToLower(err.Error()), "already running") {
-			err = fmt.Errorf("failed to start container %d: %w", ct.VMID, err)
+		if guest.VM != nil {
+			task, err = guest.VM.Start(api.bg)
+		} else {
+			task, err = guest.Container.Start(api.bg)
+		}
+
+		if err != nil && !strings.Contains(strings.ToLower(err.Error()), "already running") {
+			err = fmt.Errorf("failed to start guest %d: %w", guest.ID(), err)
 			return
 		}
 
@@ -190,15 +209,21 @@ func (api *ProxmoxAPI) BulkStart(ids []int) (err error) {
 func (api *ProxmoxAPI) BulkStop(ids []int) (err error) {
 	var tasks []*proxmox.Task
 
-	var containers []*proxmox.Container
-	if containers, err = api.GetContainers(ids); err != nil {
+	var guests []*Guest
+	if guests, err = api.GetGuests(ids); err != nil {
 		return
 	}
 
-	for _, ct := range containers {
+	for _, guest := range guests {
 		var task *proxmox.Task
-		if task, err = ct.Stop(api.bg); err != nil && !strings.Contains(strings.ToLower(err.Error()), "not running") {
-			err = fmt.Errorf("failed to stop container %d: %w", ct.VMID, err)
+		if guest.VM != nil {
+			task, err = guest.VM.Stop(api.bg)
+		} else {
+			task, err = guest.Container.Stop(api.bg)
+		}
+
+		if err != nil && !strings.Contains(strings.ToLower(err.Error()), "not running") {
+			err = fmt.Errorf("failed to stop guest %d: %w", guest.ID(), err)
 			return
 		}
 
@@ -212,15 +237,21 @@ func (api *ProxmoxAPI) BulkStop(ids []int) (err error) {
 func (api *ProxmoxAPI) BulkDelete(ids []int) (err error) {
 	var tasks []*proxmox.Task
 
-	var containers []*proxmox.Container
-	if containers, err = api.GetContainers(ids); err != nil {
+	var guests []*Guest
+	if guests, err = api.GetGuests(ids); err != nil {
 		return
 	}
 
-	for _, ct := range containers {
+	for _, guest := range guests {
 		var task *proxmox.Task
-		if task, err = ct.Delete(api.bg); err != nil {
-			err = fmt.Errorf("failed to delete container %d: %w", ct.VMID, err)
+		if guest.VM != nil {
+			task, err = guest.VM.Delete(api.bg)
+		} else {
+			task, err = guest.Container.Delete(api.bg)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("failed to delete guest %d: %w", guest.ID(), err)
 			return
 		}
 
@@ -305,6 +336,10 @@ func (api *ProxmoxAPI) RawExecuteWithRetries(ct *proxmox.Container, username, pa
 			return
 		}
 
+		if errors.Is(err, ErrGuestCredentials) {
+			return
+		}
+
 		// fmt.Printf("Raw execute on container %d failed: %v. Retrying (%d/%d)...\n", ct.VMID, err, i+1, numRetries)
 		time.Sleep(time.Second * (time.Duration(i) + 1))
 	}
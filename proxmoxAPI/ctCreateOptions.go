@@ -2,6 +2,7 @@ package proxmoxAPI
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/luthermonson/go-proxmox"
 )
@@ -20,6 +21,98 @@ type ContainerCreateOptions struct {
 	CIDRBlock        int
 	NameServer       string
 	SearchDomain     string
+	Bridge           string // Bridge or SDN VNet the interface is attached to; defaults to vmbr0
+	VLANTag          int    // 0 leaves the interface untagged
+	GatewayIPv6      string // IPv6 settings of net0; empty IPv6Address keeps it IPv4-only
+	IPv6Address      string
+	CIDRBlock6       int
+	Interfaces       []NetworkInterface // Extra interfaces, attached as net1, net2, ...
+}
+
+// NetworkInterface is an additional network device of a container or VM.
+type NetworkInterface struct {
+	Bridge      string
+	VLANTag     int
+	DHCP        bool
+	IPv4Address string
+	CIDRBlock   int
+	GatewayIPv4 string // Set on at most one interface; it then carries the default route instead of net0
+	IPv6Address string // Optional static IPv6 address next to the IPv4 settings
+	CIDRBlock6  int
+	GatewayIPv6 string
+}
+
+// ipConfig returns the interface's address settings in the form shared by container nets and VM ipconfigs.
+func (i NetworkInterface) ipConfig() string {
+	var config = "ip=dhcp"
+	if !i.DHCP {
+		config = fmt.Sprintf("ip=%s/%d", i.IPv4Address, i.CIDRBlock)
+		if i.GatewayIPv4 != "" {
+			config = fmt.Sprintf("gw=%s,%s", i.GatewayIPv4, config)
+		}
+	}
+
+	if i.IPv6Address != "" {
+		if i.GatewayIPv6 != "" {
+			config += fmt.Sprintf(",gw6=%s", i.GatewayIPv6)
+		}
+		config += fmt.Sprintf(",ip6=%s/%d", i.IPv6Address, i.CIDRBlock6)
+	}
+
+	return config
+}
+
+// primaryGateway returns the gateway of net0, which is dropped when an extra interface takes the default route.
+func primaryGateway(gateway string, interfaces []NetworkInterface) string {
+	for _, iface := range interfaces {
+		if iface.GatewayIPv4 != "" && !iface.DHCP {
+			return ""
+		}
+	}
+
+	return gateway
+}
+
+// bridgeOptions returns the bridge, VLAN tag and firewall settings shared by container and VM network devices.
+func bridgeOptions(bridge string, vlanTag int) string {
+	if bridge = strings.TrimSpace(bridge); bridge == "" {
+		bridge = "vmbr0"
+	}
+
+	if vlanTag > 0 {
+		return fmt.Sprintf("bridge=%s,tag=%d,firewall=1", bridge, vlanTag)
+	}
+
+	return fmt.Sprintf("bridge=%s,firewall=1", bridge)
+}
+
+// NetworkValue returns the container's net0 setting.
+func (c *ContainerCreateOptions) NetworkValue() string {
+	return fmt.Sprintf("name=eth0,%s,%s", bridgeOptions(c.Bridge, c.VLANTag), NetworkInterface{
+		IPv4Address: c.IPv4Address,
+		CIDRBlock:   c.CIDRBlock,
+		GatewayIPv4: primaryGateway(c.GatewayIPv4, c.Interfaces),
+		IPv6Address: c.IPv6Address,
+		CIDRBlock6:  c.CIDRBlock6,
+		GatewayIPv6: c.GatewayIPv6,
+	}.ipConfig())
+}
+
+// NetworkOptions returns net0 followed by a netN setting for every extra interface.
+func (c *ContainerCreateOptions) NetworkOptions() (opts []proxmox.ContainerOption) {
+	opts = append(opts, proxmox.ContainerOption{
+		Name:  "net0",
+		Value: c.NetworkValue(),
+	})
+
+	for idx, iface := range c.Interfaces {
+		opts = append(opts, proxmox.ContainerOption{
+			Name:  fmt.Sprintf("net%d", idx+1),
+			Value: fmt.Sprintf("name=eth%d,%s,%s", idx+1, bridgeOptions(iface.Bridge, iface.VLANTag), iface.ipConfig()),
+		})
+	}
+
+	return
 }
 
 func (c *ContainerCreateOptions) GoProxmoxOptions() (opts []proxmox.ContainerOption) {
@@ -65,10 +158,7 @@ func (c *ContainerCreateOptions) GoProxmoxOptions() (opts []proxmox.ContainerOpt
 		Value: c.Cores,
 	})
 
-	opts = append(opts, proxmox.ContainerOption{
-		Name:  "net0",
-		Value: fmt.Sprintf("name=eth0,bridge=vmbr0,firewall=1,gw=%s,ip=%s/%d", c.GatewayIPv4, c.IPv4Address, c.CIDRBlock),
-	})
+	opts = append(opts, c.NetworkOptions()...)
 
 	opts = append(opts, proxmox.ContainerOption{
 		Name:  "nameserver",
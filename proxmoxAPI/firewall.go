@@ -0,0 +1,188 @@
+package proxmoxAPI
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/luthermonson/go-proxmox"
+)
+
+// GuestFirewallOptions are the per-guest firewall settings koth manages.
+type GuestFirewallOptions struct {
+	Enable    bool
+	PolicyIn  string // ACCEPT, DROP or REJECT
+	PolicyOut string
+}
+
+// EnsureIPSet creates the cluster IPSet if needed and makes its entries match cidrs. Missing entries are added before
+// stale ones are removed, so addresses that stay in the set never drop out of it while the rules referencing it apply.
+func (api *ProxmoxAPI) EnsureIPSet(name, comment string, cidrs []string) (err error) {
+	var sets []*proxmox.FirewallIPSet
+	if err = api.client.Get(api.bg, "/cluster/firewall/ipset", &sets); err != nil {
+		return fmt.Errorf("failed to list ipsets: %w", err)
+	}
+
+	if !slices.ContainsFunc(sets, func(set *proxmox.FirewallIPSet) bool { return set.Name == name }) {
+		if err = api.client.Post(api.bg, "/cluster/firewall/ipset", &proxmox.FirewallIPSetCreationOption{Name: name, Comment: comment}, nil); err != nil {
+			return fmt.Errorf("failed to create ipset %s: %w", name, err)
+		}
+	}
+
+	var entries []*proxmox.FirewallIPSetEntry
+	if err = api.client.Get(api.bg, "/cluster/firewall/ipset/"+name, &entries); err != nil {
+		return fmt.Errorf("failed to list ipset %s: %w", name, err)
+	}
+
+	var existing = make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		existing[entry.CIDR] = true
+	}
+
+	for _, cidr := range cidrs {
+		if existing[cidr] {
+			continue
+		}
+
+		if err = api.client.Post(api.bg, "/cluster/firewall/ipset/"+name, &proxmox.FirewallIPSetEntryCreationOption{CIDR: cidr}, nil); err != nil {
+			return fmt.Errorf("failed to add %s to ipset %s: %w", cidr, name, err)
+		}
+		existing[cidr] = true
+	}
+
+	for _, entry := range entries {
+		if slices.Contains(cidrs, entry.CIDR) {
+			continue
+		}
+
+		if err = api.client.Delete(api.bg, fmt.Sprintf("/cluster/firewall/ipset/%s/%s", name, url.PathEscape(entry.CIDR)), nil); err != nil {
+			return fmt.Errorf("failed to remove %s from ipset %s: %w", entry.CIDR, name, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteIPSet removes a cluster IPSet and its entries. Missing sets are ignored.
+func (api *ProxmoxAPI) DeleteIPSet(name string) (err error) {
+	var entries []*proxmox.FirewallIPSetEntry
+	if err = api.client.Get(api.bg, "/cluster/firewall/ipset/"+name, &entries); err != nil {
+		if isMissing(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list ipset %s: %w", name, err)
+	}
+
+	for _, entry := range entries {
+		if err = api.client.Delete(api.bg, fmt.Sprintf("/cluster/firewall/ipset/%s/%s", name, url.PathEscape(entry.CIDR)), nil); err != nil {
+			return fmt.Errorf("failed to remove %s from ipset %s: %w", entry.CIDR, name, err)
+		}
+	}
+
+	if err = api.client.Delete(api.bg, "/cluster/firewall/ipset/"+name, nil); err != nil && !isMissing(err) {
+		return fmt.Errorf("failed to delete ipset %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// ReplaceSecurityGroup creates the cluster security group if needed and replaces its rules, keeping their order.
+func (api *ProxmoxAPI) ReplaceSecurityGroup(name, comment string, rules []*proxmox.FirewallRule) (err error) {
+	var groups []*proxmox.FirewallSecurityGroup
+	if groups, err = api.Cluster.FWGroups(api.bg); err != nil {
+		return fmt.Errorf("failed to list security groups: %w", err)
+	}
+
+	if !slices.ContainsFunc(groups, func(group *proxmox.FirewallSecurityGroup) bool { return group.Group == name }) {
+		if err = api.Cluster.NewFWGroup(api.bg, &proxmox.FirewallSecurityGroup{Group: name, Comment: comment}); err != nil {
+			return fmt.Errorf("failed to create security group %s: %w", name, err)
+		}
+	}
+
+	var group *proxmox.FirewallSecurityGroup
+	if group, err = api.Cluster.FWGroup(api.bg, name); err != nil {
+		return fmt.Errorf("failed to load security group %s: %w", name, err)
+	}
+
+	// Delete from the bottom so the remaining positions stay valid.
+	for idx := len(group.Rules) - 1; idx >= 0; idx-- {
+		if err = group.RuleDelete(api.bg, group.Rules[idx].Pos); err != nil {
+			return fmt.Errorf("failed to delete rule %d of security group %s: %w", group.Rules[idx].Pos, name, err)
+		}
+	}
+
+	// Proxmox inserts new rules at the top, so add them last to first.
+	for idx := len(rules) - 1; idx >= 0; idx-- {
+		if err = group.RuleCreate(api.bg, rules[idx]); err != nil {
+			return fmt.Errorf("failed to add rule to security group %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteSecurityGroup removes a cluster security group and its rules. Missing groups are ignored.
+func (api *ProxmoxAPI) DeleteSecurityGroup(name string) (err error) {
+	var group *proxmox.FirewallSecurityGroup
+	if group, err = api.Cluster.FWGroup(api.bg, name); err != nil {
+		if isMissing(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to load security group %s: %w", name, err)
+	}
+
+	for idx := len(group.Rules) - 1; idx >= 0; idx-- {
+		if err = group.RuleDelete(api.bg, group.Rules[idx].Pos); err != nil {
+			return fmt.Errorf("failed to delete rule %d of security group %s: %w", group.Rules[idx].Pos, name, err)
+		}
+	}
+
+	if err = group.Delete(api.bg); err != nil && !isMissing(err) {
+		return fmt.Errorf("failed to delete security group %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// SetGuestFirewall replaces the guest's rules whose comment starts with managedPrefix with rules (kept in order, above
+// any rules added by hand) and applies the firewall options.
+func (api *ProxmoxAPI) SetGuestFirewall(g *Guest, options GuestFirewallOptions, managedPrefix string, rules []*proxmox.FirewallRule) (err error) {
+	var path = g.apiPath() + "/firewall"
+
+	var existing []*proxmox.FirewallRule
+	if err = api.client.Get(api.bg, path+"/rules", &existing); err != nil {
+		return fmt.Errorf("failed to list firewall rules of guest %d: %w", g.ID(), err)
+	}
+
+	for idx := len(existing) - 1; idx >= 0; idx-- {
+		if !strings.HasPrefix(existing[idx].Comment, managedPrefix) {
+			continue
+		}
+
+		if err = api.client.Delete(api.bg, fmt.Sprintf("%s/rules/%d", path, existing[idx].Pos), nil); err != nil {
+			return fmt.Errorf("failed to delete firewall rule %d of guest %d: %w", existing[idx].Pos, g.ID(), err)
+		}
+	}
+
+	for idx := len(rules) - 1; idx >= 0; idx-- {
+		if err = api.client.Post(api.bg, path+"/rules", rules[idx], nil); err != nil {
+			return fmt.Errorf("failed to add firewall rule to guest %d: %w", g.ID(), err)
+		}
+	}
+
+	var enable = 0
+	if options.Enable {
+		enable = 1
+	}
+
+	if err = api.client.Put(api.bg, path+"/options", map[string]any{
+		"enable":     enable,
+		"policy_in":  options.PolicyIn,
+		"policy_out": options.PolicyOut,
+	}, nil); err != nil {
+		return fmt.Errorf("failed to set firewall options of guest %d: %w", g.ID(), err)
+	}
+
+	return nil
+}
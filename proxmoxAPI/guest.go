@@ -0,0 +1,240 @@
+package proxmoxAPI
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/luthermonson/go-proxmox"
+)
+
+const (
+	GuestKindContainer = "lxc"
+	GuestKindVM        = "vm"
+)
+
+// Guest is either an LXC container or a KVM virtual machine. Exactly one of Container and VM is set.
+type Guest struct {
+	Container *proxmox.Container
+	VM        *proxmox.VirtualMachine
+}
+
+func ContainerGuest(ct *proxmox.Container) *Guest {
+	return &Guest{Container: ct}
+}
+
+func VMGuest(vm *proxmox.VirtualMachine) *Guest {
+	return &Guest{VM: vm}
+}
+
+// apiPath returns the guest's base path in the Proxmox API.
+func (g *Guest) apiPath() string {
+	if g.VM != nil {
+		return fmt.Sprintf("/nodes/%s/qemu/%d", g.NodeName(), g.ID())
+	}
+	return fmt.Sprintf("/nodes/%s/lxc/%d", g.NodeName(), g.ID())
+}
+
+// NormalizeGuestKind maps a config.json kind to GuestKindContainer or GuestKindVM. Empty means a container.
+func NormalizeGuestKind(raw string) (string, error) {
+	switch kind := strings.ToLower(strings.TrimSpace(raw)); kind {
+	case "", GuestKindContainer, "container", "ct":
+		return GuestKindContainer, nil
+	case GuestKindVM, "qemu", "kvm":
+		return GuestKindVM, nil
+	default:
+		return "", fmt.Errorf("unknown kind %q (expected %q or %q)", raw, GuestKindContainer, GuestKindVM)
+	}
+}
+
+func (g *Guest) Kind() string {
+	if g.VM != nil {
+		return GuestKindVM
+	}
+	return GuestKindContainer
+}
+
+// IsWindows reports whether the guest is a VM with a Windows OS type. Only VMs loaded by ID carry their config, so
+// VMs from a node listing always count as Linux.
+func (g *Guest) IsWindows() bool {
+	return g.VM != nil && g.VM.VirtualMachineConfig != nil && IsWindowsOSType(g.VM.VirtualMachineConfig.OSType)
+}
+
+func (g *Guest) ID() int {
+	if g.VM != nil {
+		return int(g.VM.VMID)
+	}
+	return int(g.Container.VMID)
+}
+
+func (g *Guest) NodeName() string {
+	if g.VM != nil {
+		return g.VM.Node
+	}
+	return g.Container.Node
+}
+
+func (g *Guest) Name() string {
+	if g.VM != nil {
+		return strings.TrimSpace(g.VM.Name)
+	}
+
+	name := strings.TrimSpace(g.Container.Name)
+	if name == "" && g.Container.ContainerConfig != nil {
+		name = strings.TrimSpace(g.Container.ContainerConfig.Hostname)
+	}
+	return name
+}
+
+func (g *Guest) Status() string {
+	if g.VM != nil {
+		return g.VM.Status
+	}
+	return g.Container.Status
+}
+
+// Guest returns the container or VM with the given ID.
+func (api *ProxmoxAPI) Guest(id int) (guest *Guest, err error) {
+	var ct *proxmox.Container
+	if ct, err = api.Container(id); err == nil {
+		return ContainerGuest(ct), nil
+	}
+
+	var vm *proxmox.VirtualMachine
+	if vm, err = api.VirtualMachine(id); err == nil {
+		return VMGuest(vm), nil
+	}
+
+	return nil, err
+}
+
+// GetGuests returns every container and VM whose ID is in ids.
+func (api *ProxmoxAPI) GetGuests(ids []int) (guests []*Guest, err error) {
+	guests = make([]*Guest, 0, len(ids))
+
+	for _, node := range api.Nodes {
+		var nodeContainers []*proxmox.Container
+		if nodeContainers, err = node.Containers(api.bg); err != nil {
+			err = fmt.Errorf("failed to get containers for node %s: %w", node.Name, err)
+			return
+		}
+
+		for _, ct := range nodeContainers {
+			if slices.Contains(ids, int(ct.VMID)) {
+				guests = append(guests, ContainerGuest(ct))
+			}
+		}
+
+		var nodeVMs proxmox.VirtualMachines
+		if nodeVMs, err = node.VirtualMachines(api.bg); err != nil {
+			err = fmt.Errorf("failed to get virtual machines for node %s: %w", node.Name, err)
+			return
+		}
+
+		for _, vm := range nodeVMs {
+			if slices.Contains(ids, int(vm.VMID)) {
+				guests = append(guests, VMGuest(vm))
+			}
+		}
+	}
+
+	return
+}
+
+func (api *ProxmoxAPI) StartGuest(g *Guest) error {
+	if g.VM != nil {
+		return api.StartVM(g.VM)
+	}
+	return api.StartContainer(g.Container)
+}
+
+func (api *ProxmoxAPI) StopGuest(g *Guest) error {
+	if g.VM != nil {
+		return api.StopVM(g.VM)
+	}
+	return api.StopContainer(g.Container)
+}
+
+func (api *ProxmoxAPI) DeleteGuest(g *Guest) error {
+	if g.VM != nil {
+		return api.DeleteVM(g.VM)
+	}
+	return api.DeleteContainer(g.Container)
+}
+
+// ExecuteGuestWithRetries runs a command inside the guest: through the console for containers, and through the guest
+// agent for VMs (which ignores username and password).
+func (api *ProxmoxAPI) ExecuteGuestWithRetries(g *Guest, username, password, command string, numRetries int) (stdout string, stderr string, exitCode int, err error) {
+	if g.VM == nil {
+		return api.RawExecuteWithRetries(g.Container, username, password, command, numRetries)
+	}
+
+	for i := range numRetries + 1 {
+		if stdout, stderr, exitCode, err = api.AgentExecute(g.VM, command); err == nil {
+			return
+		}
+
+		time.Sleep(time.Second * (time.Duration(i) + 1))
+	}
+
+	return
+}
+
+// SnapshotGuest takes a disk-only snapshot of the guest. The storage pool must support snapshots.
+func (api *ProxmoxAPI) SnapshotGuest(g *Guest, name string) (err error) {
+	var task *proxmox.Task
+	if g.VM != nil {
+		task, err = g.VM.NewSnapshot(api.bg, name)
+	} else {
+		task, err = g.Container.NewSnapshot(api.bg, name)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("failed to snapshot guest: %w", err)
+	} else if err = task.Wait(api.bg, time.Second, time.Minute*5); err != nil {
+		err = fmt.Errorf("failed to wait for snapshot task: %w", err)
+	}
+
+	return
+}
+
+// HasSnapshot reports whether the guest has a snapshot with the given name.
+func (api *ProxmoxAPI) HasSnapshot(g *Guest, name string) (found bool, err error) {
+	var path = g.apiPath() + "/snapshot"
+
+	// The snapshot list names entries "name", which proxmox.ContainerSnapshot does not decode, so read it directly.
+	var snapshots []struct {
+		Name string `json:"name"`
+	}
+	if err = api.client.Get(api.bg, path, &snapshots); err != nil {
+		err = fmt.Errorf("failed to list snapshots: %w", err)
+		return
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.Name == name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// RollbackGuest restores the guest to the named snapshot, leaving it stopped.
+func (api *ProxmoxAPI) RollbackGuest(g *Guest, name string) (err error) {
+	var task *proxmox.Task
+	if g.VM != nil {
+		task, err = g.VM.SnapshotRollback(api.bg, name)
+	} else {
+		task, err = g.Container.RollbackSnapshot(api.bg, name, false)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("failed to roll back guest: %w", err)
+	} else if err = task.Wait(api.bg, time.Second, time.Minute*5); err != nil {
+		err = fmt.Errorf("failed to wait for rollback task: %w", err)
+	}
+
+	return
+}
@@ -0,0 +1,100 @@
+package proxmoxAPI
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/ssh"
+)
+
+// ErrNodeExecDisabled is returned by NodeExecute for containers when [proxmox.node_exec] is not enabled.
+var ErrNodeExecDisabled = errors.New("node exec is not enabled (see [proxmox.node_exec] in config.toml)")
+
+// NodeExecute runs a shell command inside the guest without any guest credentials: containers through pct exec over
+// SSH to the node hosting them, VMs through the guest agent. Teams changing passwords inside the guest cannot break it.
+// Nodes must present a host key listed in [proxmox.node_exec] known_hosts_path.
+func (api *ProxmoxAPI) NodeExecute(g *Guest, command string) (stdout string, stderr string, exitCode int, err error) {
+	if g.VM != nil {
+		return api.AgentExecute(g.VM, command)
+	}
+
+	var settings = config.Config.Proxmox.NodeExec
+	if !settings.Enabled {
+		return "", "", -1, ErrNodeExecDisabled
+	}
+
+	var (
+		nodeName, host string
+		vmid           int
+	)
+	if nodeName, host, vmid, err = api.resolveNodeAndHost(g.Container); err != nil {
+		return "", "", -1, err
+	}
+
+	if override := strings.TrimSpace(settings.Hosts[nodeName]); override != "" {
+		host = override
+	}
+
+	var key []byte
+	if key, err = os.ReadFile(settings.PrivateKeyPath); err != nil {
+		return "", "", -1, fmt.Errorf("read node exec key: %w", err)
+	}
+
+	auth, err := ssh.PrivateKeyAuth(key)
+	if err != nil {
+		return "", "", -1, err
+	}
+
+	hostKey, err := ssh.KnownHosts(settings.KnownHostsPath)
+	if err != nil {
+		return "", "", -1, fmt.Errorf("node exec: %w", err)
+	}
+
+	conn, err := ssh.ConnectVerified(settings.User, host, settings.Port, hostKey, auth)
+	if err != nil {
+		return "", "", -1, fmt.Errorf("connect to node %s over SSH: %w", nodeName, err)
+	}
+
+	var (
+		timedOut  atomic.Bool
+		closeOnce sync.Once
+		closeConn = func() { closeOnce.Do(func() { _ = conn.Close() }) }
+		timer     = time.AfterFunc(api.getCommandTimeout(), func() {
+			timedOut.Store(true)
+			closeConn()
+		})
+	)
+	defer closeConn()
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	exitCode, err = conn.SendWithStreams(PctExecCommand(vmid, command, settings.User != "root"), &stdoutBuf, &stderrBuf)
+	timer.Stop()
+
+	if timedOut.Load() {
+		err = fmt.Errorf("pct exec in container %d timed out after %s", vmid, api.getCommandTimeout())
+	}
+
+	return stdoutBuf.String(), stderrBuf.String(), exitCode, err
+}
+
+// PctExecCommand builds the node-side command that runs command through /bin/sh inside container vmid.
+func PctExecCommand(vmid int, command string, sudo bool) string {
+	var pct = fmt.Sprintf("pct exec %d -- /bin/sh -c %s", vmid, shellQuote(command))
+	if sudo {
+		return "sudo -n " + pct
+	}
+
+	return pct
+}
+
+// shellQuote wraps s in single quotes for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
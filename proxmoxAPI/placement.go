@@ -0,0 +1,301 @@
+package proxmoxAPI
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/luthermonson/go-proxmox"
+)
+
+const (
+	PlacementBalanced   = "balanced"
+	PlacementRoundRobin = "round_robin"
+
+	nodeStatsTTL          = 10 * time.Second
+	placementReservedTTL  = 2 * time.Minute
+	placementMemoryWeight = 0.5
+	placementCPUWeight    = 0.3
+	placementDiskWeight   = 0.2
+)
+
+// PlacementRequest describes what a new container needs from the node it is placed on.
+type PlacementRequest struct {
+	MemoryMB      int
+	Cores         int
+	StoragePool   string
+	StorageSizeGB int
+	PinnedNode    string // Always place on this node when set
+	AffinityKey   string // Containers sharing a key are placed on the same node
+}
+
+// NodeStats is the capacity snapshot placement decisions are made from.
+type NodeStats struct {
+	Name           string
+	FreeMemoryMB   int64
+	TotalMemoryMB  int64
+	CPUUsage       float64 // 0..1 across all cores
+	FreeStorageGB  int64
+	TotalStorageGB int64
+	StorageKnown   bool // False when the storage pool could not be queried on this node
+}
+
+type placementReservation struct {
+	memoryMB  int64
+	storageGB int64
+	expires   time.Time
+}
+
+type placementState struct {
+	mu           sync.Mutex
+	stats        map[string]NodeStats // Keyed by node + "/" + storage pool
+	fetchedAt    map[string]time.Time
+	reservations map[string][]placementReservation
+	affinity     map[string]string
+}
+
+// init creates any missing maps. Callers hold mu.
+func (state *placementState) init() {
+	if state.stats == nil {
+		state.stats = make(map[string]NodeStats)
+		state.fetchedAt = make(map[string]time.Time)
+	}
+	if state.reservations == nil {
+		state.reservations = make(map[string][]placementReservation)
+	}
+	if state.affinity == nil {
+		state.affinity = make(map[string]string)
+	}
+}
+
+// PlaceContainer picks the node a new container should be created on. Pinned nodes win, then an existing affinity
+// assignment, then the configured strategy.
+func (api *ProxmoxAPI) PlaceContainer(req PlacementRequest) (*proxmox.Node, error) {
+	if len(api.Nodes) == 0 {
+		return nil, fmt.Errorf("no proxmox nodes available")
+	}
+
+	if pinned := strings.TrimSpace(req.PinnedNode); pinned != "" {
+		node := api.NodeByName(pinned)
+		if node == nil {
+			return nil, fmt.Errorf("pinned node %s not found", pinned)
+		}
+
+		api.reserve(node.Name, req)
+		return node, nil
+	}
+
+	api.placement.mu.Lock()
+	api.placement.init()
+	assigned := ""
+	if req.AffinityKey != "" {
+		assigned = api.placement.affinity[req.AffinityKey]
+	}
+	api.placement.mu.Unlock()
+
+	if assigned != "" {
+		if node := api.NodeByName(assigned); node != nil {
+			api.reserve(node.Name, req)
+			return node, nil
+		}
+	}
+
+	var node *proxmox.Node
+	if strings.EqualFold(config.Config.Provisioning.Placement, PlacementRoundRobin) {
+		node = api.NextNode()
+	} else {
+		stats := api.collectNodeStats(req.StoragePool)
+		if name, ok := ChooseNode(stats, req); ok {
+			node = api.NodeByName(name)
+		}
+		if node == nil {
+			node = api.NextNode()
+		}
+	}
+
+	if node == nil {
+		return nil, fmt.Errorf("no proxmox nodes available")
+	}
+
+	api.placement.mu.Lock()
+	if req.AffinityKey != "" {
+		if existing := api.placement.affinity[req.AffinityKey]; existing != "" {
+			// Another container of the same group was placed while stats were being collected.
+			api.placement.mu.Unlock()
+			if pinned := api.NodeByName(existing); pinned != nil {
+				node = pinned
+			}
+		} else {
+			api.placement.affinity[req.AffinityKey] = node.Name
+			api.placement.mu.Unlock()
+		}
+	} else {
+		api.placement.mu.Unlock()
+	}
+
+	api.reserve(node.Name, req)
+	return node, nil
+}
+
+// SetPlacementAffinity records that containers with the given key belong on nodeName, e.g. when a team already has
+// containers on a node from an earlier provisioning run.
+func (api *ProxmoxAPI) SetPlacementAffinity(key, nodeName string) {
+	if key == "" || nodeName == "" {
+		return
+	}
+
+	api.placement.mu.Lock()
+	defer api.placement.mu.Unlock()
+
+	api.placement.init()
+	api.placement.affinity[key] = nodeName
+}
+
+// ChooseNode ranks nodes for a placement request. Nodes with too little free memory or storage are only used when no
+// node has room; among the rest the node with the most headroom (memory, then CPU, then storage) wins.
+func ChooseNode(stats []NodeStats, req PlacementRequest) (string, bool) {
+	if len(stats) == 0 {
+		return "", false
+	}
+
+	type candidate struct {
+		name  string
+		fits  bool
+		score float64
+	}
+
+	candidates := make([]candidate, 0, len(stats))
+	for _, node := range stats {
+		fits := node.FreeMemoryMB >= int64(req.MemoryMB)
+		if node.StorageKnown && node.FreeStorageGB < int64(req.StorageSizeGB) {
+			fits = false
+		}
+
+		var memFrac, diskFrac float64
+		if node.TotalMemoryMB > 0 {
+			memFrac = float64(node.FreeMemoryMB) / float64(node.TotalMemoryMB)
+		}
+		if node.StorageKnown && node.TotalStorageGB > 0 {
+			diskFrac = float64(node.FreeStorageGB) / float64(node.TotalStorageGB)
+		} else {
+			diskFrac = 0.5
+		}
+
+		cpuFree := 1 - node.CPUUsage
+		if cpuFree < 0 {
+			cpuFree = 0
+		}
+
+		candidates = append(candidates, candidate{
+			name:  node.Name,
+			fits:  fits,
+			score: memFrac*placementMemoryWeight + cpuFree*placementCPUWeight + diskFrac*placementDiskWeight,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].fits != candidates[j].fits {
+			return candidates[i].fits
+		}
+		if candidates[i].score == candidates[j].score {
+			return candidates[i].name < candidates[j].name
+		}
+		return candidates[i].score > candidates[j].score
+	})
+
+	return candidates[0].name, true
+}
+
+// collectNodeStats returns the capacity of every online node, minus recent placements Proxmox may not report yet.
+func (api *ProxmoxAPI) collectNodeStats(storagePool string) []NodeStats {
+	var stats = make([]NodeStats, 0, len(api.Nodes))
+	for _, node := range api.Nodes {
+		if node == nil {
+			continue
+		}
+
+		current, err := api.nodeStats(node, storagePool)
+		if err != nil {
+			continue
+		}
+
+		stats = append(stats, current)
+	}
+
+	api.placement.mu.Lock()
+	defer api.placement.mu.Unlock()
+
+	api.placement.init()
+	now := time.Now()
+	for idx := range stats {
+		active := api.placement.reservations[stats[idx].Name][:0]
+		for _, reservation := range api.placement.reservations[stats[idx].Name] {
+			if reservation.expires.Before(now) {
+				continue
+			}
+
+			active = append(active, reservation)
+			stats[idx].FreeMemoryMB -= reservation.memoryMB
+			stats[idx].FreeStorageGB -= reservation.storageGB
+		}
+		api.placement.reservations[stats[idx].Name] = active
+	}
+
+	return stats
+}
+
+func (api *ProxmoxAPI) nodeStats(node *proxmox.Node, storagePool string) (NodeStats, error) {
+	var key = node.Name + "/" + storagePool
+
+	api.placement.mu.Lock()
+	api.placement.init()
+	if fetched, ok := api.placement.fetchedAt[key]; ok && time.Since(fetched) < nodeStatsTTL {
+		cached := api.placement.stats[key]
+		api.placement.mu.Unlock()
+		return cached, nil
+	}
+	api.placement.mu.Unlock()
+
+	// Status refreshes the node in place, so query a copy instead of mutating the shared node.
+	var fresh = *node
+	if err := fresh.Status(api.bg); err != nil {
+		return NodeStats{}, err
+	}
+
+	stats := NodeStats{
+		Name:          node.Name,
+		FreeMemoryMB:  int64(fresh.Memory.Free / (1024 * 1024)),
+		TotalMemoryMB: int64(fresh.Memory.Total / (1024 * 1024)),
+		CPUUsage:      fresh.CPU,
+	}
+
+	if storagePool != "" {
+		if storage, err := fresh.Storage(api.bg, storagePool); err == nil && storage != nil {
+			stats.StorageKnown = true
+			stats.FreeStorageGB = int64(storage.Avail / (1024 * 1024 * 1024))
+			stats.TotalStorageGB = int64(storage.Total / (1024 * 1024 * 1024))
+		}
+	}
+
+	api.placement.mu.Lock()
+	api.placement.stats[key] = stats
+	api.placement.fetchedAt[key] = time.Now()
+	api.placement.mu.Unlock()
+
+	return stats, nil
+}
+
+func (api *ProxmoxAPI) reserve(nodeName string, req PlacementRequest) {
+	api.placement.mu.Lock()
+	defer api.placement.mu.Unlock()
+
+	api.placement.init()
+	api.placement.reservations[nodeName] = append(api.placement.reservations[nodeName], placementReservation{
+		memoryMB:  int64(req.MemoryMB),
+		storageGB: int64(req.StorageSizeGB),
+		expires:   time.Now().Add(placementReservedTTL),
+	})
+}
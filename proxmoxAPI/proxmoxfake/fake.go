@@ -0,0 +1,741 @@
+package proxmoxfake
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/luthermonson/go-proxmox"
+)
+
+// ExecResult is the scripted outcome of a command run inside a Cluster guest.
+type ExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Err      error
+}
+
+// Exec records one command run inside a Cluster guest.
+type Exec struct {
+	GuestID  int
+	Hostname string
+	Command  string
+	Node     bool // Run through NodeExecute rather than the console
+}
+
+type fakeExecRule struct {
+	hostname string
+	match    string
+	result   ExecResult
+}
+
+type fakeGuest struct {
+	ct        *proxmox.Container
+	vm        *proxmox.VirtualMachine
+	template  bool
+	snapshots []string
+	firewall  []*proxmox.FirewallRule
+	password  string // Console root password set by ChangeRootPassword; empty accepts any
+}
+
+func (g *fakeGuest) status() string {
+	if g.vm != nil {
+		return g.vm.Status
+	}
+	return g.ct.Status
+}
+
+func (g *fakeGuest) setStatus(status string) {
+	if g.vm != nil {
+		g.vm.Status = status
+	} else {
+		g.ct.Status = status
+	}
+}
+
+func (g *fakeGuest) name() string {
+	if g.vm != nil {
+		return g.vm.Name
+	}
+	return g.ct.Name
+}
+
+// guest returns a copy of the guest, so callers never share state with the simulation.
+func (g *fakeGuest) guest() *proxmoxAPI.Guest {
+	if g.vm != nil {
+		var vm = *g.vm
+		return proxmoxAPI.VMGuest(&vm)
+	}
+
+	var ct = *g.ct
+	return proxmoxAPI.ContainerGuest(&ct)
+}
+
+// Cluster is an in-memory proxmoxAPI.Backend. It tracks guests, their power state and snapshots, and the firewall and
+// SDN objects koth manages, and answers commands run in guests from scripted results instead of a console. It is safe
+// for concurrent use.
+type Cluster struct {
+	mu          sync.Mutex
+	nodes       []*proxmox.Node
+	nodeRotator int
+	nextID      int
+	guests      map[int]*fakeGuest
+	affinity    map[string]string
+	execRules   []fakeExecRule
+	execs       []Exec
+	ipSets      map[string][]string
+	groups      map[string][]*proxmox.FirewallRule
+	vnets       map[string][]string // VNet name to its subnets
+	firewallErr map[string]error    // Guest name to the error SetGuestFirewall returns for it
+}
+
+// New returns an empty simulated cluster with the given nodes ("pve" when none are named).
+func New(nodeNames ...string) *Cluster {
+	if len(nodeNames) == 0 {
+		nodeNames = []string{"pve"}
+	}
+
+	var f = &Cluster{
+		nextID:      100,
+		guests:      make(map[int]*fakeGuest),
+		affinity:    make(map[string]string),
+		ipSets:      make(map[string][]string),
+		groups:      make(map[string][]*proxmox.FirewallRule),
+		vnets:       make(map[string][]string),
+		firewallErr: make(map[string]error),
+	}
+
+	for _, name := range nodeNames {
+		f.nodes = append(f.nodes, &proxmox.Node{Name: name})
+	}
+
+	return f
+}
+
+// FailGuestFirewall makes SetGuestFirewall fail with err for the guest named hostname, or succeed again when err is
+// nil.
+func (f *Cluster) FailGuestFirewall(hostname string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		delete(f.firewallErr, hostname)
+	} else {
+		f.firewallErr[hostname] = err
+	}
+}
+
+// OnExec scripts the result of commands containing match that run on the guest named hostname, or on any guest when
+// hostname is empty. Later rules take precedence; unmatched commands succeed with no output.
+func (f *Cluster) OnExec(hostname, match string, result ExecResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.execRules = append(f.execRules, fakeExecRule{hostname: hostname, match: match, result: result})
+}
+
+// Execs returns every command run so far, in order.
+func (f *Cluster) Execs() []Exec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.execs)
+}
+
+// GuestIDs returns the IDs of every guest, including templates, in ascending order.
+func (f *Cluster) GuestIDs() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ids = make([]int, 0, len(f.guests))
+	for id := range f.guests {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+	return ids
+}
+
+// GuestStatus returns the power state of a guest ("running" or "stopped"), or "" when it does not exist.
+func (f *Cluster) GuestStatus(id int) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if g, ok := f.guests[id]; ok {
+		return g.status()
+	}
+	return ""
+}
+
+// IPSet returns the entries of a cluster IPSet and whether it exists.
+func (f *Cluster) IPSet(name string) ([]string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cidrs, ok := f.ipSets[name]
+	return slices.Clone(cidrs), ok
+}
+
+// SecurityGroup returns the rules of a security group and whether it exists.
+func (f *Cluster) SecurityGroup(name string) ([]*proxmox.FirewallRule, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rules, ok := f.groups[name]
+	return slices.Clone(rules), ok
+}
+
+// GuestFirewall returns the firewall rules of a guest, in order, and whether the guest exists.
+func (f *Cluster) GuestFirewall(id int) ([]*proxmox.FirewallRule, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	g, ok := f.guests[id]
+	if !ok {
+		return nil, false
+	}
+	return slices.Clone(g.firewall), true
+}
+
+// VNets returns the names of every SDN VNet in ascending order.
+func (f *Cluster) VNets() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var names = make([]string, 0, len(f.vnets))
+	for name := range f.vnets {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+func (f *Cluster) lookup(id int) (*fakeGuest, error) {
+	g, ok := f.guests[id]
+	if !ok {
+		return nil, fmt.Errorf("guest %d: %w", id, proxmox.ErrNotFound)
+	}
+	return g, nil
+}
+
+func (f *Cluster) nodeNamed(name string) *proxmox.Node {
+	for _, node := range f.nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return nil
+}
+
+func (f *Cluster) allocateID(requested int) (int, error) {
+	if requested <= 0 {
+		for f.guests[f.nextID] != nil {
+			f.nextID++
+		}
+		requested = f.nextID
+		f.nextID++
+	} else if f.guests[requested] != nil {
+		return 0, fmt.Errorf("container ID %d is already in use", requested)
+	}
+
+	return requested, nil
+}
+
+func (f *Cluster) NodeByName(name string) *proxmox.Node {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.nodeNamed(name)
+}
+
+// PlaceContainer honours pinned nodes and affinity keys and otherwise rotates through the nodes.
+func (f *Cluster) PlaceContainer(req proxmoxAPI.PlacementRequest) (*proxmox.Node, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if pinned := strings.TrimSpace(req.PinnedNode); pinned != "" {
+		if node := f.nodeNamed(pinned); node != nil {
+			return node, nil
+		}
+		return nil, fmt.Errorf("pinned node %s not found", pinned)
+	}
+
+	if node := f.nodeNamed(f.affinity[req.AffinityKey]); req.AffinityKey != "" && node != nil {
+		return node, nil
+	}
+
+	var node = f.nodes[f.nodeRotator%len(f.nodes)]
+	f.nodeRotator++
+	if req.AffinityKey != "" {
+		f.affinity[req.AffinityKey] = node.Name
+	}
+
+	return node, nil
+}
+
+func (f *Cluster) SetPlacementAffinity(key, nodeName string) {
+	if key == "" || nodeName == "" {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.affinity[key] = nodeName
+}
+
+func (f *Cluster) CreateContainer(node *proxmox.Node, conf *proxmoxAPI.ContainerCreateOptions) (*proxmoxAPI.ProxmoxAPICreateResult, error) {
+	return f.CreateContainerWithID(node, conf, 0)
+}
+
+func (f *Cluster) CreateContainerWithID(node *proxmox.Node, conf *proxmoxAPI.ContainerCreateOptions, ctID int) (*proxmoxAPI.ProxmoxAPICreateResult, error) {
+	if node == nil {
+		return nil, fmt.Errorf("failed to create container: no node")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id, err := f.allocateID(ctID)
+	if err != nil {
+		return nil, err
+	}
+
+	var g = &fakeGuest{ct: &proxmox.Container{
+		Name:            conf.Hostname,
+		Node:            node.Name,
+		Status:          "stopped",
+		VMID:            proxmox.StringOrUint64(id),
+		ContainerConfig: &proxmox.ContainerConfig{Hostname: conf.Hostname},
+	}}
+	f.guests[id] = g
+
+	var created = g.guest()
+	return &proxmoxAPI.ProxmoxAPICreateResult{Container: created.Container, CTID: id}, nil
+}
+
+func (f *Cluster) CreateVM(node *proxmox.Node, conf *proxmoxAPI.VMCreateOptions, vmID int) (*proxmoxAPI.ProxmoxAPICreateResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	template, err := f.lookup(conf.TemplateID)
+	if err != nil || template.vm == nil {
+		return nil, fmt.Errorf("failed to find template VM %d", conf.TemplateID)
+	}
+
+	var nodeName = template.vm.Node
+	if node != nil {
+		nodeName = node.Name
+	}
+
+	id, err := f.allocateID(vmID)
+	if err != nil {
+		return nil, err
+	}
+
+	var g = &fakeGuest{vm: &proxmox.VirtualMachine{
+		Name:                 conf.Name,
+		Node:                 nodeName,
+		Status:               "stopped",
+		VMID:                 proxmox.StringOrUint64(id),
+		VirtualMachineConfig: &proxmox.VirtualMachineConfig{OSType: template.vm.VirtualMachineConfig.OSType},
+	}}
+	f.guests[id] = g
+
+	return &proxmoxAPI.ProxmoxAPICreateResult{VM: g.guest().VM, CTID: id}, nil
+}
+
+// AddTemplateVM registers a template VM with the given Proxmox OS type (l26, win11, ...) that CreateVM can clone.
+func (f *Cluster) AddTemplateVM(id int, node, name, osType string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.guests[id] = &fakeGuest{
+		vm: &proxmox.VirtualMachine{
+			Name:                 name,
+			Node:                 node,
+			Status:               "stopped",
+			VMID:                 proxmox.StringOrUint64(id),
+			VirtualMachineConfig: &proxmox.VirtualMachineConfig{OSType: osType},
+		},
+		template: true,
+	}
+}
+
+func (f *Cluster) CreateTemplate(ct *proxmox.Container) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	g, err := f.lookup(int(ct.VMID))
+	if err != nil {
+		return err
+	}
+	if g.status() == "running" {
+		return fmt.Errorf("container %d is running", ct.VMID)
+	}
+
+	g.template = true
+	return nil
+}
+
+func (f *Cluster) CloneTemplate(ct *proxmox.Container, node *proxmox.Node, hostname string, full bool) (*proxmox.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	source, err := f.lookup(int(ct.VMID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone container: %w", err)
+	}
+	if !source.template || source.ct == nil {
+		return nil, fmt.Errorf("failed to clone container: %d is not a container template", ct.VMID)
+	}
+
+	var target = source.ct.Node
+	if node != nil {
+		target = node.Name
+	}
+
+	id, _ := f.allocateID(0)
+	var g = &fakeGuest{ct: &proxmox.Container{
+		Name:            hostname,
+		Node:            target,
+		Status:          "stopped",
+		VMID:            proxmox.StringOrUint64(id),
+		ContainerConfig: &proxmox.ContainerConfig{Hostname: hostname},
+	}}
+	f.guests[id] = g
+
+	return g.guest().Container, nil
+}
+
+func (f *Cluster) ChangeContainerNetworking(ct *proxmox.Container, conf *proxmoxAPI.ContainerCreateOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err := f.lookup(int(ct.VMID))
+	return err
+}
+
+func (f *Cluster) Guest(id int) (*proxmoxAPI.Guest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	g, err := f.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	return g.guest(), nil
+}
+
+func (f *Cluster) GetGuests(ids []int) ([]*proxmoxAPI.Guest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var guests = make([]*proxmoxAPI.Guest, 0, len(ids))
+	for _, id := range ids {
+		if g, ok := f.guests[id]; ok {
+			guests = append(guests, g.guest())
+		}
+	}
+
+	return guests, nil
+}
+
+func (f *Cluster) setPower(id int, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	g, err := f.lookup(id)
+	if err != nil {
+		return err
+	}
+	if g.template {
+		return fmt.Errorf("guest %d is a template", id)
+	}
+
+	g.setStatus(status)
+	return nil
+}
+
+func (f *Cluster) StartContainer(ct *proxmox.Container) error {
+	return f.setPower(int(ct.VMID), "running")
+}
+
+func (f *Cluster) StopContainer(ct *proxmox.Container) error {
+	return f.setPower(int(ct.VMID), "stopped")
+}
+
+func (f *Cluster) StartGuest(g *proxmoxAPI.Guest) error {
+	return f.setPower(g.ID(), "running")
+}
+
+func (f *Cluster) StopGuest(g *proxmoxAPI.Guest) error {
+	return f.setPower(g.ID(), "stopped")
+}
+
+// DeleteGuest removes a stopped guest; like Proxmox, it refuses to delete one that is running.
+func (f *Cluster) DeleteGuest(g *proxmoxAPI.Guest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.delete(g.ID())
+}
+
+func (f *Cluster) delete(id int) error {
+	g, err := f.lookup(id)
+	if err != nil {
+		return err
+	}
+	if g.status() == "running" {
+		return fmt.Errorf("guest %d is running", id)
+	}
+
+	delete(f.guests, id)
+	return nil
+}
+
+func (f *Cluster) BulkStart(ids []int) error {
+	for _, id := range ids {
+		if err := f.setPower(id, "running"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Cluster) BulkStop(ids []int) error {
+	for _, id := range ids {
+		if err := f.setPower(id, "stopped"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Cluster) BulkDelete(ids []int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, id := range ids {
+		if err := f.delete(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BulkCTActionWithRetries retries like ProxmoxAPI does, without sleeping between attempts.
+func (f *Cluster) BulkCTActionWithRetries(action func(ids []int) error, ids []int, numRetries int) (err error) {
+	for range numRetries + 1 {
+		if err = action(ids); err == nil {
+			return
+		}
+	}
+	return
+}
+
+func (f *Cluster) SnapshotGuest(g *proxmoxAPI.Guest, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fg, err := f.lookup(g.ID())
+	if err != nil {
+		return fmt.Errorf("failed to snapshot guest: %w", err)
+	}
+	if slices.Contains(fg.snapshots, name) {
+		return fmt.Errorf("failed to snapshot guest: snapshot %s already exists", name)
+	}
+
+	fg.snapshots = append(fg.snapshots, name)
+	return nil
+}
+
+func (f *Cluster) HasSnapshot(g *proxmoxAPI.Guest, name string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fg, err := f.lookup(g.ID())
+	if err != nil {
+		return false, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	return slices.Contains(fg.snapshots, name), nil
+}
+
+// RollbackGuest leaves the guest stopped, as a Proxmox rollback does.
+func (f *Cluster) RollbackGuest(g *proxmoxAPI.Guest, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fg, err := f.lookup(g.ID())
+	if err != nil {
+		return fmt.Errorf("failed to roll back guest: %w", err)
+	}
+	if !slices.Contains(fg.snapshots, name) {
+		return fmt.Errorf("failed to roll back guest: snapshot %s not found", name)
+	}
+
+	fg.setStatus("stopped")
+	return nil
+}
+
+// execute records the command and returns the newest matching scripted result. Guests must be running.
+// ChangeRootPassword simulates a team changing a container's root password: console commands logging in with any
+// other password are rejected with proxmoxAPI.ErrGuestCredentials, while NodeExecute is unaffected.
+func (f *Cluster) ChangeRootPassword(id int, password string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	g, err := f.lookup(id)
+	if err != nil {
+		return err
+	}
+
+	g.password = password
+	return nil
+}
+
+// execute runs a command in a guest. Console commands carry the password they log in with; node commands pass nil.
+func (f *Cluster) execute(id int, password *string, command string) (stdout, stderr string, exitCode int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	g, err := f.lookup(id)
+	if err != nil {
+		return "", "", -1, err
+	}
+	if g.status() != "running" {
+		return "", "", -1, fmt.Errorf("guest %d is not running", id)
+	}
+	if password != nil && g.ct != nil && g.password != "" && *password != g.password {
+		return "", "", -1, fmt.Errorf("%w: console login as root was rejected", proxmoxAPI.ErrGuestCredentials)
+	}
+
+	f.execs = append(f.execs, Exec{GuestID: id, Hostname: g.name(), Command: command, Node: password == nil})
+
+	for idx := len(f.execRules) - 1; idx >= 0; idx-- {
+		var rule = f.execRules[idx]
+		if (rule.hostname == "" || rule.hostname == g.name()) && strings.Contains(command, rule.match) {
+			return rule.result.Stdout, rule.result.Stderr, rule.result.ExitCode, rule.result.Err
+		}
+	}
+
+	return "", "", 0, nil
+}
+
+func (f *Cluster) RawExecuteWithRetries(ct *proxmox.Container, username, password, command string, numRetries int) (string, string, int, error) {
+	return f.execute(int(ct.VMID), &password, command)
+}
+
+func (f *Cluster) ExecuteGuestWithRetries(g *proxmoxAPI.Guest, username, password, command string, numRetries int) (string, string, int, error) {
+	return f.execute(g.ID(), &password, command)
+}
+
+func (f *Cluster) NodeExecute(g *proxmoxAPI.Guest, command string) (string, string, int, error) {
+	return f.execute(g.ID(), nil, command)
+}
+
+// ConsoleStats is always zero: the fake has no consoles to log into.
+func (f *Cluster) ConsoleStats() proxmoxAPI.ConsoleStats {
+	return proxmoxAPI.ConsoleStats{}
+}
+
+func (f *Cluster) WaitForGuestAgent(vm *proxmox.VirtualMachine, timeout time.Duration) error {
+	if status := f.GuestStatus(int(vm.VMID)); status != "running" {
+		return fmt.Errorf("guest agent of VM %d is not running", vm.VMID)
+	}
+	return nil
+}
+
+func (f *Cluster) EnsureIPSet(name, comment string, cidrs []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.ipSets[name] = slices.Clone(cidrs)
+	return nil
+}
+
+func (f *Cluster) DeleteIPSet(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.ipSets, name)
+	return nil
+}
+
+func (f *Cluster) ReplaceSecurityGroup(name, comment string, rules []*proxmox.FirewallRule) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.groups[name] = slices.Clone(rules)
+	return nil
+}
+
+func (f *Cluster) DeleteSecurityGroup(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.groups, name)
+	return nil
+}
+
+func (f *Cluster) SetGuestFirewall(g *proxmoxAPI.Guest, options proxmoxAPI.GuestFirewallOptions, managedPrefix string, rules []*proxmox.FirewallRule) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fg, err := f.lookup(g.ID())
+	if err != nil {
+		return err
+	}
+	if err = f.firewallErr[fg.name()]; err != nil {
+		return err
+	}
+
+	fg.firewall = slices.DeleteFunc(fg.firewall, func(rule *proxmox.FirewallRule) bool {
+		return strings.HasPrefix(rule.Comment, managedPrefix)
+	})
+	fg.firewall = append(slices.Clone(rules), fg.firewall...)
+	return nil
+}
+
+func (f *Cluster) CreateVNet(name, zone string, tag int, cidr, gateway, alias string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.vnets[name]; exists {
+		return fmt.Errorf("failed to create vnet %s: already exists", name)
+	}
+
+	f.vnets[name] = []string{cidr}
+	return nil
+}
+
+func (f *Cluster) CreateVNetSubnet(name, cidr, gateway string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.vnets[name]; !exists {
+		return fmt.Errorf("failed to create subnet %s: vnet %s not found", cidr, name)
+	}
+
+	f.vnets[name] = append(f.vnets[name], cidr)
+	return nil
+}
+
+func (f *Cluster) DeleteVNet(name, zone string, cidrs ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.vnets, name)
+	return nil
+}
+
+func (f *Cluster) ApplySDN() error {
+	return nil
+}
+
+var _ proxmoxAPI.Backend = (*Cluster)(nil)
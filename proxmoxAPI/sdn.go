@@ -0,0 +1,77 @@
+package proxmoxAPI
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/luthermonson/go-proxmox"
+)
+
+// CreateVNet creates an SDN VNet with a single subnet in an existing zone. Proxmox only rolls pending SDN changes out
+// to the nodes after ApplySDN.
+func (api *ProxmoxAPI) CreateVNet(name, zone string, tag int, cidr, gateway, alias string) (err error) {
+	if err = api.Cluster.NewSDNVNet(api.bg, &proxmox.VNetOptions{
+		Name:  name,
+		Zone:  zone,
+		Alias: alias,
+		Tag:   uint32(tag),
+		Type:  "vnet",
+	}); err != nil {
+		err = fmt.Errorf("failed to create vnet %s: %w", name, err)
+		return
+	}
+
+	return api.CreateVNetSubnet(name, cidr, gateway)
+}
+
+// CreateVNetSubnet adds another subnet, such as the IPv6 half of a dual-stack segment, to an existing VNet.
+func (api *ProxmoxAPI) CreateVNetSubnet(name, cidr, gateway string) (err error) {
+	if err = api.client.Post(api.bg, fmt.Sprintf("/cluster/sdn/vnets/%s/subnets", name), map[string]any{
+		"subnet":  cidr,
+		"type":    "subnet",
+		"gateway": gateway,
+	}, nil); err != nil {
+		err = fmt.Errorf("failed to create subnet %s on vnet %s: %w", cidr, name, err)
+	}
+
+	return
+}
+
+// DeleteVNet removes a VNet created by CreateVNet along with its subnets. VNets that no longer exist are ignored.
+func (api *ProxmoxAPI) DeleteVNet(name, zone string, cidrs ...string) (err error) {
+	for _, cidr := range cidrs {
+		var subnetID = fmt.Sprintf("%s-%s", zone, strings.Replace(cidr, "/", "-", 1))
+		if err = api.client.Delete(api.bg, fmt.Sprintf("/cluster/sdn/vnets/%s/subnets/%s", name, subnetID), nil); err != nil && !isMissing(err) {
+			return fmt.Errorf("failed to delete subnet %s of vnet %s: %w", cidr, name, err)
+		}
+	}
+
+	if err = api.Cluster.DeleteSDNVNet(api.bg, name); err != nil && !isMissing(err) {
+		return fmt.Errorf("failed to delete vnet %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// ApplySDN rolls pending SDN changes out to every node.
+func (api *ProxmoxAPI) ApplySDN() (err error) {
+	var task *proxmox.Task
+	if task, err = api.Cluster.SDNApply(api.bg); err != nil {
+		err = fmt.Errorf("failed to apply sdn configuration: %w", err)
+	} else if err = task.Wait(api.bg, time.Second, time.Minute*3); err != nil {
+		err = fmt.Errorf("failed to wait for sdn apply task: %w", err)
+	}
+
+	return
+}
+
+func isMissing(err error) bool {
+	if errors.Is(err, proxmox.ErrNotFound) {
+		return true
+	}
+
+	var msg = strings.ToLower(err.Error())
+	return strings.Contains(msg, "does not exist") || strings.Contains(msg, "not found")
+}
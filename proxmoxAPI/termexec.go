@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -31,6 +33,8 @@ type termProxySession struct {
 	User   string              `json:"user"`
 }
 
+// RawExecute runs a command through the container's console. Logged-in consoles are pooled per container and login,
+// so repeated calls skip the ticket login, termproxy and console login (see console_pool.go).
 func (api *ProxmoxAPI) RawExecute(ct *proxmox.Container, username, password, command string) (stdout string, stderr string, exitCode int, err error) {
 	var (
 		nodeName string
@@ -42,49 +46,22 @@ func (api *ProxmoxAPI) RawExecute(ct *proxmox.Container, username, password, com
 		return
 	}
 
-	baseCtx := api.bg
-	if baseCtx == nil {
-		baseCtx = context.Background()
-	}
-
-	connectCtx, cancelConnect := context.WithTimeout(baseCtx, api.getConnectTimeout())
-	defer cancelConnect()
-
-	var (
-		authTicket string
-		csrfToken  string
-	)
-	if authTicket, csrfToken, err = api.loginTicket(connectCtx, host); err != nil {
-		err = fmt.Errorf("failed to obtain PVE ticket: %w", err)
-		return
-	}
-
-	var session *termProxySession
-	if session, err = api.openTermProxy(connectCtx, host, nodeName, vmid, authTicket, csrfToken); err != nil {
-		err = fmt.Errorf("failed to open termproxy: %w", err)
+	var session *consoleSession
+	if session, err = api.checkoutConsole(consoleKey{node: nodeName, vmid: vmid, user: username, pass: password}, host); err != nil {
 		return
 	}
 
-	wsCtx, cancelWS := context.WithTimeout(baseCtx, api.getConnectTimeout())
-	defer cancelWS()
-
-	var conn *websocket.Conn
-	if conn, err = api.dialVNCWebSocket(wsCtx, host, nodeName, vmid, session, authTicket); err != nil {
-		err = fmt.Errorf("failed to dial console websocket: %w", err)
-		return
-	}
-	defer conn.Close()
+	cmdCtx, cancelCmd := context.WithTimeout(api.baseContext(), api.getCommandTimeout())
+	defer cancelCmd()
 
-	terminalUser := determineTerminalUser(session.User, api.username, api.tokenUser)
-	if terminalUser == "" {
-		err = fmt.Errorf("failed to determine terminal user for handshake")
+	if stdout, stderr, exitCode, err = api.executeAndCollect(cmdCtx, session.conn, command); err != nil {
+		// The console is in an unknown state after a failed command; never hand it out again.
+		api.consoleCounters.discarded.Add(1)
+		session.close()
 		return
 	}
 
-	cmdCtx, cancelCmd := context.WithTimeout(baseCtx, api.getCommandTimeout())
-	defer cancelCmd()
-
-	stdout, stderr, exitCode, err = api.runInteractiveCommand(cmdCtx, conn, terminalUser, session.Ticket, username, password, command)
+	api.releaseConsole(session)
 	return
 }
 
@@ -121,15 +98,16 @@ func (api *ProxmoxAPI) resolveNodeAndHost(ct *proxmox.Container) (nodeName strin
 	return
 }
 
-func (api *ProxmoxAPI) loginTicket(ctx context.Context, host string) (ticket, csrf string, err error) {
-	if api.username == "" || api.password == "" {
+// requestTicket posts to /access/ticket. Passing a still-valid ticket as the password renews it.
+func (api *ProxmoxAPI) requestTicket(ctx context.Context, host, password string) (ticket, csrf string, err error) {
+	if api.username == "" || password == "" {
 		err = fmt.Errorf("missing proxmox username/password for ticket login")
 		return
 	}
 
 	form := url.Values{}
 	form.Set("username", api.username)
-	form.Set("password", api.password)
+	form.Set("password", password)
 
 	req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://%s:%s/api2/json/access/ticket", host, api.port()), strings.NewReader(form.Encode()))
 	if reqErr != nil {
@@ -195,6 +173,9 @@ func (api *ProxmoxAPI) openTermProxy(ctx context.Context, host, node string, vmi
 		var body []byte
 		body, _ = io.ReadAll(resp.Body)
 		err = fmt.Errorf("termproxy request failed (%s): %s", resp.Status, string(body))
+		if resp.StatusCode == http.StatusUnauthorized {
+			err = fmt.Errorf("%w: %v", errTicketRejected, err)
+		}
 		return
 	}
 
@@ -215,7 +196,7 @@ func (api *ProxmoxAPI) openTermProxy(ctx context.Context, host, node string, vmi
 	return
 }
 
-func (api *ProxmoxAPI) dialVNCWebSocket(ctx context.Context, host, node string, vmid int, session *termProxySession, authTicket string) (conn *websocket.Conn, err error) {
+func (api *ProxmoxAPI) dialVNCWebSocket(ctx context.Context, host, node string, vmid int, session *termProxySession, authTicket string) (conn *consoleConn, err error) {
 	if session == nil {
 		err = fmt.Errorf("termproxy session is nil")
 		return
@@ -235,33 +216,21 @@ func (api *ProxmoxAPI) dialVNCWebSocket(ctx context.Context, host, node string,
 	headers.Set("Origin", fmt.Sprintf("https://%s:%s", host, api.port()))
 	headers.Set("User-Agent", "pve-koth/termexec")
 
-	conn, _, err = dialer.DialContext(ctx, u, headers)
+	var ws *websocket.Conn
+	if ws, _, err = dialer.DialContext(ctx, u, headers); err == nil {
+		conn = &consoleConn{Conn: ws}
+	}
 	return
 }
 
-func (api *ProxmoxAPI) runInteractiveCommand(ctx context.Context, conn *websocket.Conn, termUser, termTicket, containerUser, containerPass, command string) (stdout, stderr string, exitCode int, err error) {
-	if conn == nil {
-		err = fmt.Errorf("websocket connection is nil")
-		return
-	}
-
-	if err = api.performHandshake(ctx, conn, termUser, termTicket); err != nil {
-		return
-	}
-
-	keepAliveCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	go api.keepAlive(keepAliveCtx, conn)
-
-	if err = api.ensureConsoleLogin(ctx, conn, containerUser, containerPass); err != nil {
-		return
-	}
-
-	stdout, stderr, exitCode, err = api.executeAndCollect(ctx, conn, command)
-	return
+// consoleConn is a console websocket. Writes are serialized because a pooled console's keepalive pings share the
+// connection with the commands run on it.
+type consoleConn struct {
+	*websocket.Conn
+	writeMu sync.Mutex
 }
 
-func (api *ProxmoxAPI) performHandshake(ctx context.Context, conn *websocket.Conn, termUser, termTicket string) (err error) {
+func (api *ProxmoxAPI) performHandshake(ctx context.Context, conn *consoleConn, termUser, termTicket string) (err error) {
 	if termUser == "" || termTicket == "" {
 		err = fmt.Errorf("missing terminal user or ticket for handshake")
 		return
@@ -289,7 +258,11 @@ func (api *ProxmoxAPI) performHandshake(ctx context.Context, conn *websocket.Con
 	return
 }
 
-func (api *ProxmoxAPI) ensureConsoleLogin(ctx context.Context, conn *websocket.Conn, user, pass string) (err error) {
+// ErrGuestCredentials marks exec failures caused by the guest rejecting koth's credentials, typically because a team
+// changed the root password. Retrying with the same credentials cannot succeed.
+var ErrGuestCredentials = errors.New("guest rejected exec credentials")
+
+func (api *ProxmoxAPI) ensureConsoleLogin(ctx context.Context, conn *consoleConn, user, pass string) (err error) {
 	if user == "" {
 		err = fmt.Errorf("container login user is required")
 		return
@@ -327,7 +300,7 @@ func (api *ProxmoxAPI) ensureConsoleLogin(ctx context.Context, conn *websocket.C
 		}
 
 		if strings.Contains(lower, "login incorrect") || strings.Contains(lower, "authentication failure") {
-			err = fmt.Errorf("failed to authenticate to container console as %s", user)
+			err = fmt.Errorf("%w: console login as %s was rejected", ErrGuestCredentials, user)
 			return
 		}
 
@@ -342,7 +315,7 @@ func (api *ProxmoxAPI) ensureConsoleLogin(ctx context.Context, conn *websocket.C
 	}
 }
 
-func (api *ProxmoxAPI) executeAndCollect(ctx context.Context, conn *websocket.Conn, command string) (stdout, stderr string, exitCode int, err error) {
+func (api *ProxmoxAPI) executeAndCollect(ctx context.Context, conn *consoleConn, command string) (stdout, stderr string, exitCode int, err error) {
 	wrapped := buildWrappedCommand(command)
 	if err = api.sendInput(ctx, conn, wrapped); err != nil {
 		return
@@ -372,7 +345,7 @@ func (api *ProxmoxAPI) executeAndCollect(ctx context.Context, conn *websocket.Co
 	return
 }
 
-func (api *ProxmoxAPI) keepAlive(ctx context.Context, conn *websocket.Conn) {
+func (api *ProxmoxAPI) keepAlive(ctx context.Context, conn *consoleConn) {
 	ticker := time.NewTicker(25 * time.Second)
 	defer ticker.Stop()
 
@@ -386,12 +359,12 @@ func (api *ProxmoxAPI) keepAlive(ctx context.Context, conn *websocket.Conn) {
 	}
 }
 
-func (api *ProxmoxAPI) sendInput(ctx context.Context, conn *websocket.Conn, data string) error {
+func (api *ProxmoxAPI) sendInput(ctx context.Context, conn *consoleConn, data string) error {
 	payload := append([]byte(fmt.Sprintf("0:%d:", len(data))), []byte(data)...)
 	return api.sendFrame(ctx, conn, payload)
 }
 
-func (api *ProxmoxAPI) sendFrame(ctx context.Context, conn *websocket.Conn, payload []byte) error {
+func (api *ProxmoxAPI) sendFrame(ctx context.Context, conn *consoleConn, payload []byte) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
@@ -401,11 +374,14 @@ func (api *ProxmoxAPI) sendFrame(ctx context.Context, conn *websocket.Conn, payl
 		deadline = d
 	}
 
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+
 	_ = conn.SetWriteDeadline(deadline)
 	return conn.WriteMessage(websocket.BinaryMessage, payload)
 }
 
-func (api *ProxmoxAPI) readMessage(ctx context.Context, conn *websocket.Conn) (msg []byte, err error) {
+func (api *ProxmoxAPI) readMessage(ctx context.Context, conn *consoleConn) (msg []byte, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("websocket read panic: %v", r)
@@ -0,0 +1,293 @@
+package proxmoxAPI
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/ssh"
+	"github.com/luthermonson/go-proxmox"
+)
+
+// VMCreateOptions describes a KVM virtual machine cloned from a template VM and configured through cloud-init, or
+// cloudbase-init for Windows templates.
+type VMCreateOptions struct {
+	TemplateID       int // VMID of the template VM; it needs a cloud-init drive and the QEMU guest agent
+	StoragePool      string
+	Name             string
+	RootPassword     string
+	RootSSHPublicKey string
+	MemoryMB         int
+	Cores            int
+	GatewayIPv4      string
+	IPv4Address      string
+	CIDRBlock        int
+	NameServer       string
+	SearchDomain     string
+	Bridge           string
+	VLANTag          int
+	GatewayIPv6      string
+	IPv6Address      string
+	CIDRBlock6       int
+	Interfaces       []NetworkInterface // Extra interfaces, attached as net1/ipconfig1, net2/ipconfig2, ...
+}
+
+// CloudInitOptions returns the VM settings applied to a fresh clone of a template with the given OS type. Windows
+// clones get a config drive cloudbase-init reads, and the password and SSH key go to Administrator instead of root.
+func (c *VMCreateOptions) CloudInitOptions(osType string) (opts []proxmox.VirtualMachineOption) {
+	opts = append(opts, proxmox.VirtualMachineOption{
+		Name:  "memory",
+		Value: c.MemoryMB,
+	})
+
+	opts = append(opts, proxmox.VirtualMachineOption{
+		Name:  "cores",
+		Value: c.Cores,
+	})
+
+	opts = append(opts, proxmox.VirtualMachineOption{
+		Name:  "agent",
+		Value: "enabled=1",
+	})
+
+	opts = append(opts, proxmox.VirtualMachineOption{
+		Name:  "net0",
+		Value: "virtio," + bridgeOptions(c.Bridge, c.VLANTag),
+	})
+
+	opts = append(opts, proxmox.VirtualMachineOption{
+		Name: "ipconfig0",
+		Value: NetworkInterface{
+			IPv4Address: c.IPv4Address,
+			CIDRBlock:   c.CIDRBlock,
+			GatewayIPv4: primaryGateway(c.GatewayIPv4, c.Interfaces),
+			IPv6Address: c.IPv6Address,
+			CIDRBlock6:  c.CIDRBlock6,
+			GatewayIPv6: c.GatewayIPv6,
+		}.ipConfig(),
+	})
+
+	for idx, iface := range c.Interfaces {
+		opts = append(opts, proxmox.VirtualMachineOption{
+			Name:  fmt.Sprintf("net%d", idx+1),
+			Value: "virtio," + bridgeOptions(iface.Bridge, iface.VLANTag),
+		}, proxmox.VirtualMachineOption{
+			Name:  fmt.Sprintf("ipconfig%d", idx+1),
+			Value: iface.ipConfig(),
+		})
+	}
+
+	var user = "root"
+	if IsWindowsOSType(osType) {
+		user = "Administrator"
+		opts = append(opts, proxmox.VirtualMachineOption{
+			Name:  "citype",
+			Value: "configdrive2",
+		})
+	}
+
+	opts = append(opts, proxmox.VirtualMachineOption{
+		Name:  "ciuser",
+		Value: user,
+	})
+
+	opts = append(opts, proxmox.VirtualMachineOption{
+		Name:  "cipassword",
+		Value: c.RootPassword,
+	})
+
+	if c.RootSSHPublicKey != "" {
+		// Proxmox expects the key list URL-encoded, with spaces as %20.
+		opts = append(opts, proxmox.VirtualMachineOption{
+			Name:  "sshkeys",
+			Value: strings.ReplaceAll(url.QueryEscape(c.RootSSHPublicKey), "+", "%20"),
+		})
+	}
+
+	opts = append(opts, proxmox.VirtualMachineOption{
+		Name:  "nameserver",
+		Value: c.NameServer,
+	})
+
+	if c.SearchDomain != "" {
+		opts = append(opts, proxmox.VirtualMachineOption{
+			Name:  "searchdomain",
+			Value: c.SearchDomain,
+		})
+	}
+
+	return
+}
+
+// IsWindowsOSType reports whether a Proxmox VM OS type is a Windows one. Every Windows type (wxp, w2k8, win10, win11,
+// ...) and no other starts with "w".
+func IsWindowsOSType(osType string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(osType)), "w")
+}
+
+func (api *ProxmoxAPI) NodeForVM(vmID int) (node *proxmox.Node, err error) {
+	for _, node = range api.Nodes {
+		if _, err = node.VirtualMachine(api.bg, vmID); err == nil {
+			return
+		}
+	}
+
+	err = proxmox.ErrNotFound
+	return
+}
+
+func (api *ProxmoxAPI) VirtualMachine(vmID int) (vm *proxmox.VirtualMachine, err error) {
+	var node *proxmox.Node
+	if node, err = api.NodeForVM(vmID); err != nil {
+		return
+	}
+
+	vm, err = node.VirtualMachine(api.bg, vmID)
+	return
+}
+
+// CreateVM full-clones the template VM onto node (the template's own node when nil) and applies the cloud-init
+// settings for the template's OS type. A vmID of 0 picks the next free ID.
+func (api *ProxmoxAPI) CreateVM(node *proxmox.Node, conf *VMCreateOptions, vmID int) (result *ProxmoxAPICreateResult, err error) {
+	var template *proxmox.VirtualMachine
+	if template, err = api.VirtualMachine(conf.TemplateID); err != nil {
+		err = fmt.Errorf("failed to find template VM %d: %w", conf.TemplateID, err)
+		return
+	}
+
+	if node == nil {
+		if node = api.NodeByName(template.Node); node == nil {
+			err = fmt.Errorf("node %s not found", template.Node)
+			return
+		}
+	}
+
+	api.createLock.Lock()
+	defer api.createLock.Unlock()
+
+	if vmID > 0 {
+		var isFree bool
+		if isFree, err = api.Cluster.CheckID(api.bg, vmID); err != nil {
+			err = fmt.Errorf("failed to check VM ID %d: %w", vmID, err)
+			return
+		} else if !isFree {
+			err = fmt.Errorf("VM ID %d is already in use", vmID)
+			return
+		}
+	}
+
+	var (
+		task    *proxmox.Task
+		newID   int
+		options = &proxmox.VirtualMachineCloneOptions{
+			NewID:   vmID,
+			Name:    conf.Name,
+			Full:    1,
+			Storage: conf.StoragePool,
+		}
+	)
+
+	if node.Name != template.Node {
+		options.Target = node.Name
+	}
+
+	if newID, task, err = template.Clone(api.bg, options); err != nil {
+		err = fmt.Errorf("failed to clone template VM: %w", err)
+		return
+	} else if err = task.Wait(api.bg, time.Second, time.Minute*15); err != nil {
+		err = fmt.Errorf("failed to wait for VM clone task: %w", err)
+		return
+	}
+
+	result = &ProxmoxAPICreateResult{CTID: newID}
+
+	if result.VM, err = node.VirtualMachine(api.bg, newID); err != nil {
+		err = fmt.Errorf("failed to get cloned VM info: %w", err)
+		return
+	}
+
+	var osType string
+	if template.VirtualMachineConfig != nil {
+		osType = template.VirtualMachineConfig.OSType
+	}
+
+	if task, err = result.VM.Config(api.bg, conf.CloudInitOptions(osType)...); err != nil {
+		err = fmt.Errorf("failed to configure VM: %w", err)
+		return
+	} else if task != nil {
+		if err = task.Wait(api.bg, time.Second, time.Minute*3); err != nil {
+			err = fmt.Errorf("failed to wait for VM config task: %w", err)
+			return
+		}
+	}
+
+	return
+}
+
+func (api *ProxmoxAPI) StartVM(vm *proxmox.VirtualMachine) (err error) {
+	var task *proxmox.Task
+	if task, err = vm.Start(api.bg); err == nil {
+		err = task.Wait(api.bg, time.Second, time.Minute*3)
+	}
+
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "already running") {
+		err = nil
+	}
+
+	return
+}
+
+func (api *ProxmoxAPI) StopVM(vm *proxmox.VirtualMachine) (err error) {
+	var task *proxmox.Task
+	if task, err = vm.Stop(api.bg); err == nil {
+		err = task.Wait(api.bg, time.Second, time.Minute*3)
+	}
+
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "not running") {
+		err = nil
+	}
+
+	return
+}
+
+func (api *ProxmoxAPI) DeleteVM(vm *proxmox.VirtualMachine) (err error) {
+	var task *proxmox.Task
+	if task, err = vm.Delete(api.bg); err == nil {
+		err = task.Wait(api.bg, time.Second, time.Minute*5)
+	}
+
+	return
+}
+
+// AgentExecute runs a command inside the VM through the QEMU guest agent: a PowerShell command on Windows VMs and a
+// shell command everywhere else. It needs no guest credentials, so it keeps working when the root password changes.
+func (api *ProxmoxAPI) AgentExecute(vm *proxmox.VirtualMachine, command string) (stdout string, stderr string, exitCode int, err error) {
+	ctx, cancel := context.WithTimeout(api.bg, api.getCommandTimeout())
+	defer cancel()
+
+	var argv = []string{"/bin/sh", "-c", command}
+	if VMGuest(vm).IsWindows() {
+		argv = ssh.PowerShellArgs(command)
+	}
+
+	var pid int
+	if pid, err = vm.AgentExec(ctx, argv, ""); err != nil {
+		err = fmt.Errorf("guest agent exec failed: %w", err)
+		return
+	}
+
+	var status *proxmox.AgentExecStatus
+	if status, err = vm.WaitForAgentExecExit(ctx, pid, int(api.getCommandTimeout().Seconds())); err != nil {
+		err = fmt.Errorf("guest agent exec did not finish: %w", err)
+		return
+	}
+
+	return status.OutData, status.ErrData, status.ExitCode, nil
+}
+
+// WaitForGuestAgent blocks until the VM's guest agent answers or the timeout passes.
+func (api *ProxmoxAPI) WaitForGuestAgent(vm *proxmox.VirtualMachine, timeout time.Duration) error {
+	return vm.WaitForAgent(api.bg, int(timeout.Seconds()))
+}
@@ -2,6 +2,7 @@ import { escapeHTML } from "./shared/utils.js";
 import { setupCreateCompetitionMenu } from "./dashboard/createCompetition.js";
 import { createContainerManager } from "./dashboard/containers.js";
 import { createTeamManager } from "./dashboard/teams.js";
+import { createScoringHealthManager } from "./dashboard/scoringHealth.js";
 import { createRedeployController } from "./dashboard/redeploy.js";
 import { createTeardownController } from "./dashboard/teardown.js";
 
@@ -13,9 +14,11 @@ const canManage = list?.dataset.canManage === "true";
 
 const containerStates = new Map();
 const teamStates = new Map();
+const healthStates = new Map();
 
 const containerManager = createContainerManager({ list, containerStates });
 const teamManager = createTeamManager({ list, teamStates });
+const healthManager = createScoringHealthManager({ list, healthStates });
 const redeployController = createRedeployController({ loadCompetitionContainers: containerManager.loadCompetitionContainers });
 containerManager.setRedeployHandler(redeployController.openRedeployModal);
 
@@ -30,6 +33,38 @@ function setStats({ total = 0, publicCount = 0, privateCount = 0 } = {}) {
     statContainer.querySelector("[data-stat=\"private\"]").textContent = privateCount;
 }
 
+function formatScoringSchedule(schedule = {}) {
+    const interval = Number(schedule.intervalSeconds) || 60;
+    const jitter = Number(schedule.jitterSeconds) || 0;
+    return jitter > 0 ? `every ${interval}s ±${jitter}s` : `every ${interval}s`;
+}
+
+function isSetTime(value) {
+    const parsed = value ? new Date(value) : null;
+    return Boolean(parsed && !Number.isNaN(parsed.getTime()) && parsed.getUTCFullYear() > 1);
+}
+
+function toInputTime(value) {
+    return isSetTime(value) ? new Date(value).toISOString() : "";
+}
+
+function formatCompetitionWindow(compWindow = {}) {
+    const parts = [];
+    if (isSetTime(compWindow.startsAt)) {
+        parts.push(`Starts ${escapeHTML(new Date(compWindow.startsAt).toLocaleString())}`);
+    }
+    if (isSetTime(compWindow.endsAt)) {
+        parts.push(`Ends ${escapeHTML(new Date(compWindow.endsAt).toLocaleString())}`);
+    }
+    if (Number(compWindow.freezeMinutes) > 0) {
+        parts.push(`Freezes ${Number(compWindow.freezeMinutes)} min before end`);
+    }
+    if (isSetTime(compWindow.frozenAt)) {
+        parts.push("Public scoreboard frozen");
+    }
+    return parts.join(" · ");
+}
+
 function renderCompetitions(competitions = []) {
     if (!list) {
         return;
@@ -56,9 +91,23 @@ function renderCompetitions(competitions = []) {
             const scoringBadge = comp.scoringActive
                 ? "<span class=\"ml-2 rounded-full bg-emerald-500/20 text-emerald-200 text-xs px-2 py-0.5\">Scoring active</span>"
                 : "<span class=\"ml-2 rounded-full bg-amber-500/20 text-amber-100 text-xs px-2 py-0.5\">Scoring paused</span>";
-            const networkLabel = comp.networkCIDR ? escapeHTML(comp.networkCIDR) : "Not assigned";
+            const networkLabel = comp.networkCIDR
+                ? escapeHTML(comp.networkCIDR + (comp.networkCIDR6 ? `, ${comp.networkCIDR6}` : ""))
+                : "Not assigned";
+            const schedule = comp.scoringSchedule || {};
+            const scheduleLabel = formatScoringSchedule(schedule);
+            const compWindow = comp.window || {};
+            const windowLabel = formatCompetitionWindow(compWindow);
+            const provisioning = comp.provisioning || {};
+            const provisioningBadge =
+                provisioning.status === "provisioning_failed"
+                    ? "<span class=\"ml-2 rounded-full bg-rose-500/20 text-rose-200 text-xs px-2 py-0.5\">Provisioning failed</span>"
+                    : provisioning.status === "provisioning"
+                      ? "<span class=\"ml-2 rounded-full bg-sky-500/20 text-sky-200 text-xs px-2 py-0.5\">Provisioning</span>"
+                      : "";
             const containerMarkup = canManage ? containerManager.renderCompetitionContainerPanel(comp) : "";
             const teamMarkup = canManage ? teamManager.renderCompetitionTeamPanel(comp) : "";
+            const healthMarkup = canManage ? healthManager.renderCompetitionHealthPanel(comp) : "";
             const actions = `
                 <div class="flex flex-col items-end gap-2 mt-2">
                     <a class="text-blue-300 hover:text-blue-200" href="/scoreboard/${encodeURIComponent(comp.competitionID)}">Open scoreboard</a>
@@ -70,6 +119,33 @@ function renderCompetitions(competitions = []) {
                                     data-active="${comp.scoringActive ? "true" : "false"}"
                                     data-id="${escapeHTML(comp.competitionID)}"
                                 >${comp.scoringActive ? "Stop scoring" : "Start scoring"}</button>
+                                <button class="inline-flex items-center rounded-xl border border-white/40 px-3 py-1 text-xs font-semibold text-white/90 hover:bg-white/10 focus:outline-none focus:ring-2 focus:ring-blue-400 disabled:opacity-60"
+                                    data-action="edit-schedule"
+                                    data-id="${escapeHTML(comp.competitionID)}"
+                                    data-interval="${Number(schedule.intervalSeconds) || 60}"
+                                    data-jitter="${Number(schedule.jitterSeconds) || 0}"
+                                >Edit schedule</button>
+                                <button class="inline-flex items-center rounded-xl border border-white/40 px-3 py-1 text-xs font-semibold text-white/90 hover:bg-white/10 focus:outline-none focus:ring-2 focus:ring-blue-400 disabled:opacity-60"
+                                    data-action="edit-window"
+                                    data-id="${escapeHTML(comp.competitionID)}"
+                                    data-starts-at="${escapeHTML(toInputTime(compWindow.startsAt))}"
+                                    data-ends-at="${escapeHTML(toInputTime(compWindow.endsAt))}"
+                                    data-freeze-minutes="${Number(compWindow.freezeMinutes) || 0}"
+                                    data-power-on="${compWindow.powerOnAtStart ? "true" : "false"}"
+                                >Edit start/end</button>
+                                <button class="inline-flex items-center rounded-xl border border-white/40 px-3 py-1 text-xs font-semibold text-white/90 hover:bg-white/10 focus:outline-none focus:ring-2 focus:ring-blue-400 disabled:opacity-60"
+                                    data-action="edit-firewall"
+                                    data-id="${escapeHTML(comp.competitionID)}"
+                                >Edit firewall</button>
+                                ${
+                                    provisioning.status === "provisioning_failed"
+                                        ? `<button class="inline-flex items-center rounded-xl border border-amber-400/60 px-3 py-1 text-xs font-semibold text-amber-100 hover:bg-amber-500/10 focus:outline-none focus:ring-2 focus:ring-amber-400 disabled:opacity-60"
+                                    data-action="resume-provisioning"
+                                    data-id="${escapeHTML(comp.competitionID)}"
+                                    data-name="${escapeHTML(comp.name)}"
+                                >Resume provisioning</button>`
+                                        : ""
+                                }
                                 <button class="inline-flex items-center rounded-xl border border-rose-500/60 px-3 py-1 text-xs font-semibold text-rose-200 hover:bg-rose-500/10 focus:outline-none focus:ring-2 focus:ring-rose-400 disabled:opacity-60"
                                 data-action="teardown"
                                 data-id="${escapeHTML(comp.competitionID)}"
@@ -82,17 +158,21 @@ function renderCompetitions(competitions = []) {
             return `<li class="rounded-2xl border border-white/10 bg-white/5 p-5 flex flex-col gap-4">
                 <div class="flex flex-col gap-4 md:flex-row md:items-center md:justify-between">
                 <div>
-                    <p class="text-lg font-semibold text-white">${escapeHTML(comp.name)}${badge}${scoringBadge}</p>
+                    <p class="text-lg font-semibold text-white">${escapeHTML(comp.name)}${badge}${scoringBadge}${provisioningBadge}</p>
                     <p class="text-sm text-slate-300">${escapeHTML(comp.description || "No description")}</p>
                     <p class="text-xs text-slate-400 mt-1">Hosted by ${escapeHTML(comp.host || "Unknown")}</p>
                     <p class="text-xs text-slate-400 mt-1">Network: ${networkLabel}</p>
+                    <p class="text-xs text-slate-400 mt-1">Scoring: ${scheduleLabel}</p>
+                    ${windowLabel ? `<p class="text-xs text-slate-400 mt-1">${windowLabel}</p>` : ""}
+                    ${provisioning.error ? `<p class="text-xs text-rose-300 mt-1">Provisioning: ${escapeHTML(provisioning.error)}</p>` : ""}
+                    <pre class="hidden mt-2 max-h-48 overflow-y-auto rounded-xl border border-white/10 bg-black/40 p-3 text-xs text-slate-200" data-provision-log></pre>
                 </div>
                 <div class="text-sm text-right text-slate-300">
                     <p>${comp.teamCount} teams · ${comp.containerCount} containers</p>
                     ${actions}
                 </div>
                 </div>
-                ${canManage ? `${containerMarkup}${teamMarkup}` : ""}
+                ${canManage ? `${containerMarkup}${teamMarkup}${healthMarkup}` : ""}
             </li>`;
         })
         .join("");
@@ -111,6 +191,11 @@ function renderCompetitions(competitions = []) {
                 teamStates.delete(key);
             }
         });
+        Array.from(healthStates.keys()).forEach(function(key) {
+            if (!activeIDs.has(key)) {
+                healthStates.delete(key);
+            }
+        });
         competitions.forEach(function(comp) {
             if (!comp || !comp.competitionID) {
                 return;
@@ -127,6 +212,10 @@ function renderCompetitions(competitions = []) {
             if (!teamState.loaded && !teamState.loading) {
                 teamManager.loadCompetitionTeams(compID);
             }
+
+            // Scoring health is fetched on demand when its panel is opened.
+            healthManager.initCompetitionHealthState(compID).loaded = false;
+            healthManager.renderCompetitionHealth(compID);
         });
     }
 }
@@ -186,6 +275,230 @@ async function toggleScoring(button) {
     }
 }
 
+async function editScoringSchedule(button) {
+    if (!button) {
+        return;
+    }
+    const compID = button.dataset.id;
+    if (!compID) {
+        return;
+    }
+
+    const intervalInput = window.prompt("Seconds between scoring rounds:", button.dataset.interval || "60");
+    if (intervalInput === null) {
+        return;
+    }
+    const jitterInput = window.prompt("Random jitter in seconds (each round fires up to this early or late):", button.dataset.jitter || "0");
+    if (jitterInput === null) {
+        return;
+    }
+
+    const intervalSeconds = Number.parseInt(intervalInput, 10);
+    const jitterSeconds = Number.parseInt(jitterInput, 10);
+    if (!Number.isFinite(intervalSeconds) || !Number.isFinite(jitterSeconds)) {
+        window.alert("Interval and jitter must be whole numbers of seconds.");
+        return;
+    }
+
+    const originalText = button.textContent;
+    button.disabled = true;
+    button.textContent = "Saving…";
+
+    try {
+        const response = await fetch(`/api/competitions/${encodeURIComponent(compID)}/scoring/schedule`, {
+            method: "POST",
+            credentials: "include",
+            headers: {
+                "Content-Type": "application/json"
+            },
+            body: JSON.stringify({ intervalSeconds, jitterSeconds })
+        });
+
+        const result = await response.json().catch(function() {
+            return {};
+        });
+        if (!response.ok) {
+            throw new Error(result?.error || result?.message || "Failed to update scoring schedule");
+        }
+
+        await loadDashboard();
+    } catch (error) {
+        console.error(error);
+        window.alert(error.message || "Unable to update scoring schedule.");
+    } finally {
+        button.disabled = false;
+        button.textContent = originalText;
+    }
+}
+
+async function editCompetitionWindow(button) {
+    if (!button) {
+        return;
+    }
+    const compID = button.dataset.id;
+    if (!compID) {
+        return;
+    }
+
+    const startsAt = window.prompt("Start time (RFC3339, e.g. 2025-03-01T09:00:00-05:00; blank for none):", button.dataset.startsAt || "");
+    if (startsAt === null) {
+        return;
+    }
+    const endsAt = window.prompt("End time (RFC3339; blank for none):", button.dataset.endsAt || "");
+    if (endsAt === null) {
+        return;
+    }
+    const freezeInput = window.prompt("Freeze the public scoreboard this many minutes before the end (0 disables):", button.dataset.freezeMinutes || "0");
+    if (freezeInput === null) {
+        return;
+    }
+    const freezeMinutes = Number.parseInt(freezeInput || "0", 10);
+    if (!Number.isFinite(freezeMinutes)) {
+        window.alert("Freeze minutes must be a whole number.");
+        return;
+    }
+    const powerOnAtStart = window.confirm("Power on every competition container at the start time?");
+
+    const originalText = button.textContent;
+    button.disabled = true;
+    button.textContent = "Saving…";
+
+    try {
+        const response = await fetch(`/api/competitions/${encodeURIComponent(compID)}/window`, {
+            method: "POST",
+            credentials: "include",
+            headers: {
+                "Content-Type": "application/json"
+            },
+            body: JSON.stringify({ startsAt: startsAt.trim(), endsAt: endsAt.trim(), freezeMinutes, powerOnAtStart })
+        });
+
+        const result = await response.json().catch(function() {
+            return {};
+        });
+        if (!response.ok) {
+            throw new Error(result?.error || result?.message || "Failed to update competition schedule");
+        }
+
+        await loadDashboard();
+    } catch (error) {
+        console.error(error);
+        window.alert(error.message || "Unable to update competition schedule.");
+    } finally {
+        button.disabled = false;
+        button.textContent = originalText;
+    }
+}
+
+async function editFirewall(button) {
+    const compID = button?.dataset.id;
+    if (!compID) {
+        return;
+    }
+
+    const originalText = button.textContent;
+    button.disabled = true;
+    button.textContent = "Loading…";
+
+    try {
+        const current = await fetch(`/api/competitions/${encodeURIComponent(compID)}/firewall`, {
+            credentials: "include"
+        });
+        const currentResult = await current.json().catch(function() {
+            return {};
+        });
+        if (!current.ok) {
+            throw new Error(currentResult?.error || currentResult?.message || "Failed to load firewall policy");
+        }
+
+        const input = window.prompt(
+            "Firewall policy (JSON: enabled, defaultInbound, defaultOutbound, blockInternetEgress, rules[{direction, action, peer, protocol, ports, comment}]):",
+            JSON.stringify(currentResult.firewall || {})
+        );
+        if (input === null) {
+            return;
+        }
+
+        let policy;
+        try {
+            policy = JSON.parse(input);
+        } catch (parseError) {
+            throw new Error("The firewall policy must be valid JSON.");
+        }
+
+        button.textContent = "Applying…";
+        const response = await fetch(`/api/competitions/${encodeURIComponent(compID)}/firewall`, {
+            method: "POST",
+            credentials: "include",
+            headers: {
+                "Content-Type": "application/json"
+            },
+            body: JSON.stringify(policy)
+        });
+        const result = await response.json().catch(function() {
+            return {};
+        });
+        if (!response.ok) {
+            throw new Error(result?.error || result?.message || "Failed to update firewall policy");
+        }
+
+        window.alert(result.message || "Firewall policy applied.");
+    } catch (error) {
+        console.error(error);
+        window.alert(error.message || "Unable to update firewall policy.");
+    } finally {
+        button.disabled = false;
+        button.textContent = originalText;
+    }
+}
+
+async function resumeProvisioning(button) {
+    const compID = button?.dataset.id;
+    if (!compID) {
+        return;
+    }
+    if (!window.confirm(`Provision the missing containers of ${button.dataset.name || compID}? Containers that already exist are left alone.`)) {
+        return;
+    }
+
+    const logElement = button.closest("li")?.querySelector("[data-provision-log]");
+    button.disabled = true;
+
+    try {
+        const response = await fetch(`/api/competitions/${encodeURIComponent(compID)}/provision/resume`, {
+            method: "POST",
+            credentials: "include",
+            headers: {
+                "Content-Type": "application/json"
+            },
+            body: JSON.stringify({})
+        });
+        const result = await response.json().catch(function() {
+            return {};
+        });
+        if (!response.ok) {
+            throw new Error(result?.error || result?.message || "Failed to resume provisioning");
+        }
+
+        logElement?.classList.remove("hidden");
+        const source = new EventSource(`/api/competitions/upload/${encodeURIComponent(result.jobID)}/stream`);
+        source.onmessage = function(event) {
+            if (logElement) {
+                logElement.textContent += `${event.data}\n`;
+                logElement.scrollTop = logElement.scrollHeight;
+            }
+        };
+        source.onerror = function() {
+            source.close();
+            loadDashboard();
+        };
+    } catch (error) {
+        console.error(error);
+        window.alert(error.message || "Unable to resume provisioning.");
+        button.disabled = false;
+    }
+}
+
 function handleListClick(event) {
     if (!(event.target instanceof Element)) {
         return;
@@ -200,6 +513,20 @@ function handleListClick(event) {
         }
         return;
     }
+    const healthControl = event.target.closest("[data-health-action]");
+    if (healthControl) {
+        const panel = healthControl.closest("[data-health-panel]");
+        const compID = panel?.dataset.compId || "";
+        if (healthControl.dataset.healthAction === "refresh" && compID) {
+            healthManager.loadCompetitionHealth(compID);
+        }
+        return;
+    }
+    const rosterButton = event.target.closest("[data-team-roster]");
+    if (rosterButton) {
+        teamManager.handleTeamRoster(rosterButton);
+        return;
+    }
     const teamAction = event.target.closest("[data-team-action]");
     if (teamAction && teamAction.dataset.teamAction) {
         teamManager.handleTeamAction(teamAction);
@@ -232,6 +559,26 @@ function handleListClick(event) {
         toggleScoring(toggle);
         return;
     }
+    const scheduleButton = event.target.closest("[data-action=\"edit-schedule\"]");
+    if (scheduleButton) {
+        editScoringSchedule(scheduleButton);
+        return;
+    }
+    const windowButton = event.target.closest("[data-action=\"edit-window\"]");
+    if (windowButton) {
+        editCompetitionWindow(windowButton);
+        return;
+    }
+    const firewallButton = event.target.closest("[data-action=\"edit-firewall\"]");
+    if (firewallButton) {
+        editFirewall(firewallButton);
+        return;
+    }
+    const resumeButton = event.target.closest("[data-action=\"resume-provisioning\"]");
+    if (resumeButton) {
+        resumeProvisioning(resumeButton);
+        return;
+    }
     const teardownTarget = event.target.closest("[data-action=\"teardown\"]");
     if (teardownTarget) {
         teardownCompetition(teardownTarget);
@@ -267,6 +614,15 @@ function handleListToggle(event) {
     if (!(event.target instanceof Element)) {
         return;
     }
+    const healthPanel = event.target.closest("[data-health-panel]");
+    if (healthPanel) {
+        const compID = healthPanel.dataset.compId || "";
+        const state = healthManager.initCompetitionHealthState(compID);
+        if (healthPanel.open && compID && !state.loaded && !state.loading) {
+            healthManager.loadCompetitionHealth(compID);
+        }
+        return;
+    }
     const toggleTarget = event.target.closest("[data-team-panel]");
     if (!toggleTarget || !toggleTarget.open) {
         return;
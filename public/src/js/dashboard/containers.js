@@ -182,15 +182,23 @@ export function createContainerManager({ list, containerStates }) {
                 }
 
                 const checked = state.selected.has(id);
-                const teamName = entry.team ? escapeHTML(entry.team.name || `Team ${entry.team.id}`) : "Unassigned";
+                const teamName = entry.team
+                    ? escapeHTML(entry.team.name || `Team ${entry.team.id}`)
+                    : entry.shared
+                      ? "Shared (competition)"
+                      : "Unassigned";
                 const teamMeta = entry.team ? `<p class="text-xs text-slate-400">ID ${entry.team.id}</p>` : "";
                 const nodeInfo = entry.node ? `<p class="text-xs text-slate-400">Node ${escapeHTML(entry.node)}</p>` : "";
                 const ip = entry.ipAddress ? escapeHTML(entry.ipAddress) : "—";
+                const ip6 = entry.ipv6Address ? `<p class="font-mono text-xs text-slate-400">${escapeHTML(entry.ipv6Address)}</p>` : "";
                 const status = describePowerStatus(entry.status);
                 const containerName = entry.name || `CT-${id}`;
                 const label = escapeHTML(containerName);
                 const configName = entry.containerConfigName ? escapeHTML(entry.containerConfigName) : "";
                 const redeployDisabled = state.loading;
+                const credentialWarning = entry.execCredentialError
+                    ? `<p class="mt-1 max-w-xs text-xs text-amber-300" title="${escapeHTML(entry.execCredentialError)}">Exec credentials rejected ${formatRelativeTime(entry.execCredentialSince)}: ${escapeHTML(entry.execCredentialError)}</p>`
+                    : "";
 
                 return `<tr class="border-b border-white/5 last:border-b-0">
                     <td class="py-3 pr-3 align-top">
@@ -201,6 +209,7 @@ export function createContainerManager({ list, containerStates }) {
                 </td>
                 <td class="py-3 pr-3 align-top">
                     <p class="font-mono text-slate-100">${ip}</p>
+                    ${ip6}
                     ${nodeInfo}
                 </td>
                 <td class="py-3 pr-3 align-top">
@@ -209,6 +218,7 @@ export function createContainerManager({ list, containerStates }) {
                 </td>
                     <td class="py-3 pr-3 align-top">
                         <span class="status-pill ${status.tone}">${escapeHTML(status.label)}</span>
+                        ${credentialWarning}
                     </td>
                     <td class="py-3 pr-3 align-top text-slate-300">${formatRelativeTime(entry.lastUpdated)}</td>
                     <td class="py-3 align-top">
@@ -46,6 +46,7 @@ export function setupCreateCompetitionMenu({ loadDashboard } = {}) {
     const logElement = document.getElementById("create-log");
     const uploadButton = document.getElementById("upload-package");
     const advancedLoggingCheckbox = document.getElementById("enable-advanced-logging");
+    const keepPartialCheckbox = document.getElementById("keep-partial-provisioning");
     const state = {
         file: null,
         submitting: false,
@@ -81,6 +82,11 @@ export function setupCreateCompetitionMenu({ loadDashboard } = {}) {
             advancedLoggingCheckbox.checked = false;
             advancedLoggingCheckbox.disabled = false;
         }
+
+        if (keepPartialCheckbox) {
+            keepPartialCheckbox.checked = false;
+            keepPartialCheckbox.disabled = false;
+        }
     }
 
     function openModal() {
@@ -200,6 +206,10 @@ export function setupCreateCompetitionMenu({ loadDashboard } = {}) {
         if (advancedLoggingCheckbox) {
             advancedLoggingCheckbox.disabled = isSubmitting;
         }
+
+        if (keepPartialCheckbox) {
+            keepPartialCheckbox.disabled = isSubmitting;
+        }
     }
 
     openButton.addEventListener("click", openModal);
@@ -264,6 +274,7 @@ export function setupCreateCompetitionMenu({ loadDashboard } = {}) {
         }
 
         const enableAdvancedLogging = Boolean(advancedLoggingCheckbox?.checked);
+        const keepPartial = Boolean(keepPartialCheckbox?.checked);
         setSubmitting(true);
 
         try {
@@ -279,6 +290,9 @@ export function setupCreateCompetitionMenu({ loadDashboard } = {}) {
             const payload = new FormData();
             payload.append("file", state.file, state.file.name);
             payload.append("enableAdvancedLogging", enableAdvancedLogging ? "true" : "false");
+            if (keepPartial) {
+                payload.append("keepPartialOnFailure", "true");
+            }
 
             const response = await fetch("/api/competitions/upload", {
                 method: "POST",
@@ -7,6 +7,7 @@ export function createRedeployController({ loadCompetitionContainers } = {}) {
     const redeployCloseButton = document.getElementById("close-redeploy");
     const redeployStartCheckbox = redeployModal?.querySelector("[data-redeploy-start-checkbox]");
     const redeployAdvancedLoggingCheckbox = redeployModal?.querySelector("[data-redeploy-advanced-logging]");
+    const redeployResetCheckbox = redeployModal?.querySelector("[data-redeploy-reset-checkbox]");
     const redeployConfirmButton = redeployModal?.querySelector("[data-redeploy-confirm]");
     const redeployConfirmDefaultText =
         redeployConfirmButton?.dataset.defaultLabel?.trim() || redeployConfirmButton?.textContent?.trim() || "Redeploy";
@@ -40,6 +41,9 @@ export function createRedeployController({ loadCompetitionContainers } = {}) {
         if (redeployAdvancedLoggingCheckbox) {
             redeployAdvancedLoggingCheckbox.disabled = redeployInProgress;
         }
+        if (redeployResetCheckbox) {
+            redeployResetCheckbox.disabled = redeployInProgress;
+        }
     }
 
     function setRedeployBusy(isBusy) {
@@ -72,6 +76,9 @@ export function createRedeployController({ loadCompetitionContainers } = {}) {
         if (redeployAdvancedLoggingCheckbox) {
             redeployAdvancedLoggingCheckbox.checked = false;
         }
+        if (redeployResetCheckbox) {
+            redeployResetCheckbox.checked = false;
+        }
         if (redeployModal) {
             delete redeployModal.dataset.containerId;
             delete redeployModal.dataset.containerLabel;
@@ -95,8 +102,8 @@ export function createRedeployController({ loadCompetitionContainers } = {}) {
         if (!message) {
             return;
         }
-        if (lower.includes("redeploy completed")) {
-            updateRedeployStatus("Redeploy complete", "text-emerald-400");
+        if (lower.includes("redeploy completed") || lower.includes("reset completed")) {
+            updateRedeployStatus(lower.includes("reset completed") ? "Reset complete" : "Redeploy complete", "text-emerald-400");
             if (redeployStreamCompID && typeof loadCompetitionContainers === "function") {
                 loadCompetitionContainers(redeployStreamCompID);
                 redeployStreamCompID = "";
@@ -104,14 +111,14 @@ export function createRedeployController({ loadCompetitionContainers } = {}) {
             setRedeployBusy(false);
             return;
         }
-        if (lower.includes("redeploy failed") || lower.includes("error:")) {
+        if (lower.includes("redeploy failed") || lower.includes("reset failed") || lower.includes("error:")) {
             updateRedeployStatus("Redeploy failed", "text-rose-500");
-            if (lower.includes("redeploy failed")) {
+            if (lower.includes("redeploy failed") || lower.includes("reset failed")) {
                 setRedeployBusy(false);
             }
             return;
         }
-        if (lower.includes("redeploy job started")) {
+        if (lower.includes("redeploy job started") || lower.includes("reset job started")) {
             updateRedeployStatus("Redeploy in progress...", "text-amber-400");
         }
     }
@@ -167,14 +174,15 @@ export function createRedeployController({ loadCompetitionContainers } = {}) {
         const containerLabel = redeployModal.dataset.containerLabel || `CT-${id}`;
         const startAfter = Boolean(redeployStartCheckbox?.checked);
         const advancedLogging = Boolean(redeployAdvancedLoggingCheckbox?.checked);
+        const resetToBaseline = Boolean(redeployResetCheckbox?.checked);
 
         setRedeployBusy(true);
 
-        appendRedeployLog(`Queued redeploy for ${containerLabel}.`);
+        appendRedeployLog(`Queued ${resetToBaseline ? "reset" : "redeploy"} for ${containerLabel}.`);
         updateRedeployStatus("Redeploy in progress...", "text-amber-400");
 
         try {
-            const response = await fetch("/api/containers/redeploy", {
+            const response = await fetch(resetToBaseline ? "/api/containers/reset" : "/api/containers/redeploy", {
                 method: "POST",
                 credentials: "include",
                 headers: { "Content-Type": "application/json" },
@@ -0,0 +1,188 @@
+import { escapeHTML } from "../shared/utils.js";
+import { formatRelativeTime } from "./helpers.js";
+
+const policyLabels = {
+    none: "Unknown checks score no points",
+    carry: "Unknown checks carry their last result",
+    retry: "Failing scripts are retried once, then score no points"
+};
+
+export function createScoringHealthManager({ list, healthStates }) {
+    function initCompetitionHealthState(compID) {
+        const key = String(compID || "");
+        if (!healthStates.has(key)) {
+            healthStates.set(key, {
+                error: "",
+                loaded: false,
+                loading: false,
+                currentRound: 0,
+                roundsChecked: 0,
+                policy: "",
+                passes: []
+            });
+        }
+        return healthStates.get(key);
+    }
+
+    function getHealthPanel(compID) {
+        if (!list) {
+            return null;
+        }
+        const encoded = encodeURIComponent(String(compID || ""));
+        return list.querySelector(`[data-health-panel="${encoded}"]`);
+    }
+
+    function renderCompetitionHealthPanel(comp) {
+        const compID = String(comp.competitionID || "");
+        const encoded = encodeURIComponent(compID);
+        const escapedID = escapeHTML(compID);
+        return `
+    <details class="group rounded-2xl border border-white/10 bg-slate-900/70" data-health-panel="${encoded}" data-comp-id="${escapedID}">
+        <summary class="flex flex-col gap-1 px-4 py-3 cursor-pointer focus-visible:outline-none focus-visible:ring-2 focus-visible:ring-blue-400">
+            <div class="flex flex-col gap-2 lg:flex-row lg:items-center lg:justify-between">
+                <div>
+                    <p class="text-sm font-semibold text-white">Scoring health</p>
+                    <p class="text-xs text-slate-400">Scorer errors that left checks unknown in ${escapeHTML(comp.name)}</p>
+                </div>
+                <span class="chevron-icon text-white/80" aria-hidden="true">
+                    <svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="1.7" stroke-linecap="round" stroke-linejoin="round">
+                        <path d="M6 9l6 6 6-6"></path>
+                    </svg>
+                </span>
+            </div>
+        </summary>
+        <div class="panel-content">
+            <div class="panel-body space-y-3 border-t border-white/10 px-4 pb-4 pt-3">
+                <div class="flex flex-col gap-2 sm:flex-row sm:items-center sm:justify-between">
+                    <p class="text-xs uppercase tracking-[0.3em] text-slate-400" data-health-summary>&nbsp;</p>
+                    <button class="inline-flex items-center rounded-2xl border border-white/30 px-3 py-1.5 text-xs font-semibold uppercase tracking-[0.2em] text-white/90 hover:bg-white/10 disabled:opacity-40" type="button" data-health-action="refresh" disabled>Refresh</button>
+                </div>
+                <p class="text-xs text-slate-400" data-health-policy></p>
+                <p class="hidden text-sm text-rose-400" data-health-error></p>
+                <div class="text-slate-400 text-sm py-4 text-center border border-dashed border-white/10 rounded-2xl" data-health-empty>Not loaded yet.</div>
+                <div class="space-y-3" data-health-body></div>
+            </div>
+        </div>
+    </details>`;
+    }
+
+    function renderCompetitionHealth(compID) {
+        const panel = getHealthPanel(compID);
+        if (!panel) {
+            return;
+        }
+        const state = initCompetitionHealthState(compID);
+        const summary = panel.querySelector("[data-health-summary]");
+        const policyEl = panel.querySelector("[data-health-policy]");
+        const refreshBtn = panel.querySelector("[data-health-action=\"refresh\"]");
+        const errorEl = panel.querySelector("[data-health-error]");
+        const emptyEl = panel.querySelector("[data-health-empty]");
+        const body = panel.querySelector("[data-health-body]");
+
+        if (summary) {
+            summary.textContent = state.loading
+                ? "Loading scoring health..."
+                : state.loaded
+                ? `${state.passes.length} of the last ${state.roundsChecked} pass${state.roundsChecked === 1 ? "" : "es"} had errors`
+                : "Expand to load scoring health.";
+        }
+        if (policyEl) {
+            policyEl.textContent = policyLabels[state.policy] || "";
+        }
+        if (refreshBtn) {
+            refreshBtn.disabled = state.loading;
+        }
+        if (errorEl) {
+            errorEl.textContent = state.error;
+            errorEl.classList.toggle("hidden", !state.error);
+        }
+        if (!body) {
+            return;
+        }
+
+        if (!state.loaded || !state.passes.length) {
+            body.innerHTML = "";
+            if (emptyEl) {
+                emptyEl.textContent = !state.loaded
+                    ? "Expand the panel to load scoring health."
+                    : state.roundsChecked > 0
+                    ? "Every recent pass scored all checks."
+                    : "No scoring passes yet.";
+                emptyEl.classList.remove("hidden");
+            }
+            return;
+        }
+
+        if (emptyEl) {
+            emptyEl.classList.add("hidden");
+        }
+
+        body.innerHTML = state.passes
+            .map(function(pass) {
+                const issues = pass.issues
+                    .map(function(issue) {
+                        const team = escapeHTML(issue.teamName || `Team ${issue.teamID}`);
+                        const unknown = Number(issue.unknownChecks) || 0;
+                        return `<li class="py-2">
+                            <p class="text-sm text-white">${team} · ${escapeHTML(issue.containerName || "—")} · <span class="font-mono text-xs text-slate-300">${escapeHTML(issue.source || "")}</span></p>
+                            <p class="text-xs text-amber-200 break-words">${escapeHTML(issue.message || "")}</p>
+                            <p class="text-xs text-slate-400">${unknown} check${unknown === 1 ? "" : "s"} unknown</p>
+                        </li>`;
+                    })
+                    .join("");
+                return `<div class="rounded-2xl border border-amber-400/30 bg-amber-500/5 px-3 py-2">
+                    <p class="text-xs uppercase tracking-[0.2em] text-amber-200">Round ${Number(pass.round) || 0} · ${escapeHTML(formatRelativeTime(pass.recordedAt))}</p>
+                    <ul class="divide-y divide-white/5">${issues}</ul>
+                </div>`;
+            })
+            .join("");
+    }
+
+    async function loadCompetitionHealth(compID) {
+        const state = initCompetitionHealthState(compID);
+        if (!state || state.loading) {
+            return;
+        }
+        state.loading = true;
+        state.error = "";
+        renderCompetitionHealth(compID);
+
+        try {
+            const response = await fetch(`/api/competitions/${encodeURIComponent(compID)}/scoring/health`, {
+                credentials: "include"
+            });
+            const payload = await response.json().catch(function() {
+                return {};
+            });
+            if (!response.ok) {
+                throw new Error(payload?.error || payload?.message || "Failed to load scoring health");
+            }
+
+            state.passes = (Array.isArray(payload?.passes) ? payload.passes : []).map(function(pass) {
+                return {
+                    round: pass.round,
+                    recordedAt: pass.recordedAt || "",
+                    issues: Array.isArray(pass.issues) ? pass.issues : []
+                };
+            });
+            state.currentRound = Number(payload?.currentRound) || 0;
+            state.roundsChecked = Number(payload?.roundsChecked) || 0;
+            state.policy = payload?.unknownCheckPolicy || "";
+            state.loaded = true;
+        } catch (error) {
+            state.error = error.message || "Unable to load scoring health.";
+            state.passes = [];
+            state.loaded = true;
+        } finally {
+            state.loading = false;
+            renderCompetitionHealth(compID);
+        }
+    }
+
+    return {
+        initCompetitionHealthState,
+        renderCompetitionHealthPanel,
+        renderCompetitionHealth,
+        loadCompetitionHealth
+    };
+}
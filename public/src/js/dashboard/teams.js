@@ -190,6 +190,14 @@ export function createTeamManager({ list, teamStates }) {
                 const score = Number.isFinite(Number(team.score)) ? Number(team.score) : 0;
                 const updated = formatRelativeTime(team.lastUpdated);
                 const networkLabel = team.network ? escapeHTML(team.network) : "—";
+                const rosterParts = [];
+                if (team.ldapGroup) {
+                    rosterParts.push(`Group ${escapeHTML(team.ldapGroup)}`);
+                }
+                if (team.members.length) {
+                    rosterParts.push(escapeHTML(team.members.join(", ")));
+                }
+                const rosterLabel = rosterParts.length ? rosterParts.join(" · ") : "No members assigned";
                 return `<tr class="border-b border-white/5 last:border-b-0">
                 <td class="py-3 pr-3 align-top">
                     <input type="checkbox" class="h-4 w-4 rounded border-white/30 bg-slate-800/80" data-team-select value="${team.id}" ${checked ? "checked" : ""}>
@@ -197,6 +205,9 @@ export function createTeamManager({ list, teamStates }) {
                 <td class="py-3 pr-3 align-top">
                     <p class="text-slate-100 font-semibold">${name}</p>
                     <p class="text-xs text-slate-400">ID ${team.id}</p>
+                    ${team.claimToken ? `<p class="text-[0.65rem] text-slate-500 font-mono break-all" title="Claim token">${escapeHTML(team.claimToken)}</p>` : ""}
+                    <p class="text-xs text-slate-400 mt-1">${rosterLabel}</p>
+                    <button class="mt-1 text-[0.65rem] uppercase tracking-[0.2em] text-blue-300 hover:text-blue-200" type="button" data-team-roster="${team.id}">Edit members</button>
                 </td>
                 <td class="py-3 pr-3 align-top">
                     <p class="text-slate-100 font-semibold">${networkLabel}</p>
@@ -242,7 +253,10 @@ export function createTeamManager({ list, teamStates }) {
                     name: entry.name || `Team ${entry.id}`,
                     score: Number.isFinite(Number(entry.score)) ? Number(entry.score) : 0,
                     lastUpdated: entry.lastUpdated || "",
-                    network: entry.networkCIDR || ""
+                    network: entry.networkCIDR || "",
+                    claimToken: entry.claimToken || "",
+                    ldapGroup: entry.ldapGroup || "",
+                    members: Array.isArray(entry.members) ? entry.members : []
                 });
             });
 
@@ -418,8 +432,71 @@ export function createTeamManager({ list, teamStates }) {
         }
     }
 
+    async function handleTeamRoster(button) {
+        const panel = button.closest("[data-team-panel]");
+        if (!panel) {
+            return;
+        }
+        const compID = panel.dataset.compId || "";
+        const teamID = Number(button.dataset.teamRoster);
+        if (!compID || !Number.isFinite(teamID)) {
+            return;
+        }
+        const state = initCompetitionTeamState(compID);
+        const team = state.teams.find(function(entry) {
+            return entry.id === teamID;
+        });
+        if (!team) {
+            return;
+        }
+
+        const ldapGroup = window.prompt(`LDAP group for ${team.name} (blank for none):`, team.ldapGroup || "");
+        if (ldapGroup === null) {
+            return;
+        }
+        const membersInput = window.prompt(`Usernames on ${team.name} (comma separated):`, team.members.join(", "));
+        if (membersInput === null) {
+            return;
+        }
+        const members = membersInput
+            .split(",")
+            .map(function(value) {
+                return value.trim();
+            })
+            .filter(Boolean);
+
+        state.actionLoading = true;
+        state.error = "";
+        renderCompetitionTeams(compID);
+
+        try {
+            const response = await fetch(`/api/competitions/${encodeURIComponent(compID)}/teams/${teamID}/members`, {
+                method: "POST",
+                credentials: "include",
+                headers: { "Content-Type": "application/json" },
+                body: JSON.stringify({ ldapGroup: ldapGroup.trim(), members })
+            });
+            const result = await response.json().catch(function() {
+                return {};
+            });
+            if (!response.ok) {
+                throw new Error(result?.error || result?.message || "Failed to update team members");
+            }
+
+            team.ldapGroup = result?.ldapGroup || "";
+            team.members = Array.isArray(result?.members) ? result.members : [];
+            state.feedback = { text: `Members updated for ${team.name}.`, tone: "text-emerald-400" };
+        } catch (error) {
+            state.error = error.message || "Unable to update team members.";
+        } finally {
+            state.actionLoading = false;
+            renderCompetitionTeams(compID);
+        }
+    }
+
     return {
         initCompetitionTeamState,
+        handleTeamRoster,
         renderCompetitionTeamPanel,
         renderCompetitionTeams,
         loadCompetitionTeams,
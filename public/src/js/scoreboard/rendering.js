@@ -153,7 +153,7 @@ function buildContainerMatrices(selected) {
                 if (!id) {
                     return;
                 }
-                statusMap.set(id, Boolean(check?.passed));
+                statusMap.set(id, check?.state === "unknown" ? "unknown" : Boolean(check?.passed));
             });
 
             containerEntry.rows.push({
@@ -177,6 +177,9 @@ function renderStatusCell(status) {
     } else if (status === false) {
         classes = "bg-rose-500/25 text-rose-100 border-rose-400/30";
         label = "Down";
+    } else if (status === "unknown") {
+        classes = "bg-amber-500/20 text-amber-100 border-amber-400/30";
+        label = "Unknown";
     }
     return `<span class="matrix-status inline-flex w-full items-center justify-center rounded-xl border px-3 py-1 text-[0.7rem] font-semibold leading-tight ${classes}">${label}</span>`;
 }
@@ -246,6 +249,45 @@ function renderGridMarkup(selected) {
     }).join("");
 }
 
+function formatTenure(seconds) {
+    const total = Math.max(0, Math.floor(Number(seconds) || 0));
+    const hours = Math.floor(total / 3600);
+    const minutes = Math.floor((total % 3600) / 60);
+    if (hours > 0) {
+        return `${hours}h ${minutes}m`;
+    }
+    if (minutes > 0) {
+        return `${minutes}m`;
+    }
+    return `${total}s`;
+}
+
+function renderHillsMarkup(selected) {
+    const hills = Array.isArray(selected?.hills) ? selected.hills : [];
+    if (!hills.length) {
+        return "";
+    }
+
+    const cards = hills
+        .map(function(hill) {
+            const owner = hill.hillTeamName || (Number(hill.hillTeamID) === 0 ? "Shared" : "");
+            const hillLabel = owner
+                ? `${escapeHTML(owner)} · ${escapeHTML(hill.containerName)}`
+                : escapeHTML(hill.containerName);
+            return `<div class="rounded-xl border border-amber-400/30 bg-amber-500/10 px-3 py-2">
+                <p class="text-[0.65rem] uppercase tracking-[0.2em] text-amber-200">${hillLabel}</p>
+                <p class="text-sm font-semibold text-white">&#9813; ${escapeHTML(hill.kingTeamName || `Team ${hill.kingTeamID}`)}</p>
+                <p class="text-[0.65rem] text-slate-300">Held ${formatTenure(hill.tenureSeconds)} since ${formatDate(hill.since)} · ${Number(hill.pointsAwarded) || 0} pts</p>
+            </div>`;
+        })
+        .join("");
+
+    return `<div class="space-y-1.5">
+        <p class="text-xs uppercase tracking-[0.3em] text-slate-400">Kings of the hill</p>
+        <div class="grid gap-2 sm:grid-cols-2 lg:grid-cols-3">${cards}</div>
+    </div>`;
+}
+
 export function buildTabsMarkup(competitions = [], selected = "") {
     return competitions
         .map(function(comp) {
@@ -278,6 +320,14 @@ export function buildTableMarkup(selected, canManage) {
         ? ""
         : "<p class=\"text-[0.7rem] text-amber-200 bg-amber-500/10 border border-amber-500/20 rounded-2xl px-3 py-1\">Scoring is currently paused for this competition. Results will not update until scoring resumes.</p>";
 
+    const freezeNotice = selected.frozen
+        ? `<p class="text-[0.7rem] text-sky-200 bg-sky-500/10 border border-sky-500/20 rounded-2xl px-3 py-1">${
+              selected.snapshot
+                  ? `The scoreboard is frozen as of ${escapeHTML(new Date(selected.frozenAt).toLocaleString())}. Final results are revealed when the competition ends.`
+                  : "The public scoreboard is frozen. You are seeing live scores."
+          }</p>`
+        : "";
+
     const scoringControls =
         canManage && selected
             ? `<div class="flex items-center gap-2 justify-end">
@@ -307,6 +357,8 @@ export function buildTableMarkup(selected, canManage) {
             </div>
         </div>
         ${scoringNotice}
+        ${freezeNotice}
+        ${renderHillsMarkup(selected)}
         <div class="overflow-x-auto">
             <table class="min-w-full text-left">
                 <thead>
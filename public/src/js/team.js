@@ -0,0 +1,223 @@
+import { escapeHTML } from "./shared/utils.js";
+
+const list = document.getElementById("team-list");
+const emptyState = document.getElementById("team-empty");
+const REFRESH_INTERVAL = 30_000;
+let activeRedeployStreams = 0;
+
+function describeRedeployAllowance(redeploy = {}) {
+    if (!redeploy.enabled) {
+        return "Self-service redeploys are disabled.";
+    }
+
+    const parts = [];
+    parts.push(redeploy.remaining < 0 ? "Unlimited redeploys" : `${redeploy.remaining} redeploy${redeploy.remaining === 1 ? "" : "s"} left`);
+    if (redeploy.penaltyPoints > 0) {
+        parts.push(`each costs ${redeploy.penaltyPoints} point${redeploy.penaltyPoints === 1 ? "" : "s"}`);
+    }
+    if (redeploy.cooldownRemainingSeconds > 0) {
+        parts.push(`available again in ${redeploy.cooldownRemainingSeconds}s`);
+    }
+    return parts.join(" · ");
+}
+
+function canRedeploy(redeploy = {}) {
+    return redeploy.enabled && redeploy.remaining !== 0 && !(redeploy.cooldownRemainingSeconds > 0);
+}
+
+function renderContainerRows(containers = [], redeploy = {}) {
+    if (!containers.length) {
+        return `<tr><td class="px-3 py-4 text-center text-slate-300" colspan="5">No containers have been provisioned yet.</td></tr>`;
+    }
+
+    const allowed = canRedeploy(redeploy);
+
+    return containers
+        .map(function(ct) {
+            const credentials = ct.username
+                ? `<span class="font-mono">${escapeHTML(ct.username)}</span> / <span class="font-mono">${escapeHTML(ct.password || "")}</span>`
+                : "—";
+            return `<tr class="border-b border-white/5 last:border-b-0">
+                <td class="px-3 py-2 font-semibold text-white">${escapeHTML(ct.containerConfigName || `CT-${ct.id}`)}</td>
+                <td class="px-3 py-2 font-mono text-slate-200">${escapeHTML(ct.ipAddress || "—")}${
+                    ct.ipv6Address ? `<br><span class="text-xs text-slate-400">${escapeHTML(ct.ipv6Address)}</span>` : ""
+                }</td>
+                <td class="px-3 py-2 text-slate-300">${escapeHTML(ct.status || "unknown")}</td>
+                <td class="px-3 py-2 text-slate-200">${credentials}</td>
+                <td class="px-3 py-2 text-right">${
+                    redeploy.enabled
+                        ? `<button type="button" class="rounded-full border border-amber-400/40 px-3 py-1 text-xs text-amber-200 hover:bg-amber-500/10 disabled:opacity-40" data-redeploy-container="${Number(ct.id)}" data-redeploy-penalty="${Number(redeploy.penaltyPoints) || 0}" ${allowed && ct.status !== "redeploying" ? "" : "disabled"}>Redeploy</button>`
+                        : ""
+                }</td>
+            </tr>`;
+        })
+        .join("");
+}
+
+function renderChecks(checks = []) {
+    if (!checks.length) {
+        return `<p class="text-xs text-slate-400">No check results yet.</p>`;
+    }
+
+    return checks
+        .map(function(container) {
+            const items = (container.checks || [])
+                .map(function(check) {
+                    const tone = check.state === "unknown"
+                        ? "border-amber-400/40 bg-amber-500/10 text-amber-200"
+                        : check.passed
+                        ? "border-emerald-400/40 bg-emerald-500/10 text-emerald-200"
+                        : "border-rose-400/40 bg-rose-500/10 text-rose-200";
+                    return `<li class="rounded-xl border px-2 py-1 text-xs ${tone}">${escapeHTML(check.name || check.id)}</li>`;
+                })
+                .join("");
+            return `<div class="space-y-1">
+                <p class="text-xs uppercase tracking-[0.3em] text-slate-400">${escapeHTML(container.name)}</p>
+                <ul class="flex flex-wrap gap-2">${items}</ul>
+            </div>`;
+        })
+        .join("");
+}
+
+function renderTeams(teams = []) {
+    if (!list) {
+        return;
+    }
+
+    if (!teams.length) {
+        list.innerHTML = "";
+        emptyState?.classList.remove("hidden");
+        return;
+    }
+
+    emptyState?.classList.add("hidden");
+    list.innerHTML = teams
+        .map(function(team) {
+            const rank = team.rank ? `#${team.rank} of ${team.teamCount}` : "Unranked";
+            const frozenNotice = team.frozen
+                ? `<p class="text-[0.7rem] text-sky-200 bg-sky-500/10 border border-sky-500/20 rounded-2xl px-3 py-1">The scoreboard is frozen; your score and checks reflect the freeze.</p>`
+                : "";
+            return `<section class="rounded-3xl border border-white/10 bg-slate-900/60 p-5 sm:p-7 space-y-4">
+                <div class="flex flex-col gap-3 md:flex-row md:items-start md:justify-between">
+                    <div>
+                        <p class="text-xs uppercase tracking-[0.4em] text-slate-400">${escapeHTML(team.competitionName)}</p>
+                        <h2 class="text-2xl font-semibold text-white">${escapeHTML(team.teamName)}</h2>
+                        <p class="text-xs text-slate-300">Network: ${escapeHTML(team.networkCIDR || "Not assigned")}</p>
+                        <p class="text-xs text-slate-300">Members: ${escapeHTML((team.members || []).join(", ") || "—")}</p>
+                    </div>
+                    <div class="text-right">
+                        <p class="text-3xl font-bold text-white">${Number(team.score) || 0}</p>
+                        <p class="text-xs text-slate-400">${rank} · ${team.scoringActive ? "Scoring active" : "Scoring paused"}</p>
+                    </div>
+                </div>
+                ${frozenNotice}
+                ${
+                    team.claimToken
+                        ? `<div class="rounded-2xl border border-white/10 bg-white/5 px-3 py-2">
+                    <p class="text-xs uppercase tracking-[0.3em] text-slate-400">Claim token</p>
+                    <p class="font-mono text-sm text-white break-all">${escapeHTML(team.claimToken)}</p>
+                </div>`
+                        : ""
+                }
+                <div class="overflow-x-auto rounded-2xl border border-white/5 bg-white/5">
+                    <table class="min-w-full text-left text-sm">
+                        <thead>
+                            <tr class="text-xs uppercase tracking-[0.3em] text-slate-400">
+                                <th class="px-3 py-2">Container</th>
+                                <th class="px-3 py-2">Address</th>
+                                <th class="px-3 py-2">Status</th>
+                                <th class="px-3 py-2">Login</th>
+                                <th class="px-3 py-2"></th>
+                            </tr>
+                        </thead>
+                        <tbody>${renderContainerRows(team.containers || [], team.redeploy || {})}</tbody>
+                    </table>
+                </div>
+                <p class="text-xs text-slate-400">${escapeHTML(describeRedeployAllowance(team.redeploy || {}))}</p>
+                <pre class="hidden max-h-48 overflow-y-auto rounded-2xl border border-white/10 bg-black/40 p-3 text-xs text-slate-200" data-redeploy-log></pre>
+                <div class="space-y-2">${renderChecks(team.checks || [])}</div>
+            </section>`;
+        })
+        .join("");
+}
+
+async function loadTeams() {
+    try {
+        const response = await fetch("/api/team", { credentials: "include" });
+        if (!response.ok) {
+            throw new Error("Failed to load team");
+        }
+        const data = await response.json();
+        renderTeams(data.teams || []);
+    } catch (error) {
+        console.error(error);
+        if (emptyState) {
+            emptyState.textContent = "We couldn't load your team right now.";
+            emptyState.classList.remove("hidden");
+        }
+    }
+}
+
+function followRedeployJob(jobID, logElement) {
+    if (logElement) {
+        logElement.textContent = "";
+        logElement.classList.remove("hidden");
+    }
+
+    activeRedeployStreams += 1;
+    const source = new EventSource(`/api/containers/redeploy/${encodeURIComponent(jobID)}/stream`, { withCredentials: true });
+    source.onmessage = function(event) {
+        if (logElement) {
+            logElement.textContent += `${event.data}\n`;
+            logElement.scrollTop = logElement.scrollHeight;
+        }
+    };
+    source.onerror = function() {
+        source.close();
+        activeRedeployStreams -= 1;
+        loadTeams();
+    };
+}
+
+async function requestRedeploy(button) {
+    const containerID = Number(button.dataset.redeployContainer);
+    const penalty = Number(button.dataset.redeployPenalty) || 0;
+    const warning = penalty > 0 ? ` This costs your team ${penalty} point${penalty === 1 ? "" : "s"}.` : "";
+    if (!window.confirm(`Redeploy CT ${containerID}? Everything on it will be wiped.${warning}`)) {
+        return;
+    }
+
+    button.disabled = true;
+    try {
+        const response = await fetch("/api/team/redeploy", {
+            method: "POST",
+            credentials: "include",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ containerID })
+        });
+        if (!response.ok) {
+            throw new Error((await response.text()) || "Redeploy request failed");
+        }
+        const data = await response.json();
+        followRedeployJob(data.jobID, button.closest("section")?.querySelector("[data-redeploy-log]"));
+    } catch (error) {
+        console.error(error);
+        window.alert(error.message);
+        button.disabled = false;
+    }
+}
+
+list?.addEventListener("click", function(event) {
+    const button = event.target.closest("[data-redeploy-container]");
+    if (button) {
+        requestRedeploy(button);
+    }
+});
+
+loadTeams();
+setInterval(function() {
+    // Re-rendering would wipe a redeploy log that is still streaming.
+    if (activeRedeployStreams === 0) {
+        loadTeams();
+    }
+}, REFRESH_INTERVAL);
@@ -1,6 +1,14 @@
 package ssh
 
-import "fmt"
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf16"
+)
 
 func SetEnvs(envs map[string]any) (result string) {
 	for k, v := range envs {
@@ -25,3 +33,47 @@ func LoadAndRunScript(scriptURL, accessToken string, envs map[string]any) (fullC
 	fullCommandlet = fmt.Sprintf("%s| %sbash -s --", download, envPrefix)
 	return fullCommandlet
 }
+
+// PowerShellQuote wraps s in single quotes for PowerShell, which also treats the typographic single quotes as quotes.
+func PowerShellQuote(s string) string {
+	return "'" + strings.NewReplacer("'", "''", "‘", "‘‘", "’", "’’", "‚", "‚‚", "‛", "‛‛").Replace(s) + "'"
+}
+
+// SetPowerShellEnvs returns PowerShell statements that set envs in the current process, so scripts it starts inherit
+// them.
+func SetPowerShellEnvs(envs map[string]any) (result string) {
+	for _, k := range slices.Sorted(maps.Keys(envs)) {
+		result += fmt.Sprintf("[Environment]::SetEnvironmentVariable(%s, %s)\n", PowerShellQuote(k), PowerShellQuote(fmt.Sprint(envs[k])))
+	}
+
+	return
+}
+
+// LoadAndRunPowerShellScript is LoadAndRunScript for Windows guests. It downloads the script to a temporary .ps1 file,
+// runs it with envs set and exits with its exit code. Run it with PowerShellArgs.
+func LoadAndRunPowerShellScript(scriptURL, accessToken string, envs map[string]any) string {
+	return fmt.Sprintf(`[Net.ServicePointManager]::ServerCertificateValidationCallback = { $true }
+[Net.ServicePointManager]::SecurityProtocol = [Net.ServicePointManager]::SecurityProtocol -bor [Net.SecurityProtocolType]::Tls12
+%s$script = Join-Path $env:TEMP ('koth-' + [guid]::NewGuid().ToString() + '.ps1')
+try {
+	$client = New-Object Net.WebClient
+	$client.Headers.Add('Cookie', %s)
+	$client.DownloadFile(%s, $script)
+} catch {
+	[Console]::Error.WriteLine("download failed: $_")
+	exit 1
+}
+try { & $script; exit $LASTEXITCODE } finally { Remove-Item -Force -ErrorAction SilentlyContinue -LiteralPath $script }`, SetPowerShellEnvs(envs), PowerShellQuote("Authorization="+accessToken), PowerShellQuote(scriptURL))
+}
+
+// PowerShellArgs returns the argv that runs a PowerShell command with Windows PowerShell. The command is passed
+// base64-encoded, so no quoting between koth and the guest can mangle it.
+func PowerShellArgs(command string) []string {
+	// Progress bars would otherwise reach stderr as CLIXML.
+	var encoded []byte
+	for _, unit := range utf16.Encode([]rune("$ProgressPreference = 'SilentlyContinue'\n" + command)) {
+		encoded = binary.LittleEndian.AppendUint16(encoded, unit)
+	}
+
+	return []string{"powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-EncodedCommand", base64.StdEncoding.EncodeToString(encoded)}
+}
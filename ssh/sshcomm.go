@@ -2,10 +2,12 @@ package ssh
 
 import (
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 type SSHConnection struct {
@@ -30,12 +32,15 @@ func (conn *SSHConnection) Reset() (err error) {
 	return
 }
 
+// Close ends the session and the connection. A session that already ran its command reports EOF, which is ignored.
 func (conn *SSHConnection) Close() (err error) {
-	if err = conn.session.Close(); err != nil {
-		return
-	}
+	var sessionErr = conn.session.Close()
 
 	err = conn.client.Close()
+	if sessionErr != nil && !strings.Contains(strings.ToLower(sessionErr.Error()), "eof") {
+		err = sessionErr
+	}
+
 	return
 }
 
@@ -65,6 +70,33 @@ func (conn *SSHConnection) SendWithOutput(command string) (status int, output []
 	return
 }
 
+// SendWithStreams runs a command with its stdout and stderr copied to the writers as they arrive, returning the
+// command's exit status.
+func (conn *SSHConnection) SendWithStreams(command string, stdout, stderr io.Writer) (status int, err error) {
+	conn.session.Stdout = stdout
+	conn.session.Stderr = stderr
+
+	if err = conn.session.Run(command); err != nil {
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			return exitErr.ExitStatus(), nil
+		}
+
+		return -1, err
+	}
+
+	return 0, nil
+}
+
+// PrivateKeyAuth is WithPrivateKey for keys that may not parse.
+func PrivateKeyAuth(key []byte) (ssh.AuthMethod, error) {
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH private key: %w", err)
+	}
+
+	return ssh.PublicKeys(signer), nil
+}
+
 func WithPrivateKey(key []byte) ssh.AuthMethod {
 	var (
 		signer ssh.Signer
@@ -92,7 +124,23 @@ func WithKeyboardInteractivePassword(password string) ssh.AuthMethod {
 	})
 }
 
+// KnownHosts returns a host key callback that only accepts the keys an OpenSSH known_hosts file lists for the host.
+func KnownHosts(path string) (ssh.HostKeyCallback, error) {
+	callback, err := knownhosts.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known hosts: %w", err)
+	}
+
+	return callback, nil
+}
+
+// Connect dials host without verifying its host key, for guests koth created itself and has never seen a key of.
 func Connect(username, host string, port int, authMethods ...ssh.AuthMethod) (conn *SSHConnection, err error) {
+	return ConnectVerified(username, host, port, ssh.InsecureIgnoreHostKey(), authMethods...)
+}
+
+// ConnectVerified dials host, refusing the connection unless hostKey accepts the key it presents.
+func ConnectVerified(username, host string, port int, hostKey ssh.HostKeyCallback, authMethods ...ssh.AuthMethod) (conn *SSHConnection, err error) {
 	if len(authMethods) == 0 {
 		return nil, fmt.Errorf("no SSH auth methods provided")
 	}
@@ -106,7 +154,7 @@ func Connect(username, host string, port int, authMethods ...ssh.AuthMethod) (co
 	if conn.client, err = ssh.Dial("tcp", fmt.Sprintf("%s:%d", host, port), &ssh.ClientConfig{
 		User:            username,
 		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKey,
 		Timeout:         5 * time.Second,
 	}); err != nil {
 		return nil, err
@@ -0,0 +1,382 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/gorilla/websocket"
+	"github.com/luthermonson/go-proxmox"
+	"github.com/stretchr/testify/assert"
+)
+
+const consoleTestNode = "127.0.0.1"
+
+var (
+	wrappedCommandPattern = regexp.MustCompile(`wrap_b64='([^']*)'`)
+	innerCommandPattern   = regexp.MustCompile(`cmd_b64='([^']*)'`)
+)
+
+// fakeConsoleServer answers the PVE ticket, termproxy and console websocket endpoints koth's console execution uses.
+// Commands echo themselves; "hang-up" drops the connection and "slow" answers after a pause.
+type fakeConsoleServer struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	issued        map[string]bool
+	rejectRenewal bool
+	logins        int
+	renewals      int
+	consoles      int
+	openConsoles  int
+}
+
+func newFakeConsoleServer(t *testing.T) *fakeConsoleServer {
+	var server = &fakeConsoleServer{issued: make(map[string]bool)}
+
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/api2/json/cluster/status", func(w http.ResponseWriter, r *http.Request) {
+		writeConsoleData(w, []any{})
+	})
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		writeConsoleData(w, []map[string]string{{"node": consoleTestNode, "status": "online"}})
+	})
+	mux.HandleFunc("/api2/json/nodes/"+consoleTestNode+"/status", func(w http.ResponseWriter, r *http.Request) {
+		writeConsoleData(w, map[string]any{})
+	})
+	mux.HandleFunc("/api2/json/access/ticket", server.serveTicket)
+	mux.HandleFunc("/api2/json/nodes/"+consoleTestNode+"/lxc/100/termproxy", func(w http.ResponseWriter, r *http.Request) {
+		if !server.validTicket(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeConsoleData(w, map[string]any{"port": 5900, "ticket": "term-ticket", "user": "root@pam"})
+	})
+	mux.HandleFunc("/api2/json/nodes/"+consoleTestNode+"/lxc/100/vncwebsocket", server.serveConsole)
+
+	server.Server = httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func writeConsoleData(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func (s *fakeConsoleServer) validTicket(r *http.Request) bool {
+	cookie, err := r.Cookie("PVEAuthCookie")
+	if err != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issued[cookie.Value]
+}
+
+func (s *fakeConsoleServer) serveTicket(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	var password = r.PostForm.Get("password")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case password == config.Config.Proxmox.Password:
+		s.logins++
+	case s.issued[password] && !s.rejectRenewal:
+		s.renewals++
+	default:
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var ticket = fmt.Sprintf("PVE:ticket-%d", s.logins+s.renewals)
+	s.issued[ticket] = true
+	writeConsoleData(w, map[string]string{"ticket": ticket, "CSRFPreventionToken": "csrf"})
+}
+
+func (s *fakeConsoleServer) serveConsole(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.consoles++
+	s.openConsoles++
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.openConsoles--
+		s.mu.Unlock()
+	}()
+
+	var send = func(text string) bool {
+		return conn.WriteMessage(websocket.BinaryMessage, []byte(text)) == nil
+	}
+
+	// Terminal handshake
+	if _, _, err = conn.ReadMessage(); err != nil || !send("OK") {
+		return
+	}
+
+	var loggedIn, promptedPassword bool
+	for {
+		_, frame, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		// Only input frames ("0:<length>:<data>") matter; resizes and keepalives are ignored.
+		parts := strings.SplitN(string(frame), ":", 3)
+		if len(parts) != 3 || parts[0] != "0" {
+			continue
+		}
+
+		var input = parts[2]
+		switch {
+		case !loggedIn && input == "\n":
+			send("login: ")
+		case !loggedIn && !promptedPassword:
+			promptedPassword = true
+			send("Password: ")
+		case !loggedIn:
+			loggedIn = true
+			send("root@ct:~# ")
+		default:
+			command := consoleCommand(input)
+			switch command {
+			case "hang-up":
+				return
+			case "slow":
+				time.Sleep(300 * time.Millisecond)
+			}
+
+			send(fmt.Sprintf("__KOTH_BEGIN__\n__KOTH_RC=0__\nran %s\n__KOTH_SPLIT__\n\n__KOTH_DONE__\nroot@ct:~# ", command))
+		}
+	}
+}
+
+// consoleCommand unwraps the command koth's console wrapper carries.
+func consoleCommand(input string) string {
+	wrapper := wrappedCommandPattern.FindStringSubmatch(input)
+	if wrapper == nil {
+		return ""
+	}
+
+	script, _ := base64.StdEncoding.DecodeString(wrapper[1])
+	inner := innerCommandPattern.FindStringSubmatch(string(script))
+	if inner == nil {
+		return ""
+	}
+
+	command, _ := base64.StdEncoding.DecodeString(inner[1])
+	return string(command)
+}
+
+func (s *fakeConsoleServer) counts() (logins, renewals, consoles, open int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins, s.renewals, s.consoles, s.openConsoles
+}
+
+// consoleTestAPI connects a ProxmoxAPI to server with console pooling enabled.
+func consoleTestAPI(t *testing.T, server *fakeConsoleServer) *proxmoxAPI.ProxmoxAPI {
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("parse server URL: %v", err)
+	}
+
+	config.Config.Proxmox.Hostname = consoleTestNode
+	config.Config.Proxmox.Port = serverURL.Port()
+	config.Config.Proxmox.Username = "koth@pve"
+	config.Config.Proxmox.Password = "proxmox-password"
+	config.Config.Proxmox.ConsoleSessionIdleSeconds = 300
+
+	api, err := proxmoxAPI.InitProxmox()
+	if err != nil {
+		t.Fatalf("connect to fake console server: %v", err)
+	}
+	t.Cleanup(api.Close)
+
+	api.ConnectTimeout = 5 * time.Second
+	api.CommandTimeout = 5 * time.Second
+	return api
+}
+
+func consoleTestContainer() *proxmox.Container {
+	return &proxmox.Container{VMID: 100, Node: consoleTestNode}
+}
+
+func TestConsolePoolReusesLoggedInConsoles(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		server = newFakeConsoleServer(t)
+		api    = consoleTestAPI(t, server)
+	)
+
+	for _, command := range []string{"hostname", "uptime"} {
+		stdout, _, exitCode, err := api.RawExecute(consoleTestContainer(), "root", "guest-password", command)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, exitCode)
+		assert.Equal(t, "ran "+command+"\n", stdout)
+	}
+
+	logins, _, consoles, _ := server.counts()
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, 1, consoles, "the second command runs on the pooled console")
+
+	stats := api.ConsoleStats()
+	assert.Equal(t, int64(1), stats.SessionsOpened)
+	assert.Equal(t, int64(1), stats.SessionsReused)
+	assert.Equal(t, 1, stats.IdleSessions)
+
+	// Another login to the same container gets a console of its own.
+	_, _, _, err := api.RawExecute(consoleTestContainer(), "root", "other-password", "id")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, api.ConsoleStats().IdleSessions)
+
+	api.Close()
+	assert.Equal(t, 0, api.ConsoleStats().IdleSessions)
+	assert.Eventually(t, func() bool {
+		_, _, _, open := server.counts()
+		return open == 0
+	}, 2*time.Second, 20*time.Millisecond, "closing the API closes pooled consoles")
+}
+
+func TestConsoleTicketRenewalAndRelogin(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		server = newFakeConsoleServer(t)
+		api    = consoleTestAPI(t, server)
+	)
+
+	// Log in for every command and renew the ticket on every use after the first.
+	api.SessionIdleTimeout = 0
+	api.TicketRenewAfter = time.Nanosecond
+
+	var run = func() {
+		_, _, _, err := api.RawExecute(consoleTestContainer(), "root", "guest-password", "true")
+		assert.NoError(t, err)
+	}
+
+	run()
+	run()
+
+	logins, renewals, _, _ := server.counts()
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, 1, renewals, "a ticket past its renewal age is renewed with itself")
+	assert.Equal(t, int64(1), api.ConsoleStats().TicketRenewals)
+
+	// A ticket Proxmox will not renew falls back to a full login.
+	server.mu.Lock()
+	server.rejectRenewal = true
+	server.mu.Unlock()
+	run()
+
+	logins, renewals, _, _ = server.counts()
+	assert.Equal(t, 2, logins)
+	assert.Equal(t, 1, renewals)
+
+	stats := api.ConsoleStats()
+	assert.Equal(t, int64(2), stats.TicketLogins)
+	assert.Equal(t, int64(1), stats.TicketRenewals)
+	assert.Equal(t, 0, stats.IdleSessions, "consoles are not pooled with a zero idle timeout")
+}
+
+func TestConsolePoolDiscardsFailedConsoles(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		server = newFakeConsoleServer(t)
+		api    = consoleTestAPI(t, server)
+	)
+
+	_, _, _, err := api.RawExecute(consoleTestContainer(), "root", "guest-password", "hang-up")
+	assert.Error(t, err)
+
+	stats := api.ConsoleStats()
+	assert.Equal(t, int64(1), stats.SessionsDiscarded)
+	assert.Equal(t, 0, stats.IdleSessions, "a console whose command failed is never pooled")
+
+	_, _, _, err = api.RawExecute(consoleTestContainer(), "root", "guest-password", "true")
+	assert.NoError(t, err)
+
+	_, _, consoles, _ := server.counts()
+	assert.Equal(t, 2, consoles, "the next command opens a fresh console")
+	assert.Equal(t, int64(0), api.ConsoleStats().SessionsReused)
+}
+
+func TestConsolePoolKeepsOneConsolePerKey(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		server = newFakeConsoleServer(t)
+		api    = consoleTestAPI(t, server)
+		wg     sync.WaitGroup
+	)
+
+	// Both commands check out a console before either is released.
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _, err := api.RawExecute(consoleTestContainer(), "root", "guest-password", "slow")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	_, _, consoles, _ := server.counts()
+	assert.Equal(t, 2, consoles)
+	assert.Equal(t, 1, api.ConsoleStats().IdleSessions)
+	assert.Eventually(t, func() bool {
+		_, _, _, open := server.counts()
+		return open == 1
+	}, 2*time.Second, 20*time.Millisecond, "the console released second is closed instead of pooled")
+}
+
+func TestConsolePoolLogsInAgainPastMaxAge(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		server = newFakeConsoleServer(t)
+		api    = consoleTestAPI(t, server)
+	)
+
+	api.SessionMaxAge = time.Nanosecond
+
+	for range 2 {
+		_, _, _, err := api.RawExecute(consoleTestContainer(), "root", "guest-password", "true")
+		assert.NoError(t, err)
+	}
+
+	_, _, consoles, _ := server.counts()
+	assert.Equal(t, 2, consoles, "a pooled console past its maximum age is replaced")
+
+	stats := api.ConsoleStats()
+	assert.Equal(t, int64(1), stats.SessionsExpired)
+	assert.Equal(t, int64(0), stats.SessionsReused)
+}
@@ -2,10 +2,12 @@ package tests
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/koth"
 	"github.com/stretchr/testify/assert"
 	"github.com/z46-dev/gomysql"
 )
@@ -135,3 +137,317 @@ func TestDBCrudManyContainer(t *testing.T) {
 		}
 	}
 }
+
+func TestDBScoreHistoryRange(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var base = time.Now().Add(-time.Hour)
+	for round := int64(1); round <= 5; round++ {
+		var recorded = base.Add(time.Duration(round) * time.Minute)
+		for _, compID := range []string{"history-a", "history-b"} {
+			if err := db.ScoreHistory.Insert(&db.ScoreHistoryEntry{
+				Round:          round,
+				CompetitionID:  compID,
+				TeamID:         1,
+				ContainerName:  "web",
+				CheckID:        "http",
+				Passed:         round%2 == 0,
+				PointsAwarded:  int(round),
+				RecordedAt:     recorded,
+				RecordedAtUnix: recorded.Unix(),
+			}); err != nil {
+				t.Fatalf("failed to insert score history entry: %v", err)
+			}
+		}
+	}
+
+	all, err := db.GetScoreHistory("history-a", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("failed to load score history: %v", err)
+	}
+
+	assert.Equal(t, 5, len(all))
+	for idx, entry := range all {
+		assert.Equal(t, "history-a", entry.CompetitionID)
+		assert.Equal(t, int64(idx+1), entry.Round)
+	}
+
+	ranged, err := db.GetScoreHistory("history-a", base.Add(2*time.Minute), base.Add(4*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to load ranged score history: %v", err)
+	}
+
+	assert.Equal(t, 3, len(ranged))
+	assert.Equal(t, int64(2), ranged[0].Round)
+	assert.Equal(t, int64(4), ranged[2].Round)
+}
+
+func TestDBOwnershipCurrent(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		now      = time.Now()
+		released = &db.OwnershipRecord{
+			CompetitionID:  "hill-comp",
+			HillTeamID:     1,
+			ContainerName:  "web",
+			OwnerTeamID:    1,
+			ClaimedRound:   1,
+			ClaimedAt:      now.Add(-2 * time.Minute),
+			ReleasedAt:     now.Add(-time.Minute),
+			ReleasedAtUnix: now.Add(-time.Minute).Unix(),
+		}
+		current = &db.OwnershipRecord{
+			CompetitionID: "hill-comp",
+			HillTeamID:    1,
+			ContainerName: "web",
+			OwnerTeamID:   2,
+			ClaimedRound:  2,
+			ClaimedAt:     now.Add(-time.Minute),
+		}
+	)
+
+	for _, record := range []*db.OwnershipRecord{released, current} {
+		if err := db.Ownership.Insert(record); err != nil {
+			t.Fatalf("failed to insert ownership record: %v", err)
+		}
+	}
+
+	history, err := db.GetOwnershipHistory("hill-comp")
+	if err != nil {
+		t.Fatalf("failed to load ownership history: %v", err)
+	}
+	assert.Equal(t, 2, len(history))
+	assert.Equal(t, int64(1), history[0].OwnerTeamID)
+
+	open, err := db.GetCurrentOwnership("hill-comp")
+	if err != nil {
+		t.Fatalf("failed to load current ownership: %v", err)
+	}
+	assert.Equal(t, 1, len(open))
+	assert.Equal(t, int64(2), open[0].OwnerTeamID)
+}
+
+func TestDBTeamMembership(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		rostered = &db.Team{Name: "Team 1"}
+		grouped  = &db.Team{Name: "Team 2", LDAPGroup: "Blue-Team"}
+	)
+
+	for _, team := range []*db.Team{rostered, grouped} {
+		if err := db.Teams.Insert(team); err != nil {
+			t.Fatalf("failed to insert team: %v", err)
+		}
+	}
+
+	if err := db.SetTeamMembers("roster-comp", rostered.ID, []string{" Alice ", "bob", "alice", ""}); err != nil {
+		t.Fatalf("failed to set team members: %v", err)
+	}
+
+	members, err := db.GetTeamMembers(rostered.ID)
+	if err != nil {
+		t.Fatalf("failed to load team members: %v", err)
+	}
+	assert.Equal(t, 2, len(members))
+	assert.Equal(t, "alice", members[0].Username)
+	assert.Equal(t, "bob", members[1].Username)
+
+	teamIDs, err := db.GetUserTeamIDs("ALICE", []string{"blue-team"})
+	if err != nil {
+		t.Fatalf("failed to resolve user teams: %v", err)
+	}
+	assert.Equal(t, []int64{rostered.ID, grouped.ID}, teamIDs)
+
+	teamIDs, err = db.GetUserTeamIDs("carol", []string{"red-team"})
+	if err != nil {
+		t.Fatalf("failed to resolve user teams: %v", err)
+	}
+	assert.Empty(t, teamIDs)
+
+	if err = db.SetTeamMembers("roster-comp", rostered.ID, []string{"bob"}); err != nil {
+		t.Fatalf("failed to replace team members: %v", err)
+	}
+
+	teamIDs, err = db.GetUserTeamIDs("alice", nil)
+	if err != nil {
+		t.Fatalf("failed to resolve user teams: %v", err)
+	}
+	assert.Empty(t, teamIDs)
+}
+
+func TestDBInterruptedJobs(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		started  = time.Now().Add(-time.Minute)
+		running  = &db.Job{ID: "job_running", Kind: "provision", Owner: "alice", Status: db.JobStatusRunning, StartedAt: started, StartedAtUnix: started.Unix()}
+		finished = &db.Job{ID: "job_finished", Kind: "teardown", Owner: "alice", Status: db.JobStatusCompleted, StartedAt: started, StartedAtUnix: started.Unix() - 1}
+	)
+
+	for _, job := range []*db.Job{running, finished} {
+		if err := db.Jobs.Insert(job); err != nil {
+			t.Fatalf("failed to insert job: %v", err)
+		}
+	}
+
+	for _, message := range []string{"creating containers", "configuring team 1"} {
+		if err := db.JobLogs.Insert(&db.JobLogLine{JobID: running.ID, Message: message, LoggedAt: time.Now()}); err != nil {
+			t.Fatalf("failed to insert job log: %v", err)
+		}
+	}
+
+	interrupted, err := db.FailInterruptedJobs(time.Now())
+	if err != nil {
+		t.Fatalf("failed to reconcile jobs: %v", err)
+	}
+	assert.Equal(t, 1, len(interrupted))
+	assert.Equal(t, running.ID, interrupted[0].ID)
+
+	reloaded, err := db.Jobs.Select(running.ID)
+	if err != nil {
+		t.Fatalf("failed to reload job: %v", err)
+	}
+	assert.Equal(t, db.JobStatusFailed, reloaded.Status)
+	assert.NotEmpty(t, reloaded.Error)
+	assert.False(t, reloaded.FinishedAt.IsZero())
+
+	lines, err := db.GetJobLogs(running.ID)
+	if err != nil {
+		t.Fatalf("failed to load job logs: %v", err)
+	}
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, "creating containers", lines[0].Message)
+
+	jobs, err := db.GetJobs()
+	if err != nil {
+		t.Fatalf("failed to list jobs: %v", err)
+	}
+	assert.Equal(t, 2, len(jobs))
+	assert.Equal(t, running.ID, jobs[0].ID)
+	assert.Equal(t, db.JobStatusCompleted, jobs[1].Status)
+}
+
+func TestDBInterruptedRedeploys(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		stuck   = &db.Container{PVEID: 901, IPAddress: "10.1.1.1", Status: "redeploying"}
+		running = &db.Container{PVEID: 902, IPAddress: "10.1.1.2", Status: "running"}
+	)
+
+	for _, record := range []*db.Container{stuck, running} {
+		if err := db.Containers.Insert(record); err != nil {
+			t.Fatalf("failed to insert container: %v", err)
+		}
+	}
+
+	reset, err := db.ResetInterruptedRedeploys(time.Now())
+	if err != nil {
+		t.Fatalf("failed to reconcile redeploys: %v", err)
+	}
+	if assert.Equal(t, 1, len(reset)) {
+		assert.Equal(t, stuck.PVEID, reset[0].PVEID)
+	}
+
+	for id, status := range map[int64]string{stuck.PVEID: "unknown", running.PVEID: "running"} {
+		reloaded, err := db.Containers.Select(id)
+		if assert.NoError(t, err) && assert.NotNil(t, reloaded) {
+			assert.Equal(t, status, reloaded.Status)
+		}
+	}
+}
+
+func TestProvisioningResumeClaim(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var comp = &db.Competition{SystemID: "resume-comp", Name: "Resume Comp", ProvisioningStatus: db.ProvisioningStatusRunning}
+	if err := db.Competitions.Insert(comp); err != nil {
+		t.Fatalf("failed to insert competition: %v", err)
+	}
+
+	assert.ErrorIs(t, koth.ClaimProvisioningResume(comp), koth.ErrProvisioningNotResumable)
+
+	interrupted, err := koth.FailInterruptedProvisioning()
+	if err != nil {
+		t.Fatalf("failed to reconcile provisioning: %v", err)
+	}
+	assert.Equal(t, []string{comp.SystemID}, interrupted)
+
+	assert.NoError(t, koth.ClaimProvisioningResume(comp))
+	assert.Equal(t, db.ProvisioningStatusRunning, comp.ProvisioningStatus)
+	assert.ErrorIs(t, koth.ClaimProvisioningResume(comp), koth.ErrProvisioningNotResumable)
+}
+
+func TestDBUpdateCompetitionKeepsConcurrentEdits(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var comp = &db.Competition{SystemID: "update-comp", Name: "Update Comp"}
+	if err := db.Competitions.Insert(comp); err != nil {
+		t.Fatalf("failed to insert competition: %v", err)
+	}
+
+	var stale = *comp
+	if _, err := db.UpdateCompetition(comp.ID, func(current *db.Competition) bool {
+		current.ScoringActive = true
+		return true
+	}); err != nil {
+		t.Fatalf("failed to toggle scoring: %v", err)
+	}
+
+	updated, err := db.UpdateCompetition(stale.ID, func(current *db.Competition) bool {
+		current.ScoringRound++
+		return true
+	})
+	if err != nil {
+		t.Fatalf("failed to advance scoring round: %v", err)
+	}
+	assert.True(t, updated.ScoringActive)
+	assert.Equal(t, int64(1), updated.ScoringRound)
+
+	missing, err := db.UpdateCompetition(comp.ID+100, func(*db.Competition) bool { return true })
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestAdjustTeamScoreKeepsConcurrentChanges(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		comp = &db.Competition{SystemID: "adjust-comp", Name: "Adjust Comp"}
+		team = &db.Team{Name: "Team 1", Score: 100}
+	)
+	if err := db.Competitions.Insert(comp); err != nil {
+		t.Fatalf("failed to insert competition: %v", err)
+	}
+	if err := db.Teams.Insert(team); err != nil {
+		t.Fatalf("failed to insert team: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		// Every writer starts from the same stale copy of the team.
+		var stale = *team
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, koth.AdjustTeamScore(comp, &stale, -5, "penalty", "tests"))
+		}()
+	}
+	wg.Wait()
+
+	reloaded, err := db.Teams.Select(team.ID)
+	if err != nil {
+		t.Fatalf("failed to reload team: %v", err)
+	}
+	assert.Equal(t, 0, reloaded.Score)
+}
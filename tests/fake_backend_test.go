@@ -0,0 +1,961 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/koth"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI/proxmoxfake"
+	"github.com/luthermonson/go-proxmox"
+	"github.com/stretchr/testify/assert"
+	"github.com/z46-dev/gomysql"
+)
+
+type silentLog struct{}
+
+func (silentLog) Status(string)           {}
+func (silentLog) Statusf(string, ...any)  {}
+func (silentLog) Errorf(string, ...any)   {}
+func (silentLog) Successf(string, ...any) {}
+
+// fakeCompetitionRequest writes a two-team competition package with one scored "web" container per team. configure
+// functions adjust the request before its config.json is written.
+func fakeCompetitionRequest(t *testing.T, compID string, configure ...func(*db.CreateCompetitionRequest)) *db.CreateCompetitionRequest {
+	var (
+		packageDir = t.TempDir()
+		req        = db.CreateCompetitionRequest{
+			CompetitionID:   compID,
+			CompetitionName: "Fake lifecycle " + compID,
+			NumTeams:        2,
+			ContainerSpecsTemplates: map[string]db.ContainerSpecTemplate{
+				"small": {TemplatePath: "local:vztmpl/ubuntu.tar.zst", StoragePool: "team", RootPassword: "password", StorageSizeGB: 4, MemoryMB: 512, Cores: 1},
+			},
+			TeamContainerConfigs: []db.TeamContainerConfig{{
+				Name:                   "web",
+				LastOctetValue:         10,
+				SetupScript:            []string{"setup.sh"},
+				ScoringScript:          []string{"score.sh"},
+				ContainerSpecsTemplate: "small",
+				ScoringSchema: []db.ScoringCheck{
+					{ID: "http", Name: "HTTP", PassPoints: 5, FailPoints: -1},
+					{ID: "db", Name: "Database", PassPoints: 3, FailPoints: -2},
+				},
+			}},
+		}
+	)
+
+	for _, fn := range configure {
+		fn(&req)
+	}
+
+	if err := os.MkdirAll(filepath.Join(packageDir, "public"), 0755); err != nil {
+		t.Fatalf("create public folder: %v", err)
+	}
+
+	configJSON, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal config.json: %v", err)
+	}
+	if err = os.WriteFile(filepath.Join(packageDir, "config.json"), configJSON, 0644); err != nil {
+		t.Fatalf("write config.json: %v", err)
+	}
+	req.PackagePath = packageDir
+
+	return &req
+}
+
+func teamScores(t *testing.T, comp *db.Competition) map[string]int {
+	var scores = make(map[string]int)
+	for _, teamID := range comp.TeamIDs {
+		team, err := db.Teams.Select(teamID)
+		if assert.NoError(t, err) && assert.NotNil(t, team) {
+			scores[team.Name] = team.Score
+		}
+	}
+
+	return scores
+}
+
+func TestFakeBackendLifecycle(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var fake = proxmoxfake.New("pve1", "pve2")
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var (
+		compID = fmt.Sprintf("fake%d", time.Now().UnixNano()%1000000)
+		req    = fakeCompetitionRequest(t, compID)
+	)
+
+	var team2Host = fmt.Sprintf("koth-%s-team-2-web", compID)
+	fake.OnExec("", "score.sh", proxmoxfake.ExecResult{Stdout: `{"http": true, "db": true}`})
+	fake.OnExec(team2Host, "score.sh", proxmoxfake.ExecResult{Stdout: `{"http": true, "db": false}`})
+
+	// Provisioning
+	comp, err := koth.CreateNewCompWithLogger(req, silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, db.ProvisioningStatusReady, comp.ProvisioningStatus)
+	assert.Len(t, comp.ContainerIDs, 2)
+	for _, id := range comp.ContainerIDs {
+		assert.Equal(t, "stopped", fake.GuestStatus(int(id)), "containers are stopped after provisioning")
+	}
+
+	var setupRuns int
+	for _, exec := range fake.Execs() {
+		if strings.Contains(exec.Command, "setup.sh") {
+			setupRuns++
+		}
+	}
+	assert.Equal(t, 2, setupRuns, "setup runs once per team container")
+
+	// Scoring
+	assert.NoError(t, koth.BulkStartContainers(comp.ContainerIDs))
+
+	comp.ScoringActive = true
+	assert.NoError(t, db.Competitions.Update(comp))
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+
+	assert.Equal(t, map[string]int{"Team 1": 8, "Team 2": 3}, teamScores(t, comp))
+
+	// Teardown
+	assert.NoError(t, koth.TeardownCompetitionWithLogger(comp, silentLog{}))
+	assert.Empty(t, fake.GuestIDs(), "teardown deletes every guest")
+
+	remaining, err := db.GetCompetitionBySystemID(compID)
+	assert.NoError(t, err)
+	assert.Nil(t, remaining)
+}
+
+func TestExecSurvivesPasswordChange(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+	defer func() { config.Config.Proxmox.NodeExec.Enabled = false }()
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var compID = fmt.Sprintf("cred%d", time.Now().UnixNano()%1000000)
+	fake.OnExec("", "score.sh", proxmoxfake.ExecResult{Stdout: `{"http": true, "db": true}`})
+
+	comp, err := koth.CreateNewCompWithLogger(fakeCompetitionRequest(t, compID), silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	assert.NoError(t, koth.BulkStartContainers(comp.ContainerIDs))
+	comp.ScoringActive = true
+	assert.NoError(t, db.Competitions.Update(comp))
+
+	var changed = comp.ContainerIDs[0]
+	assert.NoError(t, fake.ChangeRootPassword(int(changed), "team-owned"))
+
+	credentialError := func() string {
+		record, err := db.Containers.Select(changed)
+		if assert.NoError(t, err) && assert.NotNil(t, record) {
+			return record.ExecCredentialError
+		}
+		return ""
+	}
+
+	// Without node exec, the rejected login is reported against the container.
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+	assert.Contains(t, credentialError(), "console login as root was rejected")
+
+	// With node exec, scoring falls back to pct exec and keeps reporting why.
+	config.Config.Proxmox.NodeExec.Enabled = true
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+	assert.Contains(t, credentialError(), "node exec instead")
+
+	var nodeExecs int
+	for _, exec := range fake.Execs() {
+		if exec.Node && exec.GuestID == int(changed) {
+			nodeExecs++
+		}
+	}
+	assert.Equal(t, 1, nodeExecs, "only the container with the changed password falls back")
+
+	// Once the console accepts the password again, the report clears.
+	assert.NoError(t, fake.ChangeRootPassword(int(changed), ""))
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+	assert.Empty(t, credentialError())
+}
+
+func TestNodeExecClearsCredentialErrors(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+	config.Config.Proxmox.NodeExec.Enabled = true
+	defer func() { config.Config.Proxmox.NodeExec.Enabled = false }()
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var compID = fmt.Sprintf("nex%d", time.Now().UnixNano()%1000000)
+	fake.OnExec("", "score.sh", proxmoxfake.ExecResult{Stdout: `{"http": true, "db": true}`})
+
+	comp, err := koth.CreateNewCompWithLogger(fakeCompetitionRequest(t, compID, func(req *db.CreateCompetitionRequest) {
+		req.TeamContainerConfigs[0].ExecTransport = koth.ExecTransportNode
+	}), silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	var readinessChecks int
+	for _, exec := range fake.Execs() {
+		if exec.Command == "echo KOTH_READY" {
+			readinessChecks++
+			assert.True(t, exec.Node, "readiness is checked over node exec")
+		}
+	}
+	assert.Equal(t, 2, readinessChecks)
+
+	// A rejection recorded while the container still ran over the console.
+	var stale = comp.ContainerIDs[0]
+	record, err := db.Containers.Select(stale)
+	if !assert.NoError(t, err) || !assert.NotNil(t, record) {
+		return
+	}
+	record.ExecCredentialError = "console login as root was rejected"
+	record.ExecCredentialSince = time.Now()
+	assert.NoError(t, db.Containers.Update(record))
+
+	assert.NoError(t, koth.BulkStartContainers(comp.ContainerIDs))
+	comp.ScoringActive = true
+	assert.NoError(t, db.Competitions.Update(comp))
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+
+	record, err = db.Containers.Select(stale)
+	if assert.NoError(t, err) && assert.NotNil(t, record) {
+		assert.Empty(t, record.ExecCredentialError)
+		assert.True(t, record.ExecCredentialSince.IsZero())
+	}
+}
+
+func TestScorerErrorsLeaveChecksUnknown(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var (
+		compID    = fmt.Sprintf("unk%d", time.Now().UnixNano()%1000000)
+		team2Host = fmt.Sprintf("koth-%s-team-2-web", compID)
+		req       = fakeCompetitionRequest(t, compID, func(req *db.CreateCompetitionRequest) {
+			req.UnknownCheckPolicy = koth.UnknownCheckCarry
+		})
+	)
+
+	fake.OnExec("", "score.sh", proxmoxfake.ExecResult{Stdout: `{"http": true, "db": true}`})
+	fake.OnExec(team2Host, "score.sh", proxmoxfake.ExecResult{Stdout: `{"http": true, "db": false}`})
+
+	comp, err := koth.CreateNewCompWithLogger(req, silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	assert.NoError(t, koth.BulkStartContainers(comp.ContainerIDs))
+	comp.ScoringActive = true
+	assert.NoError(t, db.Competitions.Update(comp))
+
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+	assert.Equal(t, map[string]int{"Team 1": 8, "Team 2": 3}, teamScores(t, comp))
+
+	// Team 2's scoring script breaks: its checks are unknown and carry their last results instead of failing.
+	fake.OnExec(team2Host, "score.sh", proxmoxfake.ExecResult{ExitCode: 1, Stderr: "python3: not found"})
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+	assert.Equal(t, map[string]int{"Team 1": 16, "Team 2": 6}, teamScores(t, comp))
+
+	issues, err := db.GetScoringIssues(comp.SystemID, 0)
+	if assert.NoError(t, err) && assert.Len(t, issues, 1) {
+		assert.Equal(t, int64(2), issues[0].Round)
+		assert.Equal(t, "score.sh", issues[0].Source)
+		assert.Equal(t, 2, issues[0].UnknownChecks)
+		assert.Contains(t, issues[0].Message, "exited with code 1")
+	}
+
+	var team2 = comp.TeamIDs[1]
+	results, err := db.ScoreResults.SelectAllWithFilter(gomysql.NewFilter().KeyCmp(db.ScoreResults.FieldBySQLName("team_id"), gomysql.OpEqual, team2))
+	if assert.NoError(t, err) && assert.Len(t, results, 2) {
+		for _, result := range results {
+			assert.Equal(t, db.CheckStateUnknown, result.State, result.CheckID)
+			assert.Equal(t, map[string]string{"http": db.CheckStatePass, "db": db.CheckStateFail}[result.CheckID], result.LastKnownState, result.CheckID)
+		}
+	}
+
+	// Carried results survive consecutive unknown rounds.
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+	assert.Equal(t, 9, teamScores(t, comp)["Team 2"])
+}
+
+func TestScriptIssueOnlyCoversItsChecks(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var (
+		compID = fmt.Sprintf("own%d", time.Now().UnixNano()%1000000)
+		req    = fakeCompetitionRequest(t, compID, func(req *db.CreateCompetitionRequest) {
+			var web = &req.TeamContainerConfigs[0]
+			web.ScoringScript = []string{"score.sh", "extra.sh"}
+			web.ScoringSchema[0].Script = "score.sh"
+			web.ScoringSchema[1].Script = "score.sh"
+			web.ScoringSchema = append(web.ScoringSchema, db.ScoringCheck{ID: "cache", Name: "Cache", PassPoints: 4, FailPoints: -4, Script: "extra.sh"})
+		})
+	)
+
+	// score.sh runs cleanly but leaves db out; extra.sh is broken.
+	fake.OnExec("", "score.sh", proxmoxfake.ExecResult{Stdout: `{"http": true}`})
+	fake.OnExec("", "extra.sh", proxmoxfake.ExecResult{ExitCode: 127})
+
+	comp, err := koth.CreateNewCompWithLogger(req, silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	assert.NoError(t, koth.BulkStartContainers(comp.ContainerIDs))
+	comp.ScoringActive = true
+	assert.NoError(t, db.Competitions.Update(comp))
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+
+	// http passes (5), db fails (-2) and only cache, which extra.sh reports, is unknown (0).
+	assert.Equal(t, map[string]int{"Team 1": 3, "Team 2": 3}, teamScores(t, comp))
+
+	results, err := db.ScoreResults.SelectAllWithFilter(gomysql.NewFilter().KeyCmp(db.ScoreResults.FieldBySQLName("team_id"), gomysql.OpEqual, comp.TeamIDs[0]))
+	if assert.NoError(t, err) && assert.Len(t, results, 3) {
+		var states = make(map[string]string)
+		for _, result := range results {
+			states[result.CheckID] = result.State
+		}
+		assert.Equal(t, map[string]string{"http": db.CheckStatePass, "db": db.CheckStateFail, "cache": db.CheckStateUnknown}, states)
+	}
+
+	issues, err := db.GetScoringIssues(comp.SystemID, 0)
+	if assert.NoError(t, err) && assert.Len(t, issues, 2) {
+		for _, issue := range issues {
+			assert.Equal(t, "extra.sh", issue.Source)
+			assert.Equal(t, 1, issue.UnknownChecks)
+		}
+	}
+}
+
+func TestSharedHillsStayInTheirCompetition(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	fake.OnExec("", "score.sh", proxmoxfake.ExecResult{Stdout: `{"http": true, "db": true}`})
+
+	var comps []*db.Competition
+	for _, prefix := range []string{"sha", "shb"} {
+		req := fakeCompetitionRequest(t, fmt.Sprintf("%s%d", prefix, time.Now().UnixNano()%1000000), func(req *db.CreateCompetitionRequest) {
+			req.SharedContainerConfigs = []db.TeamContainerConfig{{
+				Name:                   "hill",
+				LastOctetValue:         10,
+				ContainerSpecsTemplate: "small",
+				Claim:                  &db.ClaimConfig{Points: 10},
+			}}
+		})
+
+		comp, err := koth.CreateNewCompWithLogger(req, silentLog{})
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+		assert.NoError(t, koth.BulkStartContainers(comp.ContainerIDs))
+		comps = append(comps, comp)
+	}
+
+	// Both competitions have a shared "hill"; the second one's claim must be read from its own container.
+	var (
+		comp       = comps[1]
+		sharedHost = fmt.Sprintf("%s-shared-hill", comp.ContainerRestrictions.HostnamePrefix)
+	)
+
+	team1, err := db.Teams.Select(comp.TeamIDs[0])
+	if !assert.NoError(t, err) || !assert.NotNil(t, team1) {
+		return
+	}
+	fake.OnExec(sharedHost, "head -c", proxmoxfake.ExecResult{Stdout: team1.ClaimToken + "\n"})
+
+	comp.ScoringActive = true
+	assert.NoError(t, db.Competitions.Update(comp))
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+
+	var claimReads int
+	for _, exec := range fake.Execs() {
+		if strings.Contains(exec.Command, "head -c") {
+			claimReads++
+			assert.Equal(t, sharedHost, exec.Hostname)
+		}
+	}
+	assert.Equal(t, 1, claimReads)
+	assert.Equal(t, map[string]int{"Team 1": 18, "Team 2": 8}, teamScores(t, comp))
+
+	history, err := db.GetScoreHistory(comp.SystemID, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+
+	var claimRows int
+	for _, entry := range history {
+		if entry.CheckID == "claim" {
+			claimRows++
+			assert.Equal(t, db.CheckStatePass, entry.State)
+		}
+	}
+	assert.Equal(t, 1, claimRows)
+}
+
+func TestConcurrentCompetitionsGetDistinctVLANTags(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var previous = config.Config.Network
+	defer func() { config.Config.Network = previous }()
+	config.Config.Network.Isolation = db.NetworkIsolationVLAN
+	config.Config.Network.IsolationScope = "team"
+	config.Config.Network.VLANMin, config.Config.Network.VLANMax = 100, 199
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var (
+		comps = make([]*db.Competition, 2)
+		wg    sync.WaitGroup
+	)
+	for idx := range comps {
+		req := fakeCompetitionRequest(t, fmt.Sprintf("vlan%d%d", idx, time.Now().UnixNano()%100000))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			comp, err := koth.CreateNewCompWithLogger(req, silentLog{})
+			if assert.NoError(t, err) {
+				comps[idx] = comp
+			}
+		}()
+	}
+	wg.Wait()
+	if comps[0] == nil || comps[1] == nil {
+		return
+	}
+
+	var seen = make(map[int]string)
+	for _, comp := range comps {
+		assert.Len(t, comp.NetworkSegments, 2)
+		for _, segment := range comp.NetworkSegments {
+			if owner, taken := seen[segment.Tag]; taken {
+				t.Errorf("VLAN tag %d is used by both %s and %s", segment.Tag, owner, comp.SystemID)
+			}
+			seen[segment.Tag] = comp.SystemID
+		}
+	}
+
+	allocations, err := db.VLANAllocations.SelectAll()
+	assert.NoError(t, err)
+	assert.Len(t, allocations, 4)
+
+	// Teardown frees the tags for the next competition.
+	assert.NoError(t, koth.TeardownCompetitionWithLogger(comps[0], silentLog{}))
+	allocations, err = db.VLANAllocations.SelectAll()
+	assert.NoError(t, err)
+	assert.Len(t, allocations, 2)
+	for _, allocation := range allocations {
+		assert.Equal(t, comps[1].SystemID, allocation.CompetitionID)
+	}
+
+	assert.NoError(t, koth.TeardownCompetitionWithLogger(comps[1], silentLog{}))
+}
+
+func TestKeepPartialProvisioningKeepsTeams(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var (
+		compID = fmt.Sprintf("kpt%d", time.Now().UnixNano()%1000000)
+		req    = fakeCompetitionRequest(t, compID, func(req *db.CreateCompetitionRequest) {
+			req.KeepPartialOnFailure = true
+		})
+	)
+
+	fake.OnExec(fmt.Sprintf("koth-%s-team-2-web", compID), "setup.sh", proxmoxfake.ExecResult{ExitCode: 1})
+
+	_, err := koth.CreateNewCompWithLogger(req, silentLog{})
+	assert.Error(t, err)
+
+	comp, err := db.GetCompetitionBySystemID(compID)
+	if !assert.NoError(t, err) || !assert.NotNil(t, comp) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	assert.Equal(t, db.ProvisioningStatusFailed, comp.ProvisioningStatus)
+	assert.Len(t, comp.TeamIDs, 2)
+	assert.Len(t, comp.ContainerIDs, 1)
+	assert.NotEmpty(t, comp.SSHPubKeyPath)
+}
+
+// editingBackend runs edit once, the first time a container is created, to stand in for an admin changing the
+// competition while it provisions.
+type editingBackend struct {
+	*proxmoxfake.Cluster
+	once sync.Once
+	edit func()
+}
+
+func (b *editingBackend) CreateContainer(node *proxmox.Node, conf *proxmoxAPI.ContainerCreateOptions) (*proxmoxAPI.ProxmoxAPICreateResult, error) {
+	b.once.Do(b.edit)
+	return b.Cluster.CreateContainer(node, conf)
+}
+
+func TestProvisioningKeepsConcurrentCompetitionEdits(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var compID = fmt.Sprintf("edit%d", time.Now().UnixNano()%1000000)
+	var backend = &editingBackend{Cluster: proxmoxfake.New(), edit: func() {
+		stored, err := db.GetCompetitionBySystemID(compID)
+		if assert.NoError(t, err) && assert.NotNil(t, stored) {
+			_, err = db.UpdateCompetition(stored.ID, func(current *db.Competition) bool {
+				current.ScoringActive = true
+				current.ScoringRound = 7
+				return true
+			})
+			assert.NoError(t, err)
+		}
+	}}
+	koth.SetBackend(backend)
+	defer koth.SetBackend(nil)
+
+	comp, err := koth.CreateNewCompWithLogger(fakeCompetitionRequest(t, compID), silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	stored, err := db.GetCompetitionBySystemID(compID)
+	if assert.NoError(t, err) && assert.NotNil(t, stored) {
+		assert.Equal(t, db.ProvisioningStatusReady, stored.ProvisioningStatus)
+		assert.Len(t, stored.ContainerIDs, 2)
+		assert.True(t, stored.ScoringActive, "enabling scoring mid-provisioning sticks")
+		assert.Equal(t, int64(7), stored.ScoringRound)
+	}
+
+	var emptyID = fmt.Sprintf("none%d", time.Now().UnixNano()%1000000)
+	empty, err := koth.CreateNewCompWithLogger(fakeCompetitionRequest(t, emptyID, func(req *db.CreateCompetitionRequest) {
+		req.TeamContainerConfigs = nil
+	}), silentLog{})
+	if assert.NoError(t, err) {
+		defer koth.TeardownCompetitionWithLogger(empty, silentLog{})
+
+		stored, err = db.GetCompetitionBySystemID(emptyID)
+		if assert.NoError(t, err) && assert.NotNil(t, stored) {
+			assert.Equal(t, db.ProvisioningStatusReady, stored.ProvisioningStatus)
+			assert.Len(t, stored.TeamIDs, 2, "teams are stored even without containers")
+		}
+	}
+}
+
+func TestCloneTeamContainers(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var fake = proxmoxfake.New("pve1", "pve2")
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var (
+		compID    = fmt.Sprintf("cln%d", time.Now().UnixNano()%1000000)
+		team2Host = fmt.Sprintf("koth-%s-team-2-web", compID)
+		req       = fakeCompetitionRequest(t, compID, func(req *db.CreateCompetitionRequest) {
+			req.CloneTeamContainers = true
+			req.KeepPartialOnFailure = true
+			req.TeamContainerConfigs[0].PersonalizeScript = []string{"personalize.sh"}
+		})
+	)
+
+	// Team 2's first personalization fails, leaving its container to a resume.
+	fake.OnExec(team2Host, "personalize.sh", proxmoxfake.ExecResult{ExitCode: 1, Stderr: "flag server unreachable"})
+
+	_, err := koth.CreateNewCompWithLogger(req, silentLog{})
+	assert.Error(t, err)
+
+	comp, err := db.GetCompetitionBySystemID(compID)
+	if !assert.NoError(t, err) || !assert.NotNil(t, comp) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	assert.Equal(t, db.ProvisioningStatusFailed, comp.ProvisioningStatus)
+	assert.Len(t, comp.ContainerIDs, 1)
+	assert.Len(t, comp.TemplateContainerIDs, 1)
+
+	fake.OnExec(team2Host, "personalize.sh", proxmoxfake.ExecResult{})
+	assert.NoError(t, koth.ClaimProvisioningResume(comp))
+	assert.NoError(t, koth.ResumeCompetitionProvisioningWithLogger(comp, silentLog{}, false))
+
+	assert.Equal(t, db.ProvisioningStatusReady, comp.ProvisioningStatus)
+	assert.Len(t, comp.ContainerIDs, 2)
+	assert.Len(t, comp.TemplateContainerIDs, 1, "the resume clones from the existing template")
+
+	// Setup runs once, on the template and without a team identity; personalization gets each team's.
+	var (
+		templateHost = fmt.Sprintf("koth-%s-template-web", compID)
+		setupRuns    int
+		personalized = make(map[string]int)
+	)
+	for _, exec := range fake.Execs() {
+		switch {
+		case strings.Contains(exec.Command, "setup.sh"):
+			setupRuns++
+			assert.Equal(t, templateHost, exec.Hostname)
+			assert.Contains(t, exec.Command, `KOTH_TEMPLATE="1"`)
+			assert.NotContains(t, exec.Command, "KOTH_TEAM_ID=")
+			assert.NotContains(t, exec.Command, "KOTH_IP=")
+		case strings.Contains(exec.Command, "personalize.sh"):
+			personalized[exec.Hostname]++
+			assert.NotContains(t, exec.Command, "KOTH_TEMPLATE=")
+			for teamIndex, teamID := range comp.TeamIDs {
+				if exec.Hostname == fmt.Sprintf("koth-%s-team-%d-web", compID, teamIndex+1) {
+					assert.Contains(t, exec.Command, fmt.Sprintf(`KOTH_TEAM_ID="%d"`, teamID))
+				}
+			}
+		}
+	}
+	assert.Equal(t, 1, setupRuns)
+	assert.Equal(t, map[string]int{fmt.Sprintf("koth-%s-team-1-web", compID): 1, team2Host: 2}, personalized)
+}
+
+func TestClonesFollowPlacement(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var fake = proxmoxfake.New("pve1", "pve2")
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var req = fakeCompetitionRequest(t, fmt.Sprintf("plc%d", time.Now().UnixNano()%1000000), func(req *db.CreateCompetitionRequest) {
+		req.CloneTeamContainers = true
+	})
+
+	comp, err := koth.CreateNewCompWithLogger(req, silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	// The fake places round robin: the template lands on one node and the two clones on both.
+	var nodes = make(map[string]bool)
+	for _, id := range comp.ContainerIDs {
+		record, err := db.Containers.Select(id)
+		if assert.NoError(t, err) && assert.NotNil(t, record) {
+			nodes[record.NodeName] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{"pve1": true, "pve2": true}, nodes)
+}
+
+func TestHalfFinishedContainersAreResumed(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var (
+		compID    = fmt.Sprintf("hlf%d", time.Now().UnixNano()%1000000)
+		team2Host = fmt.Sprintf("koth-%s-team-2-web", compID)
+		req       = fakeCompetitionRequest(t, compID, func(req *db.CreateCompetitionRequest) {
+			req.KeepPartialOnFailure = true
+			req.Firewall.Enabled = true
+		})
+	)
+
+	// Team 2's container is set up, but its firewall cannot be applied.
+	fake.FailGuestFirewall(team2Host, fmt.Errorf("firewall API unavailable"))
+
+	_, err := koth.CreateNewCompWithLogger(req, silentLog{})
+	assert.Error(t, err)
+
+	comp, err := db.GetCompetitionBySystemID(compID)
+	if !assert.NoError(t, err) || !assert.NotNil(t, comp) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	assert.Equal(t, db.ProvisioningStatusFailed, comp.ProvisioningStatus)
+	assert.Len(t, comp.ContainerIDs, 1, "the half-finished container is not recorded")
+	assert.Len(t, fake.GuestIDs(), 1, "and it is deleted")
+
+	fake.FailGuestFirewall(team2Host, nil)
+	assert.NoError(t, koth.ClaimProvisioningResume(comp))
+	assert.NoError(t, koth.ResumeCompetitionProvisioningWithLogger(comp, silentLog{}, false))
+	assert.Len(t, comp.ContainerIDs, 2)
+
+	for _, id := range comp.ContainerIDs {
+		record, err := db.Containers.Select(id)
+		if assert.NoError(t, err) && assert.NotNil(t, record) {
+			assert.Equal(t, "stopped", record.Status)
+			assert.Equal(t, "stopped", fake.GuestStatus(int(id)))
+		}
+	}
+}
+
+func TestEarlyCreationFailureLeavesNothingBehind(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+	koth.SetBackend(nil)
+
+	var compID = fmt.Sprintf("erl%d", time.Now().UnixNano()%1000000)
+	_, err := koth.CreateNewCompWithLogger(fakeCompetitionRequest(t, compID), silentLog{})
+	assert.ErrorContains(t, err, "proxmox API is not initialized")
+
+	comp, err := db.GetCompetitionBySystemID(compID)
+	assert.NoError(t, err)
+	assert.Nil(t, comp, "the competition record is removed")
+
+	allocations, err := db.NetworkAllocations.SelectAll()
+	assert.NoError(t, err)
+	for _, allocation := range allocations {
+		assert.NotEqual(t, compID, allocation.CompetitionID, "address blocks are released")
+	}
+}
+
+func TestVMsFollowPlacement(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var fake = proxmoxfake.New("pve1", "pve2")
+	fake.AddTemplateVM(9000, "pve1", "debian-cloud", "l26")
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var req = fakeCompetitionRequest(t, fmt.Sprintf("vmp%d", time.Now().UnixNano()%1000000), func(req *db.CreateCompetitionRequest) {
+		req.ContainerSpecsTemplates["vm"] = db.ContainerSpecTemplate{StoragePool: "team", RootPassword: "password", MemoryMB: 1024, Cores: 1, VMTemplateID: 9000}
+		req.TeamContainerConfigs[0].Kind = "vm"
+		req.TeamContainerConfigs[0].ContainerSpecsTemplate = "vm"
+	})
+
+	comp, err := koth.CreateNewCompWithLogger(req, silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	// The template sits on pve1, but the fake places round robin, so one clone lands on each node.
+	var nodes = make(map[string]bool)
+	for _, id := range comp.ContainerIDs {
+		record, err := db.Containers.Select(id)
+		if assert.NoError(t, err) && assert.NotNil(t, record) {
+			assert.Equal(t, proxmoxAPI.GuestKindVM, record.Kind)
+			nodes[record.NodeName] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{"pve1": true, "pve2": true}, nodes)
+}
+
+func TestWindowsVMsUsePowerShell(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+
+	var fake = proxmoxfake.New()
+	fake.AddTemplateVM(9001, "pve", "windows-server", "win11")
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var windowsVM = func(transport string) func(*db.CreateCompetitionRequest) {
+		return func(req *db.CreateCompetitionRequest) {
+			req.ContainerSpecsTemplates["windows"] = db.ContainerSpecTemplate{StoragePool: "team", RootPassword: "password", MemoryMB: 4096, Cores: 2, VMTemplateID: 9001}
+			req.TeamContainerConfigs[0].Kind = "vm"
+			req.TeamContainerConfigs[0].ContainerSpecsTemplate = "windows"
+			req.TeamContainerConfigs[0].ExecTransport = transport
+			req.TeamContainerConfigs[0].SetupScript = []string{"setup.ps1"}
+			req.TeamContainerConfigs[0].ScoringScript = []string{"score.ps1"}
+			req.TeamContainerConfigs[0].Claim = &db.ClaimConfig{Path: `C:\koth\king.txt`, Points: 10}
+		}
+	}
+
+	var compID = fmt.Sprintf("win%d", time.Now().UnixNano()%1000000)
+	comp, err := koth.CreateNewCompWithLogger(fakeCompetitionRequest(t, compID, windowsVM("")), silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	var setupRuns int
+	for _, exec := range fake.Execs() {
+		if strings.Contains(exec.Command, "setup.ps1") {
+			setupRuns++
+			assert.Contains(t, exec.Command, "$client.DownloadFile(")
+			assert.Contains(t, exec.Command, "[Environment]::SetEnvironmentVariable('KOTH_TEAM_ID', ")
+			assert.NotContains(t, exec.Command, "bash -s")
+		}
+	}
+	assert.Equal(t, 2, setupRuns)
+
+	team1, err := db.Teams.Select(comp.TeamIDs[0])
+	if !assert.NoError(t, err) || !assert.NotNil(t, team1) {
+		return
+	}
+
+	fake.OnExec("", "score.ps1", proxmoxfake.ExecResult{Stdout: `{"http": true, "db": true}`})
+	fake.OnExec(fmt.Sprintf("koth-%s-team-1-web", compID), "IO.StreamReader('C:\\koth\\king.txt')", proxmoxfake.ExecResult{Stdout: team1.ClaimToken + "\r\n"})
+
+	assert.NoError(t, koth.BulkStartContainers(comp.ContainerIDs))
+	comp.ScoringActive = true
+	assert.NoError(t, db.Competitions.Update(comp))
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+
+	assert.Equal(t, map[string]int{"Team 1": 18, "Team 2": 8}, teamScores(t, comp))
+
+	// Windows VMs have no root to SSH in as.
+	_, err = koth.CreateNewCompWithLogger(fakeCompetitionRequest(t, compID+"s", windowsVM(koth.ExecTransportSSH)), silentLog{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Windows VM")
+	}
+}
+
+func TestHostScoringScriptsGetAMinimalEnvironment(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+	t.Setenv("PVE_KOTH_SECRET", "hunter2")
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var req = fakeCompetitionRequest(t, fmt.Sprintf("env%d", time.Now().UnixNano()%1000000), func(req *db.CreateCompetitionRequest) {
+		req.TeamContainerConfigs[0].ScoringRunner = koth.ScoringRunnerHost
+	})
+
+	// http passes when koth's own environment is hidden, db when the script still gets its KOTH_* variables and PATH.
+	var script = `hidden=false; [ -z "$PVE_KOTH_SECRET" ] && hidden=true
+given=false; [ -n "$KOTH_IP" ] && command -v sh >/dev/null && given=true
+echo "{\"http\": $hidden, \"db\": $given}"
+`
+	if err := os.WriteFile(filepath.Join(req.PackagePath, "score.sh"), []byte(script), 0644); err != nil {
+		t.Fatalf("write score.sh: %v", err)
+	}
+
+	comp, err := koth.CreateNewCompWithLogger(req, silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	assert.NoError(t, koth.BulkStartContainers(comp.ContainerIDs))
+	comp.ScoringActive = true
+	assert.NoError(t, db.Competitions.Update(comp))
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+
+	assert.Equal(t, map[string]int{"Team 1": 8, "Team 2": 8}, teamScores(t, comp))
+}
+
+func TestHostScoringScriptsCannotOutliveTheirTimeout(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+	config.Config.Scoring.HostTimeoutSeconds = 1
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var req = fakeCompetitionRequest(t, fmt.Sprintf("hto%d", time.Now().UnixNano()%1000000), func(req *db.CreateCompetitionRequest) {
+		req.TeamContainerConfigs[0].ScoringRunner = koth.ScoringRunnerHost
+	})
+
+	// The background sleep keeps the script's stdout open long after the script itself exits.
+	var writeScript = func(dir, body string) {
+		if err := os.WriteFile(filepath.Join(dir, "score.sh"), []byte("sleep 20 &\n"+body), 0644); err != nil {
+			t.Fatalf("write score.sh: %v", err)
+		}
+	}
+	writeScript(req.PackagePath, `echo '{"http": true, "db": true}'`)
+
+	comp, err := koth.CreateNewCompWithLogger(req, silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer koth.TeardownCompetitionWithLogger(comp, silentLog{})
+
+	assert.NoError(t, koth.BulkStartContainers(comp.ContainerIDs))
+	comp.ScoringActive = true
+	assert.NoError(t, db.Competitions.Update(comp))
+
+	var started = time.Now()
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+	assert.Less(t, time.Since(started), 10*time.Second, "a script that exited is not waited on for its children")
+	assert.Equal(t, map[string]int{"Team 1": 8, "Team 2": 8}, teamScores(t, comp))
+
+	writeScript(comp.PackageStoragePath, `echo '{"http": true, "db": true}'; sleep 20`)
+
+	started = time.Now()
+	assert.NoError(t, koth.ScoreCompetitionOnce(comp))
+	assert.Less(t, time.Since(started), 10*time.Second, "host_timeout_seconds bounds the script")
+	assert.Equal(t, map[string]int{"Team 1": 8, "Team 2": 8}, teamScores(t, comp), "timed out checks are unknown and earn nothing")
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/koth"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI/proxmoxfake"
+	"github.com/luthermonson/go-proxmox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeFirewallPolicy(t *testing.T) {
+	policy, err := koth.NormalizeFirewallPolicy(db.FirewallPolicy{
+		Enabled:        true,
+		DefaultInbound: "drop",
+		Rules: []db.FirewallRule{
+			{Peer: "Teams", Protocol: "TCP", Ports: "22, 80"},
+			{Direction: "out", Action: "reject", Peer: "10.0.0.0/8"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "DROP", policy.DefaultInbound)
+	assert.Equal(t, "ACCEPT", policy.DefaultOutbound)
+	assert.Equal(t, db.FirewallRule{Direction: "in", Action: "ACCEPT", Peer: db.FirewallPeerTeams, Protocol: "tcp", Ports: "22,80"}, policy.Rules[0])
+	assert.Equal(t, "REJECT", policy.Rules[1].Action)
+
+	for _, rule := range []db.FirewallRule{
+		{Direction: "sideways"},
+		{Action: "ALLOW"},
+		{Peer: "everyone"},
+		{Ports: "22"},
+		{Protocol: "tcp", Ports: "ssh"},
+	} {
+		_, err = koth.NormalizeFirewallPolicy(db.FirewallPolicy{Rules: []db.FirewallRule{rule}})
+		assert.Error(t, err, "%+v", rule)
+	}
+}
+
+// firewallVerdict walks a guest's rules the way Proxmox does, expanding the security group and IPSets, and returns
+// the action of the first rule matching traffic from or to peer on a tcp port, or fallback when none does.
+func firewallVerdict(fake *proxmoxfake.Cluster, rules []*proxmox.FirewallRule, direction, peer string, port int, fallback string) string {
+	var addressMatches = func(address string) bool {
+		var cidrs = []string{address}
+		if set, ok := strings.CutPrefix(address, "+"); ok {
+			cidrs, _ = fake.IPSet(set)
+		}
+
+		for _, cidr := range cidrs {
+			if !strings.Contains(cidr, "/") {
+				cidr += "/32"
+			}
+			if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(net.ParseIP(peer)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var portMatches = func(ports string) bool {
+		for _, part := range strings.Split(ports, ",") {
+			low, high, isRange := strings.Cut(part, ":")
+			if !isRange {
+				high = low
+			}
+			if from, _ := strconv.Atoi(low); from <= port {
+				if to, _ := strconv.Atoi(high); port <= to {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, rule := range rules {
+		if rule.Type == "group" {
+			group, _ := fake.SecurityGroup(rule.Action)
+			if verdict := firewallVerdict(fake, group, direction, peer, port, ""); verdict != "" {
+				return verdict
+			}
+			continue
+		}
+
+		var address = rule.Source
+		if direction == "out" {
+			address = rule.Dest
+		}
+
+		if rule.Type != direction ||
+			(address != "" && !addressMatches(address)) ||
+			(rule.Proto != "" && rule.Proto != "tcp") ||
+			(rule.Dport != "" && !portMatches(rule.Dport)) {
+			continue
+		}
+
+		return rule.Action
+	}
+
+	return fallback
+}
+
+func TestFirewallRulesReachGuests(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	config.Config.Storage.BasePath = t.TempDir()
+	config.Config.Firewall.ScorerSources = []string{"192.0.2.10"}
+	config.Config.Firewall.AllowedEgress = []string{"198.51.100.0/24"}
+
+	var fake = proxmoxfake.New()
+	koth.SetBackend(fake)
+	defer koth.SetBackend(nil)
+
+	var compID = fmt.Sprintf("fw%d", time.Now().UnixNano()%1000000)
+	comp, err := koth.CreateNewCompWithLogger(fakeCompetitionRequest(t, compID, func(req *db.CreateCompetitionRequest) {
+		req.Firewall, _ = koth.NormalizeFirewallPolicy(db.FirewallPolicy{
+			Enabled:             true,
+			DefaultInbound:      "DROP",
+			BlockInternetEgress: true,
+			Rules: []db.FirewallRule{
+				{Peer: db.FirewallPeerScorer, Protocol: "tcp", Ports: "9100", Comment: "exporter"},
+				{Action: "DROP", Protocol: "tcp", Ports: "9100"},
+				{Peer: db.FirewallPeerOwnTeam, Protocol: "tcp", Ports: "22"},
+				{Peer: db.FirewallPeerTeams, Protocol: "tcp", Ports: "80,8000:8100"},
+			},
+		})
+	}), silentLog{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var addresses = make(map[int64]string)
+	var guestID int
+	for _, id := range comp.ContainerIDs {
+		record, err := db.Containers.Select(id)
+		if assert.NoError(t, err) && assert.NotNil(t, record) {
+			addresses[record.TeamID] = record.IPAddress
+			if record.TeamID == comp.TeamIDs[0] {
+				guestID = int(record.PVEID)
+			}
+		}
+	}
+
+	rules, ok := fake.GuestFirewall(guestID)
+	if !assert.True(t, ok) || !assert.NotEmpty(t, rules) {
+		return
+	}
+	assert.Equal(t, "koth-managed exporter", func() string {
+		group, _ := fake.SecurityGroup(rules[len(rules)-1].Action)
+		return group[0].Comment
+	}(), "group rules keep the policy's order")
+
+	var (
+		scorer    = "192.0.2.10"
+		ownTeam   = addresses[comp.TeamIDs[0]]
+		otherTeam = addresses[comp.TeamIDs[1]]
+	)
+
+	for _, tc := range []struct {
+		direction, peer string
+		port            int
+		verdict         string
+	}{
+		{"in", scorer, 9100, "ACCEPT"},
+		{"in", ownTeam, 9100, "DROP"},
+		{"in", otherTeam, 9100, "DROP"},
+		{"in", ownTeam, 22, "ACCEPT"},
+		{"in", otherTeam, 22, "DROP"},
+		{"in", otherTeam, 80, "ACCEPT"},
+		{"in", otherTeam, 8080, "ACCEPT"},
+		{"in", scorer, 80, "DROP"},
+		{"out", otherTeam, 443, "ACCEPT"},
+		{"out", "198.51.100.7", 443, "ACCEPT"},
+		{"out", config.Config.Network.ContainerNameserver, 53, "ACCEPT"},
+		{"out", "203.0.113.1", 443, "DROP"},
+	} {
+		var fallback = comp.Firewall.DefaultOutbound
+		if tc.direction == "in" {
+			fallback = comp.Firewall.DefaultInbound
+		}
+		assert.Equal(t, tc.verdict, firewallVerdict(fake, rules, tc.direction, tc.peer, tc.port, fallback), "%s %s:%d", tc.direction, tc.peer, tc.port)
+	}
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/koth"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/UNHCSC/pve-koth/ssh"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeGuestKind(t *testing.T) {
+	for raw, want := range map[string]string{
+		"":          proxmoxAPI.GuestKindContainer,
+		"LXC":       proxmoxAPI.GuestKindContainer,
+		"container": proxmoxAPI.GuestKindContainer,
+		"vm":        proxmoxAPI.GuestKindVM,
+		" qemu ":    proxmoxAPI.GuestKindVM,
+		"kvm":       proxmoxAPI.GuestKindVM,
+	} {
+		kind, err := proxmoxAPI.NormalizeGuestKind(raw)
+		assert.NoError(t, err, raw)
+		assert.Equal(t, want, kind, raw)
+	}
+
+	_, err := proxmoxAPI.NormalizeGuestKind("docker")
+	assert.Error(t, err)
+}
+
+func TestValidateGuestKind(t *testing.T) {
+	var (
+		lxcSpec = db.ContainerSpecTemplate{TemplatePath: "local:vztmpl/ubuntu.tar.zst"}
+		vmSpec  = db.ContainerSpecTemplate{VMTemplateID: 9000}
+	)
+
+	assert.NoError(t, koth.ValidateGuestKind("", lxcSpec))
+	assert.NoError(t, koth.ValidateGuestKind("vm", vmSpec))
+	assert.Error(t, koth.ValidateGuestKind("vm", lxcSpec))
+	assert.Error(t, koth.ValidateGuestKind("lxc", vmSpec))
+	assert.Error(t, koth.ValidateGuestKind("docker", lxcSpec))
+}
+
+func TestNormalizeExecTransport(t *testing.T) {
+	for raw, want := range map[string]string{
+		"":        koth.ExecTransportConsole,
+		"Console": koth.ExecTransportConsole,
+		" ssh ":   koth.ExecTransportSSH,
+	} {
+		transport, err := koth.NormalizeExecTransport(raw)
+		assert.NoError(t, err, raw)
+		assert.Equal(t, want, transport, raw)
+	}
+
+	_, err := koth.NormalizeExecTransport("telnet")
+	assert.Error(t, err)
+
+	_, err = koth.NormalizeExecTransport("node")
+	assert.Error(t, err, "node exec must be enabled in config.toml")
+}
+
+func TestPctExecCommand(t *testing.T) {
+	assert.Equal(t, `pct exec 105 -- /bin/sh -c 'echo '\''hi'\'''`, proxmoxAPI.PctExecCommand(105, "echo 'hi'", false))
+	assert.Equal(t, "sudo -n pct exec 7 -- /bin/sh -c 'id'", proxmoxAPI.PctExecCommand(7, "id", true))
+}
+
+func TestWindowsCloudInitOptions(t *testing.T) {
+	var conf = &proxmoxAPI.VMCreateOptions{RootPassword: "password", MemoryMB: 2048, Cores: 2}
+
+	var settings = func(osType string) map[string]any {
+		var values = make(map[string]any)
+		for _, opt := range conf.CloudInitOptions(osType) {
+			values[opt.Name] = opt.Value
+		}
+		return values
+	}
+
+	var linux, windows = settings("l26"), settings("win11")
+	assert.Equal(t, "root", linux["ciuser"])
+	assert.NotContains(t, linux, "citype")
+	assert.Equal(t, "Administrator", windows["ciuser"])
+	assert.Equal(t, "configdrive2", windows["citype"], "cloudbase-init reads a config drive")
+	assert.Equal(t, "password", windows["cipassword"])
+
+	assert.True(t, proxmoxAPI.IsWindowsOSType("win10"))
+	assert.True(t, proxmoxAPI.IsWindowsOSType(" W2K8 "))
+	assert.False(t, proxmoxAPI.IsWindowsOSType("l26"))
+	assert.False(t, proxmoxAPI.IsWindowsOSType(""))
+}
+
+func TestPowerShellArgs(t *testing.T) {
+	var argv = ssh.PowerShellArgs("Write-Output 'héllo'")
+	if !assert.Len(t, argv, 7) {
+		return
+	}
+	assert.Equal(t, []string{"powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-EncodedCommand"}, argv[:6])
+
+	raw, err := base64.StdEncoding.DecodeString(argv[6])
+	if !assert.NoError(t, err) || !assert.Equal(t, 0, len(raw)%2) {
+		return
+	}
+
+	var units = make([]uint16, 0, len(raw)/2)
+	for idx := 0; idx < len(raw); idx += 2 {
+		units = append(units, binary.LittleEndian.Uint16(raw[idx:]))
+	}
+	assert.Equal(t, "$ProgressPreference = 'SilentlyContinue'\nWrite-Output 'héllo'", string(utf16.Decode(units)))
+
+	assert.Equal(t, `'it''s'`, ssh.PowerShellQuote("it's"))
+	assert.Equal(t, "[Environment]::SetEnvironmentVariable('A', '1')\n[Environment]::SetEnvironmentVariable('B', 'x''y')\n", ssh.SetPowerShellEnvs(map[string]any{"B": "x'y", "A": 1}))
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/koth"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerExtraInterfaces(t *testing.T) {
+	opts := &proxmoxAPI.ContainerCreateOptions{
+		GatewayIPv4: "10.128.1.1",
+		IPv4Address: "10.128.1.10",
+		CIDRBlock:   24,
+		Interfaces: []proxmoxAPI.NetworkInterface{
+			{Bridge: "vmbr1", IPv4Address: "192.168.50.10", CIDRBlock: 24, GatewayIPv4: "192.168.50.1"},
+			{Bridge: "vmbr2", VLANTag: 30, DHCP: true},
+		},
+	}
+
+	values := map[string]any{}
+	for _, opt := range opts.NetworkOptions() {
+		values[opt.Name] = opt.Value
+	}
+
+	assert.Equal(t, "name=eth0,bridge=vmbr0,firewall=1,ip=10.128.1.10/24", values["net0"], "eth0 gives up the default route")
+	assert.Equal(t, "name=eth1,bridge=vmbr1,firewall=1,gw=192.168.50.1,ip=192.168.50.10/24", values["net1"])
+	assert.Equal(t, "name=eth2,bridge=vmbr2,tag=30,firewall=1,ip=dhcp", values["net2"])
+}
+
+func TestValidateContainerInterfaces(t *testing.T) {
+	valid := db.TeamContainerConfig{Name: "router", Interfaces: []db.ContainerInterface{
+		{Name: "wan", Network: "shared", Offset: 100, Gateway: true},
+		{Network: "10.99.0.0/24", Bridge: "vmbr1", Offset: 10},
+		{Network: "team", DHCP: true},
+	}}
+	assert.NoError(t, koth.ValidateContainerInterfaces(valid))
+
+	cases := map[string][]db.ContainerInterface{
+		"cidr without bridge": {{Network: "10.99.0.0/24", Offset: 10}},
+		"missing offset":      {{Network: "team"}},
+		"bad network":         {{Network: "attack", Offset: 5}},
+		"two gateways":        {{Offset: 5, Gateway: true}, {Name: "b", Offset: 6, Gateway: true}},
+		"duplicate names":     {{Name: "db", Offset: 5}, {Name: "db", Offset: 6}},
+		"dhcp gateway":        {{DHCP: true, Gateway: true}},
+	}
+	for name, ifaces := range cases {
+		assert.Error(t, koth.ValidateContainerInterfaces(db.TeamContainerConfig{Name: "web", Interfaces: ifaces}), name)
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"net"
+	"testing"
+
+	"github.com/UNHCSC/pve-koth/koth"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustCIDR(t *testing.T, cidr string) *net.IPNet {
+	_, block, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatalf("parse %s: %v", cidr, err)
+	}
+	return block
+}
+
+func TestCompetitionPrefixForTeams(t *testing.T) {
+	prefix, err := koth.CompetitionPrefixForTeams(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 23, prefix, "one team plus the shared subnet fit in two /24s")
+
+	prefix, err = koth.CompetitionPrefixForTeams(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 22, prefix)
+
+	prefix, err = koth.CompetitionPrefixForTeams(12)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, prefix)
+
+	_, err = koth.CompetitionPrefixForTeams(1000)
+	assert.Error(t, err, "more teams than the largest allowed block holds")
+}
+
+func TestNextFreeBlock(t *testing.T) {
+	pool := mustCIDR(t, "10.128.0.0/16")
+
+	block, err := koth.NextFreeBlock(pool, 22, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.128.0.0/22", block.String())
+
+	allocated := []*net.IPNet{mustCIDR(t, "10.128.0.0/23"), mustCIDR(t, "10.128.4.0/22")}
+	block, err = koth.NextFreeBlock(pool, 23, allocated)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.128.2.0/23", block.String(), "fills the gap before the /22")
+
+	block, err = koth.NextFreeBlock(pool, 22, allocated)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.128.8.0/22", block.String(), "skips the partly used first /22")
+
+	_, err = koth.NextFreeBlock(mustCIDR(t, "10.128.0.0/22"), 22, allocated)
+	assert.Error(t, err, "pool is full")
+
+	block, err = koth.NextFreeBlock(mustCIDR(t, "fd00::/48"), 56, []*net.IPNet{mustCIDR(t, "fd00::/56")})
+	assert.NoError(t, err)
+	assert.Equal(t, "fd00:0:0:100::/56", block.String())
+}
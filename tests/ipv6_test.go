@@ -0,0 +1,20 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerNetworkValueDualStack(t *testing.T) {
+	opts := &proxmoxAPI.ContainerCreateOptions{
+		GatewayIPv4: "10.128.1.1",
+		IPv4Address: "10.128.1.10",
+		CIDRBlock:   24,
+		GatewayIPv6: "fd00:6b6f:7468:1::1",
+		IPv6Address: "fd00:6b6f:7468:1::a",
+		CIDRBlock6:  64,
+	}
+	assert.Equal(t, "name=eth0,bridge=vmbr0,firewall=1,gw=10.128.1.1,ip=10.128.1.10/24,gw6=fd00:6b6f:7468:1::1,ip6=fd00:6b6f:7468:1::a/64", opts.NetworkValue())
+}
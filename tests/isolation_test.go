@@ -0,0 +1,27 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/UNHCSC/pve-koth/koth"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextFreeVLANTags(t *testing.T) {
+	tags, err := koth.NextFreeVLANTags(map[int]struct{}{100: {}, 102: {}}, 100, 110, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{101, 103, 104}, tags)
+
+	_, err = koth.NextFreeVLANTags(map[int]struct{}{100: {}}, 100, 101, 2)
+	assert.Error(t, err, "range only has one free tag")
+}
+
+func TestContainerNetworkValue(t *testing.T) {
+	opts := &proxmoxAPI.ContainerCreateOptions{GatewayIPv4: "10.128.1.1", IPv4Address: "10.128.1.10", CIDRBlock: 24}
+	assert.Equal(t, "name=eth0,bridge=vmbr0,firewall=1,gw=10.128.1.1,ip=10.128.1.10/24", opts.NetworkValue())
+
+	opts.Bridge = "vmbr1"
+	opts.VLANTag = 120
+	assert.Equal(t, "name=eth0,bridge=vmbr1,tag=120,firewall=1,gw=10.128.1.1,ip=10.128.1.10/24", opts.NetworkValue())
+}
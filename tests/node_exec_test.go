@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/luthermonson/go-proxmox"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startFakeNodeSSH serves SSH on a local port with hostKey, answering every exec request with "ran <command>". It
+// returns the port.
+func startFakeNodeSSH(t *testing.T, hostKey ssh.Signer, clientKey ssh.PublicKey) int {
+	var serverConfig = &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, fmt.Errorf("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	serverConfig.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				_, channels, requests, err := ssh.NewServerConn(conn, serverConfig)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(requests)
+
+				for newChannel := range channels {
+					channel, channelRequests, err := newChannel.Accept()
+					if err != nil {
+						continue
+					}
+
+					go func() {
+						defer channel.Close()
+						for req := range channelRequests {
+							if req.Type != "exec" {
+								_ = req.Reply(false, nil)
+								continue
+							}
+
+							var command = string(req.Payload[4:])
+							_ = req.Reply(true, nil)
+							_, _ = fmt.Fprintf(channel, "ran %s", command)
+							_, _ = channel.SendRequest("exit-status", false, binary.BigEndian.AppendUint32(nil, 0))
+							return
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func newSSHKey(t *testing.T) (ed25519.PrivateKey, ssh.Signer) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+
+	return private, signer
+}
+
+func TestNodeExecVerifiesHostKeys(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		dir                = t.TempDir()
+		clientKey, client  = newSSHKey(t)
+		_, hostKey         = newSSHKey(t)
+		_, impostorHostKey = newSSHKey(t)
+		port               = startFakeNodeSSH(t, hostKey, client.PublicKey())
+		impostorPort       = startFakeNodeSSH(t, impostorHostKey, client.PublicKey())
+	)
+
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	var keyPath, knownHostsPath = filepath.Join(dir, "id_ed25519"), filepath.Join(dir, "known_hosts")
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	// Both servers listen on 127.0.0.1, so known_hosts pins each port to its key; the impostor's port lists the real key.
+	var knownHosts = strings.Join([]string{
+		knownhosts.Line([]string{knownhosts.Normalize(fmt.Sprintf("127.0.0.1:%d", port))}, hostKey.PublicKey()),
+		knownhosts.Line([]string{knownhosts.Normalize(fmt.Sprintf("127.0.0.1:%d", impostorPort))}, hostKey.PublicKey()),
+	}, "\n")
+	if err = os.WriteFile(knownHostsPath, []byte(knownHosts+"\n"), 0600); err != nil {
+		t.Fatalf("write known_hosts: %v", err)
+	}
+
+	var settings = &config.Config.Proxmox.NodeExec
+	settings.Enabled = true
+	settings.User = "root"
+	settings.PrivateKeyPath = keyPath
+	settings.KnownHostsPath = knownHostsPath
+	settings.Hosts = map[string]string{"pve1": "127.0.0.1"}
+
+	var (
+		api   = &proxmoxAPI.ProxmoxAPI{}
+		guest = proxmoxAPI.ContainerGuest(&proxmox.Container{VMID: 105, Node: "pve1"})
+	)
+
+	settings.Port = port
+	stdout, _, exitCode, err := api.NodeExecute(guest, "hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "ran "+proxmoxAPI.PctExecCommand(105, "hostname", false), stdout)
+
+	settings.Port = impostorPort
+	_, _, _, err = api.NodeExecute(guest, "hostname")
+	assert.Error(t, err, "a node presenting a different host key is refused")
+
+	if err = os.WriteFile(knownHostsPath, nil, 0600); err != nil {
+		t.Fatalf("empty known_hosts: %v", err)
+	}
+
+	settings.Port = port
+	_, _, _, err = api.NodeExecute(guest, "hostname")
+	assert.Error(t, err, "a node missing from known_hosts is refused")
+
+	settings.KnownHostsPath = filepath.Join(dir, "missing")
+	_, _, _, err = api.NodeExecute(guest, "hostname")
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/UNHCSC/pve-koth/config"
+	"github.com/UNHCSC/pve-koth/proxmoxAPI"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChooseNode(t *testing.T) {
+	_, ok := proxmoxAPI.ChooseNode(nil, proxmoxAPI.PlacementRequest{})
+	assert.False(t, ok, "no nodes means no placement")
+
+	stats := []proxmoxAPI.NodeStats{
+		{Name: "pve1", FreeMemoryMB: 2048, TotalMemoryMB: 32768, CPUUsage: 0.1, FreeStorageGB: 500, TotalStorageGB: 1000, StorageKnown: true},
+		{Name: "pve2", FreeMemoryMB: 24576, TotalMemoryMB: 32768, CPUUsage: 0.4, FreeStorageGB: 400, TotalStorageGB: 1000, StorageKnown: true},
+		{Name: "pve3", FreeMemoryMB: 30000, TotalMemoryMB: 32768, CPUUsage: 0.2, FreeStorageGB: 5, TotalStorageGB: 1000, StorageKnown: true},
+	}
+
+	name, ok := proxmoxAPI.ChooseNode(stats, proxmoxAPI.PlacementRequest{MemoryMB: 1024, StorageSizeGB: 8})
+	assert.True(t, ok)
+	assert.Equal(t, "pve2", name, "pve3 lacks storage for the container")
+
+	name, _ = proxmoxAPI.ChooseNode(stats, proxmoxAPI.PlacementRequest{MemoryMB: 1024, StorageSizeGB: 2})
+	assert.Equal(t, "pve3", name, "node with the most free memory wins when everything fits")
+
+	stats[2].StorageKnown = false
+	name, _ = proxmoxAPI.ChooseNode(stats, proxmoxAPI.PlacementRequest{MemoryMB: 1024, StorageSizeGB: 8})
+	assert.Equal(t, "pve3", name, "unknown storage does not rule a node out")
+
+	name, ok = proxmoxAPI.ChooseNode(stats[:1], proxmoxAPI.PlacementRequest{MemoryMB: 4096})
+	assert.True(t, ok, "an overcommitted node is still used when it is the only one")
+	assert.Equal(t, "pve1", name)
+}
+
+func TestPlacementKeepsReservationsMadeBeforeStats(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	// pve1 has slightly more free memory, so it wins unless the container pinned there first is counted against it.
+	var free = map[string]uint64{"pve1": 9 << 30, "pve2": 8 << 30}
+
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/api2/json/cluster/status", func(w http.ResponseWriter, r *http.Request) {
+		writeConsoleData(w, []any{})
+	})
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		writeConsoleData(w, []map[string]string{{"node": "pve1", "status": "online"}, {"node": "pve2", "status": "online"}})
+	})
+	for name, bytes := range free {
+		mux.HandleFunc("/api2/json/nodes/"+name+"/status", func(w http.ResponseWriter, r *http.Request) {
+			writeConsoleData(w, map[string]any{"cpu": 0.1, "memory": map[string]uint64{"free": bytes, "total": 32 << 30}})
+		})
+	}
+
+	var server = httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("parse server URL: %v", err)
+	}
+
+	config.Config.Proxmox.Hostname = serverURL.Hostname()
+	config.Config.Proxmox.Port = serverURL.Port()
+	config.Config.Provisioning.Placement = proxmoxAPI.PlacementBalanced
+
+	api, err := proxmoxAPI.InitProxmox()
+	if err != nil {
+		t.Fatalf("connect to fake cluster: %v", err)
+	}
+	defer api.Close()
+
+	node, err := api.PlaceContainer(proxmoxAPI.PlacementRequest{PinnedNode: "pve1", MemoryMB: 4096})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "pve1", node.Name)
+	}
+
+	node, err = api.PlaceContainer(proxmoxAPI.PlacementRequest{MemoryMB: 1024})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "pve2", node.Name, "the pinned container's memory is still reserved on pve1")
+	}
+}
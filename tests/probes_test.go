@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/koth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	check := db.ScoringCheck{ID: "tcp", Type: "tcp", Probe: &db.ScoringProbe{Port: port, TimeoutSeconds: 1}}
+
+	passed, err := koth.RunProbe(check, "127.0.0.1")
+	assert.True(t, passed)
+	assert.NoError(t, err)
+
+	listener.Close()
+
+	passed, err = koth.RunProbe(check, "127.0.0.1")
+	assert.False(t, passed)
+	assert.Error(t, err)
+}
+
+func TestProbeHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.html" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "<h1>Welcome to Team Site</h1>")
+	}))
+	defer server.Close()
+
+	host, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	probe := &db.ScoringProbe{Port: port, Path: "/index.html", BodyRegex: "Welcome to .* Site", TimeoutSeconds: 1}
+	passed, err := koth.RunProbe(db.ScoringCheck{ID: "content", Type: "http", Probe: probe}, host)
+	assert.True(t, passed)
+	assert.NoError(t, err)
+
+	probe.BodyRegex = "defaced"
+	passed, _ = koth.RunProbe(db.ScoringCheck{ID: "content", Type: "http", Probe: probe}, host)
+	assert.False(t, passed)
+
+	missing := &db.ScoringProbe{Port: port, Path: "/missing", TimeoutSeconds: 1}
+	passed, _ = koth.RunProbe(db.ScoringCheck{ID: "content", Type: "http", Probe: missing}, host)
+	assert.False(t, passed)
+}
+
+func TestValidateScoringCheck(t *testing.T) {
+	assert.NoError(t, koth.ValidateScoringCheck(db.ScoringCheck{ID: "script"}))
+	assert.NoError(t, koth.ValidateScoringCheck(db.ScoringCheck{ID: "ping", Type: "icmp"}))
+	assert.NoError(t, koth.ValidateScoringCheck(db.ScoringCheck{ID: "web", Type: "http"}), "http falls back to its defaults")
+	assert.NoError(t, koth.ValidateScoringCheck(db.ScoringCheck{ID: "dns", Type: "dns", Probe: &db.ScoringProbe{RecordName: "www.cyber.lab", RecordType: "A"}}))
+
+	assert.Error(t, koth.ValidateScoringCheck(db.ScoringCheck{ID: "bogus", Type: "gopher"}))
+	assert.Error(t, koth.ValidateScoringCheck(db.ScoringCheck{ID: "tcp", Type: "tcp"}))
+	assert.Error(t, koth.ValidateScoringCheck(db.ScoringCheck{ID: "dns", Type: "dns"}))
+	assert.Error(t, koth.ValidateScoringCheck(db.ScoringCheck{ID: "dns", Type: "dns", Probe: &db.ScoringProbe{}}))
+	assert.Error(t, koth.ValidateScoringCheck(db.ScoringCheck{ID: "web", Type: "http", Probe: &db.ScoringProbe{BodyRegex: "("}}))
+}
+
+// serveFakeSMTP answers one SMTP session per connection with greeting and an EHLO reply advertising extension.
+func serveFakeSMTP(t *testing.T, greeting, extension string) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				var text = textproto.NewConn(conn)
+				text.PrintfLine("220 %s", greeting)
+				for {
+					line, err := text.ReadLine()
+					if err != nil {
+						return
+					}
+					switch verb := strings.ToUpper(strings.Fields(line + " ")[0]); verb {
+					case "EHLO":
+						text.PrintfLine("250-mail.team.lab")
+						text.PrintfLine("250 %s", extension)
+					case "QUIT":
+						text.PrintfLine("221 bye")
+						return
+					default:
+						text.PrintfLine("502 unsupported")
+					}
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestProbeSMTPExpect(t *testing.T) {
+	var port = serveFakeSMTP(t, "mail.team.lab ESMTP Postfix", "8BITMIME")
+
+	for expect, pass := range map[string]bool{
+		"":          true,
+		"Postfix":   true,  // greeting
+		"8BITMIME":  true,  // EHLO reply
+		"Exim":      false, // neither
+		"^250 SIZE": false,
+	} {
+		probe := &db.ScoringProbe{Port: port, Expect: expect, TimeoutSeconds: 1}
+		passed, err := koth.RunProbe(db.ScoringCheck{ID: "mail", Type: "smtp", Probe: probe}, "127.0.0.1")
+		assert.Equal(t, pass, passed, "expect %q: %v", expect, err)
+	}
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/koth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfRedeployPolicy(t *testing.T) {
+	assert.NoError(t, koth.ValidateSelfRedeployPolicy(db.SelfRedeployPolicy{Enabled: true, CooldownSeconds: 300, MaxPerTeam: 2, PenaltyPoints: 50}))
+	assert.Error(t, koth.ValidateSelfRedeployPolicy(db.SelfRedeployPolicy{CooldownSeconds: -1}))
+	assert.Error(t, koth.ValidateSelfRedeployPolicy(db.SelfRedeployPolicy{MaxPerTeam: -1}))
+	assert.Error(t, koth.ValidateSelfRedeployPolicy(db.SelfRedeployPolicy{PenaltyPoints: -10}))
+
+	var (
+		now     = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		policy  = db.SelfRedeployPolicy{Enabled: true, CooldownSeconds: 300, MaxPerTeam: 2, PenaltyPoints: 50}
+		history = []*db.TeamRedeploy{{RequestedAt: now.Add(-time.Hour)}, {RequestedAt: now.Add(-2 * time.Minute)}}
+	)
+
+	status := koth.EvaluateSelfRedeploy(policy, nil, now)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, 2, status.Remaining)
+	assert.Equal(t, 0, status.CooldownRemainingSeconds)
+
+	status = koth.EvaluateSelfRedeploy(policy, history[:1], now)
+	assert.Equal(t, 1, status.Remaining)
+	assert.Equal(t, 0, status.CooldownRemainingSeconds)
+
+	status = koth.EvaluateSelfRedeploy(policy, history, now)
+	assert.Equal(t, 0, status.Remaining)
+	assert.Equal(t, 180, status.CooldownRemainingSeconds)
+	assert.Equal(t, 50, status.PenaltyPoints)
+
+	policy.MaxPerTeam = 0
+	status = koth.EvaluateSelfRedeploy(policy, history, now)
+	assert.Equal(t, -1, status.Remaining)
+}
+
+func TestSelfRedeployClaimsContainerOnce(t *testing.T) {
+	setup(t)
+	defer cleanup(t)
+
+	var (
+		team      = &db.Team{Name: "Team 1"}
+		container = &db.Container{PVEID: 701, IPAddress: "10.0.1.10", Status: "running"}
+	)
+	if err := db.Teams.Insert(team); err != nil {
+		t.Fatalf("failed to insert team: %v", err)
+	}
+
+	container.TeamID = team.ID
+	if err := db.Containers.Insert(container); err != nil {
+		t.Fatalf("failed to insert container: %v", err)
+	}
+
+	var comp = &db.Competition{
+		SystemID:     "redeploy",
+		Name:         "Redeploy",
+		TeamIDs:      []int64{team.ID},
+		SelfRedeploy: db.SelfRedeployPolicy{Enabled: true, PenaltyPoints: 5},
+	}
+	if err := db.Competitions.Insert(comp); err != nil {
+		t.Fatalf("failed to insert competition: %v", err)
+	}
+
+	// With no cooldown or limit, only the container's own redeploy state stops a second request.
+	var (
+		wg               sync.WaitGroup
+		mu               sync.Mutex
+		claimed, refused int
+	)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := koth.ClaimSelfRedeploy(comp, &db.Team{ID: team.ID, Name: team.Name}, container.PVEID, "player", time.Now())
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				claimed++
+			} else if assert.True(t, errors.Is(err, koth.ErrContainerRedeploying), err) {
+				refused++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, claimed)
+	assert.Equal(t, 4, refused)
+
+	reloaded, err := db.Teams.Select(team.ID)
+	if assert.NoError(t, err) && assert.NotNil(t, reloaded) {
+		assert.Equal(t, -5, reloaded.Score, "the penalty is deducted once")
+	}
+
+	record, err := db.Containers.Select(container.PVEID)
+	if assert.NoError(t, err) && assert.NotNil(t, record) {
+		assert.Equal(t, "redeploying", record.Status)
+	}
+
+	history, err := db.GetTeamRedeploys(team.ID)
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/UNHCSC/pve-koth/db"
+	"github.com/UNHCSC/pve-koth/koth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoringSchedule(t *testing.T) {
+	interval, jitter, err := koth.NormalizeScoringSchedule(0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, koth.DefaultScoringIntervalSeconds, interval)
+	assert.Equal(t, 0, jitter)
+
+	_, _, err = koth.NormalizeScoringSchedule(5, 0)
+	assert.Error(t, err)
+
+	_, _, err = koth.NormalizeScoringSchedule(30, 30)
+	assert.Error(t, err)
+
+	_, _, err = koth.NormalizeScoringSchedule(30, -1)
+	assert.Error(t, err)
+
+	var lowest = func(n int) int { return 0 }
+	var highest = func(n int) int { return n - 1 }
+
+	assert.Equal(t, 90*time.Second, koth.ScoringDelay(120, 30, lowest))
+	assert.Equal(t, 150*time.Second, koth.ScoringDelay(120, 30, highest))
+	assert.Equal(t, 120*time.Second, koth.ScoringDelay(120, 0, highest))
+	assert.Equal(t, time.Minute, koth.ScoringDelay(1, 0, nil))
+}
+
+func TestCompetitionWindow(t *testing.T) {
+	var (
+		start = time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+		end   = start.Add(4 * time.Hour)
+	)
+
+	assert.NoError(t, koth.ValidateCompetitionWindow(time.Time{}, time.Time{}, 0))
+	assert.NoError(t, koth.ValidateCompetitionWindow(start, end, 30))
+	assert.Error(t, koth.ValidateCompetitionWindow(end, start, 0))
+	assert.Error(t, koth.ValidateCompetitionWindow(start, time.Time{}, 30))
+	assert.Error(t, koth.ValidateCompetitionWindow(start, end, 5*60))
+	assert.Error(t, koth.ValidateCompetitionWindow(start, end, -1))
+
+	var comp = &db.Competition{StartsAt: start, EndsAt: end, FreezeMinutes: 30}
+	assert.Equal(t, end.Add(-30*time.Minute), koth.ScoreboardFreezeStart(comp))
+	assert.False(t, koth.ScoreboardFrozen(comp, end.Add(-31*time.Minute)))
+	assert.True(t, koth.ScoreboardFrozen(comp, end.Add(-30*time.Minute)))
+	assert.True(t, koth.ScoreboardFrozen(comp, end.Add(-time.Second)))
+	assert.False(t, koth.ScoreboardFrozen(comp, end))
+
+	comp.FreezeMinutes = 0
+	assert.False(t, koth.ScoreboardFrozen(comp, end.Add(-time.Minute)))
+}
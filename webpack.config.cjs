@@ -10,7 +10,8 @@ module.exports = (env = {}, argv = {}) => {
         entry: {
             global: "./public/src/js/global.js",
             dashboard: "./public/src/js/dashboard.js",
-            scoreboard: "./public/src/js/scoreboard.js"
+            scoreboard: "./public/src/js/scoreboard.js",
+            team: "./public/src/js/team.js"
         },
         output: {
             filename: "[name].js",